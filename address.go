@@ -0,0 +1,52 @@
+package eip2771toolkit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ParseAddress parses a hex address from user input. Unlike common.HexToAddress, which
+// truncates or zero-pads malformed input into a wrong address, it refuses anything but 40 hex
+// digits with ErrInvalidAddress, and mixed-case input with a wrong EIP-55 checksum. With
+// strict, the 0x prefix and a valid checksum are required, so all-lowercase input, which
+// carries no checksum, is refused too.
+func ParseAddress(s string, strict bool) (common.Address, error) {
+	digits, prefixed := strings.CutPrefix(s, "0x")
+	if !prefixed {
+		digits, prefixed = strings.CutPrefix(s, "0X")
+	}
+	if strict && !prefixed {
+		return common.Address{}, fmt.Errorf("%w: %q has no 0x prefix", ErrInvalidAddress, s)
+	}
+	if len(digits) != 2*common.AddressLength {
+		return common.Address{}, fmt.Errorf("%w: %q has %d hex digits, want %d", ErrInvalidAddress, s, len(digits), 2*common.AddressLength)
+	}
+	if !isHexDigits(digits) {
+		return common.Address{}, fmt.Errorf("%w: %q is not hex", ErrInvalidAddress, s)
+	}
+	address := common.HexToAddress(digits)
+	// The expected checksum is not reported: for a mistyped address it is that of the typo
+	if digits != address.Hex()[2:] {
+		mixedCase := digits != strings.ToLower(digits) && digits != strings.ToUpper(digits)
+		if mixedCase {
+			return common.Address{}, fmt.Errorf("%w: %q fails its EIP-55 checksum", ErrInvalidAddress, s)
+		}
+		if strict {
+			return common.Address{}, fmt.Errorf("%w: %q is not EIP-55 checksummed", ErrInvalidAddress, s)
+		}
+	}
+	return address, nil
+}
+
+// isHexDigits reports whether s consists of hex digits only
+func isHexDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+package eip2771toolkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AddressBook maps labels such as "usdc" or "base-forwarder" to addresses, so configuration
+// files and commands can name forwarders, tokens and relayers instead of repeating hex.
+// Labels are case-insensitive. It is stored as a JSON object of label to address.
+type AddressBook struct {
+	entries map[string]common.Address
+}
+
+// NewAddressBook creates an empty address book
+func NewAddressBook() *AddressBook {
+	return &AddressBook{entries: make(map[string]common.Address)}
+}
+
+// LoadAddressBook reads the address book file at path
+func LoadAddressBook(path string) (*AddressBook, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open address book: %w", err)
+	}
+	defer f.Close()
+	return ReadAddressBook(f)
+}
+
+// ReadAddressBook decodes an address book
+func ReadAddressBook(r io.Reader) (*AddressBook, error) {
+	var raw map[string]string
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to read address book: %w", err)
+	}
+	book := NewAddressBook()
+	for label, value := range raw {
+		address, err := ParseAddress(value, false)
+		if err != nil {
+			return nil, fmt.Errorf("address book entry %q: %w", label, err)
+		}
+		if err := book.Set(label, address); err != nil {
+			return nil, err
+		}
+	}
+	return book, nil
+}
+
+// Write encodes the address book with labels sorted and checksummed addresses
+func (b *AddressBook) Write(w io.Writer) error {
+	raw := make(map[string]string, len(b.entries))
+	for label, address := range b.entries {
+		raw[label] = address.Hex()
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(raw); err != nil {
+		return fmt.Errorf("failed to write address book: %w", err)
+	}
+	return nil
+}
+
+// Save writes the address book to path, replacing the file only once it is complete
+func (b *AddressBook) Save(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save address book: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save address book: %w", err)
+	}
+	if err := b.Write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save address book: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save address book: %w", err)
+	}
+	return nil
+}
+
+// Set adds or replaces the address of label. Labels may not look like addresses or ENS names,
+// so every string resolves one way only.
+func (b *AddressBook) Set(label string, address common.Address) error {
+	key := strings.ToLower(strings.TrimSpace(label))
+	if key == "" || strings.HasPrefix(key, "0x") || strings.ContainsAny(key, ". \t") {
+		return fmt.Errorf("invalid address book label %q", label)
+	}
+	b.entries[key] = address
+	return nil
+}
+
+// Remove deletes label, reporting whether it was present
+func (b *AddressBook) Remove(label string) bool {
+	key := strings.ToLower(strings.TrimSpace(label))
+	_, ok := b.entries[key]
+	delete(b.entries, key)
+	return ok
+}
+
+// Lookup returns the address of label
+func (b *AddressBook) Lookup(label string) (common.Address, bool) {
+	if b == nil {
+		return common.Address{}, false
+	}
+	address, ok := b.entries[strings.ToLower(strings.TrimSpace(label))]
+	return address, ok
+}
+
+// Labels returns the labels in order
+func (b *AddressBook) Labels() []string {
+	labels := make([]string, 0, len(b.entries))
+	for label := range b.entries {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// Resolve returns the address s names, given as a hex address or a label of the book. A nil
+// book accepts hex addresses only.
+func (b *AddressBook) Resolve(s string) (common.Address, error) {
+	if strings.HasPrefix(s, "0x") || common.IsHexAddress(s) {
+		return ParseAddress(s, false)
+	}
+	if address, ok := b.Lookup(s); ok {
+		return address, nil
+	}
+	if b == nil {
+		return common.Address{}, fmt.Errorf("%w: %q", ErrInvalidAddress, s)
+	}
+	return common.Address{}, fmt.Errorf("%w: %q is not a hex address or address book label", ErrInvalidAddress, s)
+}
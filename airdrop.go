@@ -0,0 +1,299 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DEFAULT_AIRDROP_MAX_GAS is the gas each airdrop transaction stays within by default
+const DEFAULT_AIRDROP_MAX_GAS = 10_000_000
+
+// AirdropRow is one recipient of an airdrop
+type AirdropRow struct {
+	Recipient common.Address
+	Amount    *big.Int // token base units
+}
+
+// AirdropStatus is the outcome of one airdrop row
+type AirdropStatus string
+
+const (
+	AirdropSucceeded AirdropStatus = "success"  // the transfer was executed
+	AirdropFailed    AirdropStatus = "failed"   // the forwarder executed the request but the transfer failed, or the transaction reverted
+	AirdropSkipped   AirdropStatus = "skipped"  // the forwarder did not execute the request
+	AirdropNotSent   AirdropStatus = "not_sent" // the request was never relayed
+)
+
+// AirdropResult is the outcome of one airdrop row
+type AirdropResult struct {
+	AirdropRow
+	Nonce  uint64
+	TxHash common.Hash // zero if not sent
+	Status AirdropStatus
+	Err    error
+}
+
+// AirdropConfig configures Relayer.Airdrop. Zero fields take their defaults.
+type AirdropConfig struct {
+	Gas            uint64         // inner call gas limit per transfer, default the registry default gas limit
+	TTL            time.Duration  // time until the requests' deadline, default one hour
+	MaxGasPerTx    uint64         // gas each transaction stays within, default DEFAULT_AIRDROP_MAX_GAS
+	RefundReceiver common.Address // receiver of skipped requests' value, default the relayer
+	WaitOpts       []WaitOption   // how each transaction's receipt is waited for
+}
+
+// AirdropCSVReader reads recipient,amount rows one at a time, amounts in token base units. A
+// header row, blank lines and lines starting with # are ignored.
+type AirdropCSVReader struct {
+	reader   *csv.Reader
+	first    bool
+	ctx      context.Context
+	resolver *ENSResolver
+}
+
+// NewAirdropCSVReader creates a reader of recipient,amount rows
+func NewAirdropCSVReader(r io.Reader) *AirdropCSVReader {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+	return &AirdropCSVReader{reader: reader, first: true}
+}
+
+// ResolveNames lets rows give recipients as ENS names, resolved with resolver while reading;
+// rows with unresolvable names are errors
+func (c *AirdropCSVReader) ResolveNames(ctx context.Context, resolver *ENSResolver) *AirdropCSVReader {
+	c.ctx, c.resolver = ctx, resolver
+	return c
+}
+
+// Next returns the next row, or io.EOF after the last one
+func (c *AirdropCSVReader) Next() (AirdropRow, error) {
+	for {
+		record, err := c.reader.Read()
+		if err == io.EOF {
+			return AirdropRow{}, io.EOF
+		}
+		if err != nil {
+			return AirdropRow{}, fmt.Errorf("failed to read airdrop CSV: %w", err)
+		}
+		first := c.first
+		c.first = false
+		line, _ := c.reader.FieldPos(0)
+
+		recipient, amount := strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+		if first && !strings.HasPrefix(recipient, "0x") && !common.IsHexAddress(recipient) && !IsENSName(recipient) {
+			continue // header
+		}
+		value, ok := new(big.Int).SetString(amount, 10)
+		if !ok || value.Sign() <= 0 {
+			return AirdropRow{}, fmt.Errorf("line %d: %w %q", line, ErrInvalidAmount, amount)
+		}
+		to, err := c.resolver.ResolveRecipient(c.ctx, recipient)
+		if err != nil {
+			return AirdropRow{}, fmt.Errorf("line %d: %w", line, err)
+		}
+		return AirdropRow{Recipient: to, Amount: value}, nil
+	}
+}
+
+// ReadAirdropCSV reads all recipient,amount rows like AirdropCSVReader
+func ReadAirdropCSV(r io.Reader) ([]AirdropRow, error) {
+	reader := NewAirdropCSVReader(r)
+	var rows []AirdropRow
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+// AirdropResultsWriter writes results as recipient,amount,nonce,tx_hash,status,error lines
+// after a header line
+type AirdropResultsWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+// NewAirdropResultsWriter creates a results writer
+func NewAirdropResultsWriter(w io.Writer) *AirdropResultsWriter {
+	return &AirdropResultsWriter{writer: csv.NewWriter(w)}
+}
+
+// Write writes one line per result, preceded by the header on the first call, and flushes
+func (a *AirdropResultsWriter) Write(results []AirdropResult) error {
+	if !a.header {
+		a.writer.Write([]string{"recipient", "amount", "nonce", "tx_hash", "status", "error"})
+		a.header = true
+	}
+	for _, res := range results {
+		txHash, errText := "", ""
+		if res.TxHash != (common.Hash{}) {
+			txHash = res.TxHash.Hex()
+		}
+		if res.Err != nil {
+			errText = res.Err.Error()
+		}
+		a.writer.Write([]string{
+			res.Recipient.Hex(),
+			res.Amount.String(),
+			strconv.FormatUint(res.Nonce, 10),
+			txHash,
+			string(res.Status),
+			errText,
+		})
+	}
+	a.writer.Flush()
+	return a.writer.Error()
+}
+
+// WriteAirdropResultsCSV writes the header and one line per result like AirdropResultsWriter
+func WriteAirdropResultsCSV(w io.Writer, results []AirdropResult) error {
+	return NewAirdropResultsWriter(w).Write(results)
+}
+
+// withDefaults fills the zero fields of cfg
+func (cfg AirdropConfig) withDefaults(r *Relayer) AirdropConfig {
+	if cfg.Gas == 0 {
+		cfg.Gas = r.registry.DefaultGasLimit()
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = time.Hour
+	}
+	if cfg.MaxGasPerTx == 0 {
+		cfg.MaxGasPerTx = DEFAULT_AIRDROP_MAX_GAS
+	}
+	if cfg.RefundReceiver == (common.Address{}) {
+		cfg.RefundReceiver = r.address
+	}
+	return cfg
+}
+
+// Airdrop transfers token from the signer to every row: it signs one request per row with
+// sequential forwarder nonces, splits them into executeBatch transactions within the gas
+// limit and relays those one after another, waiting for each. Every row's outcome is
+// returned; the error joins the failed rows' errors. Once a transaction cannot be sent or
+// reverts, the following rows are not sent, since their nonces depend on it. All requests are
+// held in memory; see AirdropStream for very large lists.
+func (r *Relayer) Airdrop(ctx context.Context, signerKey *ecdsa.PrivateKey, token common.Address, rows []AirdropRow, cfg AirdropConfig) ([]AirdropResult, error) {
+	cfg = cfg.withDefaults(r)
+
+	signer := AddressFromPrivateKey(signerKey)
+	startNonce, err := getMetaTxNonce(ctx, r.registry, r.profile, r.forwarder, signer, r.client)
+	if err != nil {
+		return nil, err
+	}
+	domainSeparator, err := r.domainSeparator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := uint64(time.Now().Add(cfg.TTL).Unix())
+	results := make([]AirdropResult, len(rows))
+	batch := make(BatchMetaTxRequestList, len(rows))
+	for i, row := range rows {
+		metaTx := NewMetaTx(signer, row.Recipient, token, row.Amount, cfg.Gas, startNonce+uint64(i), deadline)
+		sig, err := SignMetaTxWithSchema(r.profile.Schema, metaTx, signerKey, domainSeparator)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign row %d: %w", i, err)
+		}
+		batch[i] = BatchMetaTxRequest{MetaTx: metaTx, Signature: sig}
+		results[i] = AirdropResult{AirdropRow: row, Nonce: metaTx.Nonce, Status: AirdropNotSent}
+	}
+
+	partitions, err := r.SplitBatchByGas(batch, cfg.MaxGasPerTx)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := 0
+	for _, part := range partitions {
+		rowResults := results[offset : offset+len(part)]
+		offset += len(part)
+
+		if err := r.relayAirdropPart(ctx, part, rowResults, cfg); err != nil {
+			for i := range rowResults {
+				if rowResults[i].Err == nil {
+					rowResults[i].Err = err
+				}
+			}
+			break
+		}
+	}
+
+	var errs []error
+	for i, res := range results {
+		if res.Status != AirdropSucceeded {
+			err := res.Err
+			if err == nil {
+				err = errors.New(string(res.Status))
+			}
+			errs = append(errs, fmt.Errorf("row %d: %w", i, err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// relayAirdropPart relays one partition and records each row's outcome. It returns an error
+// if the transaction was not sent or reverted, leaving the rows' nonces unused.
+func (r *Relayer) relayAirdropPart(ctx context.Context, part BatchMetaTxRequestList, results []AirdropResult, cfg AirdropConfig) error {
+	txHash, err := r.RelayMetaTxBatch(ContextWithRecordRelay(ctx), part, cfg.RefundReceiver)
+	if err != nil {
+		return err
+	}
+	for i := range results {
+		results[i].TxHash = txHash
+	}
+
+	result, err := r.WaitForRelay(ctx, txHash, cfg.WaitOpts...)
+	if err != nil {
+		return err
+	}
+	// Already logged; the rows' outcome does not depend on it
+	_ = r.RecordRelay(ctx, result, part)
+	if !result.Succeeded() {
+		for i := range results {
+			results[i].Status = AirdropFailed
+		}
+		return fmt.Errorf("%w: relay transaction %s reverted", ErrContractCallFailed, txHash.Hex())
+	}
+
+	events, err := r.ExecutedRequests(ctx, txHash)
+	if err != nil {
+		return err
+	}
+	executed := make(map[uint64]bool, len(events))
+	for _, event := range events {
+		if event.Signer == part[0].MetaTx.From && event.Nonce.IsUint64() {
+			executed[event.Nonce.Uint64()] = event.Success
+		}
+	}
+	for i := range results {
+		success, ok := executed[results[i].Nonce]
+		switch {
+		case !ok:
+			results[i].Status, results[i].Err = AirdropSkipped, ErrRequestSkipped
+		case !success:
+			results[i].Status, results[i].Err = AirdropFailed, fmt.Errorf("%w: inner call failed", ErrContractCallFailed)
+		default:
+			results[i].Status = AirdropSucceeded
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// RelayTaskState is the lifecycle state of a request handed to a RelayBackend
+type RelayTaskState string
+
+const (
+	RelayTaskPending   RelayTaskState = "pending"   // accepted, not yet mined
+	RelayTaskSucceeded RelayTaskState = "succeeded" // mined and executed successfully
+	RelayTaskReverted  RelayTaskState = "reverted"  // mined but the forwarder call reverted
+	RelayTaskCancelled RelayTaskState = "cancelled" // dropped by the backend, will not be mined
+)
+
+// Final reports whether the task can no longer change state
+func (s RelayTaskState) Final() bool {
+	return s == RelayTaskSucceeded || s == RelayTaskReverted || s == RelayTaskCancelled
+}
+
+// RelayTaskStatus is the last known status of a relay task
+type RelayTaskStatus struct {
+	TaskID      string         `json:"taskId"`
+	State       RelayTaskState `json:"state"`
+	TxHash      common.Hash    `json:"txHash,omitempty"`      // zero until the backend has broadcast a transaction
+	BlockNumber uint64         `json:"blockNumber,omitempty"` // zero until mined
+	Message     string         `json:"message,omitempty"`     // backend-provided detail, e.g. a revert reason
+}
+
+// RelayBackend submits locally signed meta transactions for execution. Implementations may
+// broadcast from a local relayer key or hand the forwarder call to a hosted relay service;
+// either way the returned task ID can be polled with TaskStatus.
+type RelayBackend interface {
+	SubmitMetaTx(ctx context.Context, metaTx MetaTx, sig Signature) (string, error)
+	TaskStatus(ctx context.Context, taskID string) (RelayTaskStatus, error)
+}
+
+// LocalRelayBackend is a RelayBackend that broadcasts through a Relayer. Task IDs are transaction hashes.
+type LocalRelayBackend struct {
+	Relayer *Relayer
+
+	mu        sync.Mutex
+	submitted map[common.Hash]*localTask
+}
+
+// localTask tracks a request submitted through a LocalRelayBackend
+type localTask struct {
+	metaTx  MetaTx
+	checked bool   // the transfer result has been checked after mining
+	failure string // set when the transfer returned false
+}
+
+// NewLocalRelayBackend creates a RelayBackend broadcasting from the relayer's own key
+func NewLocalRelayBackend(relayer *Relayer) *LocalRelayBackend {
+	return &LocalRelayBackend{Relayer: relayer}
+}
+
+// SubmitMetaTx relays the meta transaction and returns its transaction hash as task ID
+func (b *LocalRelayBackend) SubmitMetaTx(ctx context.Context, metaTx MetaTx, sig Signature) (string, error) {
+	txHash, err := b.Relayer.RelayMetaTx(ctx, metaTx, sig)
+	if err != nil {
+		return "", err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.submitted == nil {
+		b.submitted = make(map[common.Hash]*localTask)
+	}
+	b.submitted[txHash] = &localTask{metaTx: metaTx}
+	return txHash.Hex(), nil
+}
+
+// TaskStatus looks the transaction receipt up. For requests submitted through this backend a
+// mined transaction is only reported as succeeded once the token transfer is confirmed not to
+// have returned false.
+func (b *LocalRelayBackend) TaskStatus(ctx context.Context, taskID string) (RelayTaskStatus, error) {
+	status := RelayTaskStatus{TaskID: taskID, State: RelayTaskPending}
+
+	txHash := common.HexToHash(taskID)
+	status.TxHash = txHash
+
+	receipt, err := b.Relayer.transactionReceipt(ctx, txHash)
+	if errors.Is(err, ethereum.NotFound) {
+		return status, nil
+	}
+	if err != nil {
+		return RelayTaskStatus{}, fmt.Errorf("failed to get transaction receipt: %w", err)
+	}
+
+	status.BlockNumber = receipt.BlockNumber.Uint64()
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		status.State = RelayTaskReverted
+		return status, nil
+	}
+	status.State = RelayTaskSucceeded
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	task, ok := b.submitted[txHash]
+	if !ok {
+		return status, nil
+	}
+	if !task.checked {
+		err := b.Relayer.CheckTransferResults(ctx, txHash, BatchMetaTxRequestList{{MetaTx: task.metaTx}})
+		if errors.Is(err, ErrTransferReturnedFalse) {
+			task.failure = err.Error()
+		} else if err != nil {
+			return RelayTaskStatus{}, err
+		}
+		task.checked = true
+	}
+	if task.failure != "" {
+		status.State = RelayTaskReverted
+		status.Message = task.failure
+	}
+	return status, nil
+}
+
+// WaitForRelayTask polls the backend every interval until the task reaches a final state or ctx is done
+func WaitForRelayTask(ctx context.Context, backend RelayBackend, taskID string, interval time.Duration) (RelayTaskStatus, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		status, err := backend.TaskStatus(ctx, taskID)
+		if err != nil {
+			return RelayTaskStatus{}, err
+		}
+		if status.State.Final() {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+var _ RelayBackend = (*LocalRelayBackend)(nil)
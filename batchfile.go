@@ -0,0 +1,103 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// BATCH_FILE_VERSION is the version of the batch file format written by SaveBatch
+const BATCH_FILE_VERSION = 1
+
+// BatchFile is a signed batch bound to the forwarder and EIP-712 domain it was signed for,
+// so it can be prepared offline and relayed later from another machine
+type BatchFile struct {
+	Version  int                    `json:"version"`
+	Domain   DomainBinding          `json:"domain"`
+	Requests BatchMetaTxRequestList `json:"requests"`
+}
+
+// NewBatchFile binds a signed batch to the forwarder of profile at forwarder on chainID
+func NewBatchFile(batch BatchMetaTxRequestList, chainID *big.Int, forwarder common.Address, profile *ForwarderProfile) (BatchFile, error) {
+	domainSeparator, err := profile.DomainSeparator(chainID, forwarder)
+	if err != nil {
+		return BatchFile{}, fmt.Errorf("failed to build domain separator: %w", err)
+	}
+	return BatchFile{
+		Version: BATCH_FILE_VERSION,
+		Domain: DomainBinding{
+			ChainID:         chainID.Uint64(),
+			Forwarder:       forwarder,
+			Profile:         profile.Name,
+			DomainSeparator: domainSeparator,
+		},
+		Requests: batch,
+	}, nil
+}
+
+// SealBatch binds a signed batch to the relayer's current forwarder and domain
+func (r *Relayer) SealBatch(ctx context.Context, batch BatchMetaTxRequestList) (BatchFile, error) {
+	chainID, err := r.chainID(ctx)
+	if err != nil {
+		return BatchFile{}, err
+	}
+	return NewBatchFile(batch, chainID, r.forwarder, r.profile)
+}
+
+// SaveBatch writes a batch file as indented JSON
+func SaveBatch(w io.Writer, batch BatchFile) error {
+	if batch.Version == 0 {
+		batch.Version = BATCH_FILE_VERSION
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(batch); err != nil {
+		return fmt.Errorf("failed to write batch: %w", err)
+	}
+	return nil
+}
+
+// LoadBatch reads a batch file written by SaveBatch. Signatures are not checked; use Verify
+// or check the envelopes against a relayer before relaying.
+func LoadBatch(r io.Reader) (BatchFile, error) {
+	var batch BatchFile
+	if err := json.NewDecoder(r).Decode(&batch); err != nil {
+		return BatchFile{}, fmt.Errorf("failed to read batch: %w", err)
+	}
+	if batch.Version != BATCH_FILE_VERSION {
+		return BatchFile{}, fmt.Errorf("unsupported batch file version %d", batch.Version)
+	}
+	if batch.Domain.ChainID == 0 || batch.Domain.Forwarder == (common.Address{}) || len(batch.Domain.DomainSeparator) != 32 {
+		return BatchFile{}, fmt.Errorf("batch file has no domain binding")
+	}
+	return batch, nil
+}
+
+// Verify checks every request signature against the bound domain, returning
+// ErrInvalidSignature with the index of the first invalid one
+func (b BatchFile) Verify(registry *Registry) error {
+	for i, e := range b.Envelopes() {
+		valid, err := e.Verify(registry)
+		if err != nil {
+			return fmt.Errorf("request at index %d: %w", i, err)
+		}
+		if !valid {
+			return fmt.Errorf("request at index %d: %w", i, ErrInvalidSignature)
+		}
+	}
+	return nil
+}
+
+// Envelopes returns the requests of the batch, each in an envelope bound to the batch domain,
+// e.g. for Relayer.CheckEnvelopes
+func (b BatchFile) Envelopes() []Envelope {
+	envelopes := make([]Envelope, len(b.Requests))
+	for i, req := range b.Requests {
+		envelopes[i] = Envelope{Request: req, Domain: b.Domain}
+	}
+	return envelopes
+}
@@ -0,0 +1,52 @@
+package eip2771toolkit
+
+import "fmt"
+
+// DEFAULT_MAX_BATCH_CALLDATA_BYTES bounds executeBatch calldata by default, below the 128 KiB
+// transactions Ethereum nodes accept into their pools
+const DEFAULT_MAX_BATCH_CALLDATA_BYTES = 120 << 10
+
+// BatchLimits are hard limits on the batches a relayer sends, so it never builds a relay
+// transaction that nodes or blocks refuse. Zero fields do not limit.
+type BatchLimits struct {
+	MaxRequests       int // requests per batch
+	MaxInnerDataBytes int // calldata of one forwarded call
+	MaxCalldataBytes  int // calldata of the executeBatch call
+}
+
+// WithBatchLimits replaces the relayer's batch limits, by default only MaxCalldataBytes of
+// DEFAULT_MAX_BATCH_CALLDATA_BYTES. Batches over a limit fail with ErrBatchTooLarge before
+// anything is sent; split them with SplitBatchByGas or RelayMetaTxBatchChunked.
+func WithBatchLimits(limits BatchLimits) RelayerOption {
+	return func(r *Relayer) {
+		r.batchLimits = limits
+	}
+}
+
+// checkRequests checks the number of requests of a batch and the size of their calls
+func (l BatchLimits) checkRequests(requests BatchMetaTxRequestList) error {
+	if l.MaxRequests > 0 && len(requests) > l.MaxRequests {
+		return fmt.Errorf("%w: %d requests, at most %d allowed", ErrBatchTooLarge, len(requests), l.MaxRequests)
+	}
+	if l.MaxInnerDataBytes <= 0 {
+		return nil
+	}
+	for i, req := range requests {
+		forwardRequest, err := req.MetaTx.ForwardRequest()
+		if err != nil {
+			return err
+		}
+		if len(forwardRequest.Data) > l.MaxInnerDataBytes {
+			return fmt.Errorf("%w: request at index %d calls with %d bytes, at most %d allowed", ErrBatchTooLarge, i, len(forwardRequest.Data), l.MaxInnerDataBytes)
+		}
+	}
+	return nil
+}
+
+// checkCalldata checks the size of the packed executeBatch call
+func (l BatchLimits) checkCalldata(data []byte) error {
+	if l.MaxCalldataBytes > 0 && len(data) > l.MaxCalldataBytes {
+		return fmt.Errorf("%w: %d bytes of calldata, at most %d allowed", ErrBatchTooLarge, len(data), l.MaxCalldataBytes)
+	}
+	return nil
+}
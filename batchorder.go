@@ -0,0 +1,79 @@
+package eip2771toolkit
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// FindDuplicateNonces returns the indexes of the requests of batch sharing a signer nonce, by
+// slot. Slots used by one request only are left out, so an empty result means none repeat.
+func FindDuplicateNonces(batch BatchMetaTxRequestList) map[SignerNonce][]int {
+	indexes := make(map[SignerNonce][]int, len(batch))
+	for i, req := range batch {
+		slot := req.SignerNonce()
+		indexes[slot] = append(indexes[slot], i)
+	}
+	for slot, at := range indexes {
+		if len(at) < 2 {
+			delete(indexes, slot)
+		}
+	}
+	return indexes
+}
+
+// ValidateBatchUniqueNonces checks that no two requests of the batch use the same signer
+// nonce. The forwarder executes at most one request per nonce, so the others would fail.
+func ValidateBatchUniqueNonces(batch BatchMetaTxRequestList) error {
+	seen := make(map[SignerNonce]int, len(batch))
+	for i, req := range batch {
+		slot := req.SignerNonce()
+		if first, ok := seen[slot]; ok {
+			return fmt.Errorf("%w: requests %d and %d of %s both use nonce %d", ErrDuplicateNonce, first, i, slot.From.Hex(), slot.Nonce)
+		}
+		seen[slot] = i
+	}
+	return nil
+}
+
+// DedupBatch returns the batch without the requests reusing the signer nonce of an earlier
+// one, and how many were dropped. The first request of each slot is kept, whether the later
+// ones are copies of it or conflicting requests signed for the same nonce.
+func DedupBatch(batch BatchMetaTxRequestList) (BatchMetaTxRequestList, int) {
+	seen := make(map[SignerNonce]bool, len(batch))
+	deduped := make(BatchMetaTxRequestList, 0, len(batch))
+	for _, req := range batch {
+		slot := req.SignerNonce()
+		if seen[slot] {
+			continue
+		}
+		seen[slot] = true
+		deduped = append(deduped, req)
+	}
+	return deduped, len(batch) - len(deduped)
+}
+
+// SortBatchByNonce returns the batch with each signer's requests in ascending nonce order, as
+// the forwarder needs to execute them in one transaction. Each signer's requests take the
+// positions its requests held before, so the interleaving of signers is kept.
+func SortBatchByNonce(batch BatchMetaTxRequestList) BatchMetaTxRequestList {
+	positions := make(map[common.Address][]int)
+	bySigner := make(map[common.Address]BatchMetaTxRequestList)
+	for i, req := range batch {
+		signer := req.MetaTx.From
+		positions[signer] = append(positions[signer], i)
+		bySigner[signer] = append(bySigner[signer], req)
+	}
+
+	sorted := make(BatchMetaTxRequestList, len(batch))
+	for signer, requests := range bySigner {
+		sort.SliceStable(requests, func(i, j int) bool {
+			return requests[i].MetaTx.Nonce < requests[j].MetaTx.Nonce
+		})
+		for k, i := range positions[signer] {
+			sorted[i] = requests[k]
+		}
+	}
+	return sorted
+}
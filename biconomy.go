@@ -0,0 +1,148 @@
+package eip2771toolkit
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// BICONOMY_FORWARDER_PROFILE is the registry name of the Biconomy trusted forwarder profile (batch 0)
+	BICONOMY_FORWARDER_PROFILE = "biconomy-forwarder"
+
+	// BICONOMY_DOMAIN_TYPEHASH is the EIP-712 domain type used by the Biconomy forwarder
+	BICONOMY_DOMAIN_TYPEHASH = "EIP712Domain(string name,string version,address verifyingContract,bytes32 salt)"
+
+	// BICONOMY_DOMAIN_NAME is the EIP-712 domain name used by the Biconomy forwarder
+	BICONOMY_DOMAIN_NAME = "Biconomy Forwarder"
+
+	// BICONOMY_DOMAIN_VERSION is the EIP-712 domain version used by the Biconomy forwarder
+	BICONOMY_DOMAIN_VERSION = "1"
+)
+
+// BiconomyForwarderABI is the subset of the Biconomy forwarder ABI used for relaying
+const BiconomyForwarderABI = `[
+	{
+		"inputs": [
+			{
+				"components": [
+					{"internalType": "address", "name": "from", "type": "address"},
+					{"internalType": "address", "name": "to", "type": "address"},
+					{"internalType": "address", "name": "token", "type": "address"},
+					{"internalType": "uint256", "name": "txGas", "type": "uint256"},
+					{"internalType": "uint256", "name": "tokenGasPrice", "type": "uint256"},
+					{"internalType": "uint256", "name": "batchId", "type": "uint256"},
+					{"internalType": "uint256", "name": "batchNonce", "type": "uint256"},
+					{"internalType": "uint256", "name": "deadline", "type": "uint256"},
+					{"internalType": "bytes", "name": "data", "type": "bytes"}
+				],
+				"internalType": "struct ERC20ForwardRequestTypes.ERC20ForwardRequest",
+				"name": "req",
+				"type": "tuple"
+			},
+			{"internalType": "bytes32", "name": "domainSeparator", "type": "bytes32"},
+			{"internalType": "bytes", "name": "sig", "type": "bytes"}
+		],
+		"name": "executeEIP712",
+		"outputs": [
+			{"internalType": "bool", "name": "success", "type": "bool"},
+			{"internalType": "bytes", "name": "ret", "type": "bytes"}
+		],
+		"stateMutability": "nonpayable",
+		"type": "function"
+	},
+	{
+		"inputs": [
+			{"internalType": "address", "name": "from", "type": "address"},
+			{"internalType": "uint256", "name": "batchId", "type": "uint256"}
+		],
+		"name": "getNonce",
+		"outputs": [
+			{"internalType": "uint256", "name": "", "type": "uint256"}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	}
+]`
+
+// biconomyForwardRequest mirrors ERC20ForwardRequestTypes.ERC20ForwardRequest for ABI encoding
+type biconomyForwardRequest struct {
+	From          common.Address
+	To            common.Address
+	Token         common.Address
+	TxGas         *big.Int
+	TokenGasPrice *big.Int
+	BatchId       *big.Int
+	BatchNonce    *big.Int
+	Deadline      *big.Int
+	Data          []byte
+}
+
+// NewBiconomySchema returns the request schema of the Biconomy forwarder for one nonce batch.
+// Biconomy keeps an independent nonce sequence per (signer, batchId); MetaTx.Nonce is the
+// nonce within that batch. Gas fees are not paid in tokens, so token and tokenGasPrice are zero.
+func NewBiconomySchema(batchID uint64) *RequestSchema {
+	batch := new(big.Int).SetUint64(batchID)
+	zero := big.NewInt(0)
+
+	return &RequestSchema{
+		Name:        BICONOMY_FORWARDER_PROFILE,
+		PrimaryType: "ERC20ForwardRequest",
+		Fields: []SchemaField{
+			{Name: "from", Type: "address", Value: func(r ForwardRequest) interface{} { return r.From }},
+			{Name: "to", Type: "address", Value: func(r ForwardRequest) interface{} { return r.To }},
+			{Name: "token", Type: "address", Value: func(r ForwardRequest) interface{} { return common.Address{} }},
+			{Name: "txGas", Type: "uint256", Value: func(r ForwardRequest) interface{} { return r.Gas }},
+			{Name: "tokenGasPrice", Type: "uint256", Value: func(r ForwardRequest) interface{} { return zero }},
+			{Name: "batchId", Type: "uint256", Value: func(r ForwardRequest) interface{} { return batch }},
+			{Name: "batchNonce", Type: "uint256", Value: func(r ForwardRequest) interface{} { return r.Nonce }},
+			{Name: "deadline", Type: "uint256", Value: func(r ForwardRequest) interface{} { return r.Deadline }},
+			{Name: "data", Type: "bytes", Value: func(r ForwardRequest) interface{} { return r.Data }},
+		},
+		ForwarderABI:  BiconomyForwarderABI,
+		ExecuteMethod: "executeEIP712",
+		NonceMethod:   "getNonce",
+		ExecuteArgs: func(r ForwardRequest, sig Signature, domainSeparator []byte) []interface{} {
+			return []interface{}{
+				biconomyForwardRequest{
+					From:          r.From,
+					To:            r.To,
+					TxGas:         r.Gas,
+					TokenGasPrice: zero,
+					BatchId:       batch,
+					BatchNonce:    r.Nonce,
+					Deadline:      r.Deadline,
+					Data:          r.Data,
+				},
+				common.BytesToHash(domainSeparator),
+				sig.ToBytes(),
+			}
+		},
+	}
+}
+
+// BuildBiconomyDomainSeparator creates the EIP-712 domain separator of a Biconomy forwarder.
+// Biconomy binds the chain through the salt (bytes32 chainId) instead of a chainId member.
+func BuildBiconomyDomainSeparator(chainId *big.Int, forwarder common.Address) ([]byte, error) {
+	data := make([]byte, 0, 32*5)
+	data = append(data, crypto.Keccak256([]byte(BICONOMY_DOMAIN_TYPEHASH))...)
+	data = append(data, crypto.Keccak256([]byte(BICONOMY_DOMAIN_NAME))...)
+	data = append(data, crypto.Keccak256([]byte(BICONOMY_DOMAIN_VERSION))...)
+	data = append(data, encodeAddressWord(forwarder)...)
+	data = append(data, encodeUintWord(chainId)...)
+	return crypto.Keccak256(data), nil
+}
+
+// NewBiconomyProfile returns the Biconomy forwarder profile for one nonce batch
+func NewBiconomyProfile(batchID uint64) *ForwarderProfile {
+	batch := new(big.Int).SetUint64(batchID)
+	return &ForwarderProfile{
+		Name:            BICONOMY_FORWARDER_PROFILE,
+		Schema:          NewBiconomySchema(batchID),
+		DomainSeparator: BuildBiconomyDomainSeparator,
+		NonceArgs: func(user common.Address) []interface{} {
+			return []interface{}{user, batch}
+		},
+	}
+}
@@ -0,0 +1,160 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// BidContext describes the relay transaction a fee bid is requested for
+type BidContext struct {
+	ChainID           *big.Int
+	GasLimit          uint64
+	Requests          int       // number of meta transactions carried by the relay transaction
+	NearestDeadline   uint64    // earliest deadline among the carried requests (unix timestamp)
+	Now               time.Time // time the bid is requested at
+	SuggestedGasPrice *big.Int  // legacy gas price equivalent of SuggestedFee
+	SuggestedFee      FeeBid    // network fee suggested by the relayer's GasStrategy
+	Client            EthClient // client for strategies that need additional fee data
+}
+
+// TimeToDeadline returns how long is left until the nearest deadline, or zero if it has passed
+func (bc BidContext) TimeToDeadline() time.Duration {
+	deadline := time.Unix(int64(bc.NearestDeadline), 0)
+	if !deadline.After(bc.Now) {
+		return 0
+	}
+	return deadline.Sub(bc.Now)
+}
+
+// FeeBid is the fee a relay transaction is submitted with.
+// When GasTipCap and GasFeeCap are set an EIP-1559 transaction is built, otherwise a legacy one using GasPrice.
+type FeeBid struct {
+	GasPrice  *big.Int `json:"gasPrice,omitempty"`
+	GasTipCap *big.Int `json:"maxPriorityFeePerGas,omitempty"`
+	GasFeeCap *big.Int `json:"maxFeePerGas,omitempty"`
+}
+
+// IsDynamic reports whether the bid describes an EIP-1559 fee
+func (b FeeBid) IsDynamic() bool {
+	return b.GasTipCap != nil && b.GasFeeCap != nil
+}
+
+// feePerGas returns the most the bid may pay per gas, nil if it sets no price
+func (b FeeBid) feePerGas() *big.Int {
+	if b.IsDynamic() {
+		return b.GasFeeCap
+	}
+	return b.GasPrice
+}
+
+// newTransaction builds an unsigned transaction priced with the bid
+func (b FeeBid) newTransaction(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gasLimit uint64, data []byte) (*types.Transaction, error) {
+	if b.IsDynamic() {
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     nonce,
+			GasTipCap: b.GasTipCap,
+			GasFeeCap: b.GasFeeCap,
+			Gas:       gasLimit,
+			To:        &to,
+			Value:     value,
+			Data:      data,
+		}), nil
+	}
+	if b.GasPrice == nil {
+		return nil, fmt.Errorf("fee bid has neither gas price nor EIP-1559 fee caps")
+	}
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: b.GasPrice,
+		Gas:      gasLimit,
+		To:       &to,
+		Value:    value,
+		Data:     data,
+	}), nil
+}
+
+// BidStrategy decides the fee a relay transaction is submitted with.
+// Operators plug custom bidding logic into a Relayer with WithBidStrategy.
+type BidStrategy interface {
+	Bid(ctx context.Context, bc BidContext) (FeeBid, error)
+}
+
+// BidStrategyFunc adapts a function to the BidStrategy interface
+type BidStrategyFunc func(ctx context.Context, bc BidContext) (FeeBid, error)
+
+// Bid calls f(ctx, bc)
+func (f BidStrategyFunc) Bid(ctx context.Context, bc BidContext) (FeeBid, error) {
+	return f(ctx, bc)
+}
+
+// SuggestedGasPriceBidder bids the fee suggested by the relayer's GasStrategy, by default the
+// node suggested legacy gas price. It is the Relayer default.
+type SuggestedGasPriceBidder struct{}
+
+// Bid returns the suggested fee unchanged
+func (SuggestedGasPriceBidder) Bid(ctx context.Context, bc BidContext) (FeeBid, error) {
+	if bc.SuggestedFee.IsDynamic() || bc.SuggestedFee.GasPrice != nil {
+		return bc.SuggestedFee, nil
+	}
+	return FeeBid{GasPrice: bc.SuggestedGasPrice}, nil
+}
+
+// DeadlineEscalationBidder escalates the priority fee as the nearest deadline in the
+// relayed batch approaches. Outside of Window the suggested tip is used unchanged;
+// inside it the tip grows linearly up to MaxBumpPercent above the suggestion.
+type DeadlineEscalationBidder struct {
+	Window         time.Duration // how long before the deadline escalation starts
+	MaxBumpPercent uint64        // tip increase reached at the deadline, e.g. 200 for 3x
+}
+
+// Bid returns an EIP-1559 bid, or an escalated legacy gas price on chains without a base fee
+func (d DeadlineEscalationBidder) Bid(ctx context.Context, bc BidContext) (FeeBid, error) {
+	head, err := bc.Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return FeeBid{}, fmt.Errorf("failed to get latest header: %w", err)
+	}
+
+	if head.BaseFee == nil {
+		return FeeBid{GasPrice: d.escalate(bc.SuggestedGasPrice, bc.TimeToDeadline())}, nil
+	}
+
+	tip, err := bc.Client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return FeeBid{}, fmt.Errorf("failed to get gas tip cap: %w", err)
+	}
+	tip = d.escalate(tip, bc.TimeToDeadline())
+
+	// Leave room for the base fee to double before the transaction is included
+	feeCap := new(big.Int).Mul(head.BaseFee, big.NewInt(2))
+	feeCap.Add(feeCap, tip)
+
+	return FeeBid{GasTipCap: tip, GasFeeCap: feeCap}, nil
+}
+
+// escalate raises fee proportionally to how far into the escalation window remaining is
+func (d DeadlineEscalationBidder) escalate(fee *big.Int, remaining time.Duration) *big.Int {
+	if d.Window <= 0 || remaining >= d.Window {
+		return new(big.Int).Set(fee)
+	}
+
+	// Past the deadline the bump stays at its maximum
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	// elapsed/window fraction of the maximum bump, computed in integer basis points. The
+	// product exceeds an int64 for windows over about 10 days, hence big.Int.
+	progress := new(big.Int).Mul(big.NewInt(int64(d.Window-remaining)), big.NewInt(10000))
+	progress.Div(progress, big.NewInt(int64(d.Window)))
+	bump := new(big.Int).Mul(fee, new(big.Int).SetUint64(d.MaxBumpPercent))
+	bump.Mul(bump, progress)
+	bump.Div(bump, big.NewInt(100*10000))
+
+	return bump.Add(bump, fee)
+}
@@ -0,0 +1,39 @@
+package eip2771toolkit
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestDeadlineEscalationBidderEscalate(t *testing.T) {
+	const day = 24 * time.Hour
+	tests := []struct {
+		name      string
+		window    time.Duration
+		remaining time.Duration
+		want      int64 // escalated fee of 1000 with a 200% maximum bump
+	}{
+		{"no window", 0, time.Minute, 1000},
+		{"before the window", time.Hour, 2 * time.Hour, 1000},
+		{"window starts", time.Hour, time.Hour, 1000},
+		{"halfway", time.Hour, 30 * time.Minute, 2000},
+		{"at the deadline", time.Hour, 0, 3000},
+		{"past the deadline", time.Hour, -time.Hour, 3000},
+		{"far past the deadline", time.Hour, -100 * 365 * day, 3000},
+		{"30-day window, a quarter in", 30 * day, 22*day + 12*time.Hour, 1500},
+		{"30-day window, halfway", 30 * day, 15 * day, 2000},
+		{"30-day window, last second", 30 * day, time.Second, 2999},
+		{"30-day window, at the deadline", 30 * day, 0, 3000},
+		{"10-year window, halfway", 3650 * day, 1825 * day, 2000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := DeadlineEscalationBidder{Window: tt.window, MaxBumpPercent: 200}
+			got := d.escalate(big.NewInt(1000), tt.remaining)
+			if got.Cmp(big.NewInt(tt.want)) != 0 {
+				t.Errorf("escalate = %s, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// BudgetLedger stores what each sponsor spent on relays, in wei. Ledgers in shared storage let
+// several relayer processes draw on one budget. Implementations must be safe for concurrent
+// use.
+type BudgetLedger interface {
+	// Spend atomically adds amount to what sponsor spent
+	Spend(ctx context.Context, sponsor string, amount *big.Int) error
+	// Spent returns what sponsor spent, zero for unknown sponsors
+	Spent(ctx context.Context, sponsor string) (*big.Int, error)
+}
+
+// SponsorStatus is the budget of one sponsor
+type SponsorStatus struct {
+	Sponsor   string   `json:"sponsor"`
+	Limit     *big.Int `json:"limit"`
+	Spent     *big.Int `json:"spent"`
+	Remaining *big.Int `json:"remaining"` // never negative
+}
+
+// Budget is a Policy that charges the actual cost of mined relays, gas used times effective
+// gas price plus any L1 data fee, to the sponsors of the requests and refuses requests of
+// sponsors whose budget is spent. Relays still in flight are charged once mined, so spending
+// can overshoot a limit by their cost. Requests of sponsors without a limit are refused.
+type Budget struct {
+	ledger    BudgetLedger
+	sponsorOf func(req BatchMetaTxRequest) string
+
+	mu     sync.RWMutex
+	limits map[string]*big.Int
+}
+
+// NewBudget creates a budget recording in ledger. sponsorOf names the sponsor paying for a
+// request relayed without one in its context (see ContextWithSponsor); nil charges such
+// requests to the sponsor "".
+func NewBudget(ledger BudgetLedger, sponsorOf func(req BatchMetaTxRequest) string) *Budget {
+	if sponsorOf == nil {
+		sponsorOf = func(BatchMetaTxRequest) string { return "" }
+	}
+	return &Budget{ledger: ledger, sponsorOf: sponsorOf, limits: make(map[string]*big.Int)}
+}
+
+// sponsorContextKey is the context key of the sponsor of a relay
+type sponsorContextKey struct{}
+
+// ContextWithSponsor attributes the relays made with ctx to sponsor, e.g. the owner of the API
+// key a request came with. It takes precedence over a Budget's sponsorOf and is what a
+// sponsor quota counts, see NewSponsorQuota.
+func ContextWithSponsor(ctx context.Context, sponsor string) context.Context {
+	return context.WithValue(ctx, sponsorContextKey{}, sponsor)
+}
+
+// SponsorFromContext returns the sponsor set by ContextWithSponsor
+func SponsorFromContext(ctx context.Context) (string, bool) {
+	sponsor, ok := ctx.Value(sponsorContextKey{}).(string)
+	return sponsor, ok
+}
+
+// recordRelayContextKey is the context key of ContextWithRecordRelay
+type recordRelayContextKey struct{}
+
+// ContextWithRecordRelay declares that the caller passes the mined result of the relays it
+// submits with ctx to RecordRelay, which is what charges a Budget. A relayer with a budget
+// refuses relays submitted without it with ErrBudgetNotRecorded, as nothing would charge
+// them; the waiting relay methods set it themselves.
+func ContextWithRecordRelay(ctx context.Context) context.Context {
+	return context.WithValue(ctx, recordRelayContextKey{}, true)
+}
+
+// recordsRelay reports whether ctx comes from ContextWithRecordRelay
+func recordsRelay(ctx context.Context) bool {
+	recorded, _ := ctx.Value(recordRelayContextKey{}).(bool)
+	return recorded
+}
+
+// sponsorFor returns the sponsor of req relayed with ctx
+func (b *Budget) sponsorFor(ctx context.Context, req BatchMetaTxRequest) string {
+	if sponsor, ok := SponsorFromContext(ctx); ok {
+		return sponsor
+	}
+	return b.sponsorOf(req)
+}
+
+// SponsorByTarget names the sponsor of a request after its forwarded call target, for
+// sponsors paying for calls to their own contracts; other targets get the sponsor ""
+func SponsorByTarget(sponsors map[common.Address]string) func(req BatchMetaTxRequest) string {
+	return func(req BatchMetaTxRequest) string {
+		return sponsors[req.MetaTx.Token]
+	}
+}
+
+// SetLimit sets the total a sponsor may spend, in wei
+func (b *Budget) SetLimit(sponsor string, limit *big.Int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.limits[sponsor] = new(big.Int).Set(limit)
+}
+
+// limit returns the limit of sponsor, nil if it has none
+func (b *Budget) limit(sponsor string) *big.Int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.limits[sponsor]
+}
+
+// Status returns the limit, spending and remaining budget of a sponsor
+func (b *Budget) Status(ctx context.Context, sponsor string) (SponsorStatus, error) {
+	limit := b.limit(sponsor)
+	if limit == nil {
+		return SponsorStatus{}, fmt.Errorf("no budget for sponsor %q", sponsor)
+	}
+	spent, err := b.ledger.Spent(ctx, sponsor)
+	if err != nil {
+		return SponsorStatus{}, fmt.Errorf("failed to read budget: %w", err)
+	}
+	remaining := new(big.Int).Sub(limit, spent)
+	if remaining.Sign() < 0 {
+		remaining.SetInt64(0)
+	}
+	return SponsorStatus{Sponsor: sponsor, Limit: new(big.Int).Set(limit), Spent: spent, Remaining: remaining}, nil
+}
+
+// Remaining returns what a sponsor has left to spend, in wei
+func (b *Budget) Remaining(ctx context.Context, sponsor string) (*big.Int, error) {
+	status, err := b.Status(ctx, sponsor)
+	if err != nil {
+		return nil, err
+	}
+	return status.Remaining, nil
+}
+
+// Statuses returns the budgets of all sponsors with a limit, ordered by sponsor
+func (b *Budget) Statuses(ctx context.Context) ([]SponsorStatus, error) {
+	b.mu.RLock()
+	sponsors := make([]string, 0, len(b.limits))
+	for sponsor := range b.limits {
+		sponsors = append(sponsors, sponsor)
+	}
+	b.mu.RUnlock()
+	sort.Strings(sponsors)
+
+	statuses := make([]SponsorStatus, 0, len(sponsors))
+	for _, sponsor := range sponsors {
+		status, err := b.Status(ctx, sponsor)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Check refuses requests whose sponsor has no budget or spent it
+func (b *Budget) Check(ctx context.Context, req BatchMetaTxRequest) error {
+	sponsor := b.sponsorFor(ctx, req)
+	if b.limit(sponsor) == nil {
+		return &PolicyError{Policy: "budget", Reason: fmt.Sprintf("no budget for sponsor %q", sponsor), Cause: ErrBudgetExhausted}
+	}
+	remaining, err := b.Remaining(ctx, sponsor)
+	if err != nil {
+		return err
+	}
+	if remaining.Sign() <= 0 {
+		return &PolicyError{Policy: "budget", Reason: fmt.Sprintf("budget of sponsor %q is spent", sponsor), Cause: ErrBudgetExhausted}
+	}
+	return nil
+}
+
+// Charge splits the cost of a mined relay between its requests in proportion to their gas
+// and charges each share to the request's sponsor. Results without a gas price, such as
+// sandbox receipts, cost nothing.
+func (b *Budget) Charge(ctx context.Context, result RelayResult, requests BatchMetaTxRequestList) error {
+	if result.GasPrice == nil || len(requests) == 0 {
+		return nil
+	}
+	cost := FeeSample{GasPrice: result.GasPrice, GasUsed: result.GasUsed, L1Fee: result.L1Fee}.Cost()
+
+	var totalGas uint64
+	for _, req := range requests {
+		totalGas += req.MetaTx.Gas
+	}
+	shares := make(map[string]*big.Int)
+	charged := new(big.Int)
+	for i, req := range requests {
+		share := new(big.Int)
+		switch {
+		case i == len(requests)-1:
+			share.Sub(cost, charged) // the rounding remainder
+		case totalGas == 0:
+			share.Div(cost, big.NewInt(int64(len(requests))))
+		default:
+			share.Mul(cost, new(big.Int).SetUint64(req.MetaTx.Gas))
+			share.Div(share, new(big.Int).SetUint64(totalGas))
+		}
+		charged.Add(charged, share)
+
+		sponsor := b.sponsorFor(ctx, req)
+		if shares[sponsor] == nil {
+			shares[sponsor] = new(big.Int)
+		}
+		shares[sponsor].Add(shares[sponsor], share)
+	}
+
+	for sponsor, amount := range shares {
+		if err := b.ledger.Spend(ctx, sponsor, amount); err != nil {
+			return fmt.Errorf("failed to charge sponsor %q: %w", sponsor, err)
+		}
+	}
+	return nil
+}
+
+// Handler serves the budget of ?sponsor=name, or of all sponsors without it, as JSON
+func (b *Budget) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var body interface{}
+		var err error
+		if req.URL.Query().Has("sponsor") {
+			sponsor := req.URL.Query().Get("sponsor")
+			if b.limit(sponsor) == nil {
+				http.Error(w, "unknown sponsor", http.StatusNotFound)
+				return
+			}
+			body, err = b.Status(req.Context(), sponsor)
+		} else {
+			body, err = b.Statuses(req.Context())
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	})
+}
+
+// WithBudget makes the relayer refuse requests of sponsors whose budget is spent and charge
+// the cost of relays it waits for to their sponsors. Fire-and-forget relays must then be
+// submitted with ContextWithRecordRelay and recorded once mined.
+func WithBudget(budget *Budget) RelayerOption {
+	return func(r *Relayer) {
+		r.budget = budget
+		r.policies = append(r.policies, budget)
+	}
+}
+
+// SetBudget replaces the relayer's budget, nil to stop metering, e.g. after a configuration
+// reload
+func (r *Relayer) SetBudget(budget *Budget) {
+	r.settingsMu.Lock()
+	defer r.settingsMu.Unlock()
+	policies := make([]Policy, 0, len(r.policies)+1)
+	for _, policy := range r.policies {
+		if r.budget == nil || policy != Policy(r.budget) {
+			policies = append(policies, policy)
+		}
+	}
+	if budget != nil {
+		policies = append(policies, budget)
+	}
+	r.policies = policies
+	r.budget = budget
+}
+
+// activeBudget returns the budget relays are charged to
+func (r *Relayer) activeBudget() *Budget {
+	r.settingsMu.RLock()
+	defer r.settingsMu.RUnlock()
+	return r.budget
+}
+
+// MemoryBudgetLedger is a BudgetLedger kept in memory, for single-process relayers
+type MemoryBudgetLedger struct {
+	mu    sync.Mutex
+	spent map[string]*big.Int
+}
+
+// NewMemoryBudgetLedger creates an empty in-memory ledger
+func NewMemoryBudgetLedger() *MemoryBudgetLedger {
+	return &MemoryBudgetLedger{spent: make(map[string]*big.Int)}
+}
+
+// Spend adds to what a sponsor spent
+func (l *MemoryBudgetLedger) Spend(ctx context.Context, sponsor string, amount *big.Int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.spent[sponsor] == nil {
+		l.spent[sponsor] = new(big.Int)
+	}
+	l.spent[sponsor].Add(l.spent[sponsor], amount)
+	return nil
+}
+
+// Spent returns what a sponsor spent
+func (l *MemoryBudgetLedger) Spent(ctx context.Context, sponsor string) (*big.Int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.spent[sponsor] == nil {
+		return new(big.Int), nil
+	}
+	return new(big.Int).Set(l.spent[sponsor]), nil
+}
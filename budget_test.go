@@ -0,0 +1,169 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	acmeToken   = common.HexToAddress("0xa000000000000000000000000000000000000001")
+	globexToken = common.HexToAddress("0xa000000000000000000000000000000000000002")
+	soylentCoin = common.HexToAddress("0xa000000000000000000000000000000000000003")
+)
+
+// budgetRequest is a request to token with gas, its sponsor given by SponsorByTarget
+func budgetRequest(token common.Address, gas uint64) BatchMetaTxRequest {
+	return BatchMetaTxRequest{MetaTx: MetaTx{Token: token, Gas: gas}}
+}
+
+// failingLedger refuses every charge
+type failingLedger struct{ MemoryBudgetLedger }
+
+func (l *failingLedger) Spend(ctx context.Context, sponsor string, amount *big.Int) error {
+	return errors.New("ledger unavailable")
+}
+
+func TestBudgetCharge(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   RelayResult
+		requests BatchMetaTxRequestList
+		want     map[string]int64
+	}{
+		{
+			name:     "split by gas share",
+			result:   RelayResult{GasPrice: big.NewInt(10), GasUsed: 100},
+			requests: BatchMetaTxRequestList{budgetRequest(acmeToken, 100_000), budgetRequest(globexToken, 300_000)},
+			want:     map[string]int64{"acme": 250, "globex": 750},
+		},
+		{
+			name:   "last request gets the rounding remainder",
+			result: RelayResult{GasPrice: big.NewInt(1), GasUsed: 100},
+			requests: BatchMetaTxRequestList{
+				budgetRequest(acmeToken, 1), budgetRequest(globexToken, 1), budgetRequest(soylentCoin, 1),
+			},
+			want: map[string]int64{"acme": 33, "globex": 33, "soylent": 34},
+		},
+		{
+			name:   "remainder of an uneven split",
+			result: RelayResult{GasPrice: big.NewInt(1), GasUsed: 10},
+			requests: BatchMetaTxRequestList{
+				budgetRequest(acmeToken, 2), budgetRequest(globexToken, 2), budgetRequest(soylentCoin, 3),
+			},
+			want: map[string]int64{"acme": 2, "globex": 2, "soylent": 6},
+		},
+		{
+			name:   "no gas splits evenly",
+			result: RelayResult{GasPrice: big.NewInt(1), GasUsed: 100},
+			requests: BatchMetaTxRequestList{
+				budgetRequest(acmeToken, 0), budgetRequest(globexToken, 0), budgetRequest(soylentCoin, 0),
+			},
+			want: map[string]int64{"acme": 33, "globex": 33, "soylent": 34},
+		},
+		{
+			name:     "shares of one sponsor add up",
+			result:   RelayResult{GasPrice: big.NewInt(3), GasUsed: 7},
+			requests: BatchMetaTxRequestList{budgetRequest(acmeToken, 5), budgetRequest(acmeToken, 9)},
+			want:     map[string]int64{"acme": 21},
+		},
+		{
+			name:     "L1 fee is charged",
+			result:   RelayResult{GasPrice: big.NewInt(2), GasUsed: 100, L1Fee: big.NewInt(50)},
+			requests: BatchMetaTxRequestList{budgetRequest(acmeToken, 1)},
+			want:     map[string]int64{"acme": 250},
+		},
+		{
+			name:     "unknown target charges the default sponsor",
+			result:   RelayResult{GasPrice: big.NewInt(1), GasUsed: 8},
+			requests: BatchMetaTxRequestList{budgetRequest(common.Address{}, 1), budgetRequest(acmeToken, 1)},
+			want:     map[string]int64{"": 4, "acme": 4},
+		},
+		{
+			name:     "sandbox result costs nothing",
+			result:   RelayResult{GasUsed: 100},
+			requests: BatchMetaTxRequestList{budgetRequest(acmeToken, 1)},
+			want:     map[string]int64{},
+		},
+		{
+			name:   "no requests",
+			result: RelayResult{GasPrice: big.NewInt(1), GasUsed: 100},
+			want:   map[string]int64{},
+		},
+	}
+
+	sponsors := map[common.Address]string{acmeToken: "acme", globexToken: "globex", soylentCoin: "soylent"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger := NewMemoryBudgetLedger()
+			budget := NewBudget(ledger, SponsorByTarget(sponsors))
+			if err := budget.Charge(context.Background(), tt.result, tt.requests); err != nil {
+				t.Fatalf("Charge: %v", err)
+			}
+
+			total := new(big.Int)
+			for _, sponsor := range []string{"", "acme", "globex", "soylent"} {
+				spent, err := ledger.Spent(context.Background(), sponsor)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := big.NewInt(tt.want[sponsor]); spent.Cmp(want) != 0 {
+					t.Errorf("sponsor %q spent %s, want %s", sponsor, spent, want)
+				}
+				total.Add(total, spent)
+			}
+			if tt.result.GasPrice != nil && len(tt.requests) > 0 {
+				cost := FeeSample{GasPrice: tt.result.GasPrice, GasUsed: tt.result.GasUsed, L1Fee: tt.result.L1Fee}.Cost()
+				if total.Cmp(cost) != 0 {
+					t.Errorf("charged %s in total, relay cost %s", total, cost)
+				}
+			}
+		})
+	}
+}
+
+func TestBudgetChargeSponsorFromContext(t *testing.T) {
+	ledger := NewMemoryBudgetLedger()
+	budget := NewBudget(ledger, SponsorByTarget(map[common.Address]string{acmeToken: "acme"}))
+	ctx := ContextWithSponsor(context.Background(), "globex")
+	if err := budget.Charge(ctx, RelayResult{GasPrice: big.NewInt(1), GasUsed: 10}, BatchMetaTxRequestList{budgetRequest(acmeToken, 1)}); err != nil {
+		t.Fatal(err)
+	}
+	if spent, _ := ledger.Spent(ctx, "globex"); spent.Int64() != 10 {
+		t.Errorf("globex spent %s, want 10", spent)
+	}
+	if spent, _ := ledger.Spent(ctx, "acme"); spent.Sign() != 0 {
+		t.Errorf("acme spent %s, want 0", spent)
+	}
+}
+
+func TestRecordRelayReturnsChargeError(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	budget := NewBudget(&failingLedger{}, nil)
+	r := NewRelayer(key, common.Address{}, nil, WithBudget(budget))
+
+	err := r.RecordRelay(context.Background(), RelayResult{GasPrice: big.NewInt(1), GasUsed: 1}, BatchMetaTxRequestList{budgetRequest(acmeToken, 1)})
+	if err == nil {
+		t.Fatal("RecordRelay succeeded with a failing ledger")
+	}
+}
+
+func TestBudgetRefusesUnrecordedRelays(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	budget := NewBudget(NewMemoryBudgetLedger(), nil)
+	requests := BatchMetaTxRequestList{budgetRequest(acmeToken, 1)}
+
+	r := NewRelayer(key, common.Address{}, nil, WithBudget(budget))
+	if _, err := r.sponsor(context.Background(), common.Address{}, nil, nil, requests); !errors.Is(err, ErrBudgetNotRecorded) {
+		t.Fatalf("got %v, want ErrBudgetNotRecorded", err)
+	}
+	// Recorded relays reach the budget policy, which refuses the sponsor without a limit
+	ctx := ContextWithRecordRelay(context.Background())
+	if _, err := r.sponsor(ctx, common.Address{}, nil, nil, requests); !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("got %v, want ErrBudgetExhausted", err)
+	}
+}
@@ -0,0 +1,131 @@
+package eip2771toolkit
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// transferSelector is the selector of ERC20 transfer(address,uint256)
+var transferSelector = crypto.Keccak256([]byte("transfer(address,uint256)"))[:4]
+
+// PrefixSigner signs many ERC2771Forwarder transfer requests from one user for one token,
+// e.g. an airdrop. The struct hash prefix (typehash, from, token, zero value) and the digest
+// prefix ("\x19\x01" || domainSeparator) are encoded once, and each request is hashed with
+// one reused keccak state and fixed buffers instead of the generic EIP-712 encoder. Both
+// prefixes are shorter than a keccak block, so they are rehashed for every request.
+// Digests are identical to SignMetaTx with OZForwarderV5Schema.
+//
+// A PrefixSigner reuses its hash state and is not safe for concurrent use.
+type PrefixSigner struct {
+	privKey *ecdsa.PrivateKey
+	from    common.Address
+	token   common.Address
+
+	structPrefix []byte // typehash || from || token || value
+	digestPrefix []byte // "\x19\x01" || domainSeparator
+
+	hasher crypto.KeccakState
+	buf    [4 + 32 + 32]byte
+	word   [32]byte
+}
+
+// NewPrefixSigner creates a PrefixSigner for transfers of token signed by userPrivKey
+func NewPrefixSigner(userPrivKey *ecdsa.PrivateKey, token common.Address, domainSeparator []byte) (*PrefixSigner, error) {
+	if len(domainSeparator) != 32 {
+		return nil, fmt.Errorf("invalid domain separator length %d", len(domainSeparator))
+	}
+
+	p := &PrefixSigner{
+		privKey: userPrivKey,
+		from:    crypto.PubkeyToAddress(userPrivKey.PublicKey),
+		token:   token,
+		hasher:  crypto.NewKeccakState(),
+	}
+
+	p.structPrefix = append(p.structPrefix, OZForwarderV5Schema.TypeHash()...)
+	p.structPrefix = append(p.structPrefix, encodeAddressWord(p.from)...)
+	p.structPrefix = append(p.structPrefix, encodeAddressWord(token)...)
+	p.structPrefix = append(p.structPrefix, make([]byte, 32)...) // value is always zero for ERC20 transfers
+	p.digestPrefix = append([]byte{0x19, 0x01}, domainSeparator...)
+
+	copy(p.buf[:4], transferSelector)
+	return p, nil
+}
+
+// From returns the address requests are signed for
+func (p *PrefixSigner) From() common.Address {
+	return p.from
+}
+
+// HashMetaTx returns the EIP-712 digest of metaTx, which must be from the signer for its token
+func (p *PrefixSigner) HashMetaTx(metaTx MetaTx) ([]byte, error) {
+	if metaTx.From != p.from {
+		return nil, fmt.Errorf("MetaTx from %s does not match signer %s", metaTx.From.Hex(), p.from.Hex())
+	}
+	if metaTx.Token != p.token {
+		return nil, fmt.Errorf("MetaTx token %s does not match signer token %s", metaTx.Token.Hex(), p.token.Hex())
+	}
+	if !validAmount(metaTx.Amount) {
+		return nil, ErrInvalidAmount
+	}
+
+	// data = transfer(to, amount)
+	copy(p.buf[4:36], encodeAddressWord(metaTx.To))
+	metaTx.Amount.FillBytes(p.buf[36:68])
+	p.hasher.Reset()
+	p.hasher.Write(p.buf[:])
+	dataHash := make([]byte, 32)
+	p.hasher.Read(dataHash)
+
+	// structHash = keccak(prefix || gas || nonce || deadline || keccak(data))
+	p.hasher.Reset()
+	p.hasher.Write(p.structPrefix)
+	p.writeUint64(metaTx.Gas)
+	p.writeUint64(metaTx.Nonce)
+	p.writeUint64(metaTx.Deadline)
+	p.hasher.Write(dataHash)
+	structHash := dataHash // reuse the buffer, dataHash has been absorbed
+	p.hasher.Read(structHash)
+
+	// digest = keccak("\x19\x01" || domainSeparator || structHash)
+	p.hasher.Reset()
+	p.hasher.Write(p.digestPrefix)
+	p.hasher.Write(structHash)
+	digest := make([]byte, 32)
+	p.hasher.Read(digest)
+	return digest, nil
+}
+
+// SignMetaTx signs metaTx using the precomputed prefixes
+func (p *PrefixSigner) SignMetaTx(metaTx MetaTx) (Signature, error) {
+	digest, err := p.HashMetaTx(metaTx)
+	if err != nil {
+		return Signature{}, fmt.Errorf("failed to hash MetaTx: %w", err)
+	}
+	return signDigest(digest, p.privKey)
+}
+
+// SignBatch signs all metaTxs and returns them as batch requests
+func (p *PrefixSigner) SignBatch(metaTxs []MetaTx) (BatchMetaTxRequestList, error) {
+	batch := make(BatchMetaTxRequestList, 0, len(metaTxs))
+	for i, metaTx := range metaTxs {
+		sig, err := p.SignMetaTx(metaTx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign MetaTx at index %d: %w", i, err)
+		}
+		batch = append(batch, BatchMetaTxRequest{MetaTx: metaTx, Signature: sig})
+	}
+	return batch, nil
+}
+
+// writeUint64 absorbs value as a 32-byte word
+func (p *PrefixSigner) writeUint64(value uint64) {
+	p.word = [32]byte{}
+	for i := 0; i < 8; i++ {
+		p.word[31-i] = byte(value >> (8 * i))
+	}
+	p.hasher.Write(p.word[:])
+}
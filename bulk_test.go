@@ -0,0 +1,111 @@
+package eip2771toolkit
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const bulkRecipients = 1000
+
+// bulkFixture is an airdrop of one user to bulkRecipients recipients
+type bulkFixture struct {
+	key             *ecdsa.PrivateKey
+	domainSeparator []byte
+	metaTxs         []MetaTx
+	signer          *PrefixSigner
+}
+
+func newBulkFixture(tb testing.TB) *bulkFixture {
+	tb.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	token := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	domainSeparator, err := CreateDomainSeparatorForChain(big.NewInt(1), common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	to := make([]common.Address, bulkRecipients)
+	amounts := make([]*big.Int, bulkRecipients)
+	for i := range to {
+		to[i] = common.BigToAddress(big.NewInt(int64(i + 1)))
+		amounts[i] = big.NewInt(int64(i+1) * 1e15)
+	}
+	metaTxs, err := NewMetaTxBatchWithDefaultGas(AddressFromPrivateKey(key), to, token, amounts, 0, 2_000_000_000)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	signer, err := NewPrefixSigner(key, token, domainSeparator)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return &bulkFixture{key: key, domainSeparator: domainSeparator, metaTxs: metaTxs, signer: signer}
+}
+
+func TestPrefixSignerMatchesSignMetaTx(t *testing.T) {
+	f := newBulkFixture(t)
+	batch, err := f.signer.SignBatch(f.metaTxs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, metaTx := range f.metaTxs {
+		want, err := SignMetaTx(metaTx, f.key, f.domainSeparator)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(want.ToBytes(), batch[i].Signature.ToBytes()) {
+			t.Fatalf("signature %d differs from SignMetaTx", i)
+		}
+	}
+}
+
+func BenchmarkHashMetaTx(b *testing.B) {
+	f := newBulkFixture(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := HashMetaTx(f.metaTxs[i%bulkRecipients], f.domainSeparator); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPrefixSignerHashMetaTx(b *testing.B) {
+	f := newBulkFixture(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.signer.HashMetaTx(f.metaTxs[i%bulkRecipients]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSignMetaTx(b *testing.B) {
+	f := newBulkFixture(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := SignMetaTx(f.metaTxs[i%bulkRecipients], f.key, f.domainSeparator); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPrefixSignerSignMetaTx(b *testing.B) {
+	f := newBulkFixture(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.signer.SignMetaTx(f.metaTxs[i%bulkRecipients]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package eip2771toolkit
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// PackExecuteCalldata returns the ABI-encoded ERC2771Forwarder execute call for a signed MetaTx.
+// The result can be submitted by any transaction sender, e.g. a third-party relay service.
+func PackExecuteCalldata(metaTx MetaTx, sig Signature) ([]byte, error) {
+	return PackExecuteCalldataWithProfile(OZForwarderV5Profile, metaTx, sig, nil)
+}
+
+// PackExecuteCalldataWithProfile returns the ABI-encoded execute call of the forwarder described by profile.
+// domainSeparator is only used by forwarders whose execute method takes it as an argument.
+func PackExecuteCalldataWithProfile(profile *ForwarderProfile, metaTx MetaTx, sig Signature, domainSeparator []byte) ([]byte, error) {
+	return packExecuteCall(DefaultRegistry, profile.Schema, metaTx, sig, domainSeparator)
+}
+
+// packExecuteCall packs the schema's execute method call using the registry's ABI cache
+func packExecuteCall(registry *Registry, schema *RequestSchema, metaTx MetaTx, sig Signature, domainSeparator []byte) ([]byte, error) {
+	// Parse forwarder contract ABI
+	parsedABI, err := registry.ABI(schema.ForwarderABI)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the forward request the signature covers
+	forwardRequest, err := metaTx.ForwardRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	// Pack the execute method call
+	data, err := parsedABI.Pack(schema.ExecuteMethod, schema.executeArgs(forwardRequest, sig, domainSeparator)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s call: %w", schema.ExecuteMethod, err)
+	}
+	return data, nil
+}
+
+// PackExecuteBatchCalldata returns the ABI-encoded ERC2771Forwarder executeBatch call for a
+// signed batch without broadcasting it, e.g. to propose it from a multisig or timelock.
+// A zero refundReceiver makes the batch atomic.
+func PackExecuteBatchCalldata(batchRequests BatchMetaTxRequestList, refundReceiver common.Address) ([]byte, error) {
+	return PackExecuteBatchCalldataWithProfile(OZForwarderV5Profile, batchRequests, refundReceiver)
+}
+
+// PackExecuteBatchCalldataWithProfile returns the ABI-encoded batch call of the forwarder described by profile
+func PackExecuteBatchCalldataWithProfile(profile *ForwarderProfile, batchRequests BatchMetaTxRequestList, refundReceiver common.Address) ([]byte, error) {
+	data, _, err := packExecuteBatchCall(DefaultRegistry, profile.Schema, batchRequests, refundReceiver)
+	return data, err
+}
+
+// packExecuteBatchCall packs the schema's executeBatch method call and returns it with the
+// total value the batch forwards
+func packExecuteBatchCall(registry *Registry, schema *RequestSchema, batchRequests BatchMetaTxRequestList, refundReceiver common.Address) ([]byte, *big.Int, error) {
+	if schema.ExecuteBatchMethod == "" {
+		return nil, nil, fmt.Errorf("request schema %s does not support batch execution", schema.Name)
+	}
+
+	// Parse forwarder contract ABI
+	parsedABI, err := registry.ABI(schema.ForwarderABI)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Prepare batch requests
+	forwardRequestDataList, totalValue, err := prepareBatchRequests(schema, batchRequests)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to prepare batch requests: %w", err)
+	}
+
+	// Pack the executeBatch method call
+	data, err := parsedABI.Pack(schema.ExecuteBatchMethod, forwardRequestDataList, refundReceiver)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to pack %s call: %w", schema.ExecuteBatchMethod, err)
+	}
+	return data, totalValue, nil
+}
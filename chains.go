@@ -0,0 +1,298 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// TxType selects the kind of relay transaction sent on a chain
+type TxType uint8
+
+const (
+	TxTypeAuto    TxType = iota // whatever the bid strategy returns
+	TxTypeLegacy                // legacy gas price transactions, for chains without EIP-1559
+	TxTypeDynamic               // EIP-1559 transactions
+)
+
+// ChainConfig describes how to relay on one chain
+type ChainConfig struct {
+	ChainID   *big.Int
+	Name      string
+	RPCURLs   []string // tried in order when dialing
+	Forwarder common.Address
+	Profile   string // registered ForwarderProfile name, empty for the registry default
+
+	// DomainName and DomainVersion override the EIP-712 domain of the profile, for
+	// forwarders deployed with a custom name
+	DomainName    string
+	DomainVersion string
+
+	GasStrategy GasStrategy // nil for DefaultGasStrategy of the chain
+	FeeCaps     *FeeCaps    // nil for no caps
+	GasMargin   *GasMargin  // nil for the Relayer default
+	BidStrategy BidStrategy // nil for the Relayer default
+	TxType      TxType
+
+	L1FeeEstimator L1FeeEstimator // nil for DefaultL1FeeEstimator of the chain
+
+	registry        *Registry
+	domainOnce      sync.Once
+	domainSeparator []byte
+	domainErr       error
+}
+
+// profile returns the forwarder profile of the chain
+func (c *ChainConfig) profile() (*ForwarderProfile, error) {
+	reg := c.registry
+	if reg == nil {
+		reg = DefaultRegistry
+	}
+	if c.Profile == "" {
+		return reg.DefaultForwarderProfile(), nil
+	}
+	profile, ok := reg.LookupForwarderProfile(c.Profile)
+	if !ok {
+		return nil, fmt.Errorf("unknown forwarder profile %s", c.Profile)
+	}
+	return profile, nil
+}
+
+// DomainSeparator returns the EIP-712 domain separator of the chain's forwarder.
+// It is computed once and cached.
+func (c *ChainConfig) DomainSeparator() ([]byte, error) {
+	c.domainOnce.Do(func() {
+		if c.DomainName != "" || c.DomainVersion != "" {
+			c.domainSeparator, c.domainErr = BuildDomainSeparator(c.DomainName, c.DomainVersion, c.ChainID, c.Forwarder)
+			return
+		}
+
+		profile, err := c.profile()
+		if err != nil {
+			c.domainErr = err
+			return
+		}
+		c.domainSeparator, c.domainErr = profile.DomainSeparator(c.ChainID, c.Forwarder)
+	})
+	if c.domainErr != nil {
+		return nil, fmt.Errorf("failed to build domain separator for chain %s: %w", c.ChainID, c.domainErr)
+	}
+	return c.domainSeparator, nil
+}
+
+// Dial connects to the first reachable RPC URL of the chain and checks its chain ID
+func (c *ChainConfig) Dial(ctx context.Context) (*ethclient.Client, error) {
+	if len(c.RPCURLs) == 0 {
+		return nil, fmt.Errorf("chain %s has no RPC URLs", c.ChainID)
+	}
+
+	var lastErr error
+	for _, url := range c.RPCURLs {
+		client, err := ethclient.DialContext(ctx, url)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		chainID, err := client.ChainID(ctx)
+		if err != nil {
+			client.Close()
+			lastErr = err
+			continue
+		}
+		if chainID.Cmp(c.ChainID) != 0 {
+			client.Close()
+			lastErr = fmt.Errorf("%s serves chain %s", url, chainID)
+			continue
+		}
+		return client, nil
+	}
+	return nil, fmt.Errorf("failed to connect to chain %s: %w", c.ChainID, lastErr)
+}
+
+// NewRelayer creates a Relayer for the chain's forwarder, profile and fee settings.
+// Options are applied after the chain settings and take precedence.
+func (c *ChainConfig) NewRelayer(relayerPrivKey *ecdsa.PrivateKey, ethClient EthClient, opts ...RelayerOption) (*Relayer, error) {
+	profile, err := c.profile()
+	if err != nil {
+		return nil, err
+	}
+
+	chainOpts := []RelayerOption{WithForwarderProfile(profile), WithChainID(c.ChainID)}
+	if c.registry != nil {
+		chainOpts = append(chainOpts, WithRegistry(c.registry))
+	}
+	if gas := c.GasStrategy; gas != nil {
+		chainOpts = append(chainOpts, WithGasStrategy(gas))
+	} else if gas := DefaultGasStrategy(c.ChainID); gas != nil {
+		chainOpts = append(chainOpts, WithGasStrategy(gas))
+	}
+	if l1Fees := c.L1FeeEstimator; l1Fees != nil {
+		chainOpts = append(chainOpts, WithL1FeeEstimator(l1Fees))
+	} else if l1Fees := DefaultL1FeeEstimator(c.ChainID); l1Fees != nil {
+		chainOpts = append(chainOpts, WithL1FeeEstimator(l1Fees))
+	}
+	if c.GasMargin != nil {
+		chainOpts = append(chainOpts, WithGasMargin(*c.GasMargin))
+	}
+	if c.FeeCaps != nil {
+		chainOpts = append(chainOpts, WithFeeCaps(*c.FeeCaps))
+	}
+	if c.BidStrategy != nil || c.TxType != TxTypeAuto {
+		bidder := c.BidStrategy
+		if bidder == nil {
+			bidder = SuggestedGasPriceBidder{}
+		}
+		chainOpts = append(chainOpts, WithBidStrategy(txTypeBidder{bidder: bidder, txType: c.TxType}))
+	}
+
+	return NewRelayer(relayerPrivKey, c.Forwarder, ethClient, append(chainOpts, opts...)...), nil
+}
+
+// txTypeBidder converts the bids of another strategy to a fixed transaction type
+type txTypeBidder struct {
+	bidder BidStrategy
+	txType TxType
+}
+
+// Bid returns the inner bid converted to the configured transaction type
+func (t txTypeBidder) Bid(ctx context.Context, bc BidContext) (FeeBid, error) {
+	bid, err := t.bidder.Bid(ctx, bc)
+	if err != nil {
+		return FeeBid{}, err
+	}
+
+	switch {
+	case t.txType == TxTypeLegacy && bid.IsDynamic():
+		// A legacy transaction pays its full gas price, so bid the fee cap
+		return FeeBid{GasPrice: bid.GasFeeCap}, nil
+	case t.txType == TxTypeDynamic && !bid.IsDynamic():
+		return FeeBid{GasTipCap: bid.GasPrice, GasFeeCap: bid.GasPrice}, nil
+	}
+	return bid, nil
+}
+
+// ChainRegistry holds the configuration of every chain a process relays on, with one
+// lazily dialed client per chain. All methods are safe for concurrent use.
+type ChainRegistry struct {
+	mu       sync.RWMutex
+	registry *Registry
+	chains   map[uint64]*ChainConfig
+	clients  map[uint64]*ethclient.Client
+}
+
+// NewChainRegistry creates an empty chain registry resolving profiles in registry,
+// or DefaultRegistry if nil
+func NewChainRegistry(registry *Registry) *ChainRegistry {
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	return &ChainRegistry{
+		registry: registry,
+		chains:   make(map[uint64]*ChainConfig),
+		clients:  make(map[uint64]*ethclient.Client),
+	}
+}
+
+// Register adds or replaces the configuration of a chain
+func (cr *ChainRegistry) Register(cfg *ChainConfig) error {
+	if cfg == nil || cfg.ChainID == nil || cfg.ChainID.Sign() <= 0 || !cfg.ChainID.IsUint64() {
+		return fmt.Errorf("chain config must have a valid chain ID")
+	}
+	if cfg.Forwarder == (common.Address{}) {
+		return fmt.Errorf("chain %s forwarder: %w", cfg.ChainID, ErrZeroAddress)
+	}
+	if cfg.Profile != "" {
+		if _, ok := cr.registry.LookupForwarderProfile(cfg.Profile); !ok {
+			return fmt.Errorf("chain %s: unknown forwarder profile %s", cfg.ChainID, cfg.Profile)
+		}
+	}
+	cfg.registry = cr.registry
+
+	id := cfg.ChainID.Uint64()
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.chains[id] = cfg
+	if client, ok := cr.clients[id]; ok {
+		client.Close()
+		delete(cr.clients, id)
+	}
+	return nil
+}
+
+// Lookup returns the configuration of a chain
+func (cr *ChainRegistry) Lookup(chainID uint64) (*ChainConfig, bool) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	cfg, ok := cr.chains[chainID]
+	return cfg, ok
+}
+
+// ChainIDs returns the registered chain IDs in ascending order
+func (cr *ChainRegistry) ChainIDs() []uint64 {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	ids := make([]uint64, 0, len(cr.chains))
+	for id := range cr.chains {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Client returns the chain's client, dialing it on first use
+func (cr *ChainRegistry) Client(ctx context.Context, chainID uint64) (*ethclient.Client, error) {
+	cr.mu.RLock()
+	client, ok := cr.clients[chainID]
+	cfg, known := cr.chains[chainID]
+	cr.mu.RUnlock()
+	if ok {
+		return client, nil
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown chain %d", chainID)
+	}
+
+	client, err := cfg.Dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if existing, ok := cr.clients[chainID]; ok {
+		// Another caller dialed concurrently
+		client.Close()
+		return existing, nil
+	}
+	cr.clients[chainID] = client
+	return client, nil
+}
+
+// Relayer creates a Relayer on a registered chain using the chain's client
+func (cr *ChainRegistry) Relayer(ctx context.Context, chainID uint64, relayerPrivKey *ecdsa.PrivateKey, opts ...RelayerOption) (*Relayer, error) {
+	cfg, ok := cr.Lookup(chainID)
+	if !ok {
+		return nil, fmt.Errorf("unknown chain %d", chainID)
+	}
+	client, err := cr.Client(ctx, chainID)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.NewRelayer(relayerPrivKey, client, opts...)
+}
+
+// Close closes all dialed clients
+func (cr *ChainRegistry) Close() {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	for id, client := range cr.clients {
+		client.Close()
+		delete(cr.clients, id)
+	}
+}
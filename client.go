@@ -0,0 +1,23 @@
+package eip2771toolkit
+
+import (
+	"github.com/ethereum/go-ethereum"
+)
+
+// EthClient is the subset of the Ethereum RPC client used by the toolkit.
+// Both *ethclient.Client and the simulated backend client satisfy it.
+type EthClient interface {
+	ethereum.BlockNumberReader
+	ethereum.ChainReader
+	ethereum.ChainStateReader
+	ethereum.ContractCaller
+	ethereum.GasEstimator
+	ethereum.GasPricer
+	ethereum.GasPricer1559
+	ethereum.FeeHistoryReader
+	ethereum.LogFilterer
+	ethereum.PendingStateReader
+	ethereum.TransactionReader
+	ethereum.TransactionSender
+	ethereum.ChainIDReader
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// newAddressBookCommand builds the address-book subcommand
+func newAddressBookCommand(flags *globalFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "address-book",
+		Short: "List and edit the labels of the --address-book file",
+		Long: "List and edit the --address-book file, a JSON object of label to address. Every address flag\n" +
+			"accepts its labels, e.g. --token usdc, as does the relayerd configuration with addressBook set.",
+	}
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "Print the labels and their addresses",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			book, err := flags.editAddressBook(false)
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			for _, label := range book.Labels() {
+				address, _ := book.Lookup(label)
+				fmt.Fprintf(w, "%s\t%s\n", label, address.Hex())
+			}
+			return w.Flush()
+		},
+	}
+
+	set := &cobra.Command{
+		Use:   "set <label> <address>",
+		Short: "Add or replace a label, creating the file if needed",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			address, err := eip2771toolkit.ParseAddress(args[1], false)
+			if err != nil {
+				return err
+			}
+			book, err := flags.editAddressBook(true)
+			if err != nil {
+				return err
+			}
+			if err := book.Set(args[0], address); err != nil {
+				return err
+			}
+			return book.Save(flags.addressBook)
+		},
+	}
+
+	remove := &cobra.Command{
+		Use:   "remove <label>",
+		Short: "Remove a label",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			book, err := flags.editAddressBook(false)
+			if err != nil {
+				return err
+			}
+			if !book.Remove(args[0]) {
+				return fmt.Errorf("no label %q in %s", args[0], flags.addressBook)
+			}
+			return book.Save(flags.addressBook)
+		},
+	}
+
+	cmd.AddCommand(list, set, remove)
+	return cmd
+}
+
+// editAddressBook loads the --address-book file, or with create an empty book if it does not
+// exist yet
+func (f *globalFlags) editAddressBook(create bool) (*eip2771toolkit.AddressBook, error) {
+	if f.addressBook == "" {
+		return nil, fmt.Errorf("no address book: set --address-book or $EIP2771_ADDRESS_BOOK")
+	}
+	book, err := f.loadAddressBook()
+	if create && errors.Is(err, fs.ErrNotExist) {
+		return eip2771toolkit.NewAddressBook(), nil
+	}
+	return book, err
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// newAirdropCommand builds the airdrop subcommand
+func newAirdropCommand(flags *globalFlags) *cobra.Command {
+	var (
+		csvPath, resultsPath string
+		token                string
+		signerKeyEnv         string
+		refundReceiver       string
+		cfg                  eip2771toolkit.AirdropConfig
+		timeout              time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "airdrop",
+		Short: "Transfer tokens to the recipient,amount rows of a CSV file",
+		Long: "Sign one transfer per recipient,amount row of a CSV file with sequential forwarder nonces, relay them\n" +
+			"in executeBatch transactions within --max-gas one at a time, and write a results CSV with each row's\n" +
+			"transaction hash and status as they are mined, so files of any size run in constant memory. The\n" +
+			"tokens are sent from the key in --signer-key-env, or the relayer key if unset.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tokenAddr, err := flags.parseAddress("token", token)
+			if err != nil {
+				return err
+			}
+			in, err := os.Open(csvPath)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+
+			relayer, client, err := flags.relayer(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			signerKey, err := flags.privateKey()
+			if err != nil {
+				return err
+			}
+			if hexKey := os.Getenv(signerKeyEnv); hexKey != "" {
+				if signerKey, err = eip2771toolkit.PrivateKeyFromHex(strings.TrimPrefix(hexKey, "0x")); err != nil {
+					return fmt.Errorf("invalid private key in $%s: %w", signerKeyEnv, err)
+				}
+			}
+			if refundReceiver != "" {
+				if cfg.RefundReceiver, err = flags.parseAddress("refund receiver", refundReceiver); err != nil {
+					return err
+				}
+			}
+			cfg.WaitOpts = []eip2771toolkit.WaitOption{eip2771toolkit.WithWaitTimeout(timeout)}
+
+			out, err := os.Create(resultsPath)
+			if err != nil {
+				return err
+			}
+			defer out.Close()
+			writer := eip2771toolkit.NewAirdropResultsWriter(out)
+
+			total, succeeded := 0, 0
+			resolver, err := flags.ensResolver(client)
+			if err != nil {
+				return err
+			}
+			source := eip2771toolkit.NewAirdropCSVReader(in).ResolveNames(cmd.Context(), resolver).Next
+			airdropErr := relayer.AirdropStream(cmd.Context(), signerKey, tokenAddr, source, cfg, func(results []eip2771toolkit.AirdropResult) error {
+				for _, res := range results {
+					if res.Status == eip2771toolkit.AirdropSucceeded {
+						succeeded++
+					}
+				}
+				total += len(results)
+				fmt.Fprintf(cmd.ErrOrStderr(), "%s: %d of %d transfers succeeded\n", results[0].TxHash.Hex(), succeeded, total)
+				return writer.Write(results)
+			})
+			fmt.Fprintf(cmd.OutOrStdout(), "%d of %d transfers succeeded, results in %s\n", succeeded, total, resultsPath)
+			if airdropErr != nil {
+				return airdropErr
+			}
+			if succeeded < total {
+				return fmt.Errorf("%d transfers did not succeed, see %s", total-succeeded, resultsPath)
+			}
+			return out.Close()
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&csvPath, "csv", "", "recipient,amount CSV file, amounts in token base units; recipients may be ENS names with --ens-registry")
+	f.StringVar(&resultsPath, "results", "airdrop-results.csv", "results CSV file to write")
+	f.StringVar(&token, "token", "", "ERC20 token contract")
+	f.StringVar(&signerKeyEnv, "signer-key-env", "EIP2771_SIGNER_KEY", "environment variable holding the token holder's hex private key")
+	f.StringVar(&refundReceiver, "refund-receiver", "", "receiver of skipped requests' value (default: the relayer)")
+	f.Uint64Var(&cfg.Gas, "gas", eip2771toolkit.DEFAULT_GAS_LIMIT, "gas limit of each transfer")
+	f.DurationVar(&cfg.TTL, "ttl", time.Hour, "time until the requests' deadline")
+	f.Uint64Var(&cfg.MaxGasPerTx, "max-gas", eip2771toolkit.DEFAULT_AIRDROP_MAX_GAS, "gas each transaction stays within")
+	f.DurationVar(&timeout, "timeout", 5*time.Minute, "how long to wait for each transaction")
+	cmd.MarkFlagRequired("csv")
+	cmd.MarkFlagRequired("token")
+	return cmd
+}
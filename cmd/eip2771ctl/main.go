@@ -0,0 +1,305 @@
+// Command eip2771ctl signs, verifies, simulates and relays EIP-2771 meta transactions from
+// the command line, so operators can exercise the toolkit without writing Go.
+//
+// Keys are read from a keystore file (--keystore, password from --password-file or
+// $EIP2771_KEYSTORE_PASSWORD) or from the hex private key in the environment variable named
+// by --key-env. Signed requests are read and written as JSON. Address flags take hex addresses
+// or labels of the --address-book file.
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/spf13/cobra"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// globalFlags are the flags shared by all subcommands
+type globalFlags struct {
+	rpcURL       string
+	forwarder    string
+	chainID      uint64
+	keystore     string
+	passwordFile string
+	keyEnv       string
+	addressBook  string
+	ensRegistry  string
+	verbose      bool
+
+	book *eip2771toolkit.AddressBook // loaded from addressBook on first use
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// newRootCommand builds the eip2771ctl command tree
+func newRootCommand() *cobra.Command {
+	flags := &globalFlags{}
+	root := &cobra.Command{
+		Use:          "eip2771ctl",
+		Short:        "Sign, verify, simulate and relay EIP-2771 meta transactions",
+		SilenceUsage: true,
+	}
+	pf := root.PersistentFlags()
+	pf.StringVar(&flags.rpcURL, "rpc", os.Getenv("EIP2771_RPC_URL"), "JSON-RPC endpoint (default $EIP2771_RPC_URL)")
+	pf.StringVar(&flags.forwarder, "forwarder", "", "forwarder address (default: the deployment registered for the chain)")
+	pf.Uint64Var(&flags.chainID, "chain-id", 0, "chain ID, for offline signing and verification (default: asked from --rpc)")
+	pf.StringVar(&flags.keystore, "keystore", "", "encrypted keystore file holding the key")
+	pf.StringVar(&flags.passwordFile, "password-file", "", "file holding the keystore password (default $"+eip2771toolkit.KEYSTORE_PASSWORD_ENV+")")
+	pf.StringVar(&flags.keyEnv, "key-env", eip2771toolkit.DEFAULT_KEY_ENV, "environment variable holding the hex private key")
+	pf.StringVar(&flags.addressBook, "address-book", os.Getenv("EIP2771_ADDRESS_BOOK"), "JSON address book whose labels address flags accept (default $EIP2771_ADDRESS_BOOK)")
+	pf.StringVar(&flags.ensRegistry, "ens-registry", os.Getenv("EIP2771_ENS_REGISTRY"), "ENS registry to resolve recipient names such as vitalik.eth with (default $EIP2771_ENS_REGISTRY)")
+	pf.BoolVarP(&flags.verbose, "verbose", "v", false, "log relay transactions to stderr")
+
+	root.AddCommand(
+		newSignCommand(flags),
+		newVerifyCommand(flags),
+		newNonceCommand(flags),
+		newRelayCommand(flags),
+		newBatchRelayCommand(flags),
+		newSimulateCommand(flags),
+		newAirdropCommand(flags),
+		newAddressBookCommand(flags),
+		newReconcileCommand(flags),
+	)
+	return root
+}
+
+// dial connects to the --rpc endpoint
+func (f *globalFlags) dial(ctx context.Context) (*ethclient.Client, error) {
+	if f.rpcURL == "" {
+		return nil, fmt.Errorf("no RPC endpoint: set --rpc or $EIP2771_RPC_URL")
+	}
+	client, err := ethclient.DialContext(ctx, f.rpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", f.rpcURL, err)
+	}
+	return client, nil
+}
+
+// chain returns the chain ID from --chain-id, or from the node when client is set
+func (f *globalFlags) chain(ctx context.Context, client *ethclient.Client) (*big.Int, error) {
+	if f.chainID != 0 {
+		return new(big.Int).SetUint64(f.chainID), nil
+	}
+	if client == nil {
+		return nil, fmt.Errorf("no chain ID: set --chain-id or --rpc")
+	}
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+	return chainID, nil
+}
+
+// forwarderAddress returns --forwarder, or the deployment registered for chainID
+func (f *globalFlags) forwarderAddress(chainID *big.Int) (common.Address, error) {
+	if f.forwarder != "" {
+		return f.parseAddress("forwarder", f.forwarder)
+	}
+	if deployment, ok := eip2771toolkit.LookupForwarderDeployment(chainID.Uint64()); ok {
+		return deployment.Address, nil
+	}
+	return common.Address{}, fmt.Errorf("no forwarder for chain %s: set --forwarder", chainID)
+}
+
+// domain returns the chain ID, forwarder and EIP-712 domain separator to sign and verify with.
+// client may be nil when --chain-id is set.
+func (f *globalFlags) domain(ctx context.Context, client *ethclient.Client) (*big.Int, common.Address, []byte, error) {
+	chainID, err := f.chain(ctx, client)
+	if err != nil {
+		return nil, common.Address{}, nil, err
+	}
+	forwarder, err := f.forwarderAddress(chainID)
+	if err != nil {
+		return nil, common.Address{}, nil, err
+	}
+	domainSeparator, err := eip2771toolkit.CreateDomainSeparatorForChain(chainID, forwarder)
+	if err != nil {
+		return nil, common.Address{}, nil, err
+	}
+	return chainID, forwarder, domainSeparator, nil
+}
+
+// privateKey loads the key from --keystore or the --key-env environment variable
+func (f *globalFlags) privateKey() (*ecdsa.PrivateKey, error) {
+	if f.keystore != "" {
+		keyJSON, err := os.ReadFile(f.keystore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keystore: %w", err)
+		}
+		password := os.Getenv(eip2771toolkit.KEYSTORE_PASSWORD_ENV)
+		if f.passwordFile != "" {
+			raw, err := os.ReadFile(f.passwordFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read password file: %w", err)
+			}
+			password = strings.TrimRight(string(raw), "\r\n")
+		}
+		key, err := keystore.DecryptKey(keyJSON, password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt keystore: %w", err)
+		}
+		return key.PrivateKey, nil
+	}
+
+	hexKey := os.Getenv(f.keyEnv)
+	if hexKey == "" {
+		return nil, fmt.Errorf("no key: set --keystore or $%s", f.keyEnv)
+	}
+	key, err := eip2771toolkit.PrivateKeyFromHex(strings.TrimPrefix(hexKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key in $%s: %w", f.keyEnv, err)
+	}
+	return key, nil
+}
+
+// relayer dials the node and builds a relayer for the forwarder with the loaded key and the
+// chain's default gas strategy
+func (f *globalFlags) relayer(ctx context.Context) (*eip2771toolkit.Relayer, *ethclient.Client, error) {
+	key, err := f.privateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := f.dial(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	chainID, err := f.chain(ctx, client)
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+	forwarder, err := f.forwarderAddress(chainID)
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+	cfg := &eip2771toolkit.ChainConfig{ChainID: chainID, Forwarder: forwarder}
+	var opts []eip2771toolkit.RelayerOption
+	if f.verbose {
+		opts = append(opts, eip2771toolkit.WithLogger(slog.New(slog.NewTextHandler(os.Stderr, nil))))
+	}
+	relayer, err := cfg.NewRelayer(key, client, opts...)
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+	return relayer, client, nil
+}
+
+// ensResolver returns a resolver using --ens-registry through client, or nil if it is unset
+func (f *globalFlags) ensResolver(client *ethclient.Client) (*eip2771toolkit.ENSResolver, error) {
+	if f.ensRegistry == "" {
+		return nil, nil
+	}
+	registry, err := f.parseAddress("ENS registry", f.ensRegistry)
+	if err != nil {
+		return nil, err
+	}
+	return eip2771toolkit.NewENSResolver(client, registry), nil
+}
+
+// parseRecipient parses a recipient flag given as a hex address, an address book label or,
+// with --ens-registry, an ENS name resolved through client
+func (f *globalFlags) parseRecipient(ctx context.Context, client *ethclient.Client, name, s string) (common.Address, error) {
+	if !eip2771toolkit.IsENSName(s) {
+		return f.parseAddress(name, s)
+	}
+	if f.ensRegistry == "" {
+		return common.Address{}, fmt.Errorf("cannot resolve %s %q: set --ens-registry or $EIP2771_ENS_REGISTRY", name, s)
+	}
+	resolver, err := f.ensResolver(client)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return resolver.Resolve(ctx, s)
+}
+
+// parseAddress parses an address flag given as a hex address or an address book label
+func (f *globalFlags) parseAddress(name, s string) (common.Address, error) {
+	book, err := f.loadAddressBook()
+	if err != nil {
+		return common.Address{}, err
+	}
+	address, err := book.Resolve(s)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return address, nil
+}
+
+// loadAddressBook returns the --address-book file, nil if it is unset
+func (f *globalFlags) loadAddressBook() (*eip2771toolkit.AddressBook, error) {
+	if f.book == nil && f.addressBook != "" {
+		book, err := eip2771toolkit.LoadAddressBook(f.addressBook)
+		if err != nil {
+			return nil, err
+		}
+		f.book = book
+	}
+	return f.book, nil
+}
+
+// readInput reads a file, or stdin for "-"
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// readRequests reads one signed request or a JSON list of them
+func readRequests(path string) (eip2771toolkit.BatchMetaTxRequestList, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read requests: %w", err)
+	}
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") {
+		var batch eip2771toolkit.BatchMetaTxRequestList
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return nil, fmt.Errorf("failed to decode requests: %w", err)
+		}
+		return batch, nil
+	}
+	var req eip2771toolkit.BatchMetaTxRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("failed to decode request: %w", err)
+	}
+	return eip2771toolkit.BatchMetaTxRequestList{req}, nil
+}
+
+// readRequest reads exactly one signed request
+func readRequest(path string) (eip2771toolkit.BatchMetaTxRequest, error) {
+	batch, err := readRequests(path)
+	if err != nil {
+		return eip2771toolkit.BatchMetaTxRequest{}, err
+	}
+	if len(batch) != 1 {
+		return eip2771toolkit.BatchMetaTxRequest{}, fmt.Errorf("expected one request, got %d", len(batch))
+	}
+	return batch[0], nil
+}
+
+// printJSON writes v as indented JSON
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
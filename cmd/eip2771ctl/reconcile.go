@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	_ "modernc.org/sqlite"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+	"github.com/ethanzhrepo/eip2771toolkit/server"
+)
+
+// newReconcileCommand builds the reconcile subcommand
+func newReconcileCommand(flags *globalFlags) *cobra.Command {
+	var (
+		store, relayerAddress string
+		fromBlock, toBlock    uint64
+	)
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Cross-reference a relay server's store with the chain over a block range",
+		Long: "Compare the records of a relayerd SQLite store with the forwarder transactions the relayer account\n" +
+			"sent and the forwarder's ExecutedForwardRequest events in blocks --from to --to, and print the\n" +
+			"discrepancies as JSON: transactions no record holds, executions the store did not record, and\n" +
+			"records executed without an event. Exits with an error if there are any.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			path, ok := strings.CutPrefix(store, "sqlite:")
+			if !ok {
+				return fmt.Errorf("unknown store %q, expected sqlite:<path>", store)
+			}
+			client, err := flags.dial(ctx)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			var relayer common.Address
+			if relayerAddress != "" {
+				if relayer, err = flags.parseAddress("relayer", relayerAddress); err != nil {
+					return err
+				}
+			} else {
+				key, err := flags.privateKey()
+				if err != nil {
+					return err
+				}
+				relayer = eip2771toolkit.AddressFromPrivateKey(key)
+			}
+			_, forwarder, _, err := flags.domain(ctx, client)
+			if err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("to") {
+				if toBlock, err = client.BlockNumber(ctx); err != nil {
+					return fmt.Errorf("failed to get block number: %w", err)
+				}
+			}
+			if toBlock < fromBlock {
+				return fmt.Errorf("--to %d is below --from %d", toBlock, fromBlock)
+			}
+
+			db, err := sql.Open("sqlite", path)
+			if err != nil {
+				return fmt.Errorf("failed to open store: %w", err)
+			}
+			defer db.Close()
+			sqlStore, err := server.OpenSQLStore(ctx, db, server.SQLiteDialect)
+			if err != nil {
+				return err
+			}
+
+			report, err := server.ReconcileBlocks(ctx, client, forwarder, relayer, sqlStore, fromBlock, toBlock)
+			if err != nil {
+				return err
+			}
+			if err := printJSON(cmd.OutOrStdout(), report); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "blocks %d-%d: %d relayer transactions, %d forwarder events, %d discrepancies\n",
+				report.FromBlock, report.ToBlock, report.Transactions, report.Events, len(report.Discrepancies))
+			if !report.OK() {
+				return fmt.Errorf("%d discrepancies between the store and the chain", len(report.Discrepancies))
+			}
+			return nil
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&store, "store", "", "relay server store, as sqlite:<path>")
+	f.StringVar(&relayerAddress, "relayer", "", "relayer account (default: the address of the loaded key)")
+	f.Uint64Var(&fromBlock, "from", 0, "first block to check")
+	f.Uint64Var(&toBlock, "to", 0, "last block to check (default: the latest block)")
+	cmd.MarkFlagRequired("store")
+	cmd.MarkFlagRequired("from")
+	return cmd
+}
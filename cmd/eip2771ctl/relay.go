@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/spf13/cobra"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// newNonceCommand builds the nonce subcommand
+func newNonceCommand(flags *globalFlags) *cobra.Command {
+	var address string
+	cmd := &cobra.Command{
+		Use:   "nonce",
+		Short: "Print the forwarder nonce of an address",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			client, err := flags.dial(ctx)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			var user common.Address
+			if address != "" {
+				if user, err = flags.parseAddress("address", address); err != nil {
+					return err
+				}
+			} else {
+				key, err := flags.privateKey()
+				if err != nil {
+					return err
+				}
+				user = eip2771toolkit.AddressFromPrivateKey(key)
+			}
+			_, forwarder, _, err := flags.domain(ctx, client)
+			if err != nil {
+				return err
+			}
+			nonce, err := eip2771toolkit.GetMetaTxNonce(ctx, forwarder, user, client)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), nonce)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&address, "address", "", "signer address (default: the address of the loaded key)")
+	return cmd
+}
+
+// waitFlags are the flags of subcommands that can wait for their relay to be mined
+type waitFlags struct {
+	wait    bool
+	timeout time.Duration
+}
+
+// register adds the wait flags to cmd
+func (w *waitFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&w.wait, "wait", false, "wait for the relay transaction to be mined")
+	cmd.Flags().DurationVar(&w.timeout, "timeout", 5*time.Minute, "how long to wait with --wait")
+}
+
+// report prints the relay transaction hash and, with --wait, its outcome and whether the
+// forwarder executed every request
+func (w *waitFlags) report(cmd *cobra.Command, relayer *eip2771toolkit.Relayer, txHash common.Hash, requests eip2771toolkit.BatchMetaTxRequestList) error {
+	fmt.Fprintln(cmd.OutOrStdout(), txHash.Hex())
+	if !w.wait {
+		return nil
+	}
+	result, err := relayer.WaitForRelay(cmd.Context(), txHash, eip2771toolkit.WithWaitTimeout(w.timeout))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "mined in block %d, gas used %d\n", result.BlockNumber, result.GasUsed)
+	if !result.Succeeded() {
+		if reason := relayer.RevertReason(cmd.Context(), txHash); reason != nil {
+			return reason
+		}
+		return fmt.Errorf("%w: relay transaction %s reverted", eip2771toolkit.ErrContractCallFailed, txHash.Hex())
+	}
+	return relayer.CheckExecuted(cmd.Context(), txHash, requests)
+}
+
+// newRelayCommand builds the relay subcommand
+func newRelayCommand(flags *globalFlags) *cobra.Command {
+	var (
+		in   string
+		wait waitFlags
+	)
+	cmd := &cobra.Command{
+		Use:   "relay",
+		Short: "Relay a signed request through the forwarder's execute",
+		Long:  "Relay a signed request through the forwarder's execute, paying gas with the loaded key.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req, err := readRequest(in)
+			if err != nil {
+				return err
+			}
+			relayer, client, err := flags.relayer(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			txHash, err := relayer.RelayMetaTx(cmd.Context(), req.MetaTx, req.Signature)
+			if err != nil {
+				return err
+			}
+			return wait.report(cmd, relayer, txHash, eip2771toolkit.BatchMetaTxRequestList{req})
+		},
+	}
+	cmd.Flags().StringVar(&in, "in", "-", "signed request JSON file, - for stdin")
+	wait.register(cmd)
+	return cmd
+}
+
+// newBatchRelayCommand builds the batch-relay subcommand
+func newBatchRelayCommand(flags *globalFlags) *cobra.Command {
+	var (
+		in             string
+		refundReceiver string
+		atomic         bool
+		wait           waitFlags
+	)
+	cmd := &cobra.Command{
+		Use:   "batch-relay",
+		Short: "Relay signed requests through the forwarder's executeBatch",
+		Long: "Relay a JSON list of signed requests, or a batch file written by SaveBatch, through the forwarder's\n" +
+			"executeBatch. Without --atomic, requests that would fail are skipped and their value refunded to\n" +
+			"--refund-receiver (default: the relayer).",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batch, file, err := readBatch(in)
+			if err != nil {
+				return err
+			}
+			relayer, client, err := flags.relayer(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			if file != nil {
+				if file.Domain.Forwarder != relayer.Forwarder() {
+					return fmt.Errorf("batch was signed for forwarder %s, relaying through %s", file.Domain.Forwarder.Hex(), relayer.Forwarder().Hex())
+				}
+				if err := file.Verify(eip2771toolkit.DefaultRegistry); err != nil {
+					return err
+				}
+			}
+
+			var txHash common.Hash
+			if atomic {
+				txHash, err = relayer.RelayMetaTxBatchAtomic(cmd.Context(), batch)
+			} else {
+				receiver := relayer.Address()
+				if refundReceiver != "" {
+					if receiver, err = flags.parseAddress("refund receiver", refundReceiver); err != nil {
+						return err
+					}
+				}
+				txHash, err = relayer.RelayMetaTxBatch(cmd.Context(), batch, receiver)
+			}
+			if err != nil {
+				return err
+			}
+			return wait.report(cmd, relayer, txHash, batch)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&in, "in", "-", "JSON list of signed requests or batch file, - for stdin")
+	f.StringVar(&refundReceiver, "refund-receiver", "", "receiver of the value of skipped requests")
+	f.BoolVar(&atomic, "atomic", false, "revert the whole batch if any request fails")
+	wait.register(cmd)
+	return cmd
+}
+
+// readBatch reads a JSON list of signed requests or a batch file; file is nil for lists
+func readBatch(path string) (eip2771toolkit.BatchMetaTxRequestList, *eip2771toolkit.BatchFile, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read batch: %w", err)
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		file, err := eip2771toolkit.LoadBatch(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		return file.Requests, &file, nil
+	}
+	var batch eip2771toolkit.BatchMetaTxRequestList
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode batch: %w", err)
+	}
+	return batch, nil, nil
+}
+
+// simulationOutput is the JSON printed by the simulate subcommand
+type simulationOutput struct {
+	GasEstimate     uint64        `json:"gasEstimate"`
+	GasLimit        uint64        `json:"gasLimit"`
+	InnerReturnData hexutil.Bytes `json:"innerReturnData"`
+}
+
+// newSimulateCommand builds the simulate subcommand
+func newSimulateCommand(flags *globalFlags) *cobra.Command {
+	var in string
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Dry-run relaying a signed request without sending anything",
+		Long:  "Dry-run relaying a signed request with eth_call and print the gas it would use, or why it would revert.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req, err := readRequest(in)
+			if err != nil {
+				return err
+			}
+			relayer, client, err := flags.relayer(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			result, err := relayer.SimulateRelay(cmd.Context(), req.MetaTx, req.Signature)
+			if err != nil {
+				return err
+			}
+			return printJSON(cmd.OutOrStdout(), simulationOutput{
+				GasEstimate:     result.GasEstimate,
+				GasLimit:        result.GasLimit,
+				InnerReturnData: result.InnerReturnData,
+			})
+		},
+	}
+	cmd.Flags().StringVar(&in, "in", "-", "signed request JSON file, - for stdin")
+	return cmd
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/spf13/cobra"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// newSignCommand builds the sign subcommand
+func newSignCommand(flags *globalFlags) *cobra.Command {
+	var (
+		to, token, amount string
+		gas               uint64
+		nonce             int64
+		ttl               time.Duration
+		deadline          uint64
+	)
+	cmd := &cobra.Command{
+		Use:   "sign",
+		Short: "Sign a token transfer meta transaction and print it as JSON",
+		Long: "Sign a token transfer meta transaction for the forwarder and print the signed request as JSON.\n" +
+			"Without --nonce the forwarder nonce is read from --rpc; with --nonce and --chain-id signing is offline.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			key, err := flags.privateKey()
+			if err != nil {
+				return err
+			}
+			tokenAddr, err := flags.parseAddress("token", token)
+			if err != nil {
+				return err
+			}
+			value, ok := new(big.Int).SetString(amount, 0)
+			if !ok || value.Sign() <= 0 {
+				return fmt.Errorf("invalid amount %q", amount)
+			}
+
+			var client *ethclient.Client
+			if nonce < 0 || flags.chainID == 0 || eip2771toolkit.IsENSName(to) {
+				if client, err = flags.dial(ctx); err != nil {
+					return err
+				}
+				defer client.Close()
+			}
+			toAddr, err := flags.parseRecipient(ctx, client, "to", to)
+			if err != nil {
+				return err
+			}
+			_, forwarder, domainSeparator, err := flags.domain(ctx, client)
+			if err != nil {
+				return err
+			}
+
+			from := eip2771toolkit.AddressFromPrivateKey(key)
+			if nonce < 0 {
+				n, err := eip2771toolkit.GetMetaTxNonce(ctx, forwarder, from, client)
+				if err != nil {
+					return err
+				}
+				nonce = int64(n)
+			}
+			if deadline == 0 {
+				deadline = uint64(time.Now().Add(ttl).Unix())
+			}
+
+			metaTx := eip2771toolkit.NewMetaTx(from, toAddr, tokenAddr, value, gas, uint64(nonce), deadline)
+			req, err := eip2771toolkit.CreateBatchRequest(metaTx, key, domainSeparator)
+			if err != nil {
+				return err
+			}
+			return printJSON(cmd.OutOrStdout(), req)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&to, "to", "", "token recipient, an address or with --ens-registry an ENS name")
+	f.StringVar(&token, "token", "", "ERC20 token contract")
+	f.StringVar(&amount, "amount", "", "amount in base units, decimal or 0x-hex")
+	f.Uint64Var(&gas, "gas", eip2771toolkit.DEFAULT_GAS_LIMIT, "gas limit of the inner call")
+	f.Int64Var(&nonce, "nonce", -1, "forwarder nonce (default: read from the forwarder)")
+	f.DurationVar(&ttl, "ttl", time.Hour, "time until the deadline")
+	f.Uint64Var(&deadline, "deadline", 0, "deadline as unix timestamp, overrides --ttl")
+	cmd.MarkFlagRequired("to")
+	cmd.MarkFlagRequired("token")
+	cmd.MarkFlagRequired("amount")
+	return cmd
+}
+
+// newVerifyCommand builds the verify subcommand
+func newVerifyCommand(flags *globalFlags) *cobra.Command {
+	var in string
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the signatures of signed requests",
+		Long:  "Verify the signatures of a signed request or a JSON list of them against the forwarder domain.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			batch, err := readRequests(in)
+			if err != nil {
+				return err
+			}
+
+			var client *ethclient.Client
+			if flags.chainID == 0 {
+				if client, err = flags.dial(ctx); err != nil {
+					return err
+				}
+				defer client.Close()
+			}
+			_, _, domainSeparator, err := flags.domain(ctx, client)
+			if err != nil {
+				return err
+			}
+
+			invalid := 0
+			for i, req := range batch {
+				valid, err := eip2771toolkit.VerifyMetaTxSignature(req.MetaTx, req.Signature, domainSeparator)
+				if err != nil {
+					return fmt.Errorf("request at index %d: %w", i, err)
+				}
+				status := "valid"
+				if !valid {
+					status = "INVALID"
+					invalid++
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%d\t%s\tnonce %d\t%s\n", i, req.MetaTx.From.Hex(), req.MetaTx.Nonce, status)
+			}
+			if invalid > 0 {
+				return fmt.Errorf("%d of %d signatures invalid: %w", invalid, len(batch), eip2771toolkit.ErrInvalidSignature)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&in, "in", "-", "signed request JSON file, - for stdin")
+	return cmd
+}
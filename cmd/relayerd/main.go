@@ -0,0 +1,269 @@
+// Command relayerd runs a gasless relay service from a configuration file (see
+// eip2771toolkit.LoadConfig): one relayer and REST API per configured chain, served under
+// /chains/<id>/, with request records in memory or SQLite. With server.apiKeys configured,
+// API requests must carry one of the keys and are attributed to its sponsor.
+//
+// The optional admin address serves /healthz, /debug/vars (expvar, including relay fee
+// statistics), /fees and /budgets. Policies, budget limits, fee caps and gas margins are
+// reloaded when the configuration file changes or on SIGHUP; SIGINT and SIGTERM stop the
+// daemon gracefully.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	_ "modernc.org/sqlite"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+	"github.com/ethanzhrepo/eip2771toolkit/server"
+)
+
+// CONFIG_ENV is the environment variable naming the configuration file by default
+const CONFIG_ENV = "EIP2771_CONFIG"
+
+// SHUTDOWN_TIMEOUT bounds how long in-flight HTTP requests are waited for on shutdown
+const SHUTDOWN_TIMEOUT = 30 * time.Second
+
+// daemonFlags are the command line flags of relayerd
+type daemonFlags struct {
+	configPath   string
+	logLevel     string
+	skipValidate bool
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// newRootCommand builds the relayerd command
+func newRootCommand() *cobra.Command {
+	flags := &daemonFlags{}
+	root := &cobra.Command{
+		Use:          "relayerd",
+		Short:        "Run a gasless EIP-2771 relay service",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(cmd.Context(), flags)
+		},
+	}
+	f := root.Flags()
+	f.StringVarP(&flags.configPath, "config", "c", os.Getenv(CONFIG_ENV), "YAML configuration file (default $"+CONFIG_ENV+")")
+	f.StringVar(&flags.logLevel, "log-level", "info", "debug, info, warn or error")
+	f.BoolVar(&flags.skipValidate, "skip-validate", false, "start without checking endpoints, forwarders and relayer funds")
+	return root
+}
+
+// chainService is the relayer and API server of one chain
+type chainService struct {
+	chain   *eip2771toolkit.ChainConfig
+	relayer *eip2771toolkit.Relayer
+	server  *server.Server
+	db      *sql.DB // nil for the memory store
+}
+
+// close stops the server and closes its store
+func (s *chainService) close() {
+	s.server.Close()
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+// run starts the daemon and blocks until it is stopped
+func run(ctx context.Context, flags *daemonFlags) error {
+	if flags.configPath == "" {
+		return fmt.Errorf("no configuration: set --config or $%s", CONFIG_ENV)
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(flags.logLevel)); err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := eip2771toolkit.LoadConfig(flags.configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.Server.Addr == "" {
+		return fmt.Errorf("server.addr is not set")
+	}
+	if !flags.skipValidate {
+		if err := cfg.Validate(ctx); err != nil {
+			return fmt.Errorf("invalid configuration:\n%w", err)
+		}
+	}
+
+	apiKeys := make([]server.APIKey, len(cfg.Server.APIKeys))
+	for i, k := range cfg.Server.APIKeys {
+		key, secret, err := k.Load()
+		if err != nil {
+			return err
+		}
+		apiKeys[i] = server.APIKey{Key: key, Sponsor: k.Sponsor, Secret: secret}
+	}
+
+	chains, err := cfg.ChainRegistry(nil)
+	if err != nil {
+		return err
+	}
+	defer chains.Close()
+
+	fees := eip2771toolkit.NewFeeTracker(0)
+	fees.PublishExpvar("relayFees")
+	opts := append(cfg.RelayerOptions(), eip2771toolkit.WithLogger(logger), eip2771toolkit.WithFeeTracker(fees))
+
+	api := http.NewServeMux()
+	var services []*chainService
+	defer func() {
+		for _, svc := range services {
+			svc.close()
+		}
+	}()
+	for _, chain := range cfg.Chains {
+		id := chain.ChainID.Uint64()
+		svc, err := newChainService(ctx, cfg, chains, chain, opts, apiKeys, logger.With("chain", id))
+		if err != nil {
+			return fmt.Errorf("chain %d: %w", id, err)
+		}
+		services = append(services, svc)
+		prefix := fmt.Sprintf("/chains/%d", id)
+		api.Handle(prefix+"/", http.StripPrefix(prefix, svc.server.Handler()))
+		logger.Info("relaying", "chain", id, "forwarder", chain.Forwarder, "relayer", svc.relayer.Address())
+	}
+
+	// Reloads replace cfg's policies and budget while the admin handlers read them
+	var cfgMu sync.Mutex
+	go eip2771toolkit.WatchConfig(ctx, flags.configPath, 0, func(next *eip2771toolkit.Config, err error) {
+		if err != nil {
+			logger.Error("configuration not reloaded", "error", err)
+			return
+		}
+		cfgMu.Lock()
+		defer cfgMu.Unlock()
+		cfg.Reload(next)
+		for _, svc := range services {
+			cfg.ApplyTo(svc.relayer)
+			svc.chain.ApplyTo(svc.relayer)
+		}
+		logger.Info("configuration reloaded")
+	})
+
+	servers := []*http.Server{{Addr: cfg.Server.Addr, Handler: api}}
+	if cfg.Server.AdminAddr != "" {
+		admin := http.NewServeMux()
+		admin.HandleFunc("GET /healthz", func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprintln(w, "ok")
+		})
+		admin.Handle("GET /debug/vars", expvar.Handler())
+		admin.Handle("GET /fees", fees.Handler())
+		admin.Handle("GET /budgets", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			cfgMu.Lock()
+			budget := cfg.Budget
+			cfgMu.Unlock()
+			if budget == nil {
+				http.Error(w, "no budgets configured", http.StatusNotFound)
+				return
+			}
+			budget.Handler().ServeHTTP(w, req)
+		}))
+		servers = append(servers, &http.Server{Addr: cfg.Server.AdminAddr, Handler: admin})
+	}
+
+	errc := make(chan error, len(servers))
+	for _, srv := range servers {
+		logger.Info("listening", "addr", srv.Addr)
+		go func() {
+			if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				errc <- err
+			}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		logger.Info("shutting down")
+	case err = <-errc:
+		logger.Error("server failed", "error", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+	defer cancel()
+	for _, srv := range servers {
+		srv.Shutdown(shutdownCtx)
+	}
+	return err
+}
+
+// newChainService creates the relayer, store and server of one chain
+func newChainService(ctx context.Context, cfg *eip2771toolkit.Config, chains *eip2771toolkit.ChainRegistry, chain *eip2771toolkit.ChainConfig, opts []eip2771toolkit.RelayerOption, apiKeys []server.APIKey, logger *slog.Logger) (*chainService, error) {
+	id := chain.ChainID.Uint64()
+	relayer, err := chains.Relayer(ctx, id, cfg.RelayerKey, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := &chainService{chain: chain, relayer: relayer}
+	serverOpts := []server.Option{server.WithLogger(logger)}
+	if cfg.Server.Confirmations > 0 {
+		serverOpts = append(serverOpts, server.WithConfirmations(cfg.Server.Confirmations))
+	}
+	if cfg.Server.PendingTimeout > 0 {
+		serverOpts = append(serverOpts, server.WithPendingTimeout(cfg.Server.PendingTimeout))
+	}
+	if len(apiKeys) > 0 {
+		serverOpts = append(serverOpts, server.WithAPIKeys(apiKeys...))
+	}
+
+	switch store := cfg.Server.Store; {
+	case store == "" || store == "memory":
+	case strings.HasPrefix(store, "sqlite:"):
+		path := strings.TrimPrefix(store, "sqlite:")
+		if len(cfg.Chains) > 1 {
+			path = chainStorePath(path, id)
+		}
+		svc.db, err = sql.Open("sqlite", path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open store: %w", err)
+		}
+		// SQLite serializes writers; one connection avoids busy errors
+		svc.db.SetMaxOpenConns(1)
+		sqlStore, err := server.OpenSQLStore(ctx, svc.db, server.SQLiteDialect)
+		if err != nil {
+			svc.db.Close()
+			return nil, err
+		}
+		serverOpts = append(serverOpts, server.WithStore(sqlStore))
+	default:
+		return nil, fmt.Errorf("unknown store %q, expected memory or sqlite:<path>", store)
+	}
+
+	svc.server = server.New(relayer, serverOpts...)
+	return svc, nil
+}
+
+// chainStorePath gives each chain its own database next to path, e.g. relayer-137.db, as
+// forwarders deployed at the same address on several chains would share nonce slots
+func chainStorePath(path string, chainID uint64) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), chainID, ext)
+}
@@ -0,0 +1,225 @@
+package eip2771toolkit
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// COMPACT_ENCODING_VERSION is the first byte of the compact encoding of signed requests
+const COMPACT_ENCODING_VERSION = 1
+
+// MAX_DEADLINE is the largest deadline the forwarder accepts, the uint48 maximum
+const MAX_DEADLINE = 1<<48 - 1
+
+// maxUint256 is the largest token amount
+var maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// compactRequest is the RLP layout of a signed request
+type compactRequest struct {
+	From     common.Address
+	To       common.Address
+	Token    common.Address
+	Amount   *big.Int
+	Gas      uint64
+	Nonce    uint64
+	Deadline uint64
+	V        uint8
+	R        [32]byte
+	S        [32]byte
+}
+
+// EncodeCompact encodes a signed request in a compact binary form for bandwidth-sensitive
+// transports: a version byte followed by the RLP encoding of the request fields, less than
+// half the size of the JSON encoding
+func EncodeCompact(req BatchMetaTxRequest) ([]byte, error) {
+	data, err := rlp.EncodeToBytes(toCompact(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	return append([]byte{COMPACT_ENCODING_VERSION}, data...), nil
+}
+
+// DecodeCompact decodes a request encoded by EncodeCompact. Decoding is strict: other
+// versions, non-canonical or trailing bytes, a zero or oversized amount, a zero or out of
+// range deadline and a signature V other than 0, 1, 27 or 28 are rejected.
+func DecodeCompact(data []byte) (BatchMetaTxRequest, error) {
+	payload, err := compactPayload(data)
+	if err != nil {
+		return BatchMetaTxRequest{}, err
+	}
+	var enc compactRequest
+	if err := rlp.DecodeBytes(payload, &enc); err != nil {
+		return BatchMetaTxRequest{}, fmt.Errorf("failed to decode request: %w", err)
+	}
+	return fromCompact(enc)
+}
+
+// compactBatch is the RLP layout of a batch: every distinct address once, referenced by
+// index from the requests, so batches of one signer and token shrink further
+type compactBatch struct {
+	Addresses []common.Address
+	Requests  []compactBatchEntry
+}
+
+// compactBatchEntry is a request of a compactBatch
+type compactBatchEntry struct {
+	From     uint64
+	To       uint64
+	Token    uint64
+	Amount   *big.Int
+	Gas      uint64
+	Nonce    uint64
+	Deadline uint64
+	V        uint8
+	R        [32]byte
+	S        [32]byte
+}
+
+// EncodeCompactBatch encodes a batch like EncodeCompact, storing each distinct address once
+func EncodeCompactBatch(batch BatchMetaTxRequestList) ([]byte, error) {
+	var enc compactBatch
+	indexes := make(map[common.Address]uint64)
+	index := func(addr common.Address) uint64 {
+		i, ok := indexes[addr]
+		if !ok {
+			i = uint64(len(enc.Addresses))
+			indexes[addr] = i
+			enc.Addresses = append(enc.Addresses, addr)
+		}
+		return i
+	}
+	for _, req := range batch {
+		c := toCompact(req)
+		enc.Requests = append(enc.Requests, compactBatchEntry{
+			From:     index(c.From),
+			To:       index(c.To),
+			Token:    index(c.Token),
+			Amount:   c.Amount,
+			Gas:      c.Gas,
+			Nonce:    c.Nonce,
+			Deadline: c.Deadline,
+			V:        c.V,
+			R:        c.R,
+			S:        c.S,
+		})
+	}
+	data, err := rlp.EncodeToBytes(enc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode batch: %w", err)
+	}
+	return append([]byte{COMPACT_ENCODING_VERSION}, data...), nil
+}
+
+// DecodeCompactBatch decodes a batch encoded by EncodeCompactBatch, validating every request
+// like DecodeCompact. Duplicate addresses and out of range address indexes are rejected.
+func DecodeCompactBatch(data []byte) (BatchMetaTxRequestList, error) {
+	payload, err := compactPayload(data)
+	if err != nil {
+		return nil, err
+	}
+	var enc compactBatch
+	if err := rlp.DecodeBytes(payload, &enc); err != nil {
+		return nil, fmt.Errorf("failed to decode batch: %w", err)
+	}
+
+	seen := make(map[common.Address]bool, len(enc.Addresses))
+	for _, addr := range enc.Addresses {
+		if seen[addr] {
+			return nil, fmt.Errorf("duplicate address %s in batch", addr.Hex())
+		}
+		seen[addr] = true
+	}
+	address := func(i uint64) (common.Address, error) {
+		if i >= uint64(len(enc.Addresses)) {
+			return common.Address{}, fmt.Errorf("address index %d out of range", i)
+		}
+		return enc.Addresses[i], nil
+	}
+
+	batch := make(BatchMetaTxRequestList, len(enc.Requests))
+	for i, entry := range enc.Requests {
+		c := compactRequest{
+			Amount:   entry.Amount,
+			Gas:      entry.Gas,
+			Nonce:    entry.Nonce,
+			Deadline: entry.Deadline,
+			V:        entry.V,
+			R:        entry.R,
+			S:        entry.S,
+		}
+		if c.From, err = address(entry.From); err == nil {
+			if c.To, err = address(entry.To); err == nil {
+				c.Token, err = address(entry.Token)
+			}
+		}
+		if err == nil {
+			batch[i], err = fromCompact(c)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("request at index %d: %w", i, err)
+		}
+	}
+	return batch, nil
+}
+
+// compactPayload checks the version byte and returns the RLP payload
+func compactPayload(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty compact encoding")
+	}
+	if data[0] != COMPACT_ENCODING_VERSION {
+		return nil, fmt.Errorf("unsupported compact encoding version %d", data[0])
+	}
+	return data[1:], nil
+}
+
+// toCompact converts a signed request to its RLP layout
+func toCompact(req BatchMetaTxRequest) compactRequest {
+	m, sig := req.MetaTx, req.Signature
+	amount := m.Amount
+	if amount == nil {
+		amount = new(big.Int)
+	}
+	return compactRequest{
+		From:     m.From,
+		To:       m.To,
+		Token:    m.Token,
+		Amount:   amount,
+		Gas:      m.Gas,
+		Nonce:    m.Nonce,
+		Deadline: m.Deadline,
+		V:        sig.V,
+		R:        sig.R,
+		S:        sig.S,
+	}
+}
+
+// fromCompact validates and converts the RLP layout to a signed request
+func fromCompact(enc compactRequest) (BatchMetaTxRequest, error) {
+	if enc.Amount.Sign() <= 0 || enc.Amount.Cmp(maxUint256) > 0 {
+		return BatchMetaTxRequest{}, ErrInvalidAmount
+	}
+	if enc.Deadline == 0 || enc.Deadline > MAX_DEADLINE {
+		return BatchMetaTxRequest{}, fmt.Errorf("invalid deadline %d", enc.Deadline)
+	}
+	switch enc.V {
+	case 0, 1, 27, 28:
+	default:
+		return BatchMetaTxRequest{}, fmt.Errorf("%w: v %d", ErrInvalidSignature, enc.V)
+	}
+	return BatchMetaTxRequest{
+		MetaTx: MetaTx{
+			From:     enc.From,
+			To:       enc.To,
+			Token:    enc.Token,
+			Amount:   enc.Amount,
+			Gas:      enc.Gas,
+			Nonce:    enc.Nonce,
+			Deadline: enc.Deadline,
+		},
+		Signature: Signature{V: enc.V, R: enc.R, S: enc.S},
+	}, nil
+}
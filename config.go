@@ -0,0 +1,155 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Config is the startup configuration of a relaying process
+type Config struct {
+	RelayerKey     *ecdsa.PrivateKey
+	RelayerAddress common.Address // optional, checked against RelayerKey when set
+	Chains         []*ChainConfig
+	Sandbox        bool     // dry-run mode, see WithSandbox
+	Policies       []Policy // see WithPolicies
+	Budget         *Budget  // nil for unmetered sponsorship, see WithBudget
+	Server         ServerConfig
+}
+
+// RelayerOptions returns the options relayers created from this configuration need
+func (c *Config) RelayerOptions() []RelayerOption {
+	opts := []RelayerOption{WithSandbox(c.Sandbox), WithPolicies(c.Policies...)}
+	if c.Budget != nil {
+		opts = append(opts, WithBudget(c.Budget))
+	}
+	return opts
+}
+
+// Validate checks the configuration against the live networks before any relay is attempted:
+// every RPC URL must answer eth_chainId with its chain's ID, every forwarder must have code,
+// and the relayer key must match RelayerAddress and hold funds on every chain. All problems
+// are reported together, each prefixed with the chain and endpoint it concerns.
+func (c *Config) Validate(ctx context.Context) error {
+	var errs []error
+
+	var relayer common.Address
+	if c.RelayerKey == nil {
+		errs = append(errs, fmt.Errorf("relayer key is not set"))
+	} else {
+		relayer = crypto.PubkeyToAddress(c.RelayerKey.PublicKey)
+		if c.RelayerAddress != (common.Address{}) && c.RelayerAddress != relayer {
+			errs = append(errs, fmt.Errorf("relayer key belongs to %s, but relayer address is configured as %s",
+				relayer.Hex(), c.RelayerAddress.Hex()))
+		}
+	}
+
+	if len(c.Chains) == 0 {
+		errs = append(errs, fmt.Errorf("no chains configured"))
+	}
+
+	seen := make(map[uint64]bool)
+	for i, chain := range c.Chains {
+		if chain == nil || chain.ChainID == nil || chain.ChainID.Sign() <= 0 || !chain.ChainID.IsUint64() {
+			errs = append(errs, fmt.Errorf("chain at index %d: chain ID is not set", i))
+			continue
+		}
+		id := chain.ChainID.Uint64()
+		if seen[id] {
+			errs = append(errs, fmt.Errorf("chain %d: configured more than once", id))
+			continue
+		}
+		seen[id] = true
+
+		errs = append(errs, c.validateChain(ctx, chain, relayer)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateChain checks one chain's endpoints, forwarder and relayer funding
+func (c *Config) validateChain(ctx context.Context, chain *ChainConfig, relayer common.Address) []error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("chain %s: "+format, append([]interface{}{chain.ChainID}, args...)...))
+	}
+
+	if chain.Forwarder == (common.Address{}) {
+		fail("forwarder address is not set")
+	}
+	if _, err := chain.profile(); err != nil {
+		fail("%v", err)
+	}
+	if len(chain.RPCURLs) == 0 {
+		fail("no RPC URLs configured")
+		return errs
+	}
+
+	for _, url := range chain.RPCURLs {
+		client, err := ethclient.DialContext(ctx, url)
+		if err != nil {
+			fail("rpc %s: failed to connect: %v", url, err)
+			continue
+		}
+
+		chainID, err := client.ChainID(ctx)
+		switch {
+		case err != nil:
+			fail("rpc %s: eth_chainId failed: %v", url, err)
+		case chainID.Cmp(chain.ChainID) != 0:
+			fail("rpc %s: endpoint serves chain %s", url, chainID)
+		default:
+			if chain.Forwarder != (common.Address{}) {
+				code, err := client.CodeAt(ctx, chain.Forwarder, nil)
+				switch {
+				case err != nil:
+					fail("rpc %s: failed to get forwarder code: %v", url, err)
+				case len(code) == 0:
+					fail("forwarder %s has no code", chain.Forwarder.Hex())
+				}
+			}
+			if relayer != (common.Address{}) {
+				balance, err := client.BalanceAt(ctx, relayer, nil)
+				switch {
+				case err != nil:
+					fail("rpc %s: failed to get relayer balance: %v", url, err)
+				case balance.Sign() == 0:
+					fail("relayer %s has no funds", relayer.Hex())
+				}
+			}
+		}
+		client.Close()
+	}
+
+	return dedupErrors(errs)
+}
+
+// dedupErrors drops repeated messages, e.g. a missing forwarder reported by every endpoint
+func dedupErrors(errs []error) []error {
+	seen := make(map[string]bool, len(errs))
+	out := errs[:0]
+	for _, err := range errs {
+		if seen[err.Error()] {
+			continue
+		}
+		seen[err.Error()] = true
+		out = append(out, err)
+	}
+	return out
+}
+
+// ChainRegistry registers every configured chain in a new ChainRegistry
+func (c *Config) ChainRegistry(registry *Registry) (*ChainRegistry, error) {
+	chains := NewChainRegistry(registry)
+	for _, chain := range c.Chains {
+		if err := chains.Register(chain); err != nil {
+			return nil, err
+		}
+	}
+	return chains, nil
+}
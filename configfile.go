@@ -0,0 +1,456 @@
+package eip2771toolkit
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"gopkg.in/yaml.v3"
+)
+
+// CONFIG_ENV_PREFIX prefixes the environment variables overriding a configuration file
+const CONFIG_ENV_PREFIX = "EIP2771_"
+
+// DEFAULT_KEY_ENV is the environment variable holding the hex relayer key when the
+// configuration names no keystore
+const DEFAULT_KEY_ENV = "EIP2771_PRIVATE_KEY"
+
+// KEYSTORE_PASSWORD_ENV is the environment variable holding the keystore password when the
+// configuration names no password file
+const KEYSTORE_PASSWORD_ENV = "EIP2771_KEYSTORE_PASSWORD"
+
+// ConfigFile is the YAML form of a Config. Keys are never written in the file: the relayer
+// key comes from an encrypted keystore or an environment variable. Amounts are in wei, as
+// decimal or 0x-prefixed strings, and durations are strings such as "1h". Addresses may be
+// given as labels of the AddressBook file.
+type ConfigFile struct {
+	AddressBook string       `yaml:"addressBook"` // address book file, see LoadAddressBook
+	Relayer     KeyFile      `yaml:"relayer"`
+	Sandbox     bool         `yaml:"sandbox"`
+	Chains      []ChainFile  `yaml:"chains"`
+	Policies    PolicyFile   `yaml:"policies"`
+	Server      ServerConfig `yaml:"server"`
+}
+
+// KeyFile locates the relayer key
+type KeyFile struct {
+	Address      string `yaml:"address"`      // optional, checked against the key
+	Keystore     string `yaml:"keystore"`     // encrypted keystore file
+	PasswordFile string `yaml:"passwordFile"` // keystore password, default $EIP2771_KEYSTORE_PASSWORD
+	KeyEnv       string `yaml:"keyEnv"`       // variable holding the hex key without a keystore, default DEFAULT_KEY_ENV
+}
+
+// ChainFile is the YAML form of a ChainConfig
+type ChainFile struct {
+	ChainID       uint64   `yaml:"chainId"`
+	Name          string   `yaml:"name"`
+	RPCURLs       []string `yaml:"rpcUrls"`
+	Forwarder     string   `yaml:"forwarder"`
+	Profile       string   `yaml:"profile"`
+	DomainName    string   `yaml:"domainName"`
+	DomainVersion string   `yaml:"domainVersion"`
+	Gas           GasFile  `yaml:"gas"`
+}
+
+// GasFile selects how relay transactions on a chain are priced
+type GasFile struct {
+	Strategy      string  `yaml:"strategy"`      // default, node, fee-history or fixed
+	Speed         string  `yaml:"speed"`         // slow, standard, fast or instant, for fee-history
+	GasPrice      string  `yaml:"gasPrice"`      // for fixed
+	TxType        string  `yaml:"txType"`        // auto, legacy or dynamic
+	MarginPercent *uint64 `yaml:"marginPercent"` // nil for the Relayer default
+	MarginExtra   uint64  `yaml:"marginExtra"`
+	MaxFeePerGas  string  `yaml:"maxFeePerGas"` // see FeeCaps
+	MaxBatchCost  string  `yaml:"maxBatchCost"` // see FeeCaps
+	DeferOverCap  bool    `yaml:"deferOverCap"` // wait for fees to drop instead of refusing
+}
+
+// PolicyFile configures the policies every relayed request must pass
+type PolicyFile struct {
+	AllowedTargets []string          `yaml:"allowedTargets"` // empty for any target
+	Quotas         []QuotaRuleFile   `yaml:"quotas"`         // per signer, counted in memory
+	SponsorQuotas  []QuotaRuleFile   `yaml:"sponsorQuotas"`  // per API key sponsor, see NewSponsorQuota
+	Budgets        map[string]string `yaml:"budgets"`        // limit per sponsor, spending kept in memory
+	Sponsors       map[string]string `yaml:"sponsors"`       // sponsor per call target, see SponsorByTarget
+}
+
+// QuotaRuleFile is the YAML form of a QuotaRule
+type QuotaRuleFile struct {
+	Window      time.Duration `yaml:"window"`
+	MaxRequests uint64        `yaml:"maxRequests"`
+	MaxGas      uint64        `yaml:"maxGas"`
+}
+
+// ServerConfig holds the settings of the relay HTTP server
+type ServerConfig struct {
+	Addr           string        `yaml:"addr"`           // listen address, e.g. ":8080"
+	AdminAddr      string        `yaml:"adminAddr"`      // metrics and budget address, empty to disable
+	Store          string        `yaml:"store"`          // "memory" (default) or "sqlite:<path>"
+	Confirmations  uint64        `yaml:"confirmations"`  // 0 for the server default
+	PendingTimeout time.Duration `yaml:"pendingTimeout"` // 0 for the server default
+	APIKeys        []APIKeyFile  `yaml:"apiKeys"`        // empty to serve without authentication
+}
+
+// APIKeyFile names an API key of the relay server and the sponsor its requests are attributed
+// to. Keys and secrets are read from the environment, never from the file.
+type APIKeyFile struct {
+	Sponsor   string `yaml:"sponsor"`
+	KeyEnv    string `yaml:"keyEnv"`    // variable holding the key
+	SecretEnv string `yaml:"secretEnv"` // variable holding the request signing secret, empty for unsigned requests
+}
+
+// Load reads the key and secret from the environment
+func (k APIKeyFile) Load() (key string, secret []byte, err error) {
+	if k.KeyEnv == "" {
+		return "", nil, fmt.Errorf("API key of sponsor %q: keyEnv is not set", k.Sponsor)
+	}
+	key = os.Getenv(k.KeyEnv)
+	if key == "" {
+		return "", nil, fmt.Errorf("API key of sponsor %q: $%s is not set", k.Sponsor, k.KeyEnv)
+	}
+	if k.SecretEnv != "" {
+		secret = []byte(os.Getenv(k.SecretEnv))
+		if len(secret) == 0 {
+			return "", nil, fmt.Errorf("API key of sponsor %q: $%s is not set", k.Sponsor, k.SecretEnv)
+		}
+	}
+	return key, secret, nil
+}
+
+// LoadConfig reads a YAML configuration file, applies the environment overrides (see
+// ConfigFile.ApplyEnv) and builds the Config, loading the relayer key
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	file, err := ParseConfigFile(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.ApplyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return file.Config()
+}
+
+// ParseConfigFile decodes a YAML configuration. Unknown fields are refused, so typos do not
+// silently fall back to defaults.
+func ParseConfigFile(data []byte) (*ConfigFile, error) {
+	var file ConfigFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return &file, nil
+}
+
+// ApplyEnv overrides the file with environment variables, for secrets and per-deployment
+// settings:
+//
+//	EIP2771_ADDRESS_BOOK, EIP2771_RELAYER_ADDRESS, EIP2771_KEYSTORE, EIP2771_PASSWORD_FILE,
+//	EIP2771_SANDBOX, EIP2771_SERVER_ADDR, EIP2771_ADMIN_ADDR, EIP2771_STORE, and per chain
+//	EIP2771_CHAIN_<id>_RPC_URLS (comma-separated) and EIP2771_CHAIN_<id>_FORWARDER
+func (f *ConfigFile) ApplyEnv(lookup func(string) (string, bool)) error {
+	set := func(name string, field *string) {
+		if value, ok := lookup(CONFIG_ENV_PREFIX + name); ok {
+			*field = value
+		}
+	}
+	set("ADDRESS_BOOK", &f.AddressBook)
+	set("RELAYER_ADDRESS", &f.Relayer.Address)
+	set("KEYSTORE", &f.Relayer.Keystore)
+	set("PASSWORD_FILE", &f.Relayer.PasswordFile)
+	set("SERVER_ADDR", &f.Server.Addr)
+	set("ADMIN_ADDR", &f.Server.AdminAddr)
+	set("STORE", &f.Server.Store)
+	if value, ok := lookup(CONFIG_ENV_PREFIX + "SANDBOX"); ok {
+		sandbox, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %sSANDBOX: %w", CONFIG_ENV_PREFIX, err)
+		}
+		f.Sandbox = sandbox
+	}
+
+	for i := range f.Chains {
+		chain := &f.Chains[i]
+		prefix := fmt.Sprintf("CHAIN_%d_", chain.ChainID)
+		if value, ok := lookup(CONFIG_ENV_PREFIX + prefix + "RPC_URLS"); ok {
+			chain.RPCURLs = splitList(value)
+		}
+		set(prefix+"FORWARDER", &chain.Forwarder)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Config builds the configuration the file describes, loading the relayer key
+func (f *ConfigFile) Config() (*Config, error) {
+	cfg := &Config{Sandbox: f.Sandbox, Server: f.Server}
+
+	var book *AddressBook
+	if f.AddressBook != "" {
+		var err error
+		if book, err = LoadAddressBook(f.AddressBook); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := f.Relayer.Load()
+	if err != nil {
+		return nil, err
+	}
+	cfg.RelayerKey = key
+	if f.Relayer.Address != "" {
+		if cfg.RelayerAddress, err = parseConfigAddress(book, "relayer.address", f.Relayer.Address); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, chainFile := range f.Chains {
+		chain, err := chainFile.chainConfig(book)
+		if err != nil {
+			return nil, fmt.Errorf("chain at index %d: %w", i, err)
+		}
+		cfg.Chains = append(cfg.Chains, chain)
+	}
+
+	if cfg.Policies, cfg.Budget, err = f.Policies.policies(book); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Load reads the key from the keystore, or from the key variable without one
+func (k KeyFile) Load() (*ecdsa.PrivateKey, error) {
+	if k.Keystore != "" {
+		keyJSON, err := os.ReadFile(k.Keystore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keystore: %w", err)
+		}
+		password := os.Getenv(KEYSTORE_PASSWORD_ENV)
+		if k.PasswordFile != "" {
+			raw, err := os.ReadFile(k.PasswordFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read password file: %w", err)
+			}
+			password = strings.TrimRight(string(raw), "\r\n")
+		}
+		key, err := keystore.DecryptKey(keyJSON, password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt keystore: %w", err)
+		}
+		return key.PrivateKey, nil
+	}
+
+	keyEnv := k.KeyEnv
+	if keyEnv == "" {
+		keyEnv = DEFAULT_KEY_ENV
+	}
+	hexKey := os.Getenv(keyEnv)
+	if hexKey == "" {
+		return nil, fmt.Errorf("no relayer key: set relayer.keystore or $%s", keyEnv)
+	}
+	key, err := PrivateKeyFromHex(strings.TrimPrefix(hexKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key in $%s: %w", keyEnv, err)
+	}
+	return key, nil
+}
+
+// chainConfig converts the file form of a chain
+func (c ChainFile) chainConfig(book *AddressBook) (*ChainConfig, error) {
+	chain := &ChainConfig{
+		ChainID:       new(big.Int).SetUint64(c.ChainID),
+		Name:          c.Name,
+		RPCURLs:       c.RPCURLs,
+		Profile:       c.Profile,
+		DomainName:    c.DomainName,
+		DomainVersion: c.DomainVersion,
+	}
+	if c.Forwarder != "" {
+		forwarder, err := parseConfigAddress(book, "forwarder", c.Forwarder)
+		if err != nil {
+			return nil, err
+		}
+		chain.Forwarder = forwarder
+	}
+
+	gas := c.Gas
+	switch strings.ToLower(gas.Strategy) {
+	case "", "default":
+	case "node":
+		chain.GasStrategy = NodeGasStrategy{}
+	case "fee-history":
+		speed, err := parseGasSpeed(gas.Speed)
+		if err != nil {
+			return nil, err
+		}
+		chain.GasStrategy = FeeHistoryGasStrategy{Speed: speed}
+	case "fixed":
+		gasPrice, err := parseConfigWei("gas.gasPrice", gas.GasPrice)
+		if err != nil {
+			return nil, err
+		}
+		if gasPrice == nil {
+			return nil, fmt.Errorf("gas.gasPrice is required by the fixed gas strategy")
+		}
+		chain.GasStrategy = FixedGasStrategy{GasPrice: gasPrice}
+	default:
+		return nil, fmt.Errorf("unknown gas strategy %q", gas.Strategy)
+	}
+
+	switch strings.ToLower(gas.TxType) {
+	case "", "auto":
+	case "legacy":
+		chain.TxType = TxTypeLegacy
+	case "dynamic":
+		chain.TxType = TxTypeDynamic
+	default:
+		return nil, fmt.Errorf("unknown transaction type %q", gas.TxType)
+	}
+
+	if gas.MarginPercent != nil {
+		chain.GasMargin = &GasMargin{Percent: *gas.MarginPercent, Extra: gas.MarginExtra}
+	}
+
+	maxFeePerGas, err := parseConfigWei("gas.maxFeePerGas", gas.MaxFeePerGas)
+	if err != nil {
+		return nil, err
+	}
+	maxBatchCost, err := parseConfigWei("gas.maxBatchCost", gas.MaxBatchCost)
+	if err != nil {
+		return nil, err
+	}
+	if maxFeePerGas != nil || maxBatchCost != nil {
+		chain.FeeCaps = &FeeCaps{MaxFeePerGas: maxFeePerGas, MaxBatchCost: maxBatchCost, Defer: gas.DeferOverCap}
+	}
+	return chain, nil
+}
+
+// policies builds the configured policies; the budget is returned separately as relayers
+// also charge it, see WithBudget
+func (p PolicyFile) policies(book *AddressBook) ([]Policy, *Budget, error) {
+	var policies []Policy
+	if len(p.AllowedTargets) > 0 {
+		targets := make([]common.Address, len(p.AllowedTargets))
+		for i, target := range p.AllowedTargets {
+			address, err := parseConfigAddress(book, "policies.allowedTargets", target)
+			if err != nil {
+				return nil, nil, err
+			}
+			targets[i] = address
+		}
+		policies = append(policies, NewTargetAllowlist(targets...))
+	}
+
+	if len(p.Quotas) > 0 {
+		rules, err := quotaRules("policies.quotas", p.Quotas)
+		if err != nil {
+			return nil, nil, err
+		}
+		policies = append(policies, NewQuota(NewMemoryQuotaCounter(), rules...))
+	}
+	if len(p.SponsorQuotas) > 0 {
+		rules, err := quotaRules("policies.sponsorQuotas", p.SponsorQuotas)
+		if err != nil {
+			return nil, nil, err
+		}
+		policies = append(policies, NewSponsorQuota(NewMemoryQuotaCounter(), rules...))
+	}
+
+	if len(p.Budgets) == 0 {
+		if len(p.Sponsors) > 0 {
+			return nil, nil, fmt.Errorf("policies.sponsors is set without policies.budgets")
+		}
+		return policies, nil, nil
+	}
+	sponsors := make(map[common.Address]string, len(p.Sponsors))
+	for target, sponsor := range p.Sponsors {
+		address, err := parseConfigAddress(book, "policies.sponsors", target)
+		if err != nil {
+			return nil, nil, err
+		}
+		sponsors[address] = sponsor
+	}
+	budget := NewBudget(NewMemoryBudgetLedger(), SponsorByTarget(sponsors))
+	for sponsor, limit := range p.Budgets {
+		wei, err := parseConfigWei("policies.budgets."+sponsor, limit)
+		if err != nil {
+			return nil, nil, err
+		}
+		if wei == nil {
+			return nil, nil, fmt.Errorf("policies.budgets.%s: limit is empty", sponsor)
+		}
+		budget.SetLimit(sponsor, wei)
+	}
+	return policies, budget, nil
+}
+
+// quotaRules converts the quota rules of field
+func quotaRules(field string, files []QuotaRuleFile) ([]QuotaRule, error) {
+	rules := make([]QuotaRule, len(files))
+	for i, rule := range files {
+		if rule.Window <= 0 {
+			return nil, fmt.Errorf("%s[%d]: window must be positive", field, i)
+		}
+		rules[i] = QuotaRule{Window: rule.Window, MaxRequests: rule.MaxRequests, MaxGas: rule.MaxGas}
+	}
+	return rules, nil
+}
+
+// parseConfigAddress parses a hex address or address book label of field
+func parseConfigAddress(book *AddressBook, field, value string) (common.Address, error) {
+	address, err := book.Resolve(value)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%s: %w", field, err)
+	}
+	return address, nil
+}
+
+// parseConfigWei parses a decimal or 0x-prefixed amount of field, nil when empty
+func parseConfigWei(field, value string) (*big.Int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	wei, ok := new(big.Int).SetString(value, 0)
+	if !ok || wei.Sign() < 0 {
+		return nil, fmt.Errorf("%s: invalid amount %q", field, value)
+	}
+	return wei, nil
+}
+
+// parseGasSpeed parses a gas speed name, defaulting to GasStandard
+func parseGasSpeed(name string) (GasSpeed, error) {
+	switch strings.ToLower(name) {
+	case "", "standard":
+		return GasStandard, nil
+	case "slow":
+		return GasSlow, nil
+	case "fast":
+		return GasFast, nil
+	case "instant":
+		return GasInstant, nil
+	}
+	return 0, fmt.Errorf("unknown gas speed %q", name)
+}
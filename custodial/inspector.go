@@ -0,0 +1,84 @@
+package custodial
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Inspector is a pre-sign hook, typically an external risk or approval service
+type Inspector interface {
+	Inspect(ctx context.Context, req SignRequest) error
+}
+
+// InspectorFunc adapts a function to the Inspector interface
+type InspectorFunc func(ctx context.Context, req SignRequest) error
+
+// Inspect calls f
+func (f InspectorFunc) Inspect(ctx context.Context, req SignRequest) error {
+	return f(ctx, req)
+}
+
+// WebhookInspector posts every signing request as JSON to URL and signs only if the
+// service answers 2xx with {"approved": true}. Any transport error rejects the request.
+type WebhookInspector struct {
+	URL        string
+	Headers    map[string]string // e.g. an authorization header for the inspection service
+	HTTPClient *http.Client      // defaults to a client with a 10 second timeout
+}
+
+// webhookResponse is the verdict returned by an inspection webhook
+type webhookResponse struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason"`
+}
+
+var defaultWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// Inspect asks the webhook for a verdict
+func (w *WebhookInspector) Inspect(ctx context.Context, req SignRequest) error {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to encode inspection request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create inspection request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	httpClient := w.HTTPClient
+	if httpClient == nil {
+		httpClient = defaultWebhookClient
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("%w: inspection webhook unreachable: %v", ErrRejected, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%w: failed to read inspection response: %v", ErrRejected, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: inspection webhook returned %s", ErrRejected, resp.Status)
+	}
+
+	var verdict webhookResponse
+	if err := json.Unmarshal(body, &verdict); err != nil {
+		return fmt.Errorf("%w: failed to decode inspection response: %v", ErrRejected, err)
+	}
+	if !verdict.Approved {
+		return fmt.Errorf("%w: %s", ErrRejected, verdict.Reason)
+	}
+	return nil
+}
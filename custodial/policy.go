@@ -0,0 +1,82 @@
+package custodial
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// Policy decides locally whether a custodial signing request may proceed
+type Policy interface {
+	Check(ctx context.Context, req SignRequest) error
+}
+
+// PolicyFunc adapts a function to the Policy interface
+type PolicyFunc func(ctx context.Context, req SignRequest) error
+
+// Check calls f
+func (f PolicyFunc) Check(ctx context.Context, req SignRequest) error {
+	return f(ctx, req)
+}
+
+// TokenAllowlist only permits transfers of the listed tokens
+type TokenAllowlist map[common.Address]bool
+
+// Check rejects tokens not in the list
+func (p TokenAllowlist) Check(ctx context.Context, req SignRequest) error {
+	if !p[req.MetaTx.Token] {
+		return fmt.Errorf("%w: token %s is not allowed", ErrRejected, req.MetaTx.Token.Hex())
+	}
+	return nil
+}
+
+// MaxAmount caps the amount of a single transfer per token. Tokens without a cap are unrestricted.
+type MaxAmount map[common.Address]*big.Int
+
+// Check rejects transfers above the token's cap
+func (p MaxAmount) Check(ctx context.Context, req SignRequest) error {
+	limit, ok := p[req.MetaTx.Token]
+	if !ok {
+		return nil
+	}
+	if req.MetaTx.Amount == nil || req.MetaTx.Amount.Cmp(limit) > 0 {
+		return fmt.Errorf("%w: amount %s exceeds limit %s for token %s", ErrRejected, req.MetaTx.Amount, limit, req.MetaTx.Token.Hex())
+	}
+	return nil
+}
+
+// CallPolicy only permits forwarded calls allowed by the permissions
+type CallPolicy struct {
+	Permissions *eip2771toolkit.CallPermissions
+}
+
+// Check rejects calls matching no permission rule
+func (p CallPolicy) Check(ctx context.Context, req SignRequest) error {
+	if err := p.Permissions.CheckMetaTx(req.MetaTx); err != nil {
+		return fmt.Errorf("%w: %w", ErrRejected, err)
+	}
+	return nil
+}
+
+// MaxDeadlineWindow rejects requests whose deadline lies more than Seconds after now,
+// limiting how long a custodial signature stays usable
+type MaxDeadlineWindow struct {
+	Seconds uint64
+	Now     func() uint64 // defaults to the current unix time
+}
+
+// Check rejects deadlines beyond the window
+func (p MaxDeadlineWindow) Check(ctx context.Context, req SignRequest) error {
+	now := p.Now
+	if now == nil {
+		now = eip2771toolkit.GetCurrentTimestamp
+	}
+	if req.MetaTx.Deadline > now()+p.Seconds {
+		return fmt.Errorf("%w: deadline %d is more than %d seconds ahead", ErrRejected, req.MetaTx.Deadline, p.Seconds)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+// Package custodial implements server-side signing for deployments that hold user keys.
+// It is deliberately separate from the relay path: a custodial Signer only produces
+// signatures, and every request passes its policy set and pre-sign inspectors first.
+// Non-custodial deployments, where users sign on their own devices, never need this package.
+package custodial
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+var (
+	// ErrUnknownSigner is returned when the key store holds no key for the request's From address
+	ErrUnknownSigner = errors.New("no custodial key for signer")
+
+	// ErrRejected is wrapped by the errors of policies and inspectors that refuse a request
+	ErrRejected = errors.New("signing request rejected")
+)
+
+// KeyStore provides the private keys of custodied accounts
+type KeyStore interface {
+	PrivateKey(ctx context.Context, addr common.Address) (*ecdsa.PrivateKey, error)
+}
+
+// MemoryKeyStore is an in-memory KeyStore, safe for concurrent use
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[common.Address]*ecdsa.PrivateKey
+}
+
+// NewMemoryKeyStore creates a key store holding the given keys
+func NewMemoryKeyStore(keys ...*ecdsa.PrivateKey) *MemoryKeyStore {
+	ks := &MemoryKeyStore{keys: make(map[common.Address]*ecdsa.PrivateKey)}
+	for _, key := range keys {
+		ks.Add(key)
+	}
+	return ks
+}
+
+// Add stores a key under its address
+func (ks *MemoryKeyStore) Add(key *ecdsa.PrivateKey) common.Address {
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[addr] = key
+	return addr
+}
+
+// PrivateKey returns the key of addr or ErrUnknownSigner
+func (ks *MemoryKeyStore) PrivateKey(ctx context.Context, addr common.Address) (*ecdsa.PrivateKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok := ks.keys[addr]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownSigner, addr.Hex())
+	}
+	return key, nil
+}
+
+// SignRequest is what policies and inspectors see before a signature is produced
+type SignRequest struct {
+	MetaTx          eip2771toolkit.MetaTx `json:"metaTx"`
+	Schema          string                `json:"schema"`
+	DomainSeparator common.Hash           `json:"domainSeparator"`
+	Digest          common.Hash           `json:"digest"` // EIP-712 digest that will be signed
+}
+
+// Signer signs meta transactions with custodied keys after checking its policies and inspectors
+type Signer struct {
+	keys       KeyStore
+	schema     *eip2771toolkit.RequestSchema
+	policies   []Policy
+	inspectors []Inspector
+}
+
+// Option configures a Signer
+type Option func(*Signer)
+
+// WithPolicies appends policies evaluated, in order, before every signature
+func WithPolicies(policies ...Policy) Option {
+	return func(s *Signer) {
+		s.policies = append(s.policies, policies...)
+	}
+}
+
+// WithInspectors appends pre-sign inspectors consulted, in order, after the policies pass
+func WithInspectors(inspectors ...Inspector) Option {
+	return func(s *Signer) {
+		s.inspectors = append(s.inspectors, inspectors...)
+	}
+}
+
+// WithSchema sets the request schema signatures are produced for (default OpenZeppelin v5)
+func WithSchema(schema *eip2771toolkit.RequestSchema) Option {
+	return func(s *Signer) {
+		s.schema = schema
+	}
+}
+
+// NewSigner creates a custodial Signer over keys
+func NewSigner(keys KeyStore, opts ...Option) *Signer {
+	s := &Signer{
+		keys:   keys,
+		schema: eip2771toolkit.OZForwarderV5Schema,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// SignMetaTx checks the request against the policy set and inspectors, then signs it with
+// the custodied key of metaTx.From
+func (s *Signer) SignMetaTx(ctx context.Context, metaTx eip2771toolkit.MetaTx, domainSeparator []byte) (eip2771toolkit.Signature, error) {
+	digest, err := s.schema.HashMetaTx(metaTx, domainSeparator)
+	if err != nil {
+		return eip2771toolkit.Signature{}, err
+	}
+
+	req := SignRequest{
+		MetaTx:          metaTx,
+		Schema:          s.schema.Name,
+		DomainSeparator: common.BytesToHash(domainSeparator),
+		Digest:          common.BytesToHash(digest),
+	}
+
+	// Policies are local and cheap, so they run before any inspector is contacted
+	for _, policy := range s.policies {
+		if err := policy.Check(ctx, req); err != nil {
+			return eip2771toolkit.Signature{}, err
+		}
+	}
+	for _, inspector := range s.inspectors {
+		if err := inspector.Inspect(ctx, req); err != nil {
+			return eip2771toolkit.Signature{}, err
+		}
+	}
+
+	key, err := s.keys.PrivateKey(ctx, metaTx.From)
+	if err != nil {
+		return eip2771toolkit.Signature{}, err
+	}
+
+	return eip2771toolkit.SignMetaTxWithSchema(s.schema, metaTx, key, domainSeparator)
+}
+
+// SignBatch signs every MetaTx, stopping at the first rejected request
+func (s *Signer) SignBatch(ctx context.Context, metaTxs []eip2771toolkit.MetaTx, domainSeparator []byte) (eip2771toolkit.BatchMetaTxRequestList, error) {
+	batch := make(eip2771toolkit.BatchMetaTxRequestList, len(metaTxs))
+	for i, metaTx := range metaTxs {
+		sig, err := s.SignMetaTx(ctx, metaTx, domainSeparator)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign MetaTx at index %d: %w", i, err)
+		}
+		batch[i] = eip2771toolkit.BatchMetaTxRequest{MetaTx: metaTx, Signature: sig}
+	}
+	return batch, nil
+}
+
+var _ eip2771toolkit.MetaTxSigner = (*Signer)(nil)
@@ -0,0 +1,111 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+// ChainTime returns the timestamp of the latest block, the clock the forwarder checks
+// deadlines against
+func ChainTime(ctx context.Context, client ethereum.ChainReader) (uint64, error) {
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	return header.Time, nil
+}
+
+// ValidateDeadlineOnChain checks the deadline against the latest block timestamp instead of
+// the local clock. buffer is added to the block timestamp to cover the blocks until the
+// relay is mined.
+func ValidateDeadlineOnChain(ctx context.Context, client ethereum.ChainReader, deadline uint64, buffer time.Duration) error {
+	now, err := ChainTime(ctx, client)
+	if err != nil {
+		return err
+	}
+	if now+uint64(buffer/time.Second) > deadline {
+		return ErrExpiredDeadline
+	}
+	return nil
+}
+
+// WithChainTimeDeadlines makes the relayer check deadlines against the latest block timestamp
+// plus buffer instead of the host clock, for hosts whose clock drifts and chains whose blocks
+// lag behind wall time
+func WithChainTimeDeadlines(buffer time.Duration) RelayerOption {
+	return func(r *Relayer) {
+		r.chainTimeDeadlines = true
+		r.deadlineBuffer = buffer
+	}
+}
+
+// deadlineNow returns the time requests' deadlines are compared with: the host clock, or the
+// latest block timestamp plus the buffer with WithChainTimeDeadlines
+func (r *Relayer) deadlineNow(ctx context.Context) (uint64, error) {
+	if !r.chainTimeDeadlines {
+		return uint64(time.Now().Unix()), nil
+	}
+	now, err := ChainTime(ctx, r.client)
+	if err != nil {
+		return 0, err
+	}
+	return now + uint64(r.deadlineBuffer/time.Second), nil
+}
+
+// TimeUntilDeadline returns how long is left until the request's deadline by the host clock,
+// or zero if it has passed
+func TimeUntilDeadline(metaTx MetaTx) time.Duration {
+	return timeUntil(uint64(time.Now().Unix()), metaTx.Deadline)
+}
+
+// TimeUntilDeadline returns how long is left until the nearest deadline of the batch by the
+// host clock, or zero if it has passed
+func (batch BatchMetaTxRequestList) TimeUntilDeadline() time.Duration {
+	return timeUntil(uint64(time.Now().Unix()), nearestDeadline(batch))
+}
+
+// TimeUntilDeadline returns how long is left until the request's deadline by the clock the
+// relayer checks deadlines with, see WithChainTimeDeadlines, or zero if it has passed
+func (r *Relayer) TimeUntilDeadline(ctx context.Context, metaTx MetaTx) (time.Duration, error) {
+	now, err := r.deadlineNow(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return timeUntil(now, metaTx.Deadline), nil
+}
+
+// timeUntil returns the time from now until deadline, both unix timestamps, or zero if it has passed
+func timeUntil(now, deadline uint64) time.Duration {
+	if deadline <= now {
+		return 0
+	}
+	return time.Duration(deadline-now) * time.Second
+}
+
+// ValidateDeadlineLifetime checks the deadline is at least minLifetime away by the host clock,
+// returning ErrExpiredDeadline if it has passed and ErrDeadlineTooSoon if it is closer
+func ValidateDeadlineLifetime(deadline uint64, minLifetime time.Duration) error {
+	return checkDeadline(uint64(time.Now().Unix()), deadline, minLifetime)
+}
+
+// WithMinDeadlineLifetime refuses to relay requests expiring within minLifetime with
+// ErrDeadlineTooSoon, so relays are not mined just after the deadline and revert
+func WithMinDeadlineLifetime(minLifetime time.Duration) RelayerOption {
+	return func(r *Relayer) {
+		r.minDeadlineLifetime = minLifetime
+	}
+}
+
+// checkDeadline checks deadline is at least minLifetime after now
+func checkDeadline(now, deadline uint64, minLifetime time.Duration) error {
+	if now > deadline {
+		return ErrExpiredDeadline
+	}
+	if remaining := timeUntil(now, deadline); remaining < minLifetime {
+		return fmt.Errorf("%w: expires in %s, minimum is %s", ErrDeadlineTooSoon, remaining, minLifetime)
+	}
+	return nil
+}
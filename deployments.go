@@ -0,0 +1,103 @@
+package eip2771toolkit
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ForwarderDeployment is a trusted forwarder deployed on one chain
+type ForwarderDeployment struct {
+	ChainID uint64
+	Address common.Address
+	Profile string // registered ForwarderProfile name, empty for the registry default
+}
+
+// knownForwarderDeployments returns the deployments every new Registry starts with.
+// OpenZeppelin's ERC2771Forwarder has no canonical singleton deployment: each project deploys
+// its own instance, whose address is then trusted by that project's contracts. Only verified
+// shared deployments belong here; everything else is added with RegisterForwarderDeployment.
+func knownForwarderDeployments() map[uint64]ForwarderDeployment {
+	return map[uint64]ForwarderDeployment{}
+}
+
+// RegisterForwarderDeployment sets the forwarder used on a chain, replacing any previous one
+func (reg *Registry) RegisterForwarderDeployment(deployment ForwarderDeployment) error {
+	if deployment.ChainID == 0 {
+		return fmt.Errorf("forwarder deployment must have a chain ID")
+	}
+	if deployment.Address == (common.Address{}) {
+		return fmt.Errorf("forwarder deployment on chain %d: %w", deployment.ChainID, ErrZeroAddress)
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if deployment.Profile != "" {
+		if _, ok := reg.profiles[deployment.Profile]; !ok {
+			return fmt.Errorf("forwarder deployment on chain %d: unknown profile %s", deployment.ChainID, deployment.Profile)
+		}
+	}
+	reg.deployments[deployment.ChainID] = deployment
+	return nil
+}
+
+// LookupForwarderDeployment returns the forwarder registered for a chain
+func (reg *Registry) LookupForwarderDeployment(chainID uint64) (ForwarderDeployment, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	deployment, ok := reg.deployments[chainID]
+	return deployment, ok
+}
+
+// ForwarderDeployments returns all registered deployments keyed by chain ID
+func (reg *Registry) ForwarderDeployments() map[uint64]ForwarderDeployment {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	deployments := make(map[uint64]ForwarderDeployment, len(reg.deployments))
+	for chainID, deployment := range reg.deployments {
+		deployments[chainID] = deployment
+	}
+	return deployments
+}
+
+// RegisterForwarderDeployment registers a deployment in DefaultRegistry
+func RegisterForwarderDeployment(deployment ForwarderDeployment) error {
+	return DefaultRegistry.RegisterForwarderDeployment(deployment)
+}
+
+// LookupForwarderDeployment looks a deployment up in DefaultRegistry
+func LookupForwarderDeployment(chainID uint64) (ForwarderDeployment, bool) {
+	return DefaultRegistry.LookupForwarderDeployment(chainID)
+}
+
+// NewRelayerForChain creates a Relayer for the forwarder registered for chainID, in
+// DefaultRegistry or the registry given by WithRegistry. The deployment's profile is used
+// unless an option selects another one.
+func NewRelayerForChain(chainID *big.Int, relayerPrivKey *ecdsa.PrivateKey, ethClient EthClient, opts ...RelayerOption) (*Relayer, error) {
+	if chainID == nil || !chainID.IsUint64() {
+		return nil, fmt.Errorf("invalid chain ID %v", chainID)
+	}
+
+	// Resolve the registry the options select
+	probe := &Relayer{registry: DefaultRegistry}
+	for _, opt := range opts {
+		opt(probe)
+	}
+
+	deployment, ok := probe.registry.LookupForwarderDeployment(chainID.Uint64())
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownDeployment, chainID)
+	}
+
+	if deployment.Profile != "" {
+		profile, ok := probe.registry.LookupForwarderProfile(deployment.Profile)
+		if !ok {
+			return nil, fmt.Errorf("unknown forwarder profile %s", deployment.Profile)
+		}
+		opts = append([]RelayerOption{WithForwarderProfile(profile)}, opts...)
+	}
+
+	return NewRelayer(relayerPrivKey, deployment.Address, ethClient, opts...), nil
+}
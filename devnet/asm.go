@@ -0,0 +1,152 @@
+package devnet
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// assembler builds EVM bytecode with named jump labels. Label references are always
+// emitted as PUSH2 so they can be patched once all label positions are known.
+type assembler struct {
+	code   []byte
+	labels map[string]int
+	fixups map[int]string
+}
+
+func newAssembler() *assembler {
+	return &assembler{
+		labels: make(map[string]int),
+		fixups: make(map[int]string),
+	}
+}
+
+// op appends opcodes without immediates
+func (a *assembler) op(ops ...vm.OpCode) *assembler {
+	for _, op := range ops {
+		a.code = append(a.code, byte(op))
+	}
+	return a
+}
+
+// push appends the shortest PUSH of an integer, address, hash or byte slice
+func (a *assembler) push(value interface{}) *assembler {
+	var data []byte
+	switch v := value.(type) {
+	case int:
+		data = new(big.Int).SetInt64(int64(v)).Bytes()
+	case uint64:
+		data = new(big.Int).SetUint64(v).Bytes()
+	case *big.Int:
+		data = v.Bytes()
+	case common.Address:
+		data = v.Bytes()
+	case common.Hash:
+		data = v.Bytes()
+	case []byte:
+		data = v
+	default:
+		panic(fmt.Sprintf("devnet: cannot push %T", value))
+	}
+	if len(data) == 0 {
+		data = []byte{0}
+	}
+	a.code = append(a.code, byte(vm.PUSH1)+byte(len(data)-1))
+	a.code = append(a.code, data...)
+	return a
+}
+
+// pushSelector pushes the 4-byte selector of a function or error signature
+func (a *assembler) pushSelector(signature string) *assembler {
+	return a.push(selector(signature))
+}
+
+// pushLabel pushes the position of a label, which may be defined later
+func (a *assembler) pushLabel(name string) *assembler {
+	a.code = append(a.code, byte(vm.PUSH2))
+	a.fixups[len(a.code)] = name
+	a.code = append(a.code, 0, 0)
+	return a
+}
+
+// label defines a jump destination at the current position
+func (a *assembler) label(name string) *assembler {
+	if _, ok := a.labels[name]; ok {
+		panic("devnet: duplicate label " + name)
+	}
+	a.labels[name] = len(a.code)
+	return a.op(vm.JUMPDEST)
+}
+
+// jump jumps to a label unconditionally
+func (a *assembler) jump(name string) *assembler {
+	return a.pushLabel(name).op(vm.JUMP)
+}
+
+// jumpi jumps to a label if the value on top of the stack is non-zero
+func (a *assembler) jumpi(name string) *assembler {
+	return a.pushLabel(name).op(vm.JUMPI)
+}
+
+// mstore stores the value on top of the stack at a fixed memory offset
+func (a *assembler) mstore(offset int) *assembler {
+	return a.push(offset).op(vm.MSTORE)
+}
+
+// mload loads the word at a fixed memory offset
+func (a *assembler) mload(offset int) *assembler {
+	return a.push(offset).op(vm.MLOAD)
+}
+
+// returnWord returns the value on top of the stack as a single ABI word
+func (a *assembler) returnWord() *assembler {
+	return a.mstore(0).push(32).push(0).op(vm.RETURN)
+}
+
+// revertWith reverts with a custom error; each arg pushes one ABI word argument
+func (a *assembler) revertWith(signature string, args ...func(*assembler)) *assembler {
+	a.pushSelector(signature).push(224).op(vm.SHL).mstore(0)
+	for i, arg := range args {
+		arg(a)
+		a.mstore(4 + 32*i)
+	}
+	return a.push(4 + 32*len(args)).push(0).op(vm.REVERT)
+}
+
+// bytes resolves label references and returns the bytecode
+func (a *assembler) bytes() []byte {
+	code := make([]byte, len(a.code))
+	copy(code, a.code)
+	for pos, name := range a.fixups {
+		target, ok := a.labels[name]
+		if !ok {
+			panic("devnet: undefined label " + name)
+		}
+		code[pos] = byte(target >> 8)
+		code[pos+1] = byte(target)
+	}
+	return code
+}
+
+// deployCode wraps runtime bytecode into init code that returns it
+func deployCode(runtime []byte) []byte {
+	initCode := newAssembler()
+	initCode.code = append(initCode.code, byte(vm.PUSH2), byte(len(runtime)>>8), byte(len(runtime)))
+	initCode.op(vm.DUP1)
+	initCode.code = append(initCode.code, byte(vm.PUSH2), 0, 0) // runtime offset, patched below
+	initCode.push(0).op(vm.CODECOPY).push(0).op(vm.RETURN)
+
+	code := initCode.bytes()
+	offset := len(code)
+	code[5] = byte(offset >> 8)
+	code[6] = byte(offset)
+	return append(code, runtime...)
+}
+
+// selector returns the first four bytes of the keccak256 hash of a signature
+func selector(signature string) []byte {
+	return crypto.Keccak256([]byte(signature))[:4]
+}
@@ -0,0 +1,362 @@
+package devnet
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// The contracts below are minimal hand-assembled implementations of the OpenZeppelin v5
+// ERC2771Forwarder and of an ERC2771-aware ERC20, exposing the same ABI and EIP-712 domain
+// so the toolkit can be exercised end to end without a Solidity compiler. They implement
+// only what the toolkit calls and are meant for simulated chains, not for production.
+
+// Forwarder memory layout: variables live at fixed offsets above the scratch areas
+const (
+	memStruct    = 0x80  // 8 words: typehash, from, to, value, gas, nonce, deadline, keccak(data)
+	memDomain    = 0x180 // 5 words: domain typehash, name, version, chainId, verifyingContract
+	memDigest    = 0x220 // "\x19\x01" || domainSeparator || structHash
+	memT         = 0x300 // calldata offset of the current ForwardRequestData tuple
+	memMode      = 0x320 // 0 verify, 1 execute skipping invalid requests, 2 execute reverting on invalid requests
+	memRet       = 0x340 // return label of the request processing routine
+	memOK        = 0x360 // result of the request processing routine
+	memI         = 0x380
+	memN         = 0x3a0
+	memA         = 0x3c0
+	memRefund    = 0x3e0
+	memSigner    = 0x400
+	memNonce     = 0x420
+	memDataLen   = 0x440
+	memStructSH  = 0x460
+	memExpired   = 0x480
+	memMatch     = 0x4a0
+	memValue     = 0x4c0
+	memRefundVal = 0x4e0
+	memEcrecover = 0x500 // hash, v, r, s
+	memRecovered = 0x580
+	memTrusted   = 0x5a0
+	memCalldata  = 0x1000 // forwarded calldata: data || from
+)
+
+// Forwarder contract error signatures, matching OpenZeppelin v5
+const (
+	errInvalidSigner   = "ERC2771ForwarderInvalidSigner(address,address)"
+	errExpiredRequest  = "ERC2771ForwarderExpiredRequest(uint48)"
+	errMismatchedValue = "ERC2771ForwarderMismatchedValue(uint256,uint256)"
+	errUntrustful      = "ERC2771UntrustfulTarget(address,address)"
+	errFailedInnerCall = "FailedInnerCall()"
+	errInsufficient    = "ERC20InsufficientBalance(address,uint256,uint256)"
+
+	forwardRequestTuple = "(address,address,uint256,uint256,uint48,bytes,bytes)"
+)
+
+// secp256k1HalfN is the largest s value of a non-malleable signature, as OpenZeppelin ECDSA
+// accepts
+var secp256k1HalfN = common.HexToHash("0x7fffffffffffffffffffffffffffffff5d576e7357a4501ddfe92f46681b20a0")
+
+// ForwarderCode returns the deployment bytecode of the ERC2771Forwarder replica
+func ForwarderCode() []byte {
+	return deployCode(forwarderRuntime())
+}
+
+// forwarderRuntime assembles the runtime bytecode of the forwarder
+func forwarderRuntime() []byte {
+	a := newAssembler()
+
+	// loadField pushes the calldata word at the given offset of the current request tuple
+	loadField := func(offset int) func(*assembler) {
+		return func(a *assembler) {
+			a.mload(memT)
+			if offset != 0 {
+				a.push(offset).op(vm.ADD)
+			}
+			a.op(vm.CALLDATALOAD)
+		}
+	}
+	// loadDynamic pushes the calldata offset of a dynamic member of the current request tuple
+	loadDynamic := func(offset int) {
+		loadField(offset)(a)
+		a.mload(memT).op(vm.ADD)
+	}
+
+	// Function dispatch
+	a.push(0).op(vm.CALLDATALOAD).push(224).op(vm.SHR)
+	for _, fn := range []struct{ signature, label string }{
+		{"execute(" + forwardRequestTuple + ")", "execute"},
+		{"executeBatch(" + forwardRequestTuple + "[],address)", "executeBatch"},
+		{"verify(" + forwardRequestTuple + ")", "verify"},
+		{"nonces(address)", "nonces"},
+	} {
+		a.op(vm.DUP1).pushSelector(fn.signature).op(vm.EQ).jumpi(fn.label)
+	}
+	a.push(0).push(0).op(vm.REVERT)
+
+	// nonces(address)
+	a.label("nonces").op(vm.POP)
+	a.push(4).op(vm.CALLDATALOAD, vm.SLOAD).returnWord()
+
+	// verify(ForwardRequestData)
+	a.label("verify").op(vm.POP)
+	a.push(4).op(vm.CALLDATALOAD).push(4).op(vm.ADD).mstore(memT)
+	a.push(0).mstore(memMode)
+	a.pushLabel("verifyReturn").mstore(memRet)
+	a.jump("process")
+	a.label("verifyReturn")
+	a.mload(memOK).returnWord()
+
+	// execute(ForwardRequestData)
+	a.label("execute").op(vm.POP)
+	a.push(4).op(vm.CALLDATALOAD).push(4).op(vm.ADD).mstore(memT)
+	loadField(0x40)(a)
+	a.op(vm.CALLVALUE, vm.EQ).jumpi("executeValueOK")
+	a.revertWith(errMismatchedValue, loadField(0x40), func(a *assembler) { a.op(vm.CALLVALUE) })
+	a.label("executeValueOK")
+	a.push(2).mstore(memMode)
+	a.pushLabel("executeReturn").mstore(memRet)
+	a.jump("process")
+	a.label("executeReturn")
+	a.mload(memOK).jumpi("executeDone")
+	a.revertWith(errFailedInnerCall)
+	a.label("executeDone").op(vm.STOP)
+
+	// executeBatch(ForwardRequestData[], address refundReceiver)
+	a.label("executeBatch").op(vm.POP)
+	a.push(0x24).op(vm.CALLDATALOAD).mstore(memRefund)
+	a.push(4).op(vm.CALLDATALOAD).push(4).op(vm.ADD)
+	a.op(vm.DUP1, vm.CALLDATALOAD).mstore(memN)
+	a.push(32).op(vm.ADD).mstore(memA)
+	// Atomic (reverting) mode when there is no refund receiver
+	a.mload(memRefund).op(vm.ISZERO).push(1).op(vm.ADD).mstore(memMode)
+	a.push(0).mstore(memI)
+	a.push(0).mstore(memValue)
+	a.push(0).mstore(memRefundVal)
+	a.label("batchLoop")
+	a.mload(memN).mload(memI).op(vm.LT, vm.ISZERO).jumpi("batchEnd")
+	a.mload(memI).push(5).op(vm.SHL).mload(memA).op(vm.ADD, vm.CALLDATALOAD).mload(memA).op(vm.ADD).mstore(memT)
+	loadField(0x40)(a)
+	a.mload(memValue).op(vm.ADD).mstore(memValue)
+	a.pushLabel("batchReturn").mstore(memRet)
+	a.jump("process")
+	a.label("batchReturn")
+	a.mload(memOK).jumpi("batchNext")
+	loadField(0x40)(a)
+	a.mload(memRefundVal).op(vm.ADD).mstore(memRefundVal)
+	a.label("batchNext")
+	a.mload(memI).push(1).op(vm.ADD).mstore(memI)
+	a.jump("batchLoop")
+	a.label("batchEnd")
+	a.mload(memValue).op(vm.CALLVALUE, vm.EQ).jumpi("batchValueOK")
+	a.revertWith(errMismatchedValue, func(a *assembler) { a.mload(memValue) }, func(a *assembler) { a.op(vm.CALLVALUE) })
+	a.label("batchValueOK")
+	a.mload(memRefundVal).op(vm.ISZERO).jumpi("batchDone")
+	a.push(0).push(0).push(0).push(0).mload(memRefundVal).mload(memRefund).op(vm.GAS, vm.CALL, vm.POP)
+	a.label("batchDone").op(vm.STOP)
+
+	// process validates, and depending on the mode executes, the request at memT.
+	// It stores the result in memOK and jumps back to the label stored in memRet.
+	a.label("process")
+
+	// Struct hash
+	a.push(crypto.Keccak256Hash([]byte(eip2771toolkit.FORWARD_REQUEST_TYPEHASH))).mstore(memStruct)
+	loadField(0x00)(a)
+	a.mstore(memStruct + 0x20)
+	loadField(0x20)(a)
+	a.mstore(memStruct + 0x40)
+	loadField(0x40)(a)
+	a.mstore(memStruct + 0x60)
+	loadField(0x60)(a)
+	a.mstore(memStruct + 0x80)
+	loadField(0x00)(a)
+	a.op(vm.SLOAD, vm.DUP1).mstore(memNonce).mstore(memStruct + 0xa0)
+	loadField(0x80)(a)
+	a.mstore(memStruct + 0xc0)
+	loadDynamic(0xa0)
+	a.op(vm.DUP1, vm.CALLDATALOAD, vm.DUP1).mstore(memDataLen)
+	a.op(vm.SWAP1).push(32).op(vm.ADD).push(memCalldata).op(vm.CALLDATACOPY)
+	a.mload(memDataLen).push(memCalldata).op(vm.KECCAK256).mstore(memStruct + 0xe0)
+	a.push(0x100).push(memStruct).op(vm.KECCAK256).mstore(memStructSH)
+
+	// Domain separator, computed at runtime so it follows the deployment address and chain
+	a.push(crypto.Keccak256Hash([]byte(eip2771toolkit.EIP712_DOMAIN_TYPEHASH))).mstore(memDomain)
+	a.push(crypto.Keccak256Hash([]byte("ERC2771Forwarder"))).mstore(memDomain + 0x20)
+	a.push(crypto.Keccak256Hash([]byte("1"))).mstore(memDomain + 0x40)
+	a.op(vm.CHAINID).mstore(memDomain + 0x60)
+	a.op(vm.ADDRESS).mstore(memDomain + 0x80)
+
+	// Digest
+	a.push(0x1901).push(240).op(vm.SHL).mstore(memDigest)
+	a.push(0xa0).push(memDomain).op(vm.KECCAK256).mstore(memDigest + 2)
+	a.mload(memStructSH).mstore(memDigest + 0x22)
+	a.push(0x42).push(memDigest).op(vm.KECCAK256).mstore(memEcrecover)
+
+	// Signature recovery; malformed signatures recover the zero address
+	a.push(0).mstore(memSigner)
+	loadDynamic(0xc0)
+	a.op(vm.DUP1, vm.CALLDATALOAD).push(65).op(vm.EQ, vm.ISZERO).jumpi("badSignature")
+	a.op(vm.DUP1).push(0x20).op(vm.ADD, vm.CALLDATALOAD).mstore(memEcrecover + 0x40)
+	a.op(vm.DUP1).push(0x40).op(vm.ADD, vm.CALLDATALOAD).mstore(memEcrecover + 0x60)
+	a.push(0x60).op(vm.ADD, vm.CALLDATALOAD).push(0).op(vm.BYTE).mstore(memEcrecover + 0x20)
+	a.push(secp256k1HalfN).mload(memEcrecover + 0x60).op(vm.GT).jumpi("signatureDone")
+	a.push(0).mstore(memRecovered)
+	a.push(32).push(memRecovered).push(0x80).push(memEcrecover).push(1).op(vm.GAS, vm.STATICCALL, vm.POP)
+	a.mload(memRecovered).mstore(memSigner)
+	a.jump("signatureDone")
+	a.label("badSignature").op(vm.POP)
+	a.label("signatureDone")
+
+	// Trust: success && returndatasize >= 32 && target.isTrustedForwarder(address(this))
+	a.pushSelector("isTrustedForwarder(address)").push(224).op(vm.SHL).mstore(0)
+	a.op(vm.ADDRESS).mstore(4)
+	a.push(0x20).push(0).push(0x24).push(0)
+	loadField(0x20)(a)
+	a.op(vm.GAS, vm.STATICCALL)
+	a.push(0x20).op(vm.RETURNDATASIZE, vm.LT, vm.ISZERO, vm.AND)
+	a.push(0).op(vm.MLOAD, vm.ISZERO, vm.ISZERO, vm.AND).mstore(memTrusted)
+
+	// Validation: deadline >= block.timestamp and signer == from
+	loadField(0x80)(a)
+	a.op(vm.TIMESTAMP, vm.GT).mstore(memExpired)
+	loadField(0x00)(a)
+	a.mload(memSigner).op(vm.EQ).mload(memSigner).op(vm.ISZERO, vm.ISZERO, vm.AND).mstore(memMatch)
+
+	// verify: report validity only
+	a.mload(memMode).op(vm.ISZERO, vm.ISZERO).jumpi("notVerify")
+	a.mload(memMatch).mload(memExpired).op(vm.ISZERO, vm.AND).mload(memTrusted).op(vm.AND).mstore(memOK)
+	a.jump("processReturn")
+	a.label("notVerify")
+
+	// Atomic execution reverts on invalid requests, otherwise they are skipped
+	a.mload(memMode).push(2).op(vm.EQ, vm.ISZERO).jumpi("lenientCheck")
+	a.mload(memTrusted).jumpi("strictExpiry")
+	a.revertWith(errUntrustful, loadField(0x20), func(a *assembler) { a.op(vm.ADDRESS) })
+	a.label("strictExpiry")
+	a.mload(memExpired).op(vm.ISZERO).jumpi("strictSigner")
+	a.revertWith(errExpiredRequest, loadField(0x80))
+	a.label("strictSigner")
+	a.mload(memMatch).jumpi("doExecute")
+	a.revertWith(errInvalidSigner, func(a *assembler) { a.mload(memSigner) }, loadField(0x00))
+	a.label("lenientCheck")
+	a.mload(memMatch).mload(memExpired).op(vm.ISZERO, vm.AND).mload(memTrusted).op(vm.AND).jumpi("doExecute")
+	a.push(0).mstore(memOK)
+	a.jump("processReturn")
+
+	// Use the nonce and call the target with the signer appended to the calldata
+	a.label("doExecute")
+	a.mload(memNonce).push(1).op(vm.ADD)
+	loadField(0x00)(a)
+	a.op(vm.SSTORE)
+	loadField(0x00)(a)
+	a.push(96).op(vm.SHL).mload(memDataLen).push(memCalldata).op(vm.ADD, vm.MSTORE)
+	a.push(0).push(0).mload(memDataLen).push(20).op(vm.ADD).push(memCalldata)
+	loadField(0x40)(a)
+	loadField(0x20)(a)
+	loadField(0x60)(a)
+	a.op(vm.CALL)
+
+	// _checkForwardedGas: the target must have been given the requested gas
+	a.push(63)
+	loadField(0x60)(a)
+	a.op(vm.DIV, vm.GAS, vm.LT).jumpi("insufficientGas")
+	a.op(vm.DUP1).mstore(memOK)
+
+	// emit ExecutedForwardRequest(address indexed signer, uint256 nonce, bool success)
+	a.mstore(0x20)
+	a.mload(memNonce).mstore(0)
+	loadField(0x00)(a)
+	a.push(crypto.Keccak256Hash([]byte("ExecutedForwardRequest(address,uint256,bool)")))
+	a.push(0x40).push(0).op(vm.LOG2)
+
+	a.label("processReturn")
+	a.mload(memRet).op(vm.JUMP)
+
+	a.label("insufficientGas").op(vm.INVALID)
+
+	return a.bytes()
+}
+
+// TokenCode returns the deployment bytecode of an ERC2771-aware demo token trusting forwarder.
+// Anyone can mint. If returnFalse is set, transfers with insufficient balance return false
+// instead of reverting, like some non-compliant tokens do.
+func TokenCode(forwarder common.Address, returnFalse bool) []byte {
+	return deployCode(tokenRuntime(forwarder, returnFalse))
+}
+
+// tokenRuntime assembles the runtime bytecode of the demo token. Balances are stored at the
+// holder address slot.
+func tokenRuntime(forwarder common.Address, returnFalse bool) []byte {
+	a := newAssembler()
+
+	// Function dispatch
+	a.push(0).op(vm.CALLDATALOAD).push(224).op(vm.SHR)
+	for _, fn := range []struct{ signature, label string }{
+		{"transfer(address,uint256)", "transfer"},
+		{"balanceOf(address)", "balanceOf"},
+		{"mint(address,uint256)", "mint"},
+		{"decimals()", "decimals"},
+		{"isTrustedForwarder(address)", "isTrustedForwarder"},
+	} {
+		a.op(vm.DUP1).pushSelector(fn.signature).op(vm.EQ).jumpi(fn.label)
+	}
+	a.push(0).push(0).op(vm.REVERT)
+
+	// balanceOf(address)
+	a.label("balanceOf").op(vm.POP)
+	a.push(4).op(vm.CALLDATALOAD, vm.SLOAD).returnWord()
+
+	// decimals()
+	a.label("decimals").op(vm.POP)
+	a.push(18).returnWord()
+
+	// isTrustedForwarder(address)
+	a.label("isTrustedForwarder").op(vm.POP)
+	a.push(4).op(vm.CALLDATALOAD).push(forwarder).op(vm.EQ).returnWord()
+
+	// mint(address to, uint256 amount)
+	a.label("mint").op(vm.POP)
+	a.push(0x24).op(vm.CALLDATALOAD).push(4).op(vm.CALLDATALOAD)
+	a.op(vm.DUP1, vm.SLOAD, vm.DUP3, vm.ADD, vm.SWAP1, vm.SSTORE, vm.STOP)
+
+	// transfer(address to, uint256 amount)
+	a.label("transfer").op(vm.POP)
+
+	// _msgSender(): the last 20 calldata bytes when called by the trusted forwarder
+	a.op(vm.CALLER).push(forwarder).op(vm.DUP2, vm.EQ)
+	a.push(20).op(vm.CALLDATASIZE, vm.LT, vm.ISZERO, vm.AND, vm.ISZERO).jumpi("senderDone")
+	a.op(vm.POP).push(20).op(vm.CALLDATASIZE, vm.SUB, vm.CALLDATALOAD).push(96).op(vm.SHR)
+	a.label("senderDone")
+
+	// stack: from
+	a.push(4).op(vm.CALLDATALOAD)    // to, from
+	a.push(0x24).op(vm.CALLDATALOAD) // amount, to, from
+	a.op(vm.DUP3, vm.SLOAD)          // balance, amount, to, from
+	a.op(vm.DUP2, vm.DUP2, vm.LT).jumpi("insufficient")
+	a.op(vm.DUP2, vm.SWAP1, vm.SUB, vm.DUP4, vm.SSTORE) // amount, to, from
+	a.op(vm.DUP2, vm.SLOAD, vm.DUP2, vm.ADD, vm.DUP3, vm.SSTORE)
+
+	// emit Transfer(address indexed from, address indexed to, uint256 value)
+	a.mstore(0) // to, from
+	a.push(crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")))
+	a.op(vm.SWAP1, vm.SWAP2, vm.SWAP1) // topic, from, to
+	a.push(32).push(0).op(vm.LOG3)
+	a.push(1).returnWord()
+
+	// stack: balance, amount, to, from
+	a.label("insufficient")
+	if returnFalse {
+		a.push(0).returnWord()
+	} else {
+		a.mstore(0x100) // amount, to, from
+		a.mstore(0x120) // to, from
+		a.op(vm.POP)    // from
+		a.mstore(0xe0)
+		a.revertWith(errInsufficient,
+			func(a *assembler) { a.mload(0xe0) },
+			func(a *assembler) { a.mload(0x100) },
+			func(a *assembler) { a.mload(0x120) },
+		)
+	}
+
+	return a.bytes()
+}
@@ -0,0 +1,436 @@
+package devnet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// The compiled OpenZeppelin ERC2771Forwarder is not vendored, so these tests pin the
+// replica to the behaviour of OpenZeppelin v5.0 ERC2771Forwarder.sol: its selectors, custom
+// errors, event, validation order, batch refunds, _checkForwardedGas and ERC2771Context's
+// _msgSender.
+
+// Selectors of the OpenZeppelin v5 ERC2771Forwarder ABI
+var ozSelectors = map[string]string{
+	"execute((address,address,uint256,uint256,uint48,bytes,bytes))":                "df905caf",
+	"executeBatch((address,address,uint256,uint256,uint48,bytes,bytes)[],address)": "ccf96b4a",
+	"verify((address,address,uint256,uint256,uint48,bytes,bytes))":                 "19d8d38c",
+	"nonces(address)":  "7ecebe00",
+	errInvalidSigner:   "c845a056",
+	errExpiredRequest:  "94eef58a",
+	errMismatchedValue: "70647f79",
+	errUntrustful:      "d2650cd1",
+	errFailedInnerCall: "1425ea42",
+}
+
+func TestForwarderSelectorsMatchOpenZeppelin(t *testing.T) {
+	for signature, want := range ozSelectors {
+		if got := hex.EncodeToString(selector(signature)); got != want {
+			t.Errorf("selector of %s = %s, want %s", signature, got, want)
+		}
+	}
+	if got := crypto.Keccak256Hash([]byte("ExecutedForwardRequest(address,uint256,bool)")); got != eip2771toolkit.EXECUTED_FORWARD_REQUEST_TOPIC {
+		t.Errorf("event topic %s, want %s", got.Hex(), eip2771toolkit.EXECUTED_FORWARD_REQUEST_TOPIC.Hex())
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(eip2771toolkit.ERC2771ForwarderABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"execute", "executeBatch", "verify", "nonces"} {
+		method, ok := parsed.Methods[name]
+		if !ok {
+			t.Fatalf("toolkit forwarder ABI has no %s", name)
+		}
+		if got, want := hex.EncodeToString(method.ID), ozSelectors[method.Sig]; got != want {
+			t.Errorf("toolkit ABI %s selector %s, want %s", method.Sig, got, want)
+		}
+	}
+}
+
+// forwarderFixture is a simulated chain with a user holding demo tokens
+type forwarderFixture struct {
+	t       *testing.T
+	ctx     context.Context
+	sim     *Simulated
+	abi     abi.ABI
+	user    *ecdsa.PrivateKey
+	userAt  common.Address
+	head    uint64 // timestamp of the head block
+	domain  []byte
+	untrust common.Address // demo token trusting another forwarder
+}
+
+func newForwarderFixture(t *testing.T) *forwarderFixture {
+	t.Helper()
+	ctx := context.Background()
+	sim, err := NewSimulated(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sim.Close() })
+
+	f := &forwarderFixture{t: t, ctx: ctx, sim: sim}
+	if f.abi, err = abi.JSON(strings.NewReader(eip2771toolkit.ERC2771ForwarderABI)); err != nil {
+		t.Fatal(err)
+	}
+	if f.user, err = crypto.GenerateKey(); err != nil {
+		t.Fatal(err)
+	}
+	f.userAt = crypto.PubkeyToAddress(f.user.PublicKey)
+	if err := sim.Mint(ctx, sim.Token, f.userAt, big.NewInt(1000)); err != nil {
+		t.Fatal(err)
+	}
+	if f.untrust, err = sim.Deploy(ctx, TokenCode(common.HexToAddress("0x1234"), false)); err != nil {
+		t.Fatal(err)
+	}
+	if f.domain, err = sim.DomainSeparator(); err != nil {
+		t.Fatal(err)
+	}
+	head, err := sim.Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.head = head.Time
+	return f
+}
+
+// transfer returns a request of the user transferring amount demo tokens
+func (f *forwarderFixture) transfer(nonce uint64, amount int64) eip2771toolkit.ForwardRequest {
+	metaTx := eip2771toolkit.NewMetaTx(f.userAt, common.HexToAddress("0xbeef"), f.sim.Token, big.NewInt(amount), 100000, nonce, f.head+3600)
+	req, err := metaTx.ForwardRequest()
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return req
+}
+
+// sign signs req with key and returns the forwarder's ForwardRequestData argument
+func (f *forwarderFixture) sign(req eip2771toolkit.ForwardRequest, key *ecdsa.PrivateKey) interface{} {
+	structHash, err := eip2771toolkit.OZForwarderV5Schema.HashStruct(req)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	digest := crypto.Keccak256(append(append([]byte{0x19, 0x01}, f.domain...), structHash...))
+	sigBytes, err := crypto.Sign(digest, key)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	var sig eip2771toolkit.Signature
+	if err := sig.FromBytes(sigBytes); err != nil {
+		f.t.Fatal(err)
+	}
+	sig.V += 27
+	return eip2771toolkit.OZForwarderV5Schema.RequestData(req, sig)
+}
+
+// call makes an eth_call to the forwarder from the deployer
+func (f *forwarderFixture) call(value *big.Int, gas uint64, method string, args ...interface{}) ([]byte, error) {
+	data, err := f.abi.Pack(method, args...)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return f.sim.Client.CallContract(f.ctx, ethereum.CallMsg{
+		From:  crypto.PubkeyToAddress(f.sim.Deployer.PublicKey),
+		To:    &f.sim.Forwarder,
+		Value: value,
+		Gas:   gas,
+		Data:  data,
+	}, nil)
+}
+
+// send executes a forwarder method in a mined transaction from the deployer
+func (f *forwarderFixture) send(value *big.Int, method string, args ...interface{}) {
+	data, err := f.abi.Pack(method, args...)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(f.sim.Deployer.PublicKey)
+	nonce, err := f.sim.Client.PendingNonceAt(f.ctx, from)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	gasPrice, err := f.sim.Client.SuggestGasPrice(f.ctx)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	tx, err := types.SignNewTx(f.sim.Deployer, types.LatestSignerForChainID(f.sim.ChainID), &types.LegacyTx{
+		Nonce:    nonce,
+		To:       &f.sim.Forwarder,
+		Value:    value,
+		Gas:      2_000_000,
+		GasPrice: gasPrice,
+		Data:     data,
+	})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	if err := f.sim.Client.SendTransaction(f.ctx, tx); err != nil {
+		f.t.Fatal(err)
+	}
+	receipt, err := f.sim.Mine(f.ctx, tx.Hash())
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		f.t.Fatalf("%s reverted", method)
+	}
+}
+
+func (f *forwarderFixture) nonce(account common.Address) uint64 {
+	out, err := f.call(nil, 0, "nonces", account)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return new(big.Int).SetBytes(out).Uint64()
+}
+
+func (f *forwarderFixture) balance(token, holder common.Address) int64 {
+	balance, err := f.sim.BalanceOf(f.ctx, token, holder)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return balance.Int64()
+}
+
+// revertOf decodes the custom error of a failed eth_call
+func revertOf(t *testing.T, err error) *eip2771toolkit.RevertError {
+	t.Helper()
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		t.Fatalf("no revert data in %v", err)
+	}
+	data, ok := dataErr.ErrorData().(string)
+	if !ok {
+		t.Fatalf("revert data %v is not a string", dataErr.ErrorData())
+	}
+	raw, err := hex.DecodeString(strings.TrimPrefix(data, "0x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return eip2771toolkit.DecodeRevert(raw)
+}
+
+// highS returns request data with the high-s twin of its signature, which ecrecover
+// accepts and OpenZeppelin ECDSA rejects
+func highS(data interface{}) interface{} {
+	v := reflect.New(reflect.TypeOf(data)).Elem()
+	v.Set(reflect.ValueOf(data))
+	field := v.FieldByName("Signature")
+	sig := common.CopyBytes(field.Bytes())
+	s := new(big.Int).Sub(crypto.S256().Params().N, new(big.Int).SetBytes(sig[32:64]))
+	s.FillBytes(sig[32:64])
+	sig[64] ^= 1 // 27 <-> 28
+	field.SetBytes(sig)
+	return v.Interface()
+}
+
+// typedBatch converts request data to the typed slice the ABI encoder packs as tuple[]
+func typedBatch(batch []interface{}) interface{} {
+	slice := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(batch[0])), 0, len(batch))
+	for _, data := range batch {
+		slice = reflect.Append(slice, reflect.ValueOf(data))
+	}
+	return slice.Interface()
+}
+
+// argEqual compares decoded error arguments
+func argEqual(got, want interface{}) bool {
+	if w, ok := want.(*big.Int); ok {
+		g, ok := got.(*big.Int)
+		return ok && g.Cmp(w) == 0
+	}
+	return got == want
+}
+
+func TestForwarderVerify(t *testing.T) {
+	f := newForwarderFixture(t)
+	other, _ := crypto.GenerateKey()
+
+	expired := f.transfer(0, 1)
+	expired.Deadline = new(big.Int).SetUint64(f.head - 1)
+	untrusted := f.transfer(0, 1)
+	untrusted.To = f.untrust
+	noCode := f.transfer(0, 1)
+	noCode.To = common.HexToAddress("0xdead")
+
+	tests := []struct {
+		name string
+		data interface{}
+		want bool
+	}{
+		{"valid", f.sign(f.transfer(0, 1), f.user), true},
+		{"expired", f.sign(expired, f.user), false},
+		{"other signer", f.sign(f.transfer(0, 1), other), false},
+		{"future nonce", f.sign(f.transfer(1, 1), f.user), false},
+		{"high s", highS(f.sign(f.transfer(0, 1), f.user)), false},
+		{"untrusted target", f.sign(untrusted, f.user), false},
+		{"target without code", f.sign(noCode, f.user), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := f.call(nil, 0, "verify", tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := new(big.Int).SetBytes(out).Sign() != 0; got != tt.want {
+				t.Errorf("verify = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForwarderExecuteErrors(t *testing.T) {
+	f := newForwarderFixture(t)
+	other, _ := crypto.GenerateKey()
+
+	expired := f.transfer(0, 1)
+	expired.Deadline = new(big.Int).SetUint64(f.head - 1)
+	untrusted := f.transfer(0, 1)
+	untrusted.To = f.untrust
+	untrustedExpired := f.transfer(0, 1)
+	untrustedExpired.To = f.untrust
+	untrustedExpired.Deadline = expired.Deadline
+
+	tests := []struct {
+		name  string
+		data  interface{}
+		value *big.Int
+		want  error
+		args  []interface{}
+	}{
+		{"expired", f.sign(expired, f.user), nil, eip2771toolkit.ErrForwarderExpiredRequest, []interface{}{expired.Deadline}},
+		{"other signer", f.sign(f.transfer(0, 1), other), nil, eip2771toolkit.ErrForwarderInvalidSigner,
+			[]interface{}{crypto.PubkeyToAddress(other.PublicKey), f.userAt}},
+		{"untrusted target", f.sign(untrusted, f.user), nil, eip2771toolkit.ErrUntrustfulTarget, []interface{}{f.untrust, f.sim.Forwarder}},
+		// OpenZeppelin checks trust before the deadline
+		{"untrusted and expired", f.sign(untrustedExpired, f.user), nil, eip2771toolkit.ErrUntrustfulTarget, []interface{}{f.untrust, f.sim.Forwarder}},
+		{"mismatched value", f.sign(f.transfer(0, 1), f.user), big.NewInt(1), eip2771toolkit.ErrForwarderMismatchedValue, []interface{}{big.NewInt(0), big.NewInt(1)}},
+		{"inner call reverts", f.sign(f.transfer(0, 5000), f.user), nil, eip2771toolkit.ErrForwarderFailedCall, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.call(tt.value, 0, "execute", tt.data)
+			if err == nil {
+				t.Fatal("execute succeeded")
+			}
+			revert := revertOf(t, err)
+			if !errors.Is(revert, tt.want) {
+				t.Fatalf("execute failed with %v, want %v", revert, tt.want)
+			}
+			if len(revert.Args) != len(tt.args) {
+				t.Fatalf("error arguments %v, want %v", revert.Args, tt.args)
+			}
+			for i, want := range tt.args {
+				if got := revert.Args[i]; !argEqual(got, want) {
+					t.Errorf("error argument %d = %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestForwarderExecute(t *testing.T) {
+	f := newForwarderFixture(t)
+	recipient := common.HexToAddress("0xbeef")
+
+	f.send(nil, "execute", f.sign(f.transfer(0, 10), f.user))
+
+	if got := f.nonce(f.userAt); got != 1 {
+		t.Errorf("nonce = %d, want 1", got)
+	}
+	// The token read the user from the calldata suffix, not the forwarder
+	if got := f.balance(f.sim.Token, f.userAt); got != 990 {
+		t.Errorf("user balance = %d, want 990", got)
+	}
+	if got := f.balance(f.sim.Token, recipient); got != 10 {
+		t.Errorf("recipient balance = %d, want 10", got)
+	}
+
+	logs, err := f.sim.Client.FilterLogs(f.ctx, ethereum.FilterQuery{Addresses: []common.Address{f.sim.Forwarder}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 {
+		t.Fatalf("%d forwarder logs, want 1", len(logs))
+	}
+	event, ok := eip2771toolkit.ParseExecutedForwardRequest(&logs[0])
+	if !ok || event.Signer != f.userAt || event.Nonce.Sign() != 0 || !event.Success {
+		t.Errorf("event %+v, want signer %s, nonce 0, success", event, f.userAt.Hex())
+	}
+
+	// A replayed request no longer matches the signer's nonce
+	if _, err := f.call(nil, 0, "execute", f.sign(f.transfer(0, 10), f.user)); !errors.Is(revertOf(t, err), eip2771toolkit.ErrForwarderInvalidSigner) {
+		t.Errorf("replay failed with %v, want invalid signer", err)
+	}
+}
+
+func TestForwarderCheckForwardedGas(t *testing.T) {
+	f := newForwarderFixture(t)
+
+	// Less gas left after the call than 1/63 of the signed gas means the target was not given
+	// it: OpenZeppelin hits invalid() rather than record the request as executed
+	req := f.transfer(0, 1)
+	req.Gas = big.NewInt(20_000_000)
+	_, err := f.call(nil, 300_000, "execute", f.sign(req, f.user))
+	if err == nil || !strings.Contains(err.Error(), "invalid opcode") {
+		t.Fatalf("execute with too little gas = %v, want invalid opcode", err)
+	}
+	if _, err := f.call(nil, 25_000_000, "execute", f.sign(req, f.user)); err != nil {
+		t.Fatalf("execute with enough gas: %v", err)
+	}
+}
+
+func TestForwarderExecuteBatch(t *testing.T) {
+	f := newForwarderFixture(t)
+	refund := common.HexToAddress("0x5eed")
+
+	expired := f.transfer(1, 1)
+	expired.Deadline = new(big.Int).SetUint64(f.head - 1)
+	expired.Value = big.NewInt(7)
+	valued := f.transfer(1, 2)
+	valued.Value = big.NewInt(5)
+	batch := []interface{}{f.sign(f.transfer(0, 1), f.user), f.sign(expired, f.user), f.sign(valued, f.user)}
+
+	// Atomic batches revert on the first invalid request
+	_, err := f.call(big.NewInt(12), 0, "executeBatch", typedBatch(batch), common.Address{})
+	if !errors.Is(revertOf(t, err), eip2771toolkit.ErrForwarderExpiredRequest) {
+		t.Fatalf("atomic batch failed with %v, want expired request", err)
+	}
+	// Value is checked against the sum of every request
+	_, err = f.call(big.NewInt(5), 0, "executeBatch", typedBatch(batch), refund)
+	if !errors.Is(revertOf(t, err), eip2771toolkit.ErrForwarderMismatchedValue) {
+		t.Fatalf("batch with short value failed with %v, want mismatched value", err)
+	}
+
+	// Other batches skip invalid requests and refund their value
+	f.send(big.NewInt(12), "executeBatch", typedBatch(batch), refund)
+	if got := f.nonce(f.userAt); got != 2 {
+		t.Errorf("nonce = %d, want 2", got)
+	}
+	if got := f.balance(f.sim.Token, common.HexToAddress("0xbeef")); got != 3 {
+		t.Errorf("recipient balance = %d, want 3", got)
+	}
+	refunded, err := f.sim.Client.BalanceAt(f.ctx, refund, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refunded.Int64() != 7 {
+		t.Errorf("refund = %s, want 7", refunded)
+	}
+}
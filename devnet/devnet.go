@@ -0,0 +1,220 @@
+// Package devnet provides an in-process simulated chain with an ERC2771Forwarder and an
+// ERC2771-aware demo token deployed, for examples and integration tests of the toolkit, and
+// helpers running them against an anvil or Hardhat fork of a live chain.
+package devnet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// DemoTokenABI is the ABI of the demo token deployed by the harness
+const DemoTokenABI = `[
+	{"inputs": [{"name": "to", "type": "address"}, {"name": "amount", "type": "uint256"}], "name": "transfer", "outputs": [{"name": "", "type": "bool"}], "stateMutability": "nonpayable", "type": "function"},
+	{"inputs": [{"name": "to", "type": "address"}, {"name": "amount", "type": "uint256"}], "name": "mint", "outputs": [], "stateMutability": "nonpayable", "type": "function"},
+	{"inputs": [{"name": "account", "type": "address"}], "name": "balanceOf", "outputs": [{"name": "", "type": "uint256"}], "stateMutability": "view", "type": "function"},
+	{"inputs": [], "name": "decimals", "outputs": [{"name": "", "type": "uint8"}], "stateMutability": "view", "type": "function"},
+	{"inputs": [{"name": "forwarder", "type": "address"}], "name": "isTrustedForwarder", "outputs": [{"name": "", "type": "bool"}], "stateMutability": "view", "type": "function"},
+	{"anonymous": false, "inputs": [{"indexed": true, "name": "from", "type": "address"}, {"indexed": true, "name": "to", "type": "address"}, {"indexed": false, "name": "value", "type": "uint256"}], "name": "Transfer", "type": "event"}
+]`
+
+// DefaultBalance is the ether balance of every prefunded account (100 ETH)
+var DefaultBalance = new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))
+
+// Simulated is an in-process chain with a forwarder and a demo token deployed
+type Simulated struct {
+	Backend   *simulated.Backend
+	Client    simulated.Client
+	ChainID   *big.Int
+	Deployer  *ecdsa.PrivateKey // prefunded key that deploys contracts and mints tokens
+	Forwarder common.Address
+	Token     common.Address
+}
+
+// NewSimulated starts a simulated chain, prefunds the given accounts with DefaultBalance and
+// deploys the forwarder and a demo token trusting it
+func NewSimulated(ctx context.Context, fund ...common.Address) (*Simulated, error) {
+	deployer, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate deployer key: %w", err)
+	}
+
+	alloc := types.GenesisAlloc{
+		crypto.PubkeyToAddress(deployer.PublicKey): {Balance: DefaultBalance},
+	}
+	for _, addr := range fund {
+		alloc[addr] = types.Account{Balance: DefaultBalance}
+	}
+
+	backend := simulated.NewBackend(alloc)
+	s := &Simulated{
+		Backend:  backend,
+		Client:   backend.Client(),
+		Deployer: deployer,
+	}
+
+	s.ChainID, err = s.Client.ChainID(ctx)
+	if err != nil {
+		backend.Close()
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+
+	s.Forwarder, err = s.Deploy(ctx, ForwarderCode())
+	if err != nil {
+		backend.Close()
+		return nil, fmt.Errorf("failed to deploy forwarder: %w", err)
+	}
+
+	s.Token, err = s.DeployToken(ctx, false)
+	if err != nil {
+		backend.Close()
+		return nil, fmt.Errorf("failed to deploy token: %w", err)
+	}
+
+	return s, nil
+}
+
+// Close shuts the simulated chain down
+func (s *Simulated) Close() error {
+	return s.Backend.Close()
+}
+
+// DomainSeparator returns the EIP-712 domain separator of the deployed forwarder
+func (s *Simulated) DomainSeparator() ([]byte, error) {
+	return eip2771toolkit.CreateDomainSeparatorForChain(s.ChainID, s.Forwarder)
+}
+
+// Deploy deploys contract init code from the deployer account and mines it
+func (s *Simulated) Deploy(ctx context.Context, code []byte) (common.Address, error) {
+	receipt, err := s.transact(ctx, nil, code)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return receipt.ContractAddress, nil
+}
+
+// DeployToken deploys another demo token trusting the forwarder. With returnFalse set,
+// transfers exceeding the balance return false instead of reverting.
+func (s *Simulated) DeployToken(ctx context.Context, returnFalse bool) (common.Address, error) {
+	return s.Deploy(ctx, TokenCode(s.Forwarder, returnFalse))
+}
+
+// Mint mints demo tokens to an account and mines the transaction
+func (s *Simulated) Mint(ctx context.Context, token, to common.Address, amount *big.Int) error {
+	data, err := mintData(to, amount)
+	if err != nil {
+		return err
+	}
+	_, err = s.transact(ctx, &token, data)
+	return err
+}
+
+// mintData packs a demo token mint call
+func mintData(to common.Address, amount *big.Int) ([]byte, error) {
+	parsedABI, err := eip2771toolkit.DefaultRegistry.ABI(DemoTokenABI)
+	if err != nil {
+		return nil, err
+	}
+	data, err := parsedABI.Pack("mint", to, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack mint call: %w", err)
+	}
+	return data, nil
+}
+
+// BalanceOf returns the token balance of an account
+func (s *Simulated) BalanceOf(ctx context.Context, token, holder common.Address) (*big.Int, error) {
+	return balanceOf(ctx, s.Client, token, holder)
+}
+
+// balanceOf calls the ERC20 balanceOf function of token
+func balanceOf(ctx context.Context, caller ethereum.ContractCaller, token, holder common.Address) (*big.Int, error) {
+	parsedABI, err := eip2771toolkit.DefaultRegistry.ABI(DemoTokenABI)
+	if err != nil {
+		return nil, err
+	}
+	data, err := parsedABI.Pack("balanceOf", holder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack balanceOf call: %w", err)
+	}
+
+	result, err := caller.CallContract(ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call contract: %w", err)
+	}
+
+	var balance *big.Int
+	if err := parsedABI.UnpackIntoInterface(&balance, "balanceOf", result); err != nil {
+		return nil, fmt.Errorf("failed to unpack result: %w", err)
+	}
+	return balance, nil
+}
+
+// Mine commits the pending block and returns the receipt of a transaction included in it
+func (s *Simulated) Mine(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	s.Backend.Commit()
+
+	receipt, err := s.Client.TransactionReceipt(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction receipt: %w", err)
+	}
+	return receipt, nil
+}
+
+// transact sends a transaction from the deployer, mines it and requires it to succeed
+func (s *Simulated) transact(ctx context.Context, to *common.Address, data []byte) (*types.Receipt, error) {
+	return transact(ctx, s.Client, s.ChainID, s.Deployer, to, data, s.Mine)
+}
+
+// transact sends a transaction from key, waits for its receipt with mine and requires it to
+// succeed
+func transact(ctx context.Context, client eip2771toolkit.EthClient, chainID *big.Int, key *ecdsa.PrivateKey, to *common.Address, data []byte, mine func(context.Context, common.Hash) (*types.Receipt, error)) (*types.Receipt, error) {
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	nonce, err := client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployer nonce: %w", err)
+	}
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gas price: %w", err)
+	}
+	gasLimit, err := client.EstimateGas(ctx, ethereum.CallMsg{From: from, To: to, Data: data})
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate gas: %w", err)
+	}
+
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(chainID), &types.LegacyTx{
+		Nonce:    nonce,
+		To:       to,
+		Gas:      gasLimit,
+		GasPrice: gasPrice,
+		Data:     data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	if err := client.SendTransaction(ctx, tx); err != nil {
+		return nil, fmt.Errorf("failed to send transaction: %w", err)
+	}
+
+	receipt, err := mine(ctx, tx.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("transaction %s reverted", tx.Hash().Hex())
+	}
+	return receipt, nil
+}
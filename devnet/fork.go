@@ -0,0 +1,357 @@
+package devnet
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// DEFAULT_FORK_START_TIMEOUT is how long StartAnvilFork waits for anvil to serve by default
+const DEFAULT_FORK_START_TIMEOUT = 30 * time.Second
+
+// DEFAULT_ANVIL_IMAGE is the Foundry image that runs anvil in docker
+const DEFAULT_ANVIL_IMAGE = "ghcr.io/foundry-rs/foundry:latest"
+
+// ForkOptions configures StartAnvilFork
+type ForkOptions struct {
+	ForkURL      string         // JSON-RPC endpoint of the chain to fork, required
+	BlockNumber  uint64         // block to fork at, 0 for the latest
+	Anvil        string         // anvil binary, default "anvil" from $PATH
+	Docker       string         // image to run anvil in with docker instead, e.g. DEFAULT_ANVIL_IMAGE
+	Port         int            // port to serve on, default a free one
+	Forwarder    common.Address // forwarder deployed on the forked chain to use, zero to deploy the replica
+	StartTimeout time.Duration  // default DEFAULT_FORK_START_TIMEOUT
+}
+
+// Fork is a forked chain served by anvil, or any anvil or Hardhat node attached to, with a
+// funded deployer key and a forwarder. Real tokens on the fork do not trust the replica
+// forwarder, which like OpenZeppelin's rejects relays to them with ERC2771UntrustfulTarget;
+// deploy demo tokens with DeployToken, or use a forwarder the target trusts.
+type Fork struct {
+	URL       string
+	RPC       *rpc.Client
+	Client    *ethclient.Client
+	ChainID   *big.Int
+	Deployer  *ecdsa.PrivateKey // funded key that deploys contracts
+	Forwarder common.Address
+
+	namespace string       // "anvil" or "hardhat", the prefix of the node's testing methods
+	stop      func() error // stops the anvil started, nil when attached
+}
+
+// StartAnvilFork starts anvil forking opts.ForkURL and attaches to it. Close stops anvil.
+func StartAnvilFork(ctx context.Context, opts ForkOptions) (*Fork, error) {
+	if opts.ForkURL == "" {
+		return nil, errors.New("no fork URL")
+	}
+	return startAnvil(ctx, opts, nil)
+}
+
+// startAnvil starts anvil, forking opts.ForkURL if set, and attaches to it
+func startAnvil(ctx context.Context, opts ForkOptions, extraArgs []string) (*Fork, error) {
+	port := opts.Port
+	if port == 0 {
+		var err error
+		if port, err = freePort(); err != nil {
+			return nil, err
+		}
+	}
+	timeout := opts.StartTimeout
+	if timeout <= 0 {
+		timeout = DEFAULT_FORK_START_TIMEOUT
+	}
+
+	args := extraArgs
+	if opts.ForkURL != "" {
+		args = append(args, "--fork-url", opts.ForkURL)
+	}
+	if opts.BlockNumber != 0 {
+		args = append(args, "--fork-block-number", strconv.FormatUint(opts.BlockNumber, 10))
+	}
+
+	var cmd *exec.Cmd
+	var stop func() error
+	if opts.Docker != "" {
+		// The image's entrypoint is a shell, so anvil runs as one command line
+		name := fmt.Sprintf("eip2771-devnet-%d", port)
+		anvil := strings.Join(append([]string{"anvil", "--host", "0.0.0.0", "--silent"}, args...), " ")
+		cmd = exec.Command("docker", "run", "--rm", "--name", name, "-p", fmt.Sprintf("127.0.0.1:%d:8545", port), opts.Docker, anvil)
+		stop = func() error {
+			if out, err := exec.Command("docker", "rm", "-f", name).CombinedOutput(); err != nil {
+				return fmt.Errorf("failed to remove container %s: %v: %s", name, err, strings.TrimSpace(string(out)))
+			}
+			return nil
+		}
+	} else {
+		binary := opts.Anvil
+		if binary == "" {
+			binary = "anvil"
+		}
+		cmd = exec.Command(binary, append([]string{"--port", strconv.Itoa(port), "--silent"}, args...)...)
+		stop = func() error {
+			if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+				return fmt.Errorf("failed to stop anvil: %w", err)
+			}
+			return nil
+		}
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start anvil: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d", port)
+	startCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		client, err := ethclient.DialContext(startCtx, url)
+		if err == nil {
+			if _, err = client.ChainID(startCtx); err == nil {
+				client.Close()
+				break
+			}
+			client.Close()
+		}
+		select {
+		case err := <-exited:
+			return nil, fmt.Errorf("anvil exited: %v: %s", err, strings.TrimSpace(stderr.String()))
+		case <-startCtx.Done():
+			stop()
+			return nil, fmt.Errorf("anvil did not serve %s in time: %w", url, startCtx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	f, err := AttachFork(ctx, url, opts.Forwarder)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	f.stop = stop
+	return f, nil
+}
+
+// AttachFork attaches to a running anvil or Hardhat node at url, funds a new deployer key
+// and deploys the forwarder replica, unless forwarder is set
+func AttachFork(ctx context.Context, url string, forwarder common.Address) (*Fork, error) {
+	rpcClient, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", url, err)
+	}
+	f := &Fork{URL: url, RPC: rpcClient, Client: ethclient.NewClient(rpcClient), Forwarder: forwarder}
+
+	var version string
+	if err := rpcClient.CallContext(ctx, &version, "web3_clientVersion"); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to get client version: %w", err)
+	}
+	switch lower := strings.ToLower(version); {
+	case strings.HasPrefix(lower, "anvil"):
+		f.namespace = "anvil"
+	case strings.HasPrefix(lower, "hardhat"):
+		f.namespace = "hardhat"
+	default:
+		f.Close()
+		return nil, fmt.Errorf("%s is neither anvil nor Hardhat", version)
+	}
+
+	if f.ChainID, err = f.Client.ChainID(ctx); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+	if f.Deployer, err = crypto.GenerateKey(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to generate deployer key: %w", err)
+	}
+	if err := f.Fund(ctx, crypto.PubkeyToAddress(f.Deployer.PublicKey)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if f.Forwarder == (common.Address{}) {
+		if f.Forwarder, err = f.Deploy(ctx, ForwarderCode()); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to deploy forwarder: %w", err)
+		}
+	}
+	return f, nil
+}
+
+// Close disconnects, and stops anvil if StartAnvilFork started it
+func (f *Fork) Close() error {
+	f.RPC.Close()
+	if f.stop == nil {
+		return nil
+	}
+	return f.stop()
+}
+
+// DomainSeparator returns the EIP-712 domain separator of the forwarder
+func (f *Fork) DomainSeparator() ([]byte, error) {
+	return eip2771toolkit.CreateDomainSeparatorForChain(f.ChainID, f.Forwarder)
+}
+
+// NewRelayer creates a relayer submitting to the forwarder with key, which should be funded
+func (f *Fork) NewRelayer(key *ecdsa.PrivateKey, opts ...eip2771toolkit.RelayerOption) *eip2771toolkit.Relayer {
+	return eip2771toolkit.NewRelayer(key, f.Forwarder, f.Client, opts...)
+}
+
+// SetBalance sets the ether balance of account
+func (f *Fork) SetBalance(ctx context.Context, account common.Address, wei *big.Int) error {
+	if err := f.RPC.CallContext(ctx, nil, f.namespace+"_setBalance", account, (*hexutil.Big)(wei)); err != nil {
+		return fmt.Errorf("failed to set balance of %s: %w", account.Hex(), err)
+	}
+	return nil
+}
+
+// Fund sets the ether balance of every account to DefaultBalance
+func (f *Fork) Fund(ctx context.Context, accounts ...common.Address) error {
+	for _, account := range accounts {
+		if err := f.SetBalance(ctx, account, DefaultBalance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Impersonate lets SendAs send transactions from account without its key, until
+// StopImpersonating
+func (f *Fork) Impersonate(ctx context.Context, account common.Address) error {
+	if err := f.RPC.CallContext(ctx, nil, f.namespace+"_impersonateAccount", account); err != nil {
+		return fmt.Errorf("failed to impersonate %s: %w", account.Hex(), err)
+	}
+	return nil
+}
+
+// StopImpersonating ends the impersonation of account
+func (f *Fork) StopImpersonating(ctx context.Context, account common.Address) error {
+	if err := f.RPC.CallContext(ctx, nil, f.namespace+"_stopImpersonatingAccount", account); err != nil {
+		return fmt.Errorf("failed to stop impersonating %s: %w", account.Hex(), err)
+	}
+	return nil
+}
+
+// SendAs sends a transaction from an impersonated account, waits for it to be mined and
+// requires it to succeed
+func (f *Fork) SendAs(ctx context.Context, from, to common.Address, data []byte) (*types.Receipt, error) {
+	var txHash common.Hash
+	tx := map[string]interface{}{"from": from, "to": to, "data": hexutil.Bytes(data)}
+	if err := f.RPC.CallContext(ctx, &txHash, "eth_sendTransaction", tx); err != nil {
+		return nil, fmt.Errorf("failed to send transaction as %s: %w", from.Hex(), err)
+	}
+	receipt, err := f.Mine(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("transaction %s reverted", txHash.Hex())
+	}
+	return receipt, nil
+}
+
+// SeedTokens transfers amount of a real token from whale, a holder of enough of it, to each
+// of accounts by impersonating the whale, which is given ether for the gas
+func (f *Fork) SeedTokens(ctx context.Context, token, whale common.Address, amount *big.Int, accounts ...common.Address) error {
+	if err := f.Fund(ctx, whale); err != nil {
+		return err
+	}
+	if err := f.Impersonate(ctx, whale); err != nil {
+		return err
+	}
+	defer f.StopImpersonating(context.WithoutCancel(ctx), whale)
+
+	for _, account := range accounts {
+		data, err := (&eip2771toolkit.MetaTx{To: account, Amount: amount}).TransferData()
+		if err != nil {
+			return err
+		}
+		if _, err := f.SendAs(ctx, whale, token, data); err != nil {
+			return fmt.Errorf("failed to transfer %s from whale %s: %w", token.Hex(), whale.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// BalanceOf returns the token balance of an account
+func (f *Fork) BalanceOf(ctx context.Context, token, holder common.Address) (*big.Int, error) {
+	return balanceOf(ctx, f.Client, token, holder)
+}
+
+// Deploy deploys contract init code from the deployer account and waits for it to be mined
+func (f *Fork) Deploy(ctx context.Context, code []byte) (common.Address, error) {
+	receipt, err := transact(ctx, f.Client, f.ChainID, f.Deployer, nil, code, f.Mine)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return receipt.ContractAddress, nil
+}
+
+// DeployToken deploys a demo token trusting the forwarder, see TokenCode
+func (f *Fork) DeployToken(ctx context.Context, returnFalse bool) (common.Address, error) {
+	return f.Deploy(ctx, TokenCode(f.Forwarder, returnFalse))
+}
+
+// Mint mints tokens deployed with DeployToken to an account
+func (f *Fork) Mint(ctx context.Context, token, to common.Address, amount *big.Int) error {
+	data, err := mintData(to, amount)
+	if err != nil {
+		return err
+	}
+	_, err = transact(ctx, f.Client, f.ChainID, f.Deployer, &token, data, f.Mine)
+	return err
+}
+
+// Mine returns the receipt of a sent transaction, mining a block with evm_mine if the node
+// does not mine it on its own
+func (f *Fork) Mine(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	for i := 0; ; i++ {
+		receipt, err := f.Client.TransactionReceipt(ctx, txHash)
+		if err == nil {
+			return receipt, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return nil, fmt.Errorf("failed to get transaction receipt: %w", err)
+		}
+		if i > 0 {
+			if err := f.RPC.CallContext(ctx, nil, "evm_mine"); err != nil {
+				return nil, fmt.Errorf("failed to mine: %w", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// freePort returns a TCP port no one listens on
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
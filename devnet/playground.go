@@ -0,0 +1,177 @@
+package devnet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// DEFAULT_BLOCK_TIME is how often Start mines a block on the simulated chain by default
+const DEFAULT_BLOCK_TIME = time.Second
+
+// startConfig holds the settings of Start
+type startConfig struct {
+	docker    string
+	blockTime time.Duration
+	fund      []common.Address
+}
+
+// StartOption configures Start
+type StartOption func(*startConfig)
+
+// WithDocker runs anvil in docker with image, e.g. DEFAULT_ANVIL_IMAGE, instead of the
+// in-process simulated chain
+func WithDocker(image string) StartOption {
+	return func(c *startConfig) {
+		c.docker = image
+	}
+}
+
+// WithBlockTime sets how often a block is mined, default DEFAULT_BLOCK_TIME on the simulated
+// chain and on every transaction on anvil. 0 mines the simulated chain only on Mine.
+func WithBlockTime(interval time.Duration) StartOption {
+	return func(c *startConfig) {
+		c.blockTime = interval
+	}
+}
+
+// WithFund also prefunds accounts with DefaultBalance
+func WithFund(accounts ...common.Address) StartOption {
+	return func(c *startConfig) {
+		c.fund = append(c.fund, accounts...)
+	}
+}
+
+// Playground is a ready local chain: a forwarder, a demo token trusting it and a funded
+// relayer, described by a ChainConfig
+type Playground struct {
+	Chain      *eip2771toolkit.ChainConfig
+	Client     eip2771toolkit.EthClient
+	Token      common.Address
+	RelayerKey *ecdsa.PrivateKey // funded with DefaultBalance
+	Relayer    *eip2771toolkit.Relayer
+
+	Simulated *Simulated // the in-process chain, nil on anvil
+	Anvil     *Fork      // the anvil chain of WithDocker, nil on the simulated chain
+
+	done   chan struct{} // closed to stop mining the simulated chain
+	mining sync.WaitGroup
+}
+
+// Start starts a local chain, the in-process simulated chain unless WithDocker is given,
+// deploys the forwarder and a demo token and funds a new relayer key. Close stops the chain.
+func Start(ctx context.Context, opts ...StartOption) (*Playground, error) {
+	cfg := startConfig{blockTime: -1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	relayerKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate relayer key: %w", err)
+	}
+	fund := append([]common.Address{crypto.PubkeyToAddress(relayerKey.PublicKey)}, cfg.fund...)
+	p := &Playground{RelayerKey: relayerKey, done: make(chan struct{})}
+
+	var chain *eip2771toolkit.ChainConfig
+	if cfg.docker != "" {
+		var args []string
+		if cfg.blockTime > 0 {
+			args = []string{"--block-time", strconv.FormatInt(max(int64(cfg.blockTime/time.Second), 1), 10)}
+		}
+		if p.Anvil, err = startAnvil(ctx, ForkOptions{Docker: cfg.docker}, args); err != nil {
+			return nil, err
+		}
+		if err := p.Anvil.Fund(ctx, fund...); err != nil {
+			p.Close()
+			return nil, err
+		}
+		if p.Token, err = p.Anvil.DeployToken(ctx, false); err != nil {
+			p.Close()
+			return nil, fmt.Errorf("failed to deploy token: %w", err)
+		}
+		p.Client = p.Anvil.Client
+		chain = &eip2771toolkit.ChainConfig{ChainID: p.Anvil.ChainID, RPCURLs: []string{p.Anvil.URL}, Forwarder: p.Anvil.Forwarder}
+	} else {
+		if p.Simulated, err = NewSimulated(ctx, fund...); err != nil {
+			return nil, err
+		}
+		p.Token = p.Simulated.Token
+		p.Client = p.Simulated.Client
+		chain = &eip2771toolkit.ChainConfig{ChainID: p.Simulated.ChainID, Forwarder: p.Simulated.Forwarder}
+
+		blockTime := cfg.blockTime
+		if blockTime < 0 {
+			blockTime = DEFAULT_BLOCK_TIME
+		}
+		if blockTime > 0 {
+			p.mining.Add(1)
+			go p.mine(blockTime)
+		}
+	}
+
+	chain.Name = "devnet"
+	chain.Profile = eip2771toolkit.OZ_FORWARDER_V5_SCHEMA
+	p.Chain = chain
+	if p.Relayer, err = chain.NewRelayer(relayerKey, p.Client); err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Close stops the chain
+func (p *Playground) Close() error {
+	if p.Anvil != nil {
+		return p.Anvil.Close()
+	}
+	close(p.done)
+	p.mining.Wait()
+	return p.Simulated.Close()
+}
+
+// Mint mints demo tokens to an account
+func (p *Playground) Mint(ctx context.Context, to common.Address, amount *big.Int) error {
+	if p.Anvil != nil {
+		return p.Anvil.Mint(ctx, p.Token, to, amount)
+	}
+	return p.Simulated.Mint(ctx, p.Token, to, amount)
+}
+
+// BalanceOf returns the demo token balance of an account
+func (p *Playground) BalanceOf(ctx context.Context, holder common.Address) (*big.Int, error) {
+	return balanceOf(ctx, p.Client, p.Token, holder)
+}
+
+// Mine returns the receipt of a sent transaction, mining a block if needed
+func (p *Playground) Mine(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if p.Anvil != nil {
+		return p.Anvil.Mine(ctx, txHash)
+	}
+	return p.Simulated.Mine(ctx, txHash)
+}
+
+// mine commits a block of the simulated chain every interval until Close
+func (p *Playground) mine(interval time.Duration) {
+	defer p.mining.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.Simulated.Backend.Commit()
+		}
+	}
+}
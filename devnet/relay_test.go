@@ -0,0 +1,169 @@
+package devnet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// relayFixture is a simulated chain with a funded relayer key and a user holding demo tokens
+type relayFixture struct {
+	t          *testing.T
+	ctx        context.Context
+	sim        *Simulated
+	relayerKey *ecdsa.PrivateKey
+	userKey    *ecdsa.PrivateKey
+	user       common.Address
+	domain     []byte
+	deadline   uint64
+}
+
+func newRelayFixture(t *testing.T) *relayFixture {
+	t.Helper()
+	f := &relayFixture{t: t, ctx: context.Background()}
+	f.relayerKey, _ = crypto.GenerateKey()
+	f.userKey, _ = crypto.GenerateKey()
+	f.user = crypto.PubkeyToAddress(f.userKey.PublicKey)
+
+	var err error
+	if f.sim, err = NewSimulated(f.ctx, crypto.PubkeyToAddress(f.relayerKey.PublicKey)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.sim.Close() })
+	if err := f.sim.Mint(f.ctx, f.sim.Token, f.user, big.NewInt(1000)); err != nil {
+		t.Fatal(err)
+	}
+	if f.domain, err = f.sim.DomainSeparator(); err != nil {
+		t.Fatal(err)
+	}
+	head, err := f.sim.Client.HeaderByNumber(f.ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.deadline = head.Time + 3600
+	return f
+}
+
+// request returns a transfer of 10 demo tokens from from with nonce and gas, signed by key
+func (f *relayFixture) request(from common.Address, key *ecdsa.PrivateKey, nonce, gas uint64) eip2771toolkit.BatchMetaTxRequest {
+	metaTx := eip2771toolkit.NewMetaTx(from, common.HexToAddress("0xbeef"), f.sim.Token, big.NewInt(10), gas, nonce, f.deadline)
+	sig, err := eip2771toolkit.SignMetaTx(metaTx, key, f.domain)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return eip2771toolkit.BatchMetaTxRequest{MetaTx: metaTx, Signature: sig}
+}
+
+// mine mines a relay transaction and returns its result
+func (f *relayFixture) mine(relayer *eip2771toolkit.Relayer, txHash common.Hash) eip2771toolkit.RelayResult {
+	if _, err := f.sim.Mine(f.ctx, txHash); err != nil {
+		f.t.Fatal(err)
+	}
+	result, err := relayer.WaitForRelay(f.ctx, txHash)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	if !result.Succeeded() {
+		f.t.Fatalf("relay %s reverted", txHash.Hex())
+	}
+	return result
+}
+
+func TestRelayerRaisesGasLimitToForwardedGas(t *testing.T) {
+	f := newRelayFixture(t)
+	relayer := eip2771toolkit.NewRelayer(f.relayerKey, f.sim.Forwarder, f.sim.Client)
+
+	// The transfer uses a fraction of its Gas, so EstimateGas alone finds a lower limit
+	req := f.request(f.user, f.userKey, 0, 2_000_000)
+	txHash, err := relayer.RelayMetaTx(f.ctx, req.MetaTx, req.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := f.mine(relayer, txHash)
+
+	tx, _, err := f.sim.Client.TransactionByHash(f.ctx, txHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := eip2771toolkit.CheckRelayGasLimit(tx.Gas(), eip2771toolkit.BatchMetaTxRequestList{req}, tx.Data()); err != nil {
+		t.Errorf("relay sent with gas limit %d (used %d): %v", tx.Gas(), result.GasUsed, err)
+	}
+}
+
+func TestNonceTrackerFollowsExecutedRequests(t *testing.T) {
+	f := newRelayFixture(t)
+	tracker := eip2771toolkit.NewNonceTracker(f.sim.Forwarder, f.sim.Client)
+	relayer := eip2771toolkit.NewRelayer(f.relayerKey, f.sim.Forwarder, f.sim.Client, eip2771toolkit.WithNonceTracker(tracker))
+
+	// Sending does not move the tracker, recording the mined relay does
+	req := f.request(f.user, f.userKey, 0, 100_000)
+	txHash, err := relayer.RelayMetaTx(f.ctx, req.MetaTx, req.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tracker.Peek(f.user); ok {
+		t.Fatal("tracker advanced by a relay not mined yet")
+	}
+	result := f.mine(relayer, txHash)
+	if err := relayer.RecordRelay(f.ctx, result, eip2771toolkit.BatchMetaTxRequestList{req}); err != nil {
+		t.Fatal(err)
+	}
+	if next, ok := tracker.Peek(f.user); !ok || next != 1 {
+		t.Fatalf("next nonce %d (tracked %v) after the executed request, want 1", next, ok)
+	}
+
+	// A request the forwarder skips in a lenient batch leaves its signer untracked
+	otherKey, _ := crypto.GenerateKey()
+	other := crypto.PubkeyToAddress(otherKey.PublicKey)
+	forged := f.request(other, f.userKey, 0, 100_000)
+	batch := eip2771toolkit.BatchMetaTxRequestList{f.request(f.user, f.userKey, 1, 100_000), forged}
+	txHash, err = relayer.RelayMetaTxBatch(f.ctx, batch, crypto.PubkeyToAddress(f.relayerKey.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = f.mine(relayer, txHash)
+	if err := relayer.RecordRelay(f.ctx, result, batch); err != nil {
+		t.Fatal(err)
+	}
+	if next, _ := tracker.Peek(f.user); next != 2 {
+		t.Errorf("next nonce %d after the second executed request, want 2", next)
+	}
+	if next, ok := tracker.Peek(other); ok {
+		t.Errorf("skipped request moved its signer to nonce %d", next)
+	}
+}
+
+func TestRelayerResyncsAfterNonceConflict(t *testing.T) {
+	f := newRelayFixture(t)
+	relayer := eip2771toolkit.NewRelayer(f.relayerKey, f.sim.Forwarder, f.sim.Client)
+
+	req := f.request(f.user, f.userKey, 0, 100_000)
+	txHash, err := relayer.RelayMetaTx(f.ctx, req.MetaTx, req.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.mine(relayer, txHash)
+
+	// Another process sending from the relayer key takes the nonce the manager hands out next
+	to := common.HexToAddress("0xbeef")
+	if _, err := transact(f.ctx, f.sim.Client, f.sim.ChainID, f.relayerKey, &to, nil, f.sim.Mine); err != nil {
+		t.Fatal(err)
+	}
+	req = f.request(f.user, f.userKey, 1, 100_000)
+	if _, err := relayer.RelayMetaTx(f.ctx, req.MetaTx, req.Signature); err == nil {
+		t.Fatal("relay with a used nonce was sent")
+	}
+
+	// The conflict resynced the manager, so the retry goes through
+	txHash, err = relayer.RelayMetaTx(f.ctx, req.MetaTx, req.Signature)
+	if err != nil {
+		t.Fatalf("retry after the nonce conflict: %v", err)
+	}
+	f.mine(relayer, txHash)
+}
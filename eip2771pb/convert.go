@@ -0,0 +1,141 @@
+// Package eip2771pb provides protobuf messages for passing signed meta transactions between
+// services, e.g. over gRPC or Kafka, with converters to and from the eip2771toolkit types.
+package eip2771pb
+
+//go:generate protoc --go_out=.. --go_opt=paths=source_relative --proto_path=.. eip2771pb/metatx.proto
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// FromMetaTx converts a MetaTx to its message
+func FromMetaTx(m eip2771toolkit.MetaTx) *MetaTx {
+	var amount []byte
+	if m.Amount != nil {
+		amount = m.Amount.Bytes()
+	}
+	return &MetaTx{
+		From:     m.From.Bytes(),
+		To:       m.To.Bytes(),
+		Token:    m.Token.Bytes(),
+		Amount:   amount,
+		Gas:      m.Gas,
+		Nonce:    m.Nonce,
+		Deadline: m.Deadline,
+	}
+}
+
+// ToMetaTx converts the message to a MetaTx
+func (m *MetaTx) ToMetaTx() (eip2771toolkit.MetaTx, error) {
+	if m == nil {
+		return eip2771toolkit.MetaTx{}, fmt.Errorf("missing meta transaction")
+	}
+	from, err := toAddress("from", m.From)
+	if err != nil {
+		return eip2771toolkit.MetaTx{}, err
+	}
+	to, err := toAddress("to", m.To)
+	if err != nil {
+		return eip2771toolkit.MetaTx{}, err
+	}
+	token, err := toAddress("token", m.Token)
+	if err != nil {
+		return eip2771toolkit.MetaTx{}, err
+	}
+	if len(m.Amount) > 32 {
+		return eip2771toolkit.MetaTx{}, fmt.Errorf("invalid amount length %d, expected at most 32 bytes", len(m.Amount))
+	}
+	return eip2771toolkit.MetaTx{
+		From:     from,
+		To:       to,
+		Token:    token,
+		Amount:   new(big.Int).SetBytes(m.Amount),
+		Gas:      m.Gas,
+		Nonce:    m.Nonce,
+		Deadline: m.Deadline,
+	}, nil
+}
+
+// FromSignature converts a Signature to its message
+func FromSignature(s eip2771toolkit.Signature) *Signature {
+	return &Signature{
+		R: common.CopyBytes(s.R[:]),
+		S: common.CopyBytes(s.S[:]),
+		V: uint32(s.V),
+	}
+}
+
+// ToSignature converts the message to a Signature
+func (s *Signature) ToSignature() (eip2771toolkit.Signature, error) {
+	if s == nil {
+		return eip2771toolkit.Signature{}, fmt.Errorf("missing signature")
+	}
+	if len(s.R) != 32 || len(s.S) != 32 {
+		return eip2771toolkit.Signature{}, fmt.Errorf("invalid signature r/s length %d/%d, expected 32 bytes", len(s.R), len(s.S))
+	}
+	if s.V > 255 {
+		return eip2771toolkit.Signature{}, fmt.Errorf("invalid signature v %d", s.V)
+	}
+	sig := eip2771toolkit.Signature{V: byte(s.V)}
+	copy(sig.R[:], s.R)
+	copy(sig.S[:], s.S)
+	return sig, nil
+}
+
+// FromBatchMetaTxRequest converts a signed request to its message
+func FromBatchMetaTxRequest(req eip2771toolkit.BatchMetaTxRequest) *BatchMetaTxRequest {
+	return &BatchMetaTxRequest{
+		MetaTx:    FromMetaTx(req.MetaTx),
+		Signature: FromSignature(req.Signature),
+	}
+}
+
+// ToBatchMetaTxRequest converts the message to a signed request
+func (r *BatchMetaTxRequest) ToBatchMetaTxRequest() (eip2771toolkit.BatchMetaTxRequest, error) {
+	if r == nil {
+		return eip2771toolkit.BatchMetaTxRequest{}, fmt.Errorf("missing request")
+	}
+	metaTx, err := r.MetaTx.ToMetaTx()
+	if err != nil {
+		return eip2771toolkit.BatchMetaTxRequest{}, err
+	}
+	sig, err := r.Signature.ToSignature()
+	if err != nil {
+		return eip2771toolkit.BatchMetaTxRequest{}, err
+	}
+	return eip2771toolkit.BatchMetaTxRequest{MetaTx: metaTx, Signature: sig}, nil
+}
+
+// FromBatchMetaTxRequestList converts a batch to its message
+func FromBatchMetaTxRequestList(batch eip2771toolkit.BatchMetaTxRequestList) *BatchMetaTxRequestList {
+	requests := make([]*BatchMetaTxRequest, len(batch))
+	for i, req := range batch {
+		requests[i] = FromBatchMetaTxRequest(req)
+	}
+	return &BatchMetaTxRequestList{Requests: requests}
+}
+
+// ToBatchMetaTxRequestList converts the message to a batch
+func (l *BatchMetaTxRequestList) ToBatchMetaTxRequestList() (eip2771toolkit.BatchMetaTxRequestList, error) {
+	batch := make(eip2771toolkit.BatchMetaTxRequestList, len(l.GetRequests()))
+	for i, req := range l.GetRequests() {
+		var err error
+		if batch[i], err = req.ToBatchMetaTxRequest(); err != nil {
+			return nil, fmt.Errorf("request at index %d: %w", i, err)
+		}
+	}
+	return batch, nil
+}
+
+// toAddress converts a 20-byte field to an address
+func toAddress(field string, b []byte) (common.Address, error) {
+	if len(b) != common.AddressLength {
+		return common.Address{}, fmt.Errorf("invalid %s address length %d, expected %d bytes", field, len(b), common.AddressLength)
+	}
+	return common.BytesToAddress(b), nil
+}
@@ -0,0 +1,438 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: eip2771pb/metatx.proto
+
+package eip2771pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// MetaTx is a meta transaction following the EIP-2771 standard
+type MetaTx struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// 20-byte signer address
+	From []byte `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	// 20-byte token recipient
+	To []byte `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	// 20-byte token contract
+	Token []byte `protobuf:"bytes,3,opt,name=token,proto3" json:"token,omitempty"`
+	// Big-endian unsigned integer of at most 32 bytes
+	Amount []byte `protobuf:"bytes,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	// Gas limit for the inner transaction
+	Gas   uint64 `protobuf:"varint,5,opt,name=gas,proto3" json:"gas,omitempty"`
+	Nonce uint64 `protobuf:"varint,6,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	// Unix timestamp
+	Deadline uint64 `protobuf:"varint,7,opt,name=deadline,proto3" json:"deadline,omitempty"`
+}
+
+func (x *MetaTx) Reset() {
+	*x = MetaTx{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_eip2771pb_metatx_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MetaTx) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetaTx) ProtoMessage() {}
+
+func (x *MetaTx) ProtoReflect() protoreflect.Message {
+	mi := &file_eip2771pb_metatx_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MetaTx.ProtoReflect.Descriptor instead.
+func (*MetaTx) Descriptor() ([]byte, []int) {
+	return file_eip2771pb_metatx_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *MetaTx) GetFrom() []byte {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *MetaTx) GetTo() []byte {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *MetaTx) GetToken() []byte {
+	if x != nil {
+		return x.Token
+	}
+	return nil
+}
+
+func (x *MetaTx) GetAmount() []byte {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *MetaTx) GetGas() uint64 {
+	if x != nil {
+		return x.Gas
+	}
+	return 0
+}
+
+func (x *MetaTx) GetNonce() uint64 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+func (x *MetaTx) GetDeadline() uint64 {
+	if x != nil {
+		return x.Deadline
+	}
+	return 0
+}
+
+// Signature is an ECDSA signature
+type Signature struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// 32 bytes
+	R []byte `protobuf:"bytes,1,opt,name=r,proto3" json:"r,omitempty"`
+	// 32 bytes
+	S []byte `protobuf:"bytes,2,opt,name=s,proto3" json:"s,omitempty"`
+	V uint32 `protobuf:"varint,3,opt,name=v,proto3" json:"v,omitempty"`
+}
+
+func (x *Signature) Reset() {
+	*x = Signature{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_eip2771pb_metatx_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Signature) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Signature) ProtoMessage() {}
+
+func (x *Signature) ProtoReflect() protoreflect.Message {
+	mi := &file_eip2771pb_metatx_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Signature.ProtoReflect.Descriptor instead.
+func (*Signature) Descriptor() ([]byte, []int) {
+	return file_eip2771pb_metatx_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Signature) GetR() []byte {
+	if x != nil {
+		return x.R
+	}
+	return nil
+}
+
+func (x *Signature) GetS() []byte {
+	if x != nil {
+		return x.S
+	}
+	return nil
+}
+
+func (x *Signature) GetV() uint32 {
+	if x != nil {
+		return x.V
+	}
+	return 0
+}
+
+// BatchMetaTxRequest is a signed meta transaction
+type BatchMetaTxRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MetaTx    *MetaTx    `protobuf:"bytes,1,opt,name=meta_tx,json=metaTx,proto3" json:"meta_tx,omitempty"`
+	Signature *Signature `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (x *BatchMetaTxRequest) Reset() {
+	*x = BatchMetaTxRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_eip2771pb_metatx_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchMetaTxRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchMetaTxRequest) ProtoMessage() {}
+
+func (x *BatchMetaTxRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eip2771pb_metatx_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchMetaTxRequest.ProtoReflect.Descriptor instead.
+func (*BatchMetaTxRequest) Descriptor() ([]byte, []int) {
+	return file_eip2771pb_metatx_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BatchMetaTxRequest) GetMetaTx() *MetaTx {
+	if x != nil {
+		return x.MetaTx
+	}
+	return nil
+}
+
+func (x *BatchMetaTxRequest) GetSignature() *Signature {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+// BatchMetaTxRequestList is a list of signed meta transactions
+type BatchMetaTxRequestList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Requests []*BatchMetaTxRequest `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+}
+
+func (x *BatchMetaTxRequestList) Reset() {
+	*x = BatchMetaTxRequestList{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_eip2771pb_metatx_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchMetaTxRequestList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchMetaTxRequestList) ProtoMessage() {}
+
+func (x *BatchMetaTxRequestList) ProtoReflect() protoreflect.Message {
+	mi := &file_eip2771pb_metatx_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchMetaTxRequestList.ProtoReflect.Descriptor instead.
+func (*BatchMetaTxRequestList) Descriptor() ([]byte, []int) {
+	return file_eip2771pb_metatx_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchMetaTxRequestList) GetRequests() []*BatchMetaTxRequest {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+var File_eip2771pb_metatx_proto protoreflect.FileDescriptor
+
+var file_eip2771pb_metatx_proto_rawDesc = []byte{
+	0x0a, 0x16, 0x65, 0x69, 0x70, 0x32, 0x37, 0x37, 0x31, 0x70, 0x62, 0x2f, 0x6d, 0x65, 0x74, 0x61,
+	0x74, 0x78, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x11, 0x65, 0x69, 0x70, 0x32, 0x37, 0x37,
+	0x31, 0x74, 0x6f, 0x6f, 0x6c, 0x6b, 0x69, 0x74, 0x2e, 0x76, 0x31, 0x22, 0x9e, 0x01, 0x0a, 0x06,
+	0x4d, 0x65, 0x74, 0x61, 0x54, 0x78, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x6f,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x74, 0x6f, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f,
+	0x6b, 0x65, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e,
+	0x12, 0x16, 0x0a, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x67, 0x61, 0x73, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x67, 0x61, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f,
+	0x6e, 0x63, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65,
+	0x12, 0x1a, 0x0a, 0x08, 0x64, 0x65, 0x61, 0x64, 0x6c, 0x69, 0x6e, 0x65, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x08, 0x64, 0x65, 0x61, 0x64, 0x6c, 0x69, 0x6e, 0x65, 0x22, 0x35, 0x0a, 0x09,
+	0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x0c, 0x0a, 0x01, 0x72, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x01, 0x72, 0x12, 0x0c, 0x0a, 0x01, 0x73, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x01, 0x73, 0x12, 0x0c, 0x0a, 0x01, 0x76, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x01, 0x76, 0x22, 0x84, 0x01, 0x0a, 0x12, 0x42, 0x61, 0x74, 0x63, 0x68, 0x4d, 0x65, 0x74,
+	0x61, 0x54, 0x78, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x32, 0x0a, 0x07, 0x6d, 0x65,
+	0x74, 0x61, 0x5f, 0x74, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x65, 0x69,
+	0x70, 0x32, 0x37, 0x37, 0x31, 0x74, 0x6f, 0x6f, 0x6c, 0x6b, 0x69, 0x74, 0x2e, 0x76, 0x31, 0x2e,
+	0x4d, 0x65, 0x74, 0x61, 0x54, 0x78, 0x52, 0x06, 0x6d, 0x65, 0x74, 0x61, 0x54, 0x78, 0x12, 0x3a,
+	0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x1c, 0x2e, 0x65, 0x69, 0x70, 0x32, 0x37, 0x37, 0x31, 0x74, 0x6f, 0x6f, 0x6c, 0x6b,
+	0x69, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52,
+	0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x22, 0x5b, 0x0a, 0x16, 0x42, 0x61,
+	0x74, 0x63, 0x68, 0x4d, 0x65, 0x74, 0x61, 0x54, 0x78, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x4c, 0x69, 0x73, 0x74, 0x12, 0x41, 0x0a, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x65, 0x69, 0x70, 0x32, 0x37, 0x37, 0x31,
+	0x74, 0x6f, 0x6f, 0x6c, 0x6b, 0x69, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x61, 0x74, 0x63, 0x68,
+	0x4d, 0x65, 0x74, 0x61, 0x54, 0x78, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52, 0x08, 0x72,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x42, 0x31, 0x5a, 0x2f, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x65, 0x74, 0x68, 0x61, 0x6e, 0x7a, 0x68, 0x72, 0x65, 0x70,
+	0x6f, 0x2f, 0x65, 0x69, 0x70, 0x32, 0x37, 0x37, 0x31, 0x74, 0x6f, 0x6f, 0x6c, 0x6b, 0x69, 0x74,
+	0x2f, 0x65, 0x69, 0x70, 0x32, 0x37, 0x37, 0x31, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
+	file_eip2771pb_metatx_proto_rawDescOnce sync.Once
+	file_eip2771pb_metatx_proto_rawDescData = file_eip2771pb_metatx_proto_rawDesc
+)
+
+func file_eip2771pb_metatx_proto_rawDescGZIP() []byte {
+	file_eip2771pb_metatx_proto_rawDescOnce.Do(func() {
+		file_eip2771pb_metatx_proto_rawDescData = protoimpl.X.CompressGZIP(file_eip2771pb_metatx_proto_rawDescData)
+	})
+	return file_eip2771pb_metatx_proto_rawDescData
+}
+
+var file_eip2771pb_metatx_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_eip2771pb_metatx_proto_goTypes = []any{
+	(*MetaTx)(nil),                 // 0: eip2771toolkit.v1.MetaTx
+	(*Signature)(nil),              // 1: eip2771toolkit.v1.Signature
+	(*BatchMetaTxRequest)(nil),     // 2: eip2771toolkit.v1.BatchMetaTxRequest
+	(*BatchMetaTxRequestList)(nil), // 3: eip2771toolkit.v1.BatchMetaTxRequestList
+}
+var file_eip2771pb_metatx_proto_depIdxs = []int32{
+	0, // 0: eip2771toolkit.v1.BatchMetaTxRequest.meta_tx:type_name -> eip2771toolkit.v1.MetaTx
+	1, // 1: eip2771toolkit.v1.BatchMetaTxRequest.signature:type_name -> eip2771toolkit.v1.Signature
+	2, // 2: eip2771toolkit.v1.BatchMetaTxRequestList.requests:type_name -> eip2771toolkit.v1.BatchMetaTxRequest
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_eip2771pb_metatx_proto_init() }
+func file_eip2771pb_metatx_proto_init() {
+	if File_eip2771pb_metatx_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_eip2771pb_metatx_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*MetaTx); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_eip2771pb_metatx_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*Signature); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_eip2771pb_metatx_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*BatchMetaTxRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_eip2771pb_metatx_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*BatchMetaTxRequestList); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_eip2771pb_metatx_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_eip2771pb_metatx_proto_goTypes,
+		DependencyIndexes: file_eip2771pb_metatx_proto_depIdxs,
+		MessageInfos:      file_eip2771pb_metatx_proto_msgTypes,
+	}.Build()
+	File_eip2771pb_metatx_proto = out.File
+	file_eip2771pb_metatx_proto_rawDesc = nil
+	file_eip2771pb_metatx_proto_goTypes = nil
+	file_eip2771pb_metatx_proto_depIdxs = nil
+}
@@ -29,8 +29,7 @@ func BuildDomainSeparator(name, version string, chainId *big.Int, verifyingContr
 	versionHash := crypto.Keccak256([]byte(version))
 
 	// Convert chainId to 32 bytes
-	chainIdBytes := make([]byte, 32)
-	chainId.FillBytes(chainIdBytes)
+	chainIdBytes := encodeUintWord(chainId)
 
 	// Concatenate all parts
 	data := make([]byte, 0, 32*5)
@@ -38,7 +37,7 @@ func BuildDomainSeparator(name, version string, chainId *big.Int, verifyingContr
 	data = append(data, nameHash...)
 	data = append(data, versionHash...)
 	data = append(data, chainIdBytes...)
-	data = append(data, verifyingContract.Bytes()...)
+	data = append(data, encodeAddressWord(verifyingContract)...)
 
 	// Hash the concatenated data
 	domainSeparator := crypto.Keccak256(data)
@@ -47,104 +46,74 @@ func BuildDomainSeparator(name, version string, chainId *big.Int, verifyingContr
 
 // HashMetaTx generates the EIP-712 digest for a MetaTx (compatible with ERC2771Forwarder)
 func HashMetaTx(metaTx MetaTx, domainSeparator []byte) ([]byte, error) {
-	// Calculate struct typehash
-	structTypeHash := crypto.Keccak256([]byte(FORWARD_REQUEST_TYPEHASH))
-
-	// Prepare ERC20 transfer data
-	transferData, err := metaTx.TransferData()
-	if err != nil {
-		return nil, fmt.Errorf("failed to prepare transfer data: %w", err)
-	}
-
-	// Encode ForwardRequest struct according to new ERC2771Forwarder format
-	// ForwardRequest(address from,address to,uint256 value,uint256 gas,uint256 nonce,uint48 deadline,bytes data)
-	structData := make([]byte, 0, 32*7)
-	structData = append(structData, structTypeHash...)
-	structData = append(structData, metaTx.From.Bytes()...)
-	structData = append(structData, metaTx.Token.Bytes()...) // 'to' field points to token contract
-
-	// Value is 0 for ERC20 transfers
-	valueBytes := make([]byte, 32)
-	structData = append(structData, valueBytes...)
-
-	// Gas limit from MetaTx.Gas field
-	gasBytes := make([]byte, 32)
-	new(big.Int).SetUint64(metaTx.Gas).FillBytes(gasBytes)
-	structData = append(structData, gasBytes...)
-
-	// Convert nonce to 32 bytes
-	nonceBytes := make([]byte, 32)
-	new(big.Int).SetUint64(metaTx.Nonce).FillBytes(nonceBytes)
-	structData = append(structData, nonceBytes...)
+	return OZForwarderV5Schema.HashMetaTx(metaTx, domainSeparator)
+}
 
-	// Convert deadline to 32 bytes (uint48 but encoded as uint256 in hash)
-	deadlineBytes := make([]byte, 32)
-	new(big.Int).SetUint64(metaTx.Deadline).FillBytes(deadlineBytes)
-	structData = append(structData, deadlineBytes...)
+// SignMetaTx signs a MetaTx using EIP-712
+func SignMetaTx(metaTx MetaTx, userPrivKey *ecdsa.PrivateKey, domainSeparator []byte) (Signature, error) {
+	return SignMetaTxWithSchema(OZForwarderV5Schema, metaTx, userPrivKey, domainSeparator)
+}
 
-	// Hash of the data field
-	dataHash := crypto.Keccak256(transferData)
-	structData = append(structData, dataHash...)
+// VerifyMetaTxSignature verifies a MetaTx signature
+func VerifyMetaTxSignature(metaTx MetaTx, sig Signature, domainSeparator []byte) (bool, error) {
+	return VerifyMetaTxSignatureWithSchema(OZForwarderV5Schema, metaTx, sig, domainSeparator)
+}
 
-	// Hash the struct data
-	structHash := crypto.Keccak256(structData)
+// encodeAddressWord left-pads an address to a 32-byte EIP-712 word
+func encodeAddressWord(addr common.Address) []byte {
+	return common.LeftPadBytes(addr.Bytes(), 32)
+}
 
-	// Create EIP-712 digest: "\x19\x01" || domainSeparator || structHash
-	digest := make([]byte, 0, 2+32+32)
-	digest = append(digest, 0x19, 0x01)
-	digest = append(digest, domainSeparator...)
-	digest = append(digest, structHash...)
+// encodeUintWord encodes an unsigned integer as a 32-byte EIP-712 word
+func encodeUintWord(value *big.Int) []byte {
+	word := make([]byte, 32)
+	if value != nil {
+		value.FillBytes(word)
+	}
+	return word
+}
 
-	// Final hash
-	finalHash := crypto.Keccak256(digest)
-	return finalHash, nil
+// encodeUint64Word encodes a uint64 as a 32-byte EIP-712 word
+func encodeUint64Word(value uint64) []byte {
+	return encodeUintWord(new(big.Int).SetUint64(value))
 }
 
-// SignMetaTx signs a MetaTx using EIP-712
-func SignMetaTx(metaTx MetaTx, userPrivKey *ecdsa.PrivateKey, domainSeparator []byte) (Signature, error) {
+// signDigest signs an EIP-712 digest and returns it as a Signature
+func signDigest(digest []byte, privKey *ecdsa.PrivateKey) (Signature, error) {
 	var sig Signature
 
-	// Get the hash to sign
-	hash, err := HashMetaTx(metaTx, domainSeparator)
-	if err != nil {
-		return sig, fmt.Errorf("failed to hash MetaTx: %w", err)
-	}
-
-	// Sign the hash
-	sigBytes, err := crypto.Sign(hash, userPrivKey)
+	sigBytes, err := crypto.Sign(digest, privKey)
 	if err != nil {
 		return sig, fmt.Errorf("failed to sign hash: %w", err)
 	}
 
-	// Convert to our Signature format
-	err = sig.FromBytes(sigBytes)
-	if err != nil {
+	if err := sig.FromBytes(sigBytes); err != nil {
 		return sig, fmt.Errorf("failed to parse signature: %w", err)
 	}
-
+	// Contracts (ecrecover, OpenZeppelin ECDSA) expect V in {27, 28}
+	sig.V += 27
 	return sig, nil
 }
 
-// VerifyMetaTxSignature verifies a MetaTx signature
-func VerifyMetaTxSignature(metaTx MetaTx, sig Signature, domainSeparator []byte) (bool, error) {
-	// Get the hash that was signed
-	hash, err := HashMetaTx(metaTx, domainSeparator)
-	if err != nil {
-		return false, fmt.Errorf("failed to hash MetaTx: %w", err)
-	}
-
-	// Convert signature to bytes
+// recoverSigner returns the address that produced sig over digest, accepting V in {0, 1} or {27, 28}
+func recoverSigner(digest []byte, sig Signature) (common.Address, error) {
 	sigBytes := sig.ToBytes()
+	if sigBytes[64] >= 27 {
+		sigBytes[64] -= 27
+	}
 
-	// Recover public key from signature
-	recoveredPubKey, err := crypto.SigToPub(hash, sigBytes)
+	pubKey, err := crypto.SigToPub(digest, sigBytes)
 	if err != nil {
-		return false, fmt.Errorf("failed to recover public key: %w", err)
+		return common.Address{}, fmt.Errorf("failed to recover public key: %w", err)
 	}
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
 
-	// Get the address from recovered public key
-	recoveredAddr := crypto.PubkeyToAddress(*recoveredPubKey)
-
-	// Check if recovered address matches the from address
-	return recoveredAddr == metaTx.From, nil
+// typedDataDigest computes keccak256("\x19\x01" || domainSeparator || structHash)
+func typedDataDigest(domainSeparator, structHash []byte) []byte {
+	digest := make([]byte, 0, 2+32+32)
+	digest = append(digest, 0x19, 0x01)
+	digest = append(digest, domainSeparator...)
+	digest = append(digest, structHash...)
+	return crypto.Keccak256(digest)
 }
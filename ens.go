@@ -0,0 +1,121 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	ensResolverSelector = crypto.Keccak256([]byte("resolver(bytes32)"))[:4]
+	ensAddrSelector     = crypto.Keccak256([]byte("addr(bytes32)"))[:4]
+)
+
+// ENSResolver resolves ENS names such as vitalik.eth to addresses through an ENS registry,
+// so recipients can be given by name
+type ENSResolver struct {
+	client   ethereum.ContractCaller
+	registry common.Address
+}
+
+// NewENSResolver creates a resolver reading the ENS registry at registry through client
+func NewENSResolver(client ethereum.ContractCaller, registry common.Address) *ENSResolver {
+	return &ENSResolver{client: client, registry: registry}
+}
+
+// IsENSName reports whether s is meant as an ENS name rather than a hex address
+func IsENSName(s string) bool {
+	return !common.IsHexAddress(s) && !strings.HasPrefix(s, "0x") && strings.Contains(s, ".")
+}
+
+// ENSNameHash returns the EIP-137 namehash of name. Names are lowercased; names with other
+// than ASCII letters, digits, hyphens and underscores are refused by Resolve, as their
+// normalization is not implemented.
+func ENSNameHash(name string) common.Hash {
+	var node common.Hash
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(strings.ToLower(name), ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = crypto.Keccak256Hash(node[:], crypto.Keccak256([]byte(labels[i])))
+	}
+	return node
+}
+
+// Resolve returns the address name resolves to, or ErrUnresolvableName if it has no resolver
+// or no address
+func (r *ENSResolver) Resolve(ctx context.Context, name string) (common.Address, error) {
+	if err := checkENSName(name); err != nil {
+		return common.Address{}, err
+	}
+	node := ENSNameHash(name)
+	resolver, err := r.callAddress(ctx, r.registry, ensResolverSelector, node)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to get resolver of %s: %w", name, err)
+	}
+	if resolver == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("%w: %s has no resolver", ErrUnresolvableName, name)
+	}
+	addr, err := r.callAddress(ctx, resolver, ensAddrSelector, node)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to resolve %s: %w", name, err)
+	}
+	if addr == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("%w: %s has no address", ErrUnresolvableName, name)
+	}
+	return addr, nil
+}
+
+// ResolveRecipient returns the address of a recipient given as a hex address or ENS name. A
+// nil resolver accepts hex addresses only.
+func (r *ENSResolver) ResolveRecipient(ctx context.Context, recipient string) (common.Address, error) {
+	recipient = strings.TrimSpace(recipient)
+	if strings.HasPrefix(recipient, "0x") || common.IsHexAddress(recipient) {
+		return ParseAddress(recipient, false)
+	}
+	if !IsENSName(recipient) {
+		return common.Address{}, fmt.Errorf("invalid recipient %q", recipient)
+	}
+	if r == nil {
+		return common.Address{}, fmt.Errorf("%w: %s: no ENS resolver configured", ErrUnresolvableName, recipient)
+	}
+	return r.Resolve(ctx, recipient)
+}
+
+// callAddress calls a view function of contract taking node and returning an address
+func (r *ENSResolver) callAddress(ctx context.Context, contract common.Address, selector []byte, node common.Hash) (common.Address, error) {
+	data := append(append([]byte(nil), selector...), node[:]...)
+	out, err := r.client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(out) == 0 {
+		return common.Address{}, nil // no contract, or one not implementing the function
+	}
+	values, err := unpackArguments([]string{"address"}, out)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return values[0].(common.Address), nil
+}
+
+// checkENSName refuses names whose namehash would depend on normalization rules not
+// implemented here
+func checkENSName(name string) error {
+	for _, label := range strings.Split(name, ".") {
+		if label == "" {
+			return fmt.Errorf("%w: %q has an empty label", ErrUnresolvableName, name)
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return fmt.Errorf("%w: %q contains %q, only ASCII names are supported", ErrUnresolvableName, name, c)
+			}
+		}
+	}
+	return nil
+}
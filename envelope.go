@@ -0,0 +1,173 @@
+package eip2771toolkit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// DomainBinding records the forwarder deployment and EIP-712 domain a request was signed for
+type DomainBinding struct {
+	ChainID         uint64         `json:"chainId"`
+	Forwarder       common.Address `json:"forwarder"`
+	Profile         string         `json:"profile"` // forwarder profile name
+	DomainSeparator hexutil.Bytes  `json:"domainSeparator"`
+}
+
+// Envelope is a signed request bound to the domain it was signed against. Requests held in
+// queues or stores should travel in envelopes: if the configured forwarder is upgraded or
+// its domain changes while they wait, they still verify and relay through the forwarder
+// they were signed for instead of failing against the new one.
+type Envelope struct {
+	Request BatchMetaTxRequest `json:"request"`
+	Domain  DomainBinding      `json:"domain"`
+}
+
+// NewEnvelope binds a signed request to the forwarder of profile at forwarder on chainID
+func NewEnvelope(req BatchMetaTxRequest, chainID *big.Int, forwarder common.Address, profile *ForwarderProfile) (Envelope, error) {
+	domainSeparator, err := profile.DomainSeparator(chainID, forwarder)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to build domain separator: %w", err)
+	}
+	return Envelope{
+		Request: req,
+		Domain: DomainBinding{
+			ChainID:         chainID.Uint64(),
+			Forwarder:       forwarder,
+			Profile:         profile.Name,
+			DomainSeparator: domainSeparator,
+		},
+	}, nil
+}
+
+// Seal binds a signed request to the relayer's current forwarder and domain
+func (r *Relayer) Seal(ctx context.Context, req BatchMetaTxRequest) (Envelope, error) {
+	chainID, err := r.chainID(ctx)
+	if err != nil {
+		return Envelope{}, err
+	}
+	return NewEnvelope(req, chainID, r.forwarder, r.profile)
+}
+
+// Verify checks the request signature against the bound domain
+func (e Envelope) Verify(registry *Registry) (bool, error) {
+	profile, err := e.profile(registry)
+	if err != nil {
+		return false, err
+	}
+	return VerifyMetaTxSignatureWithSchema(profile.Schema, e.Request.MetaTx, e.Request.Signature, e.Domain.DomainSeparator)
+}
+
+// profile resolves the bound forwarder profile in registry
+func (e Envelope) profile(registry *Registry) (*ForwarderProfile, error) {
+	if e.Domain.Profile == "" {
+		return registry.DefaultForwarderProfile(), nil
+	}
+	profile, ok := registry.LookupForwarderProfile(e.Domain.Profile)
+	if !ok {
+		return nil, fmt.Errorf("unknown forwarder profile %q", e.Domain.Profile)
+	}
+	return profile, nil
+}
+
+// IsCurrentDomain reports whether the envelope is bound to the relayer's current forwarder and domain
+func (r *Relayer) IsCurrentDomain(ctx context.Context, e Envelope) (bool, error) {
+	domainSeparator, err := r.domainSeparator(ctx)
+	if err != nil {
+		return false, err
+	}
+	return e.Domain.Forwarder == r.forwarder && bytes.Equal(e.Domain.DomainSeparator, domainSeparator), nil
+}
+
+// CheckEnvelope returns an error wrapping ErrUnexecutable if the forwarder the request was
+// signed for can no longer execute it: another chain, a passed deadline, a signature that
+// does not match the bound domain, a forwarder without code, an already used nonce
+// (ErrAlreadyExecuted) or a nonce the forwarder does not expect yet.
+func (r *Relayer) CheckEnvelope(ctx context.Context, e Envelope) error {
+	chainID, err := r.chainID(ctx)
+	if err != nil {
+		return err
+	}
+	if e.Domain.ChainID != chainID.Uint64() {
+		return fmt.Errorf("%w: signed for chain %d, relayer is on chain %d", ErrUnexecutable, e.Domain.ChainID, chainID)
+	}
+	now, err := r.deadlineNow(ctx)
+	if err != nil {
+		return err
+	}
+	if now > e.Request.MetaTx.Deadline {
+		return fmt.Errorf("%w: %w", ErrUnexecutable, ErrExpiredDeadline)
+	}
+
+	valid, err := e.Verify(r.registry)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("%w: %w for the bound domain", ErrUnexecutable, ErrInvalidSignature)
+	}
+
+	code, err := r.client.CodeAt(ctx, e.Domain.Forwarder, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get forwarder code: %w", err)
+	}
+	if len(code) == 0 {
+		return fmt.Errorf("%w: no forwarder deployed at %s", ErrUnexecutable, e.Domain.Forwarder.Hex())
+	}
+
+	profile, err := e.profile(r.registry)
+	if err != nil {
+		return err
+	}
+	nonce, err := getMetaTxNonce(ctx, r.registry, profile, e.Domain.Forwarder, e.Request.MetaTx.From, r.client)
+	if err != nil {
+		return err
+	}
+	if nonce > e.Request.MetaTx.Nonce {
+		return fmt.Errorf("%w: %w: signed with %d, forwarder expects %d", ErrUnexecutable, ErrAlreadyExecuted, e.Request.MetaTx.Nonce, nonce)
+	}
+	if nonce != e.Request.MetaTx.Nonce {
+		return fmt.Errorf("%w: %w: signed with %d, forwarder expects %d", ErrUnexecutable, ErrInvalidNonce, e.Request.MetaTx.Nonce, nonce)
+	}
+	return nil
+}
+
+// CheckEnvelopes checks every envelope and returns the errors by index; nil entries are executable
+func (r *Relayer) CheckEnvelopes(ctx context.Context, envelopes []Envelope) []error {
+	errs := make([]error, len(envelopes))
+	for i, e := range envelopes {
+		errs[i] = r.CheckEnvelope(ctx, e)
+	}
+	return errs
+}
+
+// RelayEnvelope relays a request through the forwarder it was signed for, which may differ
+// from the relayer's current forwarder after an upgrade
+func (r *Relayer) RelayEnvelope(ctx context.Context, e Envelope) (common.Hash, error) {
+	if err := r.CheckEnvelope(ctx, e); err != nil {
+		return common.Hash{}, err
+	}
+
+	requests := BatchMetaTxRequestList{e.Request}
+	if err := r.checkPermissions(requests); err != nil {
+		return common.Hash{}, err
+	}
+
+	profile, err := e.profile(r.registry)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	data, err := packExecuteCall(r.registry, profile.Schema, e.Request.MetaTx, e.Request.Signature, e.Domain.DomainSeparator)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	forwardRequest, err := e.Request.MetaTx.ForwardRequest()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return r.sponsor(ctx, e.Domain.Forwarder, data, forwardRequest.Value, requests)
+}
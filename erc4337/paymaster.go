@@ -0,0 +1,128 @@
+package erc4337
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DEFAULT_SPONSOR_METHOD is the JSON-RPC method most sponsorship services expose
+const DEFAULT_SPONSOR_METHOD = "pm_sponsorUserOperation"
+
+// ErrNotSponsored is returned when a paymaster declines to sponsor a user operation
+var ErrNotSponsored = errors.New("user operation not sponsored")
+
+// Sponsorship is a paymaster's decision to pay for a user operation.
+// Gas limits are set when the paymaster re-estimated them for its own validation cost.
+type Sponsorship struct {
+	PaymasterAndData     []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+}
+
+// PaymasterClient decides whether a user operation is sponsored and returns the paymaster fields.
+// Implementations wrap a sponsorship service; the toolkit builds, hashes and signs the operation.
+type PaymasterClient interface {
+	SponsorUserOperation(ctx context.Context, op UserOperation, entryPoint common.Address) (Sponsorship, error)
+}
+
+// PaymasterFunc adapts a function to PaymasterClient
+type PaymasterFunc func(ctx context.Context, op UserOperation, entryPoint common.Address) (Sponsorship, error)
+
+// SponsorUserOperation calls f
+func (f PaymasterFunc) SponsorUserOperation(ctx context.Context, op UserOperation, entryPoint common.Address) (Sponsorship, error) {
+	return f(ctx, op, entryPoint)
+}
+
+// RPCPaymaster requests sponsorship from a JSON-RPC paymaster service
+type RPCPaymaster struct {
+	Client  *rpc.Client
+	Method  string      // defaults to DEFAULT_SPONSOR_METHOD
+	Context interface{} // optional service specific third parameter, e.g. a sponsorship policy ID
+}
+
+// NewRPCPaymaster connects to a paymaster service at url
+func NewRPCPaymaster(ctx context.Context, url string) (*RPCPaymaster, error) {
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to paymaster: %w", err)
+	}
+	return &RPCPaymaster{Client: client}, nil
+}
+
+// sponsorshipJSON is the result of pm_sponsorUserOperation
+type sponsorshipJSON struct {
+	PaymasterAndData     hexutil.Bytes `json:"paymasterAndData"`
+	CallGasLimit         *hexutil.Big  `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big  `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big  `json:"preVerificationGas"`
+}
+
+// SponsorUserOperation implements PaymasterClient
+func (p *RPCPaymaster) SponsorUserOperation(ctx context.Context, op UserOperation, entryPoint common.Address) (Sponsorship, error) {
+	method := p.Method
+	if method == "" {
+		method = DEFAULT_SPONSOR_METHOD
+	}
+
+	args := []interface{}{op, entryPoint}
+	if p.Context != nil {
+		args = append(args, p.Context)
+	}
+
+	var res sponsorshipJSON
+	if err := p.Client.CallContext(ctx, &res, method, args...); err != nil {
+		return Sponsorship{}, fmt.Errorf("%w: %v", ErrNotSponsored, err)
+	}
+	if len(res.PaymasterAndData) == 0 {
+		return Sponsorship{}, ErrNotSponsored
+	}
+
+	return Sponsorship{
+		PaymasterAndData:     res.PaymasterAndData,
+		CallGasLimit:         (*big.Int)(res.CallGasLimit),
+		VerificationGasLimit: (*big.Int)(res.VerificationGasLimit),
+		PreVerificationGas:   (*big.Int)(res.PreVerificationGas),
+	}, nil
+}
+
+// Sponsor asks paymaster to sponsor op and returns op with the paymaster fields and any
+// re-estimated gas limits applied. The result still has to be signed.
+func Sponsor(ctx context.Context, paymaster PaymasterClient, op UserOperation, entryPoint common.Address) (UserOperation, error) {
+	// Paymasters validate the unsigned operation, signing happens after their fields are set
+	op.Signature = nil
+
+	s, err := paymaster.SponsorUserOperation(ctx, op, entryPoint)
+	if err != nil {
+		return UserOperation{}, err
+	}
+	if len(s.PaymasterAndData) < common.AddressLength {
+		return UserOperation{}, fmt.Errorf("%w: paymasterAndData too short", ErrNotSponsored)
+	}
+
+	op.PaymasterAndData = s.PaymasterAndData
+	if s.CallGasLimit != nil {
+		op.CallGasLimit = s.CallGasLimit
+	}
+	if s.VerificationGasLimit != nil {
+		op.VerificationGasLimit = s.VerificationGasLimit
+	}
+	if s.PreVerificationGas != nil {
+		op.PreVerificationGas = s.PreVerificationGas
+	}
+	return op, nil
+}
+
+// Paymaster returns the paymaster address encoded in PaymasterAndData, if any
+func (op *UserOperation) Paymaster() (common.Address, bool) {
+	if len(op.PaymasterAndData) < common.AddressLength {
+		return common.Address{}, false
+	}
+	return common.BytesToAddress(op.PaymasterAndData[:common.AddressLength]), true
+}
@@ -0,0 +1,206 @@
+// Package erc4337 bridges EIP-2771 meta transactions to ERC-4337 account abstraction:
+// the same token transfer a MetaTx describes is executed by the user's smart account
+// through an EntryPoint, with gas optionally sponsored by a paymaster.
+package erc4337
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ENTRY_POINT_V06 is the canonical EntryPoint v0.6 deployment address
+const ENTRY_POINT_V06 = "0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"
+
+// SIMPLE_ACCOUNT_ABI is the execute method of the reference SimpleAccount implementation
+const SIMPLE_ACCOUNT_ABI = `[{"inputs":[{"internalType":"address","name":"dest","type":"address"},{"internalType":"uint256","name":"value","type":"uint256"},{"internalType":"bytes","name":"func","type":"bytes"}],"name":"execute","outputs":[],"stateMutability":"nonpayable","type":"function"}]`
+
+// UserOperation is an ERC-4337 v0.6 user operation
+type UserOperation struct {
+	Sender               common.Address
+	Nonce                *big.Int
+	InitCode             []byte
+	CallData             []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	PaymasterAndData     []byte
+	Signature            []byte
+}
+
+// NewTransferUserOperation builds a user operation in which the smart account sender executes
+// the ERC20 transfer described by metaTx. metaTx.From is ignored: the account is the token holder.
+// Gas fields are left nil for the caller, a bundler estimate or a paymaster to fill in.
+func NewTransferUserOperation(sender common.Address, nonce *big.Int, metaTx eip2771toolkit.MetaTx) (UserOperation, error) {
+	transferData, err := metaTx.TransferData()
+	if err != nil {
+		return UserOperation{}, fmt.Errorf("failed to prepare transfer data: %w", err)
+	}
+
+	parsedABI, err := eip2771toolkit.DefaultRegistry.ABI(SIMPLE_ACCOUNT_ABI)
+	if err != nil {
+		return UserOperation{}, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+	callData, err := parsedABI.Pack("execute", metaTx.Token, big.NewInt(0), transferData)
+	if err != nil {
+		return UserOperation{}, fmt.Errorf("failed to pack execute call: %w", err)
+	}
+
+	return UserOperation{
+		Sender:   sender,
+		Nonce:    nonce,
+		CallData: callData,
+	}, nil
+}
+
+var userOpPackArgs = abi.Arguments{
+	{Type: mustType("address")},
+	{Type: mustType("uint256")},
+	{Type: mustType("bytes32")},
+	{Type: mustType("bytes32")},
+	{Type: mustType("uint256")},
+	{Type: mustType("uint256")},
+	{Type: mustType("uint256")},
+	{Type: mustType("uint256")},
+	{Type: mustType("uint256")},
+	{Type: mustType("bytes32")},
+}
+
+var userOpHashArgs = abi.Arguments{
+	{Type: mustType("bytes32")},
+	{Type: mustType("address")},
+	{Type: mustType("uint256")},
+}
+
+// Hash returns the user operation hash as computed by EntryPoint.getUserOpHash
+func (op *UserOperation) Hash(entryPoint common.Address, chainID *big.Int) (common.Hash, error) {
+	packed, err := userOpPackArgs.Pack(
+		op.Sender,
+		orZero(op.Nonce),
+		crypto.Keccak256Hash(op.InitCode),
+		crypto.Keccak256Hash(op.CallData),
+		orZero(op.CallGasLimit),
+		orZero(op.VerificationGasLimit),
+		orZero(op.PreVerificationGas),
+		orZero(op.MaxFeePerGas),
+		orZero(op.MaxPriorityFeePerGas),
+		crypto.Keccak256Hash(op.PaymasterAndData),
+	)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to pack user operation: %w", err)
+	}
+
+	encoded, err := userOpHashArgs.Pack(crypto.Keccak256Hash(packed), entryPoint, orZero(chainID))
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to pack user operation hash: %w", err)
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// Sign sets the signature of an account owned by ownerKey using the SimpleAccount scheme,
+// an eth_sign signature over the user operation hash. It must be called after the paymaster
+// fields are final since PaymasterAndData is part of the hash.
+func (op *UserOperation) Sign(ownerKey *ecdsa.PrivateKey, entryPoint common.Address, chainID *big.Int) error {
+	hash, err := op.Hash(entryPoint, chainID)
+	if err != nil {
+		return err
+	}
+
+	sig, err := crypto.Sign(accounts.TextHash(hash.Bytes()), ownerKey)
+	if err != nil {
+		return fmt.Errorf("failed to sign user operation: %w", err)
+	}
+	sig[64] += 27
+	op.Signature = sig
+	return nil
+}
+
+// userOperationJSON is the hex encoding used by bundler and paymaster RPC methods
+type userOperationJSON struct {
+	Sender               common.Address `json:"sender"`
+	Nonce                *hexutil.Big   `json:"nonce"`
+	InitCode             hexutil.Bytes  `json:"initCode"`
+	CallData             hexutil.Bytes  `json:"callData"`
+	CallGasLimit         *hexutil.Big   `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big   `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big   `json:"preVerificationGas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	PaymasterAndData     hexutil.Bytes  `json:"paymasterAndData"`
+	Signature            hexutil.Bytes  `json:"signature"`
+}
+
+// MarshalJSON encodes the user operation in the shape expected by bundler RPC methods
+func (op UserOperation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(userOperationJSON{
+		Sender:               op.Sender,
+		Nonce:                (*hexutil.Big)(orZero(op.Nonce)),
+		InitCode:             orEmpty(op.InitCode),
+		CallData:             orEmpty(op.CallData),
+		CallGasLimit:         (*hexutil.Big)(orZero(op.CallGasLimit)),
+		VerificationGasLimit: (*hexutil.Big)(orZero(op.VerificationGasLimit)),
+		PreVerificationGas:   (*hexutil.Big)(orZero(op.PreVerificationGas)),
+		MaxFeePerGas:         (*hexutil.Big)(orZero(op.MaxFeePerGas)),
+		MaxPriorityFeePerGas: (*hexutil.Big)(orZero(op.MaxPriorityFeePerGas)),
+		PaymasterAndData:     orEmpty(op.PaymasterAndData),
+		Signature:            orEmpty(op.Signature),
+	})
+}
+
+// UnmarshalJSON decodes a user operation in the bundler RPC format
+func (op *UserOperation) UnmarshalJSON(input []byte) error {
+	var dec userOperationJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+
+	*op = UserOperation{
+		Sender:               dec.Sender,
+		Nonce:                (*big.Int)(dec.Nonce),
+		InitCode:             dec.InitCode,
+		CallData:             dec.CallData,
+		CallGasLimit:         (*big.Int)(dec.CallGasLimit),
+		VerificationGasLimit: (*big.Int)(dec.VerificationGasLimit),
+		PreVerificationGas:   (*big.Int)(dec.PreVerificationGas),
+		MaxFeePerGas:         (*big.Int)(dec.MaxFeePerGas),
+		MaxPriorityFeePerGas: (*big.Int)(dec.MaxPriorityFeePerGas),
+		PaymasterAndData:     dec.PaymasterAndData,
+		Signature:            dec.Signature,
+	}
+	return nil
+}
+
+// orZero returns v, or zero if v is nil
+func orZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
+
+// orEmpty returns b, or an empty slice (encoded as "0x") if b is nil
+func orEmpty(b []byte) hexutil.Bytes {
+	if b == nil {
+		return hexutil.Bytes{}
+	}
+	return b
+}
+
+// mustType returns the ABI type t or panics
+func mustType(t string) abi.Type {
+	typ, err := abi.NewType(t, "", nil)
+	if err != nil {
+		panic(err)
+	}
+	return typ
+}
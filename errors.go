@@ -12,9 +12,15 @@ var (
 	// ErrExpiredDeadline is returned when the deadline has passed
 	ErrExpiredDeadline = errors.New("deadline has expired")
 
+	// ErrDeadlineTooSoon is returned when a request expires within the relayer's minimum deadline lifetime
+	ErrDeadlineTooSoon = errors.New("deadline too soon to relay")
+
 	// ErrInvalidNonce is returned when nonce is invalid
 	ErrInvalidNonce = errors.New("invalid nonce")
 
+	// ErrAlreadyExecuted is returned when the forwarder already used a request's nonce, so relaying it would revert
+	ErrAlreadyExecuted = errors.New("request already executed")
+
 	// ErrZeroAddress is returned when address is zero
 	ErrZeroAddress = errors.New("address cannot be zero")
 
@@ -23,4 +29,82 @@ var (
 
 	// ErrContractCallFailed is returned when contract call fails
 	ErrContractCallFailed = errors.New("contract call failed")
+
+	// ErrTransferReturnedFalse is returned when a forwarded ERC20 transfer returned false instead of reverting
+	ErrTransferReturnedFalse = errors.New("token transfer returned false")
+
+	// ErrRelayExpired is returned when a relay transaction was not included before the nearest request deadline
+	ErrRelayExpired = errors.New("relay transaction not included before deadline")
+
+	// ErrUnknownDeployment is returned when no forwarder deployment is registered for a chain
+	ErrUnknownDeployment = errors.New("no forwarder deployment registered for chain")
+
+	// ErrChainIDChanged is returned when the node reports a different chain than the relayer was using
+	ErrChainIDChanged = errors.New("chain ID changed")
+
+	// ErrCallNotPermitted is returned when a forwarded call matches no CallPermissions rule
+	ErrCallNotPermitted = errors.New("call not permitted")
+
+	// ErrPolicyViolation is wrapped by the PolicyError a relayer policy refuses a request with
+	ErrPolicyViolation = errors.New("request refused by relay policy")
+
+	// ErrQuotaExceeded is wrapped by the PolicyError of a request over its signer's Quota
+	ErrQuotaExceeded = errors.New("relay quota exceeded")
+
+	// ErrBudgetExhausted is wrapped by the PolicyError of a request whose sponsor has no budget left
+	ErrBudgetExhausted = errors.New("sponsor budget exhausted")
+
+	// ErrBudgetNotRecorded is returned when a relayer with a budget is asked for a relay nothing would charge, see ContextWithRecordRelay
+	ErrBudgetNotRecorded = errors.New("relay would not be charged to the budget")
+
+	// ErrInvalidFeeQuote is returned when a fee quote is malformed, for another request or not signed by both parties
+	ErrInvalidFeeQuote = errors.New("invalid fee quote")
+
+	// ErrFeeQuoteExpired is returned when a fee quote is used after its validity
+	ErrFeeQuoteExpired = errors.New("fee quote expired")
+
+	// ErrStalePrice is returned when a price feed's latest answer is older than allowed
+	ErrStalePrice = errors.New("stale price")
+
+	// ErrCircuitOpen is returned by RetryClient while its circuit breaker is open
+	ErrCircuitOpen = errors.New("circuit breaker open: node is failing")
+
+	// ErrAlreadyMined is returned when replacing a relay transaction that was already mined
+	ErrAlreadyMined = errors.New("relay transaction already mined")
+
+	// ErrFeeCapExceeded is returned when the fee of a relay transaction exceeds the relayer's fee caps
+	ErrFeeCapExceeded = errors.New("relay fee exceeds cap")
+
+	// ErrInsufficientGas is returned when a relay gas limit cannot forward the gas the requests ask for
+	ErrInsufficientGas = errors.New("insufficient relay gas limit")
+
+	// ErrRequestSkipped is returned when the forwarder did not execute a relayed request
+	ErrRequestSkipped = errors.New("request skipped by the forwarder")
+
+	// ErrUnexecutable is returned when a signed request can no longer be executed by the forwarder it was signed for
+	ErrUnexecutable = errors.New("request can no longer be executed")
+
+	// ErrInvalidMetaTx wraps the error of a Validator rejecting a request
+	ErrInvalidMetaTx = errors.New("invalid MetaTx")
+
+	// ErrDeadlineTooFar is returned when a deadline lies further ahead than a DeadlineWindow allows
+	ErrDeadlineTooFar = errors.New("deadline too far in the future")
+
+	// ErrInvalidGas is returned when a request's inner call gas is outside the bounds of a GasBounds rule
+	ErrInvalidGas = errors.New("invalid gas limit")
+
+	// ErrBatchTooLarge is returned when a batch exceeds the relayer's BatchLimits
+	ErrBatchTooLarge = errors.New("batch too large")
+
+	// ErrInsufficientRelayerFunds is returned when the relayer's balance cannot pay the value and maximum fee of a relay transaction
+	ErrInsufficientRelayerFunds = errors.New("insufficient relayer funds")
+
+	// ErrDuplicateNonce is returned when several requests of a batch use the same signer nonce
+	ErrDuplicateNonce = errors.New("duplicate signer nonce in batch")
+
+	// ErrUnresolvableName is returned when an ENS name does not resolve to an address
+	ErrUnresolvableName = errors.New("unresolvable ENS name")
+
+	// ErrInvalidAddress is returned by ParseAddress for malformed addresses and wrong EIP-55 checksums
+	ErrInvalidAddress = errors.New("invalid address")
 )
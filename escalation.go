@@ -0,0 +1,230 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DEFAULT_MIN_BUMP_PERCENT is the minimum fee increase nodes accept for a replacement transaction
+const DEFAULT_MIN_BUMP_PERCENT = 10
+
+// EscalationPolicy controls how a pending relay transaction is repriced until it is mined
+type EscalationPolicy struct {
+	Interval       time.Duration // how often inclusion is checked and the bid re-evaluated, default 15s
+	MinBumpPercent uint64        // minimum increase of a replacement, default DEFAULT_MIN_BUMP_PERCENT
+	MaxFeePerGas   *big.Int      // budget: highest gas price / fee cap ever bid, nil for no cap
+}
+
+// EscalateUntilMined watches a relay transaction sent by this relayer and, while it is not
+// included, asks the relayer's bid strategy for a new fee. Whenever the strategy bids higher
+// (e.g. DeadlineEscalationBidder as the nearest deadline approaches), the transaction is
+// replaced with the same nonce and calldata at the new fee, raised at least MinBumpPercent
+// and never above MaxFeePerGas. Once the budget is exhausted the last transaction is left
+// pending. If the nearest request deadline passes before any version is mined,
+// ErrRelayExpired is returned; the forwarder would reject the requests anyway.
+func (r *Relayer) EscalateUntilMined(ctx context.Context, txHash common.Hash, requests BatchMetaTxRequestList, policy EscalationPolicy) (*types.Receipt, error) {
+	interval := policy.Interval
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	minBump := policy.MinBumpPercent
+	if minBump == 0 {
+		minBump = DEFAULT_MIN_BUMP_PERCENT
+	}
+
+	current, err := r.transactionByHash(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	chainID, err := r.chainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := nearestDeadline(requests)
+	sent := []common.Hash{txHash}
+	capped := false
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Any of the sent versions may be the one that gets mined
+		receipt, err := r.findReceipt(ctx, sent)
+		if receipt != nil || err != nil {
+			return receipt, err
+		}
+
+		now := time.Now()
+		if deadline != 0 && uint64(now.Unix()) > deadline {
+			return nil, fmt.Errorf("%w: last transaction %s", ErrRelayExpired, current.Hash().Hex())
+		}
+
+		if !capped {
+			replacement, err := r.rebid(ctx, chainID, current, requests, now, minBump, policy.MaxFeePerGas)
+			switch {
+			case errors.Is(err, errBudgetExhausted):
+				capped = true
+				r.log().Warn("relay fee cap reached, leaving transaction pending", txAttrs(current)...)
+			case err != nil:
+				return nil, err
+			case replacement != nil:
+				if err := r.send(ctx, replacement); err != nil {
+					// The previous version may have been mined in the meantime
+					if receipt, _ := r.findReceipt(ctx, sent); receipt != nil {
+						return receipt, nil
+					}
+					return nil, fmt.Errorf("failed to send replacement transaction: %w", err)
+				}
+				r.log().Info("relay transaction fee bumped", append(txAttrs(replacement), "replaced", current.Hash())...)
+				if r.events != nil {
+					r.events.OnSubmitted(replacement.Hash(), requests)
+				}
+				current = replacement
+				sent = append(sent, replacement.Hash())
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// errBudgetExhausted signals that no replacement can be priced within the policy's fee cap
+var errBudgetExhausted = errors.New("fee budget exhausted")
+
+// rebid returns a signed replacement of current if the bid strategy now bids higher,
+// nil if the current fee is still adequate, or errBudgetExhausted
+func (r *Relayer) rebid(ctx context.Context, chainID *big.Int, current *types.Transaction, requests BatchMetaTxRequestList, now time.Time, minBump uint64, maxFee *big.Int) (*types.Transaction, error) {
+	suggestedFee, gasPrice, err := r.suggestFee(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bid, err := r.bidder.Bid(ctx, BidContext{
+		ChainID:           chainID,
+		GasLimit:          current.Gas(),
+		Requests:          len(requests),
+		NearestDeadline:   nearestDeadline(requests),
+		Now:               now,
+		SuggestedGasPrice: gasPrice,
+		SuggestedFee:      suggestedFee,
+		Client:            r.client,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to bid relay fee: %w", err)
+	}
+
+	next, ok := escalateBid(feeBidOf(current), bid, minBump, maxFee)
+	if !ok {
+		return nil, errBudgetExhausted
+	}
+	if next == nil {
+		return nil, nil
+	}
+
+	tx, err := next.newTransaction(chainID, current.Nonce(), *current.To(), current.Value(), current.Gas(), current.Data())
+	if err != nil {
+		return nil, err
+	}
+	signedTx, err := types.SignTx(tx, types.LatestSignerForChainID(chainID), r.privKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	return signedTx, nil
+}
+
+// findReceipt returns the receipt of the first mined transaction among hashes
+func (r *Relayer) findReceipt(ctx context.Context, hashes []common.Hash) (*types.Receipt, error) {
+	for _, hash := range hashes {
+		receipt, err := r.transactionReceipt(ctx, hash)
+		if err == nil {
+			return receipt, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return nil, fmt.Errorf("failed to get transaction receipt: %w", err)
+		}
+	}
+	return nil, nil
+}
+
+// feeBidOf returns the fee a transaction was priced with
+func feeBidOf(tx *types.Transaction) FeeBid {
+	if tx.Type() == types.DynamicFeeTxType {
+		return FeeBid{GasTipCap: tx.GasTipCap(), GasFeeCap: tx.GasFeeCap()}
+	}
+	return FeeBid{GasPrice: tx.GasPrice()}
+}
+
+// escalateBid computes the replacement fee for prev given the strategy's new bid.
+// It returns nil if the new bid is not higher than prev, and false if the minimum
+// replacement fee exceeds maxFee. The replacement keeps the transaction type of prev.
+func escalateBid(prev, bid FeeBid, minBump uint64, maxFee *big.Int) (*FeeBid, bool) {
+	if prev.IsDynamic() {
+		tip, feeCap := bid.GasTipCap, bid.GasFeeCap
+		if !bid.IsDynamic() {
+			tip, feeCap = bid.GasPrice, bid.GasPrice
+		}
+		if tip.Cmp(prev.GasTipCap) <= 0 && feeCap.Cmp(prev.GasFeeCap) <= 0 {
+			return nil, true
+		}
+
+		tip = maxBig(tip, bumpFee(prev.GasTipCap, minBump))
+		feeCap = maxBig(feeCap, bumpFee(prev.GasFeeCap, minBump))
+		if maxFee != nil && feeCap.Cmp(maxFee) > 0 {
+			feeCap = new(big.Int).Set(maxFee)
+		}
+		if feeCap.Cmp(bumpFee(prev.GasFeeCap, minBump)) < 0 {
+			return nil, false
+		}
+		if tip.Cmp(feeCap) > 0 {
+			tip = new(big.Int).Set(feeCap)
+		}
+		if tip.Cmp(bumpFee(prev.GasTipCap, minBump)) < 0 {
+			return nil, false
+		}
+		return &FeeBid{GasTipCap: tip, GasFeeCap: feeCap}, true
+	}
+
+	price := bid.GasPrice
+	if bid.IsDynamic() {
+		price = bid.GasFeeCap
+	}
+	if price.Cmp(prev.GasPrice) <= 0 {
+		return nil, true
+	}
+
+	price = maxBig(price, bumpFee(prev.GasPrice, minBump))
+	if maxFee != nil && price.Cmp(maxFee) > 0 {
+		price = new(big.Int).Set(maxFee)
+	}
+	if price.Cmp(bumpFee(prev.GasPrice, minBump)) < 0 {
+		return nil, false
+	}
+	return &FeeBid{GasPrice: price}, true
+}
+
+// bumpFee returns fee increased by percent, rounded up
+func bumpFee(fee *big.Int, percent uint64) *big.Int {
+	bumped := new(big.Int).Mul(fee, new(big.Int).SetUint64(100+percent))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+// maxBig returns the larger of a and b
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,98 @@
+package eip2771toolkit
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Events is notified as requests and relay transactions change state, so applications can
+// update their records without wrapping every relaying call. Methods are called on the
+// goroutine making the change and should return quickly.
+type Events interface {
+	// OnQueued is called when a request is pushed to a RequestQueue
+	OnQueued(req QueuedRequest)
+	// OnSubmitted is called when a relay transaction carrying requests is sent, including
+	// the fee-bumped replacements sent by EscalateUntilMined
+	OnSubmitted(hash common.Hash, requests BatchMetaTxRequestList)
+	// OnMined is called when a relay transaction is recorded as mined, see RecordRelay
+	OnMined(result RelayResult, requests BatchMetaTxRequestList)
+	// OnInnerFailure is called for each request of a mined relay whose inner call failed,
+	// with ErrContractCallFailed, or that the forwarder skipped, with ErrRequestSkipped
+	OnInnerFailure(hash common.Hash, index int, err error)
+	// OnDropped is called when a ReorgWatcher finds a mined relay transaction in no block
+	// of the canonical chain anymore
+	OnDropped(hash common.Hash)
+}
+
+// EventFuncs implements Events with optional callbacks; nil ones are skipped
+type EventFuncs struct {
+	Queued       func(req QueuedRequest)
+	Submitted    func(hash common.Hash, requests BatchMetaTxRequestList)
+	Mined        func(result RelayResult, requests BatchMetaTxRequestList)
+	InnerFailure func(hash common.Hash, index int, err error)
+	Dropped      func(hash common.Hash)
+}
+
+// OnQueued implements Events
+func (e EventFuncs) OnQueued(req QueuedRequest) {
+	if e.Queued != nil {
+		e.Queued(req)
+	}
+}
+
+// OnSubmitted implements Events
+func (e EventFuncs) OnSubmitted(hash common.Hash, requests BatchMetaTxRequestList) {
+	if e.Submitted != nil {
+		e.Submitted(hash, requests)
+	}
+}
+
+// OnMined implements Events
+func (e EventFuncs) OnMined(result RelayResult, requests BatchMetaTxRequestList) {
+	if e.Mined != nil {
+		e.Mined(result, requests)
+	}
+}
+
+// OnInnerFailure implements Events
+func (e EventFuncs) OnInnerFailure(hash common.Hash, index int, err error) {
+	if e.InnerFailure != nil {
+		e.InnerFailure(hash, index, err)
+	}
+}
+
+// OnDropped implements Events
+func (e EventFuncs) OnDropped(hash common.Hash) {
+	if e.Dropped != nil {
+		e.Dropped(hash)
+	}
+}
+
+// WithEvents makes the relayer notify events of its relays. Mined relays are checked for
+// failed inner calls, which costs a receipt lookup per relay.
+func WithEvents(events Events) RelayerOption {
+	return func(r *Relayer) {
+		r.events = events
+	}
+}
+
+// notifyMined reports a mined relay and the requests of it that did not execute, given the
+// relay's ExecutedForwardRequest events, see executedIn
+func (r *Relayer) notifyMined(result RelayResult, requests BatchMetaTxRequestList, events []ExecutedForwardRequest, eventsErr error) {
+	if r.events == nil {
+		return
+	}
+	r.events.OnMined(result, requests)
+	// Sandbox receipts have no events, and a reverted relay executed nothing
+	if r.sandbox || !result.Succeeded() {
+		return
+	}
+	if eventsErr != nil {
+		r.log().Warn("failed to check inner calls", "tx", result.Hash, "error", eventsErr)
+		return
+	}
+	for i, err := range matchExecuted(events, requests) {
+		if err != nil {
+			r.events.OnInnerFailure(result.Hash, i, err)
+		}
+	}
+}
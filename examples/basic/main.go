@@ -1,19 +1,24 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math/big"
 
 	"github.com/ethanzhrepo/eip2771toolkit"
+	"github.com/ethanzhrepo/eip2771toolkit/devnet"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 func main() {
 	fmt.Println("EIP-2771 Toolkit Basic Usage Example")
 	fmt.Println("====================================")
 
-	// Example 1: Generate private keys
+	ctx := context.Background()
+
+	// 1. Generate private keys
 	fmt.Println("\n1. Generating private keys...")
 
 	userPrivKey, err := eip2771toolkit.GeneratePrivateKey()
@@ -30,19 +35,45 @@ func main() {
 	relayerAddr := eip2771toolkit.AddressFromPrivateKey(relayerPrivKey)
 	fmt.Printf("Relayer address: %s\n", relayerAddr.Hex())
 
-	// Example 2: Create MetaTx
-	fmt.Println("\n2. Creating MetaTx...")
+	// 2. Start a simulated chain with the forwarder and a token deployed.
+	// Only the relayer holds ETH: the user pays no gas.
+	fmt.Println("\n2. Starting simulated chain...")
+
+	sim, err := devnet.NewSimulated(ctx, relayerAddr)
+	if err != nil {
+		log.Fatalf("Failed to start simulated chain: %v", err)
+	}
+	defer sim.Close()
+
+	fmt.Printf("Chain ID: %s\n", sim.ChainID)
+	fmt.Printf("ERC2771Forwarder: %s\n", sim.Forwarder.Hex())
+	fmt.Printf("Token: %s\n", sim.Token.Hex())
+
+	initialBalance := eip2771toolkit.ToWei(big.NewFloat(10))
+	if err := sim.Mint(ctx, sim.Token, userAddr, initialBalance); err != nil {
+		log.Fatalf("Failed to mint tokens: %v", err)
+	}
+	fmt.Printf("Minted %s tokens to user\n", eip2771toolkit.FromWei(initialBalance).String())
+
+	// 3. Create MetaTx with the user's current forwarder nonce
+	fmt.Println("\n3. Creating MetaTx...")
 
-	// Example addresses and values
-	recipientAddr := common.HexToAddress("0x742b15cf35dF7bcdFAcE36CB4E8C4cF03B06cE85")
-	tokenAddr := common.HexToAddress("0xA0b86a33E6411d01c8a96B60Dc2d7c8DB76A57E5") // Example ERC20 token
-	amount := big.NewInt(1000000000000000000)                                      // 1 token (18 decimals)
-	nonce := uint64(1)
+	nonce, err := eip2771toolkit.GetMetaTxNonceWithProfile(ctx, eip2771toolkit.OZForwarderV5Profile, sim.Forwarder, userAddr, sim.Client)
+	if err != nil {
+		log.Fatalf("Failed to get nonce: %v", err)
+	}
+
+	recipientKey, err := eip2771toolkit.GeneratePrivateKey()
+	if err != nil {
+		log.Fatalf("Failed to generate recipient key: %v", err)
+	}
+	recipientAddr := eip2771toolkit.AddressFromPrivateKey(recipientKey)
+	amount := big.NewInt(1000000000000000000) // 1 token (18 decimals)
 
 	metaTx := eip2771toolkit.NewMetaTxWithDelay(
 		userAddr,      // from
 		recipientAddr, // to
-		tokenAddr,     // token
+		sim.Token,     // token
 		amount,        // amount
 		100000,        // gas limit
 		nonce,         // nonce
@@ -58,142 +89,78 @@ func main() {
 	fmt.Printf("  Nonce: %d\n", metaTx.Nonce)
 	fmt.Printf("  Deadline: %d\n", metaTx.Deadline)
 
-	// Example 3: Build domain separator
-	fmt.Println("\n3. Building domain separator...")
-
-	chainId := big.NewInt(1) // Ethereum mainnet
-	forwarderAddr := common.HexToAddress("0x123456789012345678901234567890123456789")
+	// 4. Build domain separator
+	fmt.Println("\n4. Building domain separator...")
 
-	domainSeparator, err := eip2771toolkit.CreateDomainSeparatorForChain(chainId, forwarderAddr)
+	domainSeparator, err := eip2771toolkit.CreateDomainSeparatorForChain(sim.ChainID, sim.Forwarder)
 	if err != nil {
 		log.Fatalf("Failed to build domain separator: %v", err)
 	}
 	fmt.Printf("Domain separator: %x\n", domainSeparator)
 
-	// Example 4: Sign MetaTx
-	fmt.Println("\n4. Signing MetaTx...")
+	// 5. Sign and verify MetaTx
+	fmt.Println("\n5. Signing MetaTx...")
 
 	signature, err := eip2771toolkit.SignMetaTx(metaTx, userPrivKey, domainSeparator)
 	if err != nil {
 		log.Fatalf("Failed to sign MetaTx: %v", err)
 	}
-	fmt.Printf("Signature created:\n")
-	fmt.Printf("  V: %d\n", signature.V)
-	fmt.Printf("  R: %x\n", signature.R)
-	fmt.Printf("  S: %x\n", signature.S)
-
-	// Example 5: Verify signature
-	fmt.Println("\n5. Verifying signature...")
+	fmt.Printf("Signature: %x\n", signature.ToBytes())
 
 	isValid, err := eip2771toolkit.VerifyMetaTxSignature(metaTx, signature, domainSeparator)
 	if err != nil {
 		log.Fatalf("Failed to verify signature: %v", err)
 	}
+	if !isValid {
+		log.Fatalf("Signature does not verify")
+	}
 	fmt.Printf("Signature is valid: %t\n", isValid)
 
-	// Example 6: Demonstrate utility functions
-	fmt.Println("\n6. Utility functions...")
+	// 6. Relay through the forwarder
+	fmt.Println("\n6. Relaying MetaTx...")
+
+	relayer := eip2771toolkit.NewRelayer(relayerPrivKey, sim.Forwarder, sim.Client)
+	txHash, err := relayer.RelayMetaTx(ctx, metaTx, signature)
+	if err != nil {
+		log.Fatalf("Failed to relay MetaTx: %v", err)
+	}
+	fmt.Printf("Relay transaction: %s\n", txHash.Hex())
+
+	receipt, err := sim.Mine(ctx, txHash)
+	if err != nil {
+		log.Fatalf("Failed to mine relay transaction: %v", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		log.Fatalf("Relay transaction reverted")
+	}
+	fmt.Printf("Mined in block %d, gas used %d\n", receipt.BlockNumber.Uint64(), receipt.GasUsed)
 
-	// Convert amounts
-	etherAmount := big.NewFloat(1.5) // 1.5 ETH
-	weiAmount := eip2771toolkit.ToWei(etherAmount)
-	fmt.Printf("1.5 ETH = %s wei\n", weiAmount.String())
+	// 7. Check the outcome on chain
+	fmt.Println("\n7. Checking balances and nonce...")
 
-	backToEther := eip2771toolkit.FromWei(weiAmount)
-	fmt.Printf("%s wei = %s ETH\n", weiAmount.String(), backToEther.String())
+	expectBalance(ctx, sim, userAddr, new(big.Int).Sub(initialBalance, amount))
+	expectBalance(ctx, sim, recipientAddr, amount)
 
-	// Generate random nonce
-	randomNonce, err := eip2771toolkit.GenerateRandomNonce()
+	newNonce, err := eip2771toolkit.GetMetaTxNonceWithProfile(ctx, eip2771toolkit.OZForwarderV5Profile, sim.Forwarder, userAddr, sim.Client)
 	if err != nil {
-		log.Fatalf("Failed to generate random nonce: %v", err)
-	}
-	fmt.Printf("Random nonce: %d\n", randomNonce)
-
-	// Current timestamp
-	timestamp := eip2771toolkit.GetCurrentTimestamp()
-	fmt.Printf("Current timestamp: %d\n", timestamp)
-
-	fmt.Println("\n7. Example: How to relay transaction (requires live connection)")
-	fmt.Println("// Connect to Ethereum node")
-	fmt.Println("// client, err := ethclient.Dial(\"https://mainnet.infura.io/v3/YOUR-PROJECT-ID\")")
-	fmt.Println("// if err != nil {")
-	fmt.Println("//     log.Fatal(err)")
-	fmt.Println("// }")
-	fmt.Println("//")
-	fmt.Println("// ctx := context.Background()")
-	fmt.Println("// txHash, err := eip2771toolkit.RelayMetaTx(")
-	fmt.Println("//     ctx,")
-	fmt.Println("//     metaTx,")
-	fmt.Println("//     signature,")
-	fmt.Println("//     relayerPrivKey,")
-	fmt.Println("//     erc2771ForwarderAddr,  // Updated: now uses ERC2771Forwarder")
-	fmt.Println("//     client,")
-	fmt.Println("// )")
-	fmt.Println("// if err != nil {")
-	fmt.Println("//     log.Fatal(err)")
-	fmt.Println("// }")
-	fmt.Println("// fmt.Printf(\"Transaction hash: %s\", txHash.Hex())")
+		log.Fatalf("Failed to get nonce: %v", err)
+	}
+	if newNonce != nonce+1 {
+		log.Fatalf("Expected forwarder nonce %d, got %d", nonce+1, newNonce)
+	}
+	fmt.Printf("Forwarder nonce advanced to %d\n", newNonce)
 
 	fmt.Println("\nExample completed successfully!")
 }
 
-// ExampleWithRealConnection demonstrates usage with a real Ethereum connection
-func ExampleWithRealConnection() {
-	// This function shows how to use the toolkit with a real Ethereum connection
-	// Uncomment and modify the following code when you have access to an Ethereum node
-
-	/*
-		// Connect to Ethereum node (replace with your node URL)
-		client, err := ethclient.Dial("https://mainnet.infura.io/v3/YOUR-PROJECT-ID")
-		if err != nil {
-			log.Fatalf("Failed to connect to Ethereum node: %v", err)
-		}
-
-		// Setup
-		userPrivKey, _ := eip2771toolkit.GeneratePrivateKey()
-		relayerPrivKey, _ := eip2771toolkit.GeneratePrivateKey()
-		userAddr := eip2771toolkit.AddressFromPrivateKey(userPrivKey)
-
-		// Contract addresses (replace with real addresses)
-		forwarderAddr := common.HexToAddress("0x...")
-		tokenAddr := common.HexToAddress("0x...")
-		recipientAddr := common.HexToAddress("0x...")
-
-		// Get current nonce from the forwarder contract
-		ctx := context.Background()
-		nonce, err := eip2771toolkit.GetMetaTxNonce(ctx, forwarderAddr, userAddr, client)
-		if err != nil {
-			log.Fatalf("Failed to get nonce: %v", err)
-		}
-
-		// Create MetaTx
-		amount := big.NewInt(1000000000000000000) // 1 token
-		metaTx := eip2771toolkit.NewMetaTxWithDelay(userAddr, recipientAddr, tokenAddr, amount, 100000, nonce, 3600)
-
-		// Get chain ID
-		chainId, err := client.NetworkID(ctx)
-		if err != nil {
-			log.Fatalf("Failed to get chain ID: %v", err)
-		}
-
-		// Build domain separator
-		domainSeparator, err := eip2771toolkit.CreateDomainSeparatorForChain(chainId, forwarderAddr)
-		if err != nil {
-			log.Fatalf("Failed to build domain separator: %v", err)
-		}
-
-		// Sign MetaTx
-		signature, err := eip2771toolkit.SignMetaTx(metaTx, userPrivKey, domainSeparator)
-		if err != nil {
-			log.Fatalf("Failed to sign MetaTx: %v", err)
-		}
-
-		// Relay transaction
-		txHash, err := eip2771toolkit.RelayMetaTx(ctx, metaTx, signature, relayerPrivKey, forwarderAddr, client)
-		if err != nil {
-			log.Fatalf("Failed to relay transaction: %v", err)
-		}
-
-		fmt.Printf("Transaction relayed successfully! Hash: %s\n", txHash.Hex())
-	*/
+// expectBalance fails the example if holder's token balance differs from want
+func expectBalance(ctx context.Context, sim *devnet.Simulated, holder common.Address, want *big.Int) {
+	balance, err := sim.BalanceOf(ctx, sim.Token, holder)
+	if err != nil {
+		log.Fatalf("Failed to get balance: %v", err)
+	}
+	if balance.Cmp(want) != 0 {
+		log.Fatalf("Expected balance %s for %s, got %s", want, holder.Hex(), balance)
+	}
+	fmt.Printf("✓ %s holds %s\n", holder.Hex(), balance)
 }
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const recipients = 1000
+
+func main() {
+	fmt.Println("EIP-2771 Toolkit - Bulk Signing Example")
+	fmt.Println("=======================================")
+
+	// This example signs an airdrop from one user with PrefixSigner and checks that it produces
+	// the same signatures as SignMetaTx
+
+	// 1. Build the airdrop requests
+	fmt.Println("\n1. Creating airdrop requests...")
+
+	userPrivKey := newKey()
+	userAddr := eip2771toolkit.AddressFromPrivateKey(userPrivKey)
+	token := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	forwarder := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+
+	domainSeparator, err := eip2771toolkit.CreateDomainSeparatorForChain(big.NewInt(1), forwarder)
+	if err != nil {
+		log.Fatalf("Failed to build domain separator: %v", err)
+	}
+
+	to := make([]common.Address, recipients)
+	amounts := make([]*big.Int, recipients)
+	for i := range to {
+		to[i] = eip2771toolkit.AddressFromPrivateKey(newKey())
+		amounts[i] = big.NewInt(int64(i+1) * 1e15)
+	}
+	metaTxs, err := eip2771toolkit.NewMetaTxBatchWithDefaultGas(userAddr, to, token, amounts, 0, eip2771toolkit.GetCurrentTimestamp()+3600)
+	if err != nil {
+		log.Fatalf("Failed to create MetaTx batch: %v", err)
+	}
+	fmt.Printf("Created %d MetaTx from %s\n", len(metaTxs), userAddr.Hex())
+
+	// 2. PrefixSigner must agree with the generic signer
+	fmt.Println("\n2. Comparing with SignMetaTx...")
+
+	signer, err := eip2771toolkit.NewPrefixSigner(userPrivKey, token, domainSeparator)
+	if err != nil {
+		log.Fatalf("Failed to create prefix signer: %v", err)
+	}
+	batch, err := signer.SignBatch(metaTxs)
+	if err != nil {
+		log.Fatalf("Failed to sign batch: %v", err)
+	}
+	for i, metaTx := range metaTxs {
+		want, err := eip2771toolkit.SignMetaTx(metaTx, userPrivKey, domainSeparator)
+		if err != nil {
+			log.Fatalf("Failed to sign MetaTx: %v", err)
+		}
+		if !bytes.Equal(want.ToBytes(), batch[i].Signature.ToBytes()) {
+			log.Fatalf("Signature %d differs from SignMetaTx", i)
+		}
+	}
+	fmt.Printf("✓ %d signatures identical\n", len(batch))
+
+	fmt.Println("\nCompare their speed with: go test -bench MetaTx github.com/ethanzhrepo/eip2771toolkit")
+
+	fmt.Println("\nBulk signing example completed successfully!")
+}
+
+// newKey generates a private key or aborts
+func newKey() *ecdsa.PrivateKey {
+	key, err := eip2771toolkit.GeneratePrivateKey()
+	if err != nil {
+		log.Fatalf("Failed to generate private key: %v", err)
+	}
+	return key
+}
@@ -1,179 +1,194 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
 
 	"github.com/ethanzhrepo/eip2771toolkit"
+	"github.com/ethanzhrepo/eip2771toolkit/devnet"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 func main() {
 	fmt.Println("EIP-2771 Toolkit - ERC2771Forwarder Integration Example")
 	fmt.Println("======================================================")
 
-	// This example demonstrates the updated integration with OpenZeppelin's
-	// ERC2771Forwarder contract (v5.x)
+	// This example exercises the OpenZeppelin v5 ERC2771Forwarder interface directly:
+	// verify(), execute(), the ExecutedForwardRequest event and signer checks
 
-	// 1. Generate keys for demonstration
-	fmt.Println("\n1. Setting up accounts...")
+	ctx := context.Background()
+
+	// 1. Setting up accounts and chain
+	fmt.Println("\n1. Setting up accounts and chain...")
 
 	userPrivKey, err := eip2771toolkit.GeneratePrivateKey()
 	if err != nil {
 		log.Fatalf("Failed to generate user private key: %v", err)
 	}
 	userAddr := eip2771toolkit.AddressFromPrivateKey(userPrivKey)
-	fmt.Printf("User address: %s\n", userAddr.Hex())
 
 	relayerPrivKey, err := eip2771toolkit.GeneratePrivateKey()
 	if err != nil {
 		log.Fatalf("Failed to generate relayer private key: %v", err)
 	}
 	relayerAddr := eip2771toolkit.AddressFromPrivateKey(relayerPrivKey)
-	fmt.Printf("Relayer address: %s\n", relayerAddr.Hex())
-
-	// 2. Contract addresses (example addresses - replace with real ones)
-	fmt.Println("\n2. Contract addresses...")
 
-	// Example ERC2771Forwarder contract address
-	forwarderAddr := common.HexToAddress("0x0000000000000000000000000000000000000001")
-	fmt.Printf("ERC2771Forwarder address: %s\n", forwarderAddr.Hex())
+	sim, err := devnet.NewSimulated(ctx, relayerAddr)
+	if err != nil {
+		log.Fatalf("Failed to start simulated chain: %v", err)
+	}
+	defer sim.Close()
 
-	// Example ERC20 token contract address
-	tokenAddr := common.HexToAddress("0xA0b86a33E6411d01c8a96B60Dc2d7c8DB76A57E5")
-	fmt.Printf("Token contract address: %s\n", tokenAddr.Hex())
+	fmt.Printf("User address: %s\n", userAddr.Hex())
+	fmt.Printf("Relayer address: %s\n", relayerAddr.Hex())
+	fmt.Printf("ERC2771Forwarder address: %s\n", sim.Forwarder.Hex())
+	fmt.Printf("Token contract address: %s\n", sim.Token.Hex())
 
-	// Recipient address
-	recipientAddr := common.HexToAddress("0x742b15cf35dF7bcdFAcE36CB4E8C4cF03B06cE85")
-	fmt.Printf("Recipient address: %s\n", recipientAddr.Hex())
+	if err := sim.Mint(ctx, sim.Token, userAddr, eip2771toolkit.ToWei(big.NewFloat(5))); err != nil {
+		log.Fatalf("Failed to mint tokens: %v", err)
+	}
 
-	// 3. Create MetaTx
-	fmt.Println("\n3. Creating MetaTx for ERC2771Forwarder...")
+	// 2. Sign a MetaTx for the forwarder's domain
+	fmt.Println("\n2. Signing MetaTx for ERC2771Forwarder...")
 
-	amount := big.NewInt(1000000000000000000) // 1 token (18 decimals)
-	nonce := uint64(1)
-
-	metaTx := eip2771toolkit.NewMetaTxWithDelay(
-		userAddr,      // from
-		recipientAddr, // to
-		tokenAddr,     // token contract
-		amount,        // amount
-		100000,        // gas limit
-		nonce,         // nonce
-		3600,          // deadline in 1 hour
-	)
-
-	fmt.Printf("MetaTx for ERC2771Forwarder:\n")
-	fmt.Printf("  From: %s\n", metaTx.From.Hex())
-	fmt.Printf("  To (recipient): %s\n", metaTx.To.Hex())
-	fmt.Printf("  Token: %s\n", metaTx.Token.Hex())
-	fmt.Printf("  Amount: %s\n", metaTx.Amount.String())
-	fmt.Printf("  Nonce: %d\n", metaTx.Nonce)
-	fmt.Printf("  Deadline: %d\n", metaTx.Deadline)
-
-	// 4. Build domain separator for ERC2771Forwarder
-	fmt.Println("\n4. Building ERC2771Forwarder domain separator...")
-
-	chainId := big.NewInt(1) // Ethereum mainnet
-	domainSeparator, err := eip2771toolkit.CreateDomainSeparatorForChain(chainId, forwarderAddr)
+	domainSeparator, err := sim.DomainSeparator()
 	if err != nil {
 		log.Fatalf("Failed to build domain separator: %v", err)
 	}
 	fmt.Printf("Domain separator: %x\n", domainSeparator)
 	fmt.Printf("Domain uses: name='ERC2771Forwarder', version='1'\n")
 
-	// 5. Sign MetaTx with ERC2771Forwarder structure
-	fmt.Println("\n5. Signing MetaTx for ERC2771Forwarder...")
+	recipientAddr := common.HexToAddress("0x742b15cf35df7BCDFace36CB4e8C4cf03B06cE85")
+	amount := big.NewInt(1000000000000000000) // 1 token (18 decimals)
 
+	metaTx := eip2771toolkit.NewMetaTxWithDelay(userAddr, recipientAddr, sim.Token, amount, 100000, 0, 3600)
 	signature, err := eip2771toolkit.SignMetaTx(metaTx, userPrivKey, domainSeparator)
 	if err != nil {
 		log.Fatalf("Failed to sign MetaTx: %v", err)
 	}
-	fmt.Printf("Signature created:\n")
-	fmt.Printf("  V: %d\n", signature.V)
-	fmt.Printf("  R: %x\n", signature.R)
-	fmt.Printf("  S: %x\n", signature.S)
-	fmt.Printf("  Signature bytes: %x\n", signature.ToBytes())
+	fmt.Printf("Signature bytes: %x\n", signature.ToBytes())
+
+	// 3. Ask the forwarder to verify the request
+	fmt.Println("\n3. Calling ERC2771Forwarder.verify()...")
+
+	valid := forwarderVerify(ctx, sim, metaTx, signature)
+	if !valid {
+		log.Fatalf("Forwarder rejected a correctly signed request")
+	}
+	fmt.Printf("verify(request) = %t\n", valid)
+
+	// A request whose fields differ from what was signed recovers a different signer
+	tampered := metaTx
+	tampered.Amount = new(big.Int).Mul(amount, big.NewInt(2))
+	if forwarderVerify(ctx, sim, tampered, signature) {
+		log.Fatalf("Forwarder accepted a tampered request")
+	}
+	fmt.Printf("verify(tampered request) = false\n")
+
+	// 4. Relay through execute() and inspect the forwarder event
+	fmt.Println("\n4. Relaying through ERC2771Forwarder.execute()...")
+
+	relayer := eip2771toolkit.NewRelayer(relayerPrivKey, sim.Forwarder, sim.Client)
+	txHash, err := relayer.RelayMetaTx(ctx, metaTx, signature)
+	if err != nil {
+		log.Fatalf("Failed to relay MetaTx: %v", err)
+	}
+	receipt, err := sim.Mine(ctx, txHash)
+	if err != nil {
+		log.Fatalf("Failed to mine relay transaction: %v", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		log.Fatalf("Relay transaction reverted")
+	}
+
+	transferTopic := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	for _, l := range receipt.Logs {
+		switch {
+		case l.Address == sim.Forwarder && l.Topics[0] == eip2771toolkit.EXECUTED_FORWARD_REQUEST_TOPIC:
+			event, _ := eip2771toolkit.ParseExecutedForwardRequest(l)
+			fmt.Printf("ExecutedForwardRequest(signer=%s, nonce=%d, success=%t)\n",
+				event.Signer.Hex(), event.Nonce, event.Success)
+		case l.Address == sim.Token && l.Topics[0] == transferTopic:
+			// The token sees the user, not the forwarder, as sender (_msgSender())
+			from := common.BytesToAddress(l.Topics[1].Bytes())
+			if from != userAddr {
+				log.Fatalf("Token saw sender %s, expected %s", from.Hex(), userAddr.Hex())
+			}
+			fmt.Printf("Transfer(from=%s, to=%s, value=%s)\n",
+				from.Hex(), common.BytesToAddress(l.Topics[2].Bytes()).Hex(), new(big.Int).SetBytes(l.Data))
+		}
+	}
 
-	// 6. Verify signature
-	fmt.Println("\n6. Verifying signature...")
+	// 5. Replaying the same signature fails: the nonce has been used
+	fmt.Println("\n5. Replaying the same request...")
 
-	isValid, err := eip2771toolkit.VerifyMetaTxSignature(metaTx, signature, domainSeparator)
+	if forwarderVerify(ctx, sim, metaTx, signature) {
+		log.Fatalf("Forwarder accepted a replayed request")
+	}
+	fmt.Printf("verify(replayed request) = false\n")
+
+	// 6. Tokens that return false instead of reverting
+	fmt.Println("\n6. Detecting transfers that return false...")
+
+	lenientToken, err := sim.DeployToken(ctx, true)
 	if err != nil {
-		log.Fatalf("Failed to verify signature: %v", err)
-	}
-	fmt.Printf("Signature is valid: %t\n", isValid)
-
-	// 7. Show ForwardRequest structure that would be sent to ERC2771Forwarder
-	fmt.Println("\n7. ERC2771Forwarder ForwardRequestData structure:")
-	fmt.Printf("ForwardRequestData {\n")
-	fmt.Printf("  from: %s\n", metaTx.From.Hex())
-	fmt.Printf("  to: %s  // Target contract (token)\n", metaTx.Token.Hex())
-	fmt.Printf("  value: 0  // No ETH for ERC20 transfer\n")
-	fmt.Printf("  gas: 100000  // Gas limit for inner call\n")
-	fmt.Printf("  deadline: %d  // uint48 deadline\n", metaTx.Deadline)
-	fmt.Printf("  data: transfer(%s, %s)  // ERC20 transfer call\n", metaTx.To.Hex(), metaTx.Amount.String())
-	fmt.Printf("  signature: %x\n", signature.ToBytes())
-	fmt.Printf("}\n")
-
-	// 8. Example with real connection (commented out)
-	fmt.Println("\n8. Example with real ERC2771Forwarder connection:")
-	fmt.Println("// To use with a real ERC2771Forwarder contract:")
-	fmt.Println("//")
-	fmt.Println("// client, err := ethclient.Dial(\"https://mainnet.infura.io/v3/YOUR-PROJECT-ID\")")
-	fmt.Println("// if err != nil {")
-	fmt.Println("//     log.Fatal(err)")
-	fmt.Println("// }")
-	fmt.Println("//")
-	fmt.Println("// ctx := context.Background()")
-	fmt.Println("//")
-	fmt.Println("// // Get current nonce from ERC2771Forwarder")
-	fmt.Println("// currentNonce, err := eip2771toolkit.GetMetaTxNonce(ctx, forwarderAddr, userAddr, client)")
-	fmt.Println("// if err != nil {")
-	fmt.Println("//     log.Fatal(err)")
-	fmt.Println("// }")
-	fmt.Println("//")
-	fmt.Println("// // Create MetaTx with current nonce")
-	fmt.Println("// metaTx := eip2771toolkit.NewMetaTxWithDelay(userAddr, recipientAddr, tokenAddr, amount, currentNonce, 3600)")
-	fmt.Println("//")
-	fmt.Println("// // Get chain ID and build domain separator")
-	fmt.Println("// chainId, _ := client.NetworkID(ctx)")
-	fmt.Println("// domainSeparator, _ := eip2771toolkit.CreateDomainSeparatorForChain(chainId, forwarderAddr)")
-	fmt.Println("//")
-	fmt.Println("// // Sign and relay")
-	fmt.Println("// signature, _ := eip2771toolkit.SignMetaTx(metaTx, userPrivKey, domainSeparator)")
-	fmt.Println("// txHash, err := eip2771toolkit.RelayMetaTx(ctx, metaTx, signature, relayerPrivKey, forwarderAddr, client)")
-	fmt.Println("// if err != nil {")
-	fmt.Println("//     log.Fatal(err)")
-	fmt.Println("// }")
-	fmt.Println("// fmt.Printf(\"Transaction hash: %s\", txHash.Hex())")
-
-	fmt.Println("\n9. Key differences from MinimalForwarder:")
-	fmt.Println("✓ Updated to ERC2771Forwarder contract structure")
-	fmt.Printf("✓ Domain name: 'ERC2771Forwarder' (was 'MinimalForwarder')\n")
-	fmt.Printf("✓ Domain version: '1' (was '0.0.1')\n")
-	fmt.Printf("✓ Method: nonces() instead of getNonce()\n")
-	fmt.Printf("✓ Deadline field: uint48 instead of uint256\n")
-	fmt.Printf("✓ Signature included in ForwardRequestData struct\n")
-	fmt.Printf("✓ Updated TypeHash for ForwardRequest\n")
+		log.Fatalf("Failed to deploy token: %v", err)
+	}
+
+	// The user holds none of this token, so the transfer returns false while execute() succeeds
+	overdraft := eip2771toolkit.NewMetaTxWithDelay(userAddr, recipientAddr, lenientToken, amount, 100000, 1, 3600)
+	overdraftSig, err := eip2771toolkit.SignMetaTx(overdraft, userPrivKey, domainSeparator)
+	if err != nil {
+		log.Fatalf("Failed to sign MetaTx: %v", err)
+	}
+	txHash, err = relayer.RelayMetaTx(ctx, overdraft, overdraftSig)
+	if err != nil {
+		log.Fatalf("Failed to relay MetaTx: %v", err)
+	}
+	receipt, err = sim.Mine(ctx, txHash)
+	if err != nil {
+		log.Fatalf("Failed to mine relay transaction: %v", err)
+	}
+	fmt.Printf("Receipt status: %d (transaction succeeded)\n", receipt.Status)
+
+	err = relayer.CheckTransferResults(ctx, txHash, eip2771toolkit.BatchMetaTxRequestList{{MetaTx: overdraft, Signature: overdraftSig}})
+	if !errors.Is(err, eip2771toolkit.ErrTransferReturnedFalse) {
+		log.Fatalf("Expected ErrTransferReturnedFalse, got %v", err)
+	}
+	fmt.Printf("CheckTransferResults: %v\n", err)
 
 	fmt.Println("\nERC2771Forwarder integration example completed successfully!")
 }
 
-// DemoERC2771ForwarderStructure shows the exact structure expected by the contract
-func DemoERC2771ForwarderStructure() {
-	fmt.Println("\nERC2771Forwarder expects this exact structure:")
-	fmt.Println("struct ForwardRequestData {")
-	fmt.Println("    address from;      // Signer address")
-	fmt.Println("    address to;        // Target contract")
-	fmt.Println("    uint256 value;     // ETH value")
-	fmt.Println("    uint256 gas;       // Gas limit")
-	fmt.Println("    uint48 deadline;   // Expiration timestamp")
-	fmt.Println("    bytes data;        // Call data")
-	fmt.Println("    bytes signature;   // EIP-712 signature")
-	fmt.Println("}")
-	fmt.Println("")
-	fmt.Println("TypeHash:")
-	fmt.Println("'ForwardRequest(address from,address to,uint256 value,uint256 gas,uint256 nonce,uint48 deadline,bytes data)'")
+// forwarderVerify calls the forwarder's verify(ForwardRequestData) view
+func forwarderVerify(ctx context.Context, sim *devnet.Simulated, metaTx eip2771toolkit.MetaTx, sig eip2771toolkit.Signature) bool {
+	parsedABI, err := eip2771toolkit.DefaultRegistry.ABI(eip2771toolkit.ERC2771ForwarderABI)
+	if err != nil {
+		log.Fatalf("Failed to parse ABI: %v", err)
+	}
+	forwardRequest, err := metaTx.ForwardRequest()
+	if err != nil {
+		log.Fatalf("Failed to build forward request: %v", err)
+	}
+	data, err := parsedABI.Pack("verify", eip2771toolkit.OZForwarderV5Schema.RequestData(forwardRequest, sig))
+	if err != nil {
+		log.Fatalf("Failed to pack verify call: %v", err)
+	}
+
+	result, err := sim.Client.CallContract(ctx, ethereum.CallMsg{To: &sim.Forwarder, Data: data}, nil)
+	if err != nil {
+		log.Fatalf("Failed to call verify: %v", err)
+	}
+
+	var valid bool
+	if err := parsedABI.UnpackIntoInterface(&valid, "verify", result); err != nil {
+		log.Fatalf("Failed to unpack verify result: %v", err)
+	}
+	return valid
 }
@@ -0,0 +1,130 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// EXECUTED_FORWARD_REQUEST_TOPIC is the topic of ERC2771Forwarder's
+// ExecutedForwardRequest(address indexed signer, uint256 nonce, bool success) event
+var EXECUTED_FORWARD_REQUEST_TOPIC = crypto.Keccak256Hash([]byte("ExecutedForwardRequest(address,uint256,bool)"))
+
+// ExecutedForwardRequest is an ExecutedForwardRequest event: the forwarder consumed the
+// signer's nonce and called the target, which succeeded or failed
+type ExecutedForwardRequest struct {
+	Forwarder common.Address `json:"forwarder"`
+	Signer    common.Address `json:"signer"`
+	Nonce     *big.Int       `json:"nonce"`
+	Success   bool           `json:"success"`
+	LogIndex  uint           `json:"logIndex"`
+}
+
+// ParseExecutedForwardRequest decodes an ExecutedForwardRequest log. It returns false for
+// other logs.
+func ParseExecutedForwardRequest(log *types.Log) (ExecutedForwardRequest, bool) {
+	if len(log.Topics) != 2 || log.Topics[0] != EXECUTED_FORWARD_REQUEST_TOPIC || len(log.Data) != 64 {
+		return ExecutedForwardRequest{}, false
+	}
+	return ExecutedForwardRequest{
+		Forwarder: log.Address,
+		Signer:    common.BytesToAddress(log.Topics[1].Bytes()),
+		Nonce:     new(big.Int).SetBytes(log.Data[:32]),
+		Success:   new(big.Int).SetBytes(log.Data[32:64]).Sign() != 0,
+		LogIndex:  log.Index,
+	}, true
+}
+
+// ParseExecutedForwardRequests returns the ExecutedForwardRequest events forwarder emitted in
+// receipt, in execution order. Events from other contracts are ignored, since any target can
+// emit a look-alike event.
+func ParseExecutedForwardRequests(receipt *types.Receipt, forwarder common.Address) []ExecutedForwardRequest {
+	var events []ExecutedForwardRequest
+	for _, log := range receipt.Logs {
+		if log.Address != forwarder {
+			continue
+		}
+		if event, ok := ParseExecutedForwardRequest(log); ok {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// ExecutedRequests returns the requests the forwarder executed in a mined relay transaction.
+// A mined transaction only means the forwarder ran; the events tell whether each inner call
+// succeeded. Sandbox transactions are never executed and have none.
+func (r *Relayer) ExecutedRequests(ctx context.Context, txHash common.Hash) ([]ExecutedForwardRequest, error) {
+	tx, err := r.transactionByHash(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relay transaction: %w", err)
+	}
+	if tx.To() == nil {
+		return nil, fmt.Errorf("transaction %s is not a forwarder call", txHash.Hex())
+	}
+	receipt, err := r.transactionReceipt(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction receipt: %w", err)
+	}
+	return ParseExecutedForwardRequests(receipt, *tx.To()), nil
+}
+
+// executedIn returns the ExecutedForwardRequest events of a mined relay when the relayer's
+// events or nonce tracker need them. Sandbox and reverted relays executed nothing.
+func (r *Relayer) executedIn(ctx context.Context, result RelayResult) ([]ExecutedForwardRequest, error) {
+	if (r.events == nil && r.nonceTracker == nil) || r.sandbox || !result.Succeeded() {
+		return nil, nil
+	}
+	return r.ExecutedRequests(ctx, result.Hash)
+}
+
+// CheckExecuted matches the requests of a mined relay transaction with its
+// ExecutedForwardRequest events. Requests whose inner call failed are reported with
+// ErrContractCallFailed, requests the forwarder skipped (e.g. with an invalid signature, nonce
+// or deadline in a non-atomic batch) with ErrRequestSkipped; the errors are joined.
+func (r *Relayer) CheckExecuted(ctx context.Context, txHash common.Hash, requests BatchMetaTxRequestList) error {
+	events, err := r.ExecutedRequests(ctx, txHash)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i, err := range matchExecuted(events, requests) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("request at index %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// matchExecuted returns, for each request, ErrContractCallFailed or ErrRequestSkipped unless
+// events show it executed successfully
+func matchExecuted(events []ExecutedForwardRequest, requests BatchMetaTxRequestList) []error {
+	type key struct {
+		signer common.Address
+		nonce  uint64
+	}
+	executed := make(map[key]bool, len(events))
+	for _, event := range events {
+		if event.Nonce.IsUint64() {
+			executed[key{event.Signer, event.Nonce.Uint64()}] = event.Success
+		}
+	}
+
+	errs := make([]error, len(requests))
+	for i, req := range requests {
+		success, ok := executed[key{req.MetaTx.From, req.MetaTx.Nonce}]
+		switch {
+		case !ok:
+			errs[i] = ErrRequestSkipped
+		case !success:
+			errs[i] = fmt.Errorf("%w: inner call failed", ErrContractCallFailed)
+		}
+	}
+	return errs
+}
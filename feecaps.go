@@ -0,0 +1,107 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// DEFAULT_FEE_CAP_RETRY_INTERVAL is how often a deferred relay re-checks the network fee
+const DEFAULT_FEE_CAP_RETRY_INTERVAL = 15 * time.Second
+
+// FeeCaps protect the sponsor from relaying during fee spikes. A relay whose bid exceeds a
+// cap is refused with ErrFeeCapExceeded, or with Defer set, held back until fees drop.
+type FeeCaps struct {
+	MaxFeePerGas *big.Int // highest gas price / EIP-1559 fee cap bid, nil for no cap
+	MaxBatchCost *big.Int // highest fee per gas times gas limit of one relay transaction (single request or batch) in wei, nil for no cap
+
+	// Defer waits for the fee to drop below the caps instead of refusing the relay. The wait
+	// ends with ErrRelayExpired at the nearest request deadline, or when ctx is done.
+	Defer         bool
+	RetryInterval time.Duration // default DEFAULT_FEE_CAP_RETRY_INTERVAL
+}
+
+// Check returns an error wrapping ErrFeeCapExceeded if bid, for a transaction of gasLimit,
+// exceeds a cap
+func (c *FeeCaps) Check(bid FeeBid, gasLimit uint64) error {
+	if c == nil {
+		return nil
+	}
+	feePerGas := bid.feePerGas()
+	if feePerGas == nil {
+		return nil
+	}
+	if c.MaxFeePerGas != nil && feePerGas.Cmp(c.MaxFeePerGas) > 0 {
+		return fmt.Errorf("%w: fee per gas %s above cap %s", ErrFeeCapExceeded, feePerGas, c.MaxFeePerGas)
+	}
+	if c.MaxBatchCost != nil {
+		cost := new(big.Int).Mul(feePerGas, new(big.Int).SetUint64(gasLimit))
+		if cost.Cmp(c.MaxBatchCost) > 0 {
+			return fmt.Errorf("%w: transaction cost %s above cap %s", ErrFeeCapExceeded, cost, c.MaxBatchCost)
+		}
+	}
+	return nil
+}
+
+// WithFeeCaps refuses or defers relays whose bid exceeds the caps
+func WithFeeCaps(caps FeeCaps) RelayerOption {
+	return func(r *Relayer) {
+		r.feeCaps = &caps
+	}
+}
+
+// SetFeeCaps replaces the relayer's fee caps, nil for none, e.g. after a configuration reload
+func (r *Relayer) SetFeeCaps(caps *FeeCaps) {
+	r.settingsMu.Lock()
+	defer r.settingsMu.Unlock()
+	if caps != nil {
+		c := *caps
+		caps = &c
+	}
+	r.feeCaps = caps
+}
+
+// activeFeeCaps returns the fee caps relays are checked against
+func (r *Relayer) activeFeeCaps() *FeeCaps {
+	r.settingsMu.RLock()
+	defer r.settingsMu.RUnlock()
+	return r.feeCaps
+}
+
+// bidWithinCaps asks the bid strategy for the fee of a relay and checks it against the fee
+// caps, re-asking the gas strategy and bidder every RetryInterval while a deferred relay waits
+func (r *Relayer) bidWithinCaps(ctx context.Context, bc BidContext) (FeeBid, error) {
+	for {
+		bid, err := r.bidder.Bid(ctx, bc)
+		if err != nil {
+			return FeeBid{}, fmt.Errorf("failed to bid relay fee: %w", err)
+		}
+		caps := r.activeFeeCaps()
+		capErr := caps.Check(bid, bc.GasLimit)
+		if capErr == nil || !caps.Defer {
+			return bid, capErr
+		}
+
+		interval := caps.RetryInterval
+		if interval <= 0 {
+			interval = DEFAULT_FEE_CAP_RETRY_INTERVAL
+		}
+		if bc.NearestDeadline != 0 && time.Now().Add(interval).Unix() >= int64(bc.NearestDeadline) {
+			return FeeBid{}, fmt.Errorf("%w: %w", ErrRelayExpired, capErr)
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return FeeBid{}, fmt.Errorf("%w: %w", ctx.Err(), capErr)
+		case <-timer.C:
+		}
+
+		bc.SuggestedFee, bc.SuggestedGasPrice, err = r.suggestFee(ctx)
+		if err != nil {
+			return FeeBid{}, err
+		}
+		bc.Now = time.Now()
+	}
+}
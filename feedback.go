@@ -0,0 +1,125 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// QuotaStatus is a user's sponsored quota in the current window
+type QuotaStatus struct {
+	Limit     uint64    `json:"limit"`
+	Used      uint64    `json:"used"`
+	Remaining uint64    `json:"remaining"`
+	ResetsAt  time.Time `json:"resetsAt"`
+}
+
+// QuotaReporter reports how much sponsored relaying a user has left
+type QuotaReporter interface {
+	Quota(ctx context.Context, from common.Address) (QuotaStatus, error)
+}
+
+// PolicyLimits are the sponsorship rules a relay service publishes to its users
+type PolicyLimits struct {
+	AllowedTokens     []common.Address            `json:"allowedTokens,omitempty"`     // empty for any token
+	MaxAmounts        map[common.Address]*big.Int `json:"maxAmounts,omitempty"`        // per-transfer cap by token
+	MaxDeadlineWindow uint64                      `json:"maxDeadlineWindow,omitempty"` // seconds, 0 for no limit
+}
+
+// UserFeedback tells a dApp what to expect before it asks a user to sign
+type UserFeedback struct {
+	From                 common.Address `json:"from"`
+	Quota                *QuotaStatus   `json:"quota,omitempty"` // nil when relaying is not metered
+	Limits               PolicyLimits   `json:"limits"`
+	QueueDepth           int            `json:"queueDepth"` // requests ahead of a new one at the given priority
+	EstimatedWaitSeconds float64        `json:"estimatedWaitSeconds"`
+}
+
+// FeedbackService answers user-facing questions about quota, limits and waiting time
+type FeedbackService struct {
+	Queue         *RequestQueue // nil if requests are relayed immediately
+	Quota         QuotaReporter // nil if relaying is not metered
+	Limits        PolicyLimits
+	BatchSize     int           // requests taken from the queue per relay, default 1
+	BatchInterval time.Duration // time between relays, default 15s
+}
+
+// Feedback reports the quota and limits of from and how long a request submitted now at
+// priority would wait: one BatchInterval per batch ahead of it, including its own
+func (s *FeedbackService) Feedback(ctx context.Context, from common.Address, priority Priority) (UserFeedback, error) {
+	feedback := UserFeedback{From: from, Limits: s.Limits}
+
+	if s.Quota != nil {
+		quota, err := s.Quota.Quota(ctx, from)
+		if err != nil {
+			return UserFeedback{}, err
+		}
+		feedback.Quota = &quota
+	}
+
+	if s.Queue != nil {
+		stats := s.Queue.Stats()
+		for p := priority; p <= PriorityHigh; p++ {
+			feedback.QueueDepth += stats.ByPriority[p.String()].Depth
+		}
+	}
+
+	batchSize := s.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	interval := s.BatchInterval
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	batches := feedback.QueueDepth/batchSize + 1
+	feedback.EstimatedWaitSeconds = (time.Duration(batches) * interval).Seconds()
+	return feedback, nil
+}
+
+// Handler serves Feedback as JSON for GET ?from=0x...&priority=low|normal|high
+func (s *FeedbackService) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		from, err := ParseAddress(req.URL.Query().Get("from"), false)
+		if err != nil {
+			http.Error(w, "invalid from address", http.StatusBadRequest)
+			return
+		}
+		priority, ok := parsePriority(req.URL.Query().Get("priority"))
+		if !ok {
+			http.Error(w, "invalid priority", http.StatusBadRequest)
+			return
+		}
+
+		feedback, err := s.Feedback(req.Context(), from, priority)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(feedback)
+	})
+}
+
+// parsePriority parses a priority name, defaulting to PriorityNormal
+func parsePriority(name string) (Priority, bool) {
+	switch strings.ToLower(name) {
+	case "", "normal":
+		return PriorityNormal, true
+	case "low":
+		return PriorityLow, true
+	case "high":
+		return PriorityHigh, true
+	}
+	return 0, false
+}
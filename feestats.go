@@ -0,0 +1,214 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DEFAULT_FEE_WINDOW is the number of recent relays per chain FeeTracker keeps
+const DEFAULT_FEE_WINDOW = 500
+
+// FeeSample is the cost of one mined relay transaction
+type FeeSample struct {
+	GasPrice *big.Int  // effective gas price paid
+	GasUsed  uint64    // gas used by the relay transaction
+	L1Fee    *big.Int  // rollup data fee paid on top of the gas, nil on L1 chains
+	Requests int       // meta transactions it carried
+	MinedAt  time.Time // when the sample was recorded
+}
+
+// Cost returns the fee paid for the relay transaction in wei, including any L1 data fee
+func (s FeeSample) Cost() *big.Int {
+	cost := new(big.Int).Mul(s.GasPrice, new(big.Int).SetUint64(s.GasUsed))
+	if s.L1Fee != nil {
+		cost.Add(cost, s.L1Fee)
+	}
+	return cost
+}
+
+// FeeStats summarizes the recent relay costs of one chain
+type FeeStats struct {
+	Samples           int      `json:"samples"`
+	Requests          int      `json:"requests"`
+	P50GasPrice       *big.Int `json:"p50GasPrice"`       // median effective gas price, wei
+	P95GasPrice       *big.Int `json:"p95GasPrice"`       // 95th percentile effective gas price, wei
+	AvgGasPerRequest  uint64   `json:"avgGasPerRequest"`  // relay gas used divided by requests carried
+	AvgCostPerRequest *big.Int `json:"avgCostPerRequest"` // fees paid divided by requests carried, wei
+}
+
+// FeeTracker keeps rolling statistics of what relays actually cost per chain, from their
+// receipts. It is safe for concurrent use.
+type FeeTracker struct {
+	window int
+
+	mu      sync.Mutex
+	samples map[uint64][]FeeSample
+	now     func() time.Time
+}
+
+// NewFeeTracker creates a tracker keeping the last window relays per chain, or
+// DEFAULT_FEE_WINDOW if window is not positive
+func NewFeeTracker(window int) *FeeTracker {
+	if window <= 0 {
+		window = DEFAULT_FEE_WINDOW
+	}
+	return &FeeTracker{
+		window:  window,
+		samples: make(map[uint64][]FeeSample),
+		now:     time.Now,
+	}
+}
+
+// Record adds a mined relay carrying requests meta transactions. Results without a gas
+// price, such as sandbox receipts, are ignored.
+func (t *FeeTracker) Record(chainID uint64, result RelayResult, requests int) {
+	if result.GasPrice == nil || requests <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sample := FeeSample{
+		GasPrice: new(big.Int).Set(result.GasPrice),
+		GasUsed:  result.GasUsed,
+		Requests: requests,
+		MinedAt:  t.now(),
+	}
+	if result.L1Fee != nil {
+		sample.L1Fee = new(big.Int).Set(result.L1Fee)
+	}
+	samples := append(t.samples[chainID], sample)
+	if len(samples) > t.window {
+		samples = samples[len(samples)-t.window:]
+	}
+	t.samples[chainID] = samples
+}
+
+// Samples returns a copy of the recorded samples of a chain, oldest first
+func (t *FeeTracker) Samples(chainID uint64) []FeeSample {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]FeeSample(nil), t.samples[chainID]...)
+}
+
+// ChainIDs returns the chains with recorded samples, in ascending order
+func (t *FeeTracker) ChainIDs() []uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ids := make([]uint64, 0, len(t.samples))
+	for id := range t.samples {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Stats summarizes the recorded samples of a chain; the zero FeeStats if there are none
+func (t *FeeTracker) Stats(chainID uint64) FeeStats {
+	samples := t.Samples(chainID)
+	if len(samples) == 0 {
+		return FeeStats{}
+	}
+
+	prices := make([]*big.Int, len(samples))
+	totalCost := new(big.Int)
+	var totalGas uint64
+	stats := FeeStats{Samples: len(samples)}
+	for i, s := range samples {
+		prices[i] = s.GasPrice
+		totalCost.Add(totalCost, s.Cost())
+		totalGas += s.GasUsed
+		stats.Requests += s.Requests
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Cmp(prices[j]) < 0 })
+
+	stats.P50GasPrice = new(big.Int).Set(percentile(prices, 50))
+	stats.P95GasPrice = new(big.Int).Set(percentile(prices, 95))
+	stats.AvgGasPerRequest = totalGas / uint64(stats.Requests)
+	stats.AvgCostPerRequest = totalCost.Div(totalCost, big.NewInt(int64(stats.Requests)))
+	return stats
+}
+
+// percentile returns the nearest-rank percentile p of sorted, which must not be empty
+func percentile(sorted []*big.Int, p int) *big.Int {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// AllStats returns the stats of every chain, keyed by decimal chain ID
+func (t *FeeTracker) AllStats() map[string]FeeStats {
+	all := make(map[string]FeeStats)
+	for _, id := range t.ChainIDs() {
+		all[strconv.FormatUint(id, 10)] = t.Stats(id)
+	}
+	return all
+}
+
+// Handler serves the stats of every chain as JSON
+func (t *FeeTracker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.AllStats())
+	})
+}
+
+// PublishExpvar exposes the stats of every chain under name on /debug/vars.
+// Like expvar.Publish it panics if name is already in use.
+func (t *FeeTracker) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return t.AllStats()
+	}))
+}
+
+// WithFeeTracker makes the waiting relay methods record the cost of every mined relay
+func WithFeeTracker(tracker *FeeTracker) RelayerOption {
+	return func(r *Relayer) {
+		r.feeTracker = tracker
+	}
+}
+
+// RecordRelay adds the cost of a mined relay carrying requests to the relayer's fee tracker,
+// charges it to the sponsors of its budget and notifies its events, if any. The waiting relay
+// methods call it themselves; callers waiting with WaitForRelay call it once per mined relay,
+// with a context from ContextWithRecordRelay when they submitted it. An error means the
+// budget was not charged or the fee not tracked; the relay itself is mined.
+func (r *Relayer) RecordRelay(ctx context.Context, result RelayResult, requests BatchMetaTxRequestList) error {
+	events, eventsErr := r.executedIn(ctx, result)
+	r.notifyMined(result, requests, events, eventsErr)
+	if r.nonceTracker != nil {
+		if eventsErr != nil {
+			r.log().Warn("nonce tracker not advanced", "tx", result.Hash, "error", eventsErr)
+		}
+		r.nonceTracker.observeExecuted(events)
+	}
+	if budget := r.activeBudget(); budget != nil {
+		if err := budget.Charge(ctx, result, requests); err != nil {
+			r.log().Error("relay not charged", "tx", result.Hash, "requests", len(requests), "error", err)
+			return fmt.Errorf("failed to record relay %s: %w", result.Hash.Hex(), err)
+		}
+	}
+	if r.feeTracker == nil {
+		return nil
+	}
+	chainID, err := r.chainID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to record relay %s: %w", result.Hash.Hex(), err)
+	}
+	r.feeTracker.Record(chainID.Uint64(), result, len(requests))
+	return nil
+}
@@ -0,0 +1,99 @@
+package eip2771toolkit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Custom errors of the OpenZeppelin v5 ERC2771Forwarder, matched by RevertError
+var (
+	// ErrForwarderInvalidSigner is ERC2771ForwarderInvalidSigner: the signature does not belong to the request's from
+	ErrForwarderInvalidSigner = errors.New("forwarder: invalid signer")
+
+	// ErrForwarderExpiredRequest is ERC2771ForwarderExpiredRequest: the request deadline has passed
+	ErrForwarderExpiredRequest = errors.New("forwarder: expired request")
+
+	// ErrForwarderMismatchedValue is ERC2771ForwarderMismatchedValue: msg.value differs from the requested value
+	ErrForwarderMismatchedValue = errors.New("forwarder: mismatched value")
+
+	// ErrUntrustfulTarget is ERC2771UntrustfulTarget: the target does not trust the forwarder
+	ErrUntrustfulTarget = errors.New("forwarder: target does not trust the forwarder")
+
+	// ErrForwarderInvalidNonce is InvalidAccountNonce: the request nonce is not the signer's current one
+	ErrForwarderInvalidNonce = errors.New("forwarder: invalid account nonce")
+
+	// ErrForwarderFailedCall is FailedCall (FailedInnerCall before v5.1): the target call of execute() reverted
+	ErrForwarderFailedCall = errors.New("forwarder: inner call failed")
+)
+
+// forwarderError describes a custom error the forwarder reverts with
+type forwarderError struct {
+	signature string
+	params    []string // parameter names, in order
+	err       error    // nil for errors that are only decoded
+	related   error    // generic toolkit error the custom error also matches
+}
+
+// forwarderErrors are indexed by selector
+var forwarderErrors = map[[4]byte]forwarderError{}
+
+func init() {
+	for _, fe := range []forwarderError{
+		{"ERC2771ForwarderInvalidSigner(address,address)", []string{"signer", "from"}, ErrForwarderInvalidSigner, ErrInvalidSignature},
+		{"ERC2771ForwarderExpiredRequest(uint48)", []string{"deadline"}, ErrForwarderExpiredRequest, ErrExpiredDeadline},
+		{"ERC2771ForwarderMismatchedValue(uint256,uint256)", []string{"requestedValue", "msgValue"}, ErrForwarderMismatchedValue, nil},
+		{"ERC2771UntrustfulTarget(address,address)", []string{"target", "forwarder"}, ErrUntrustfulTarget, nil},
+		{"InvalidAccountNonce(address,uint256)", []string{"account", "currentNonce"}, ErrForwarderInvalidNonce, ErrInvalidNonce},
+		{"FailedCall()", nil, ErrForwarderFailedCall, nil},
+		{"FailedInnerCall()", nil, ErrForwarderFailedCall, nil},
+		// ERC-6093 error of OpenZeppelin tokens, the usual reason of a failed transfer
+		{"ERC20InsufficientBalance(address,uint256,uint256)", []string{"sender", "balance", "needed"}, nil, nil},
+	} {
+		var selector [4]byte
+		copy(selector[:], crypto.Keccak256([]byte(fe.signature))[:4])
+		forwarderErrors[selector] = fe
+	}
+}
+
+// decodeForwarderError fills revert with the forwarder custom error its data encodes, if any
+func decodeForwarderError(revert *RevertError) {
+	if len(revert.Data) < 4 {
+		return
+	}
+	var selector [4]byte
+	copy(selector[:], revert.Data[:4])
+	fe, ok := forwarderErrors[selector]
+	if !ok {
+		return
+	}
+
+	name := fe.signature[:strings.IndexByte(fe.signature, '(')]
+	var args []interface{}
+	if len(fe.params) > 0 {
+		types := strings.Split(fe.signature[len(name)+1:len(fe.signature)-1], ",")
+		var err error
+		if args, err = unpackArguments(types, revert.Data[4:]); err != nil {
+			return
+		}
+	}
+
+	var desc bytes.Buffer
+	desc.WriteString(name + "(")
+	for i, arg := range args {
+		if i > 0 {
+			desc.WriteString(", ")
+		}
+		fmt.Fprintf(&desc, "%s=%v", fe.params[i], arg)
+	}
+	desc.WriteString(")")
+
+	revert.Reason = desc.String()
+	revert.Name = name
+	revert.Args = args
+	revert.Err = fe.err
+	revert.related = fe.related
+}
@@ -0,0 +1,182 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Gas overhead of the OpenZeppelin v5 ERC2771Forwarder on top of the gas forwarded to the
+// targets (the MetaTx Gas fields). The figures cover the worst case of each code path: the
+// signer's nonce slot written for the first time and cold target accounts. They exclude the
+// intrinsic transaction cost and calldata, see TX_BASE_GAS and CalldataGas.
+const (
+	// TX_BASE_GAS is the intrinsic cost of every transaction
+	TX_BASE_GAS = 21000
+
+	// CALLDATA_ZERO_BYTE_GAS is the cost of a zero calldata byte
+	CALLDATA_ZERO_BYTE_GAS = 4
+
+	// CALLDATA_NONZERO_BYTE_GAS is the cost of a non-zero calldata byte
+	CALLDATA_NONZERO_BYTE_GAS = 16
+
+	// FORWARDER_EXECUTE_OVERHEAD_GAS is the cost of execute() beyond the forwarded gas:
+	// request hashing, ecrecover, nonce update, the target call and the ExecutedForwardRequest event
+	FORWARDER_EXECUTE_OVERHEAD_GAS = 36000
+
+	// FORWARDER_BATCH_BASE_GAS is the fixed cost of executeBatch(): dispatch, the request
+	// array decoding and the msg.value / refund accounting
+	FORWARDER_BATCH_BASE_GAS = 5000
+
+	// FORWARDER_BATCH_PER_REQUEST_GAS is the cost of each request inside executeBatch()
+	// beyond its forwarded gas, including the loop iteration
+	FORWARDER_BATCH_PER_REQUEST_GAS = 37000
+
+	// FORWARDER_REFUND_GAS is the extra cost of executeBatch() sending a non-zero refund to the refund receiver
+	FORWARDER_REFUND_GAS = 9000
+)
+
+// EstimateBatchOverhead returns the forwarder gas an executeBatch() of n requests needs
+// beyond the sum of their forwarded gas, excluding the transaction base cost and calldata
+func EstimateBatchOverhead(n int) uint64 {
+	if n <= 0 {
+		return 0
+	}
+	return FORWARDER_BATCH_BASE_GAS + uint64(n)*FORWARDER_BATCH_PER_REQUEST_GAS
+}
+
+// CalldataGas returns the intrinsic gas charged for data as transaction input
+func CalldataGas(data []byte) uint64 {
+	var gas uint64
+	for _, b := range data {
+		if b == 0 {
+			gas += CALLDATA_ZERO_BYTE_GAS
+		} else {
+			gas += CALLDATA_NONZERO_BYTE_GAS
+		}
+	}
+	return gas
+}
+
+// EstimateBatchGas returns an upper bound of the gas limit for relaying batch through
+// executeBatch(), given the packed calldata: base cost, calldata, forwarder overhead and
+// the forwarded gas of every request. The forwarder only passes 63/64 of the remaining gas
+// to a call (EIP-150) and reverts if a request may have received less than its Gas, so each
+// request's Gas is counted as Gas * 64/63. The bound also covers execute() of one request.
+func EstimateBatchGas(batch BatchMetaTxRequestList, data []byte) uint64 {
+	gas := TX_BASE_GAS + CalldataGas(data) + EstimateBatchOverhead(len(batch))
+	for _, req := range batch {
+		gas += forwardedGasNeeded(req.MetaTx.Gas)
+	}
+	return gas
+}
+
+// forwardedGasNeeded returns the gas that must be left before a call for the forwarder to
+// pass it gas under the 63/64 rule: ceil(gas * 64/63), so that gas/63 is still left once a
+// call used all of it, which is what the forwarder checks
+func forwardedGasNeeded(gas uint64) uint64 {
+	return (gas*64 + 62) / 63
+}
+
+// CheckRelayGasLimit returns an error wrapping ErrInsufficientGas if a relay transaction with
+// gasLimit may not be able to forward the Gas of every request, see EstimateBatchGas.
+// EstimateGas only finds the limit the requests need in the current state; a Relayer raises
+// estimated limits below the bound to EstimateBatchGas before sending.
+func CheckRelayGasLimit(gasLimit uint64, batch BatchMetaTxRequestList, data []byte) error {
+	var forwarded uint64
+	for _, req := range batch {
+		forwarded += req.MetaTx.Gas
+	}
+	if needed := EstimateBatchGas(batch, data); gasLimit < needed {
+		return fmt.Errorf("%w: gas limit %d is below the %d needed to forward %d gas to %d requests under the 63/64 rule",
+			ErrInsufficientGas, gasLimit, needed, forwarded, len(batch))
+	}
+	return nil
+}
+
+// DEFAULT_GAS_MARGIN_PERCENT is how much a Relayer adds to EstimateGas results by default
+const DEFAULT_GAS_MARGIN_PERCENT = 20
+
+// GasMargin is headroom added to estimated gas before it becomes a relay transaction's gas
+// limit. Estimates are taken against the current state and the forwarder's 63/64 rule can
+// make real usage exceed them; an unused gas limit is not charged.
+type GasMargin struct {
+	Percent uint64 // added on top of the estimate, e.g. 20 for 1.2x
+	Extra   uint64 // fixed gas added after the percentage
+}
+
+// Apply returns estimate increased by the margin
+func (m GasMargin) Apply(estimate uint64) uint64 {
+	return estimate + estimate*m.Percent/100 + m.Extra
+}
+
+// WithGasMargin sets the headroom added to estimated gas, default DEFAULT_GAS_MARGIN_PERCENT
+// percent. GasMargin{} uses estimates verbatim.
+func WithGasMargin(margin GasMargin) RelayerOption {
+	return func(r *Relayer) {
+		r.gasMargin = margin
+	}
+}
+
+// SetGasMargin replaces the relayer's gas margin, e.g. after a configuration reload
+func (r *Relayer) SetGasMargin(margin GasMargin) {
+	r.settingsMu.Lock()
+	defer r.settingsMu.Unlock()
+	r.gasMargin = margin
+}
+
+// activeGasMargin returns the margin added to gas estimates
+func (r *Relayer) activeGasMargin() GasMargin {
+	r.settingsMu.RLock()
+	defer r.settingsMu.RUnlock()
+	return r.gasMargin
+}
+
+// estimateGasLimit estimates msg and adds the relayer's gas margin. Reverts are reported as
+// a RevertError with the decoded reason.
+func (r *Relayer) estimateGasLimit(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	gas, err := r.client.EstimateGas(ctx, msg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate gas: %w", explainRevert(ctx, r.client, msg, nil, err))
+	}
+	return r.activeGasMargin().Apply(gas), nil
+}
+
+// EstimateInnerGas estimates the gas the call of metaTx uses when forwarder executes it: the
+// target is called from the forwarder with the signer appended to the calldata, as ERC-2771
+// prescribes, and the intrinsic transaction cost is subtracted. Use it, with some headroom,
+// as MetaTx.Gas instead of a fixed default that overpays or runs out of gas.
+func EstimateInnerGas(ctx context.Context, metaTx MetaTx, forwarder common.Address, client EthClient) (uint64, error) {
+	req, err := metaTx.ForwardRequest()
+	if err != nil {
+		return 0, err
+	}
+	data := append(append([]byte{}, req.Data...), metaTx.From.Bytes()...)
+
+	gas, err := client.EstimateGas(ctx, ethereum.CallMsg{
+		From:  forwarder,
+		To:    &req.To,
+		Value: req.Value,
+		Data:  data,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate inner call gas: %w", err)
+	}
+	intrinsic := TX_BASE_GAS + CalldataGas(data)
+	if gas < intrinsic {
+		return 0, nil
+	}
+	return gas - intrinsic, nil
+}
+
+// EstimateInnerGas estimates the inner call gas of metaTx through the relayer's forwarder,
+// with the relayer's gas margin added
+func (r *Relayer) EstimateInnerGas(ctx context.Context, metaTx MetaTx) (uint64, error) {
+	gas, err := EstimateInnerGas(ctx, metaTx, r.forwarder, r.client)
+	if err != nil {
+		return 0, err
+	}
+	return r.activeGasMargin().Apply(gas), nil
+}
@@ -0,0 +1,84 @@
+package eip2771toolkit
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestForwardedGasNeeded(t *testing.T) {
+	tests := []struct {
+		gas  uint64
+		want uint64
+	}{
+		{0, 0},
+		{1, 2},
+		{62, 63},
+		{63, 64},
+		{64, 66},
+		{126, 128},
+		{127, 130},
+		{100_000, 101_588},
+		{1_000_000, 1_015_874},
+		{30_000_000, 30_476_191},
+	}
+	for _, tt := range tests {
+		got := forwardedGasNeeded(tt.gas)
+		if got != tt.want {
+			t.Errorf("forwardedGasNeeded(%d) = %d, want %d", tt.gas, got, tt.want)
+		}
+	}
+
+	for _, gas := range []uint64{1, 2, 62, 63, 64, 65, 125, 126, 127, 4_031, 4_032, 99_999, 21_000_000} {
+		needed := forwardedGasNeeded(gas)
+		// With needed left, a call passing gas that uses all of it leaves the forwarder gas/63
+		if (needed-gas)*63 < gas {
+			t.Errorf("forwardedGasNeeded(%d) = %d leaves %d, below gas/63", gas, needed, needed-gas)
+		}
+		// EIP-150 lets the forwarder pass the full gas
+		if needed-needed/64 < gas {
+			t.Errorf("forwardedGasNeeded(%d) = %d forwards at most %d", gas, needed, needed-needed/64)
+		}
+		// One less is not enough: the bound is tight
+		if below := needed - 1; (below-gas)*63 >= gas {
+			t.Errorf("forwardedGasNeeded(%d) = %d is not the least sufficient gas", gas, needed)
+		}
+	}
+}
+
+func TestCheckRelayGasLimit(t *testing.T) {
+	batch := BatchMetaTxRequestList{
+		{MetaTx: MetaTx{Gas: 100_000}},
+		{MetaTx: MetaTx{Gas: 63}},
+	}
+	data := []byte{0x00, 0x01, 0x02}
+	needed := EstimateBatchGas(batch, data)
+
+	want := uint64(TX_BASE_GAS + CALLDATA_ZERO_BYTE_GAS + 2*CALLDATA_NONZERO_BYTE_GAS +
+		FORWARDER_BATCH_BASE_GAS + 2*FORWARDER_BATCH_PER_REQUEST_GAS + 101_588 + 64)
+	if needed != want {
+		t.Fatalf("EstimateBatchGas = %d, want %d", needed, want)
+	}
+
+	tests := []struct {
+		name     string
+		gasLimit uint64
+		wantErr  bool
+	}{
+		{"exact bound", needed, false},
+		{"above bound", math.MaxUint64, false},
+		{"one below bound", needed - 1, true},
+		{"zero", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckRelayGasLimit(tt.gasLimit, batch, data)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("CheckRelayGasLimit(%d) = %v, want error %v", tt.gasLimit, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInsufficientGas) {
+				t.Errorf("error %v does not wrap ErrInsufficientGas", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// Polygon Gas Station v2 endpoints
+const (
+	POLYGON_GAS_STATION_URL      = "https://gasstation.polygon.technology/v2"
+	POLYGON_AMOY_GAS_STATION_URL = "https://gasstation.polygon.technology/amoy"
+)
+
+// DEFAULT_OP_STACK_MIN_TIP is the smallest priority fee OPStackGasStrategy bids, the
+// minimum op-geth itself suggests (0.001 gwei)
+var DEFAULT_OP_STACK_MIN_TIP = big.NewInt(1_000_000)
+
+var defaultGasStationClient = &http.Client{Timeout: 10 * time.Second}
+
+// DefaultGasStrategy returns the gas oracle known to price relays correctly on a chain, or nil
+// if the node's own suggestion is fine. ChainConfig.NewRelayer uses it when no GasStrategy
+// is configured.
+func DefaultGasStrategy(chainID *big.Int) GasStrategy {
+	if chainID == nil || !chainID.IsUint64() {
+		return nil
+	}
+	switch chainID.Uint64() {
+	case 137: // Polygon PoS
+		return PolygonGasStation{URL: POLYGON_GAS_STATION_URL, Speed: GasStandard}
+	case 80002: // Polygon Amoy
+		return PolygonGasStation{URL: POLYGON_AMOY_GAS_STATION_URL, Speed: GasStandard}
+	case 10, 8453, 11155420, 84532: // OP Mainnet, Base, OP Sepolia, Base Sepolia
+		return OPStackGasStrategy{}
+	}
+	return nil
+}
+
+// FixedGasStrategy always suggests the same legacy gas price. It suits chains such as BNB
+// Smart Chain where validators accept a fixed minimum and eth_gasPrice often overshoots it.
+type FixedGasStrategy struct {
+	GasPrice *big.Int
+}
+
+// SuggestFee returns the fixed gas price
+func (s FixedGasStrategy) SuggestFee(ctx context.Context, client EthClient) (FeeBid, error) {
+	if s.GasPrice == nil || s.GasPrice.Sign() <= 0 {
+		return FeeBid{}, fmt.Errorf("fixed gas strategy has no gas price")
+	}
+	return FeeBid{GasPrice: new(big.Int).Set(s.GasPrice)}, nil
+}
+
+// PolygonGasStation suggests EIP-1559 fees from a Polygon Gas Station v2 endpoint. Polygon
+// nodes suggest tips below the network's enforced minimum, leaving transactions stuck.
+type PolygonGasStation struct {
+	URL        string       // e.g. POLYGON_GAS_STATION_URL
+	Speed      GasSpeed     // GasSlow uses safeLow, GasFast and GasInstant use fast
+	HTTPClient *http.Client // defaults to a client with a 10 second timeout
+}
+
+// polygonGasStationFee is one speed tier of a gas station response, in gwei
+type polygonGasStationFee struct {
+	MaxPriorityFee float64 `json:"maxPriorityFee"`
+	MaxFee         float64 `json:"maxFee"`
+}
+
+// polygonGasStationResponse is the body of a gas station v2 response
+type polygonGasStationResponse struct {
+	SafeLow  polygonGasStationFee `json:"safeLow"`
+	Standard polygonGasStationFee `json:"standard"`
+	Fast     polygonGasStationFee `json:"fast"`
+}
+
+// SuggestFee implements GasStrategy
+func (s PolygonGasStation) SuggestFee(ctx context.Context, client EthClient) (FeeBid, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return FeeBid{}, fmt.Errorf("failed to create gas station request: %w", err)
+	}
+	httpClient := s.HTTPClient
+	if httpClient == nil {
+		httpClient = defaultGasStationClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return FeeBid{}, fmt.Errorf("failed to query gas station: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return FeeBid{}, fmt.Errorf("gas station returned %s", resp.Status)
+	}
+
+	var body polygonGasStationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return FeeBid{}, fmt.Errorf("failed to decode gas station response: %w", err)
+	}
+	fee := body.Standard
+	switch s.Speed {
+	case GasSlow:
+		fee = body.SafeLow
+	case GasFast, GasInstant:
+		fee = body.Fast
+	}
+	if fee.MaxFee <= 0 || fee.MaxPriorityFee <= 0 {
+		return FeeBid{}, fmt.Errorf("gas station returned no fee")
+	}
+	return FeeBid{GasTipCap: gweiToWei(fee.MaxPriorityFee), GasFeeCap: gweiToWei(fee.MaxFee)}, nil
+}
+
+// gweiToWei converts a fractional gwei amount to wei, rounding up
+func gweiToWei(gwei float64) *big.Int {
+	wei := new(big.Float).Mul(big.NewFloat(gwei), big.NewFloat(1e9))
+	result, accuracy := wei.Int(nil)
+	if accuracy == big.Below {
+		result.Add(result, big.NewInt(1))
+	}
+	return result
+}
+
+// OPStackGasStrategy suggests EIP-1559 fees on OP Stack chains such as OP Mainnet and Base,
+// where eth_gasPrice includes no headroom for the base fee, which can rise quickly at two
+// second blocks. The fee cap is twice the latest base fee plus the tip. The L1 data fee is
+// charged separately and is not part of the bid.
+type OPStackGasStrategy struct {
+	MinTip *big.Int // smallest tip bid, default DEFAULT_OP_STACK_MIN_TIP
+}
+
+// SuggestFee implements GasStrategy
+func (s OPStackGasStrategy) SuggestFee(ctx context.Context, client EthClient) (FeeBid, error) {
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return FeeBid{}, fmt.Errorf("failed to get latest header: %w", err)
+	}
+	if head.BaseFee == nil {
+		return NodeGasStrategy{}.SuggestFee(ctx, client)
+	}
+
+	tip, err := client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return FeeBid{}, fmt.Errorf("failed to get gas tip cap: %w", err)
+	}
+	minTip := s.MinTip
+	if minTip == nil {
+		minTip = DEFAULT_OP_STACK_MIN_TIP
+	}
+	tip = new(big.Int).Set(maxBig(tip, minTip))
+
+	feeCap := new(big.Int).Mul(head.BaseFee, big.NewInt(2))
+	feeCap.Add(feeCap, tip)
+	return FeeBid{GasTipCap: tip, GasFeeCap: feeCap}, nil
+}
@@ -0,0 +1,154 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// DEFAULT_FEE_HISTORY_BLOCKS is the number of recent blocks FeeHistoryGasStrategy samples
+const DEFAULT_FEE_HISTORY_BLOCKS = 20
+
+// GasStrategy suggests the network fee a relay transaction should pay. The relayer passes
+// the suggestion to its BidStrategy as BidContext.SuggestedFee; the default bidder submits
+// it unchanged.
+type GasStrategy interface {
+	SuggestFee(ctx context.Context, client EthClient) (FeeBid, error)
+}
+
+// GasStrategyFunc adapts a function to the GasStrategy interface
+type GasStrategyFunc func(ctx context.Context, client EthClient) (FeeBid, error)
+
+// SuggestFee calls f(ctx, client)
+func (f GasStrategyFunc) SuggestFee(ctx context.Context, client EthClient) (FeeBid, error) {
+	return f(ctx, client)
+}
+
+// NodeGasStrategy suggests the node's legacy gas price. It is the Relayer default.
+type NodeGasStrategy struct{}
+
+// SuggestFee returns eth_gasPrice
+func (NodeGasStrategy) SuggestFee(ctx context.Context, client EthClient) (FeeBid, error) {
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return FeeBid{}, fmt.Errorf("failed to get gas price: %w", err)
+	}
+	return FeeBid{GasPrice: gasPrice}, nil
+}
+
+// GasSpeed trades fee against inclusion speed
+type GasSpeed int
+
+const (
+	GasSlow GasSpeed = iota
+	GasStandard
+	GasFast
+	GasInstant
+)
+
+// percentile returns the priority fee percentile of recent blocks the speed bids
+func (s GasSpeed) percentile() float64 {
+	switch s {
+	case GasSlow:
+		return 10
+	case GasFast:
+		return 75
+	case GasInstant:
+		return 95
+	}
+	return 50
+}
+
+// legacyPercent returns how the speed scales the node gas price on chains without a base fee
+func (s GasSpeed) legacyPercent() int64 {
+	switch s {
+	case GasSlow:
+		return 90
+	case GasFast:
+		return 125
+	case GasInstant:
+		return 150
+	}
+	return 100
+}
+
+// FeeHistoryGasStrategy bids the priority fee paid at the Speed's percentile (10th, 50th,
+// 75th or 95th) of recent blocks, per eth_feeHistory, with a fee cap leaving room for the
+// base fee to double. On chains without a base fee it scales the node gas price instead.
+type FeeHistoryGasStrategy struct {
+	Speed  GasSpeed
+	Blocks uint64 // blocks sampled, default DEFAULT_FEE_HISTORY_BLOCKS
+}
+
+// SuggestFee implements GasStrategy
+func (s FeeHistoryGasStrategy) SuggestFee(ctx context.Context, client EthClient) (FeeBid, error) {
+	blocks := s.Blocks
+	if blocks == 0 {
+		blocks = DEFAULT_FEE_HISTORY_BLOCKS
+	}
+
+	history, err := client.FeeHistory(ctx, blocks, nil, []float64{s.Speed.percentile()})
+	if err != nil {
+		return FeeBid{}, fmt.Errorf("failed to get fee history: %w", err)
+	}
+
+	// The last base fee is the one of the next block
+	var nextBaseFee *big.Int
+	if n := len(history.BaseFee); n > 0 {
+		nextBaseFee = history.BaseFee[n-1]
+	}
+	if nextBaseFee == nil || nextBaseFee.Sign() == 0 {
+		gasPrice, err := client.SuggestGasPrice(ctx)
+		if err != nil {
+			return FeeBid{}, fmt.Errorf("failed to get gas price: %w", err)
+		}
+		gasPrice.Mul(gasPrice, big.NewInt(s.Speed.legacyPercent()))
+		return FeeBid{GasPrice: gasPrice.Div(gasPrice, big.NewInt(100))}, nil
+	}
+
+	// Median of the per-block percentiles; empty blocks report zero and are skipped
+	var rewards []*big.Int
+	for _, reward := range history.Reward {
+		if len(reward) > 0 && reward[0] != nil && reward[0].Sign() > 0 {
+			rewards = append(rewards, reward[0])
+		}
+	}
+	var tip *big.Int
+	if len(rewards) > 0 {
+		sort.Slice(rewards, func(i, j int) bool { return rewards[i].Cmp(rewards[j]) < 0 })
+		tip = new(big.Int).Set(rewards[len(rewards)/2])
+	} else {
+		tip, err = client.SuggestGasTipCap(ctx)
+		if err != nil {
+			return FeeBid{}, fmt.Errorf("failed to get gas tip cap: %w", err)
+		}
+	}
+
+	feeCap := new(big.Int).Mul(nextBaseFee, big.NewInt(2))
+	feeCap.Add(feeCap, tip)
+	return FeeBid{GasTipCap: tip, GasFeeCap: feeCap}, nil
+}
+
+// WithGasStrategy sets how the relayer determines the network fee it bids from
+func WithGasStrategy(strategy GasStrategy) RelayerOption {
+	return func(r *Relayer) {
+		r.gas = strategy
+	}
+}
+
+// suggestFee asks the relayer's gas strategy for a fee and returns it with its legacy gas
+// price equivalent, the most the transaction may pay per gas
+func (r *Relayer) suggestFee(ctx context.Context) (FeeBid, *big.Int, error) {
+	fee, err := r.gas.SuggestFee(ctx, r.client)
+	if err != nil {
+		return FeeBid{}, nil, err
+	}
+	if fee.IsDynamic() {
+		return fee, fee.GasFeeCap, nil
+	}
+	if fee.GasPrice == nil {
+		return FeeBid{}, nil, fmt.Errorf("gas strategy suggested neither a gas price nor EIP-1559 fee caps")
+	}
+	return fee, fee.GasPrice, nil
+}
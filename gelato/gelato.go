@@ -0,0 +1,245 @@
+// Package gelato implements an eip2771toolkit.RelayBackend on top of the Gelato Relay HTTP API.
+// Meta transactions are still signed locally; Gelato only submits the forwarder call.
+package gelato
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// DEFAULT_BASE_URL is the public Gelato Relay API endpoint
+const DEFAULT_BASE_URL = "https://api.gelato.digital"
+
+// Mode selects the Gelato Relay payment method
+type Mode int
+
+const (
+	// SponsoredCall pays relay fees from the sponsor's Gelato balance (requires an API key)
+	SponsoredCall Mode = iota
+	// CallWithSyncFee pays relay fees from the target contract during execution; the
+	// forwarder must be a Gelato relay-context aware contract
+	CallWithSyncFee
+)
+
+// Task states reported by the Gelato task status API
+const (
+	TaskCheckPending           = "CheckPending"
+	TaskExecPending            = "ExecPending"
+	TaskWaitingForConfirmation = "WaitingForConfirmation"
+	TaskExecSuccess            = "ExecSuccess"
+	TaskExecReverted           = "ExecReverted"
+	TaskCancelled              = "Cancelled"
+	TaskBlacklisted            = "Blacklisted"
+	TaskNotFound               = "NotFound"
+)
+
+// Backend submits forwarder calls to Gelato Relay
+type Backend struct {
+	BaseURL    string
+	APIKey     string         // sponsor API key, required for SponsoredCall
+	Mode       Mode           // payment method
+	FeeToken   common.Address // fee token for CallWithSyncFee, zero for the native token
+	GasLimit   uint64         // optional gas limit override, 0 lets Gelato estimate
+	ChainID    *big.Int
+	Forwarder  common.Address
+	Profile    *eip2771toolkit.ForwarderProfile
+	HTTPClient *http.Client
+}
+
+// NewBackend creates a sponsored-call Backend for the OpenZeppelin forwarder at forwarder on chainID
+func NewBackend(apiKey string, chainID *big.Int, forwarder common.Address) *Backend {
+	return &Backend{
+		BaseURL:    DEFAULT_BASE_URL,
+		APIKey:     apiKey,
+		Mode:       SponsoredCall,
+		ChainID:    chainID,
+		Forwarder:  forwarder,
+		Profile:    eip2771toolkit.OZForwarderV5Profile,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// relayRequest is the body of the sponsored-call and call-with-sync-fee endpoints
+type relayRequest struct {
+	ChainID        string  `json:"chainId"`
+	Target         string  `json:"target"`
+	Data           string  `json:"data"`
+	SponsorAPIKey  string  `json:"sponsorApiKey,omitempty"`
+	FeeToken       string  `json:"feeToken,omitempty"`
+	IsRelayContext *bool   `json:"isRelayContext,omitempty"`
+	GasLimit       *string `json:"gasLimit,omitempty"`
+}
+
+// relayResponse is the response of the relay endpoints
+type relayResponse struct {
+	TaskID  string `json:"taskId"`
+	Message string `json:"message"`
+}
+
+// statusResponse is the response of the task status endpoint
+type statusResponse struct {
+	Task struct {
+		TaskID           string `json:"taskId"`
+		TaskState        string `json:"taskState"`
+		TransactionHash  string `json:"transactionHash"`
+		BlockNumber      uint64 `json:"blockNumber"`
+		LastCheckMessage string `json:"lastCheckMessage"`
+	} `json:"task"`
+	Message string `json:"message"`
+}
+
+// SubmitMetaTx packs the forwarder execute call and hands it to Gelato, returning the Gelato task ID
+func (b *Backend) SubmitMetaTx(ctx context.Context, metaTx eip2771toolkit.MetaTx, sig eip2771toolkit.Signature) (string, error) {
+	if b.ChainID == nil {
+		return "", fmt.Errorf("gelato backend requires a chain ID")
+	}
+	if b.Forwarder == (common.Address{}) {
+		return "", eip2771toolkit.ErrZeroAddress
+	}
+
+	// Some forwarders take the domain separator as an execute argument
+	domainSeparator, err := b.Profile.DomainSeparator(b.ChainID, b.Forwarder)
+	if err != nil {
+		return "", fmt.Errorf("failed to build domain separator: %w", err)
+	}
+
+	data, err := eip2771toolkit.PackExecuteCalldataWithProfile(b.Profile, metaTx, sig, domainSeparator)
+	if err != nil {
+		return "", err
+	}
+
+	return b.SubmitCall(ctx, data)
+}
+
+// SubmitCall relays arbitrary calldata to the forwarder and returns the Gelato task ID
+func (b *Backend) SubmitCall(ctx context.Context, data []byte) (string, error) {
+	req := relayRequest{
+		ChainID: b.ChainID.String(),
+		Target:  b.Forwarder.Hex(),
+		Data:    hexutil.Encode(data),
+	}
+	if b.GasLimit != 0 {
+		gasLimit := fmt.Sprintf("%d", b.GasLimit)
+		req.GasLimit = &gasLimit
+	}
+
+	var path string
+	switch b.Mode {
+	case SponsoredCall:
+		if b.APIKey == "" {
+			return "", fmt.Errorf("gelato sponsored call requires an API key")
+		}
+		path = "/relays/v2/sponsored-call"
+		req.SponsorAPIKey = b.APIKey
+	case CallWithSyncFee:
+		path = "/relays/v2/call-with-sync-fee"
+		feeToken := b.FeeToken
+		if feeToken == (common.Address{}) {
+			feeToken = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE") // Gelato's native token marker
+		}
+		req.FeeToken = feeToken.Hex()
+		isRelayContext := true
+		req.IsRelayContext = &isRelayContext
+	default:
+		return "", fmt.Errorf("unknown gelato relay mode %d", b.Mode)
+	}
+
+	var resp relayResponse
+	if err := b.do(ctx, http.MethodPost, path, req, &resp); err != nil {
+		return "", err
+	}
+	if resp.TaskID == "" {
+		return "", fmt.Errorf("gelato returned no task ID: %s", resp.Message)
+	}
+	return resp.TaskID, nil
+}
+
+// TaskStatus fetches the Gelato task status and maps it onto the toolkit's task states
+func (b *Backend) TaskStatus(ctx context.Context, taskID string) (eip2771toolkit.RelayTaskStatus, error) {
+	var resp statusResponse
+	if err := b.do(ctx, http.MethodGet, "/tasks/status/"+url.PathEscape(taskID), nil, &resp); err != nil {
+		return eip2771toolkit.RelayTaskStatus{}, err
+	}
+
+	status := eip2771toolkit.RelayTaskStatus{
+		TaskID:      taskID,
+		State:       mapTaskState(resp.Task.TaskState),
+		BlockNumber: resp.Task.BlockNumber,
+		Message:     resp.Task.LastCheckMessage,
+	}
+	if resp.Task.TransactionHash != "" {
+		status.TxHash = common.HexToHash(resp.Task.TransactionHash)
+	}
+	return status, nil
+}
+
+// mapTaskState converts a Gelato task state to a RelayTaskState
+func mapTaskState(state string) eip2771toolkit.RelayTaskState {
+	switch state {
+	case TaskExecSuccess:
+		return eip2771toolkit.RelayTaskSucceeded
+	case TaskExecReverted:
+		return eip2771toolkit.RelayTaskReverted
+	case TaskCancelled, TaskBlacklisted:
+		return eip2771toolkit.RelayTaskCancelled
+	default:
+		// CheckPending, ExecPending, WaitingForConfirmation and NotFound (not indexed yet)
+		return eip2771toolkit.RelayTaskPending
+	}
+}
+
+// do sends a JSON request to the Gelato API and decodes the JSON response into out
+func (b *Backend) do(ctx context.Context, method, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode gelato request: %w", err)
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.BaseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create gelato request: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := b.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call gelato: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read gelato response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("gelato returned %s: %s", resp.Status, bytes.TrimSpace(respBody))
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode gelato response: %w", err)
+	}
+	return nil
+}
+
+var _ eip2771toolkit.RelayBackend = (*Backend)(nil)
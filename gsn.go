@@ -0,0 +1,249 @@
+package eip2771toolkit
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// GSN_DOMAIN_NAME is the EIP-712 domain name used by the GSN v3 Forwarder
+	GSN_DOMAIN_NAME = "GSN Relayed Transaction"
+
+	// GSN_DOMAIN_VERSION is the EIP-712 domain version used by the GSN v3 Forwarder
+	GSN_DOMAIN_VERSION = "3"
+
+	// GSN_RELAY_DATA_TYPE is the EIP-712 type of the GSN v3 RelayData struct
+	GSN_RELAY_DATA_TYPE = "RelayData(uint256 maxFeePerGas,uint256 maxPriorityFeePerGas,uint256 transactionCalldataGasUsed,address relayWorker,address paymaster,address forwarder,bytes paymasterData,uint256 clientId)"
+
+	// GSN_RELAY_REQUEST_TYPEHASH is the EIP-712 type of the GSN v3 RelayRequest struct, including its RelayData dependency
+	GSN_RELAY_REQUEST_TYPEHASH = "RelayRequest(address from,address to,uint256 value,uint256 gas,uint256 nonce,bytes data,uint256 validUntilTime,RelayData relayData)" + GSN_RELAY_DATA_TYPE
+)
+
+// GSNForwardRequest is the IForwarder.ForwardRequest part of a GSN v3 relay request
+type GSNForwardRequest struct {
+	From           common.Address
+	To             common.Address
+	Value          *big.Int
+	Gas            *big.Int
+	Nonce          *big.Int
+	Data           []byte
+	ValidUntilTime *big.Int
+}
+
+// GSNRelayData carries the relay fee and routing parameters of a GSN v3 relay request
+type GSNRelayData struct {
+	MaxFeePerGas               *big.Int
+	MaxPriorityFeePerGas       *big.Int
+	TransactionCalldataGasUsed *big.Int
+	RelayWorker                common.Address
+	Paymaster                  common.Address
+	Forwarder                  common.Address
+	PaymasterData              []byte
+	ClientID                   *big.Int
+}
+
+// GSNRelayRequest is the full envelope submitted to a GSN v3 relay server
+type GSNRelayRequest struct {
+	Request   GSNForwardRequest
+	RelayData GSNRelayData
+}
+
+// NewGSNRelayRequest builds a GSN v3 relay request carrying the ERC20 transfer described by metaTx.
+// The MetaTx deadline becomes the request's validUntilTime.
+func NewGSNRelayRequest(metaTx MetaTx, relayData GSNRelayData) (GSNRelayRequest, error) {
+	if err := validateMetaTx(metaTx); err != nil {
+		return GSNRelayRequest{}, fmt.Errorf("invalid MetaTx: %w", err)
+	}
+
+	transferData, err := metaTx.TransferData()
+	if err != nil {
+		return GSNRelayRequest{}, fmt.Errorf("failed to prepare transfer data: %w", err)
+	}
+
+	return GSNRelayRequest{
+		Request: GSNForwardRequest{
+			From:           metaTx.From,
+			To:             metaTx.Token,
+			Value:          big.NewInt(0),
+			Gas:            new(big.Int).SetUint64(metaTx.Gas),
+			Nonce:          new(big.Int).SetUint64(metaTx.Nonce),
+			Data:           transferData,
+			ValidUntilTime: new(big.Int).SetUint64(metaTx.Deadline),
+		},
+		RelayData: relayData,
+	}, nil
+}
+
+// BuildGSNDomainSeparator creates the EIP-712 domain separator of a GSN v3 Forwarder
+func BuildGSNDomainSeparator(chainId *big.Int, forwarder common.Address) []byte {
+	data := make([]byte, 0, 32*5)
+	data = append(data, crypto.Keccak256([]byte(EIP712_DOMAIN_TYPEHASH))...)
+	data = append(data, crypto.Keccak256([]byte(GSN_DOMAIN_NAME))...)
+	data = append(data, crypto.Keccak256([]byte(GSN_DOMAIN_VERSION))...)
+	data = append(data, encodeUintWord(chainId)...)
+	data = append(data, encodeAddressWord(forwarder)...)
+	return crypto.Keccak256(data)
+}
+
+// hashGSNRelayData computes the EIP-712 struct hash of the RelayData
+func hashGSNRelayData(rd GSNRelayData) []byte {
+	data := make([]byte, 0, 32*9)
+	data = append(data, crypto.Keccak256([]byte(GSN_RELAY_DATA_TYPE))...)
+	data = append(data, encodeUintWord(rd.MaxFeePerGas)...)
+	data = append(data, encodeUintWord(rd.MaxPriorityFeePerGas)...)
+	data = append(data, encodeUintWord(rd.TransactionCalldataGasUsed)...)
+	data = append(data, encodeAddressWord(rd.RelayWorker)...)
+	data = append(data, encodeAddressWord(rd.Paymaster)...)
+	data = append(data, encodeAddressWord(rd.Forwarder)...)
+	data = append(data, crypto.Keccak256(rd.PaymasterData)...)
+	data = append(data, encodeUintWord(rd.ClientID)...)
+	return crypto.Keccak256(data)
+}
+
+// HashGSNRelayRequest generates the EIP-712 digest the GSN v3 Forwarder verifies
+func HashGSNRelayRequest(req GSNRelayRequest, domainSeparator []byte) []byte {
+	r := req.Request
+
+	data := make([]byte, 0, 32*9)
+	data = append(data, crypto.Keccak256([]byte(GSN_RELAY_REQUEST_TYPEHASH))...)
+	data = append(data, encodeAddressWord(r.From)...)
+	data = append(data, encodeAddressWord(r.To)...)
+	data = append(data, encodeUintWord(r.Value)...)
+	data = append(data, encodeUintWord(r.Gas)...)
+	data = append(data, encodeUintWord(r.Nonce)...)
+	data = append(data, crypto.Keccak256(r.Data)...)
+	data = append(data, encodeUintWord(r.ValidUntilTime)...)
+	data = append(data, hashGSNRelayData(req.RelayData)...)
+
+	return typedDataDigest(domainSeparator, crypto.Keccak256(data))
+}
+
+// SignGSNRelayRequest signs a GSN v3 relay request using EIP-712
+func SignGSNRelayRequest(req GSNRelayRequest, userPrivKey *ecdsa.PrivateKey, domainSeparator []byte) (Signature, error) {
+	return signDigest(HashGSNRelayRequest(req, domainSeparator), userPrivKey)
+}
+
+// VerifyGSNRelayRequestSignature checks that sig was produced by the request's from address
+func VerifyGSNRelayRequestSignature(req GSNRelayRequest, sig Signature, domainSeparator []byte) (bool, error) {
+	signer, err := recoverSigner(HashGSNRelayRequest(req, domainSeparator), sig)
+	if err != nil {
+		return false, err
+	}
+	return signer == req.Request.From, nil
+}
+
+// gsnForwardRequestJSON mirrors the field names and decimal string encoding used by GSN relay servers
+type gsnForwardRequestJSON struct {
+	From           common.Address `json:"from"`
+	To             common.Address `json:"to"`
+	Value          string         `json:"value"`
+	Gas            string         `json:"gas"`
+	Nonce          string         `json:"nonce"`
+	Data           hexutil.Bytes  `json:"data"`
+	ValidUntilTime string         `json:"validUntilTime"`
+}
+
+type gsnRelayDataJSON struct {
+	MaxFeePerGas               string         `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas       string         `json:"maxPriorityFeePerGas"`
+	TransactionCalldataGasUsed string         `json:"transactionCalldataGasUsed"`
+	RelayWorker                common.Address `json:"relayWorker"`
+	Paymaster                  common.Address `json:"paymaster"`
+	Forwarder                  common.Address `json:"forwarder"`
+	PaymasterData              hexutil.Bytes  `json:"paymasterData"`
+	ClientID                   string         `json:"clientId"`
+}
+
+type gsnRelayRequestJSON struct {
+	Request   gsnForwardRequestJSON `json:"request"`
+	RelayData gsnRelayDataJSON      `json:"relayData"`
+}
+
+// MarshalJSON encodes the request in the shape expected by GSN relay servers
+func (req GSNRelayRequest) MarshalJSON() ([]byte, error) {
+	r, rd := req.Request, req.RelayData
+	return json.Marshal(gsnRelayRequestJSON{
+		Request: gsnForwardRequestJSON{
+			From:           r.From,
+			To:             r.To,
+			Value:          decimalString(r.Value),
+			Gas:            decimalString(r.Gas),
+			Nonce:          decimalString(r.Nonce),
+			Data:           r.Data,
+			ValidUntilTime: decimalString(r.ValidUntilTime),
+		},
+		RelayData: gsnRelayDataJSON{
+			MaxFeePerGas:               decimalString(rd.MaxFeePerGas),
+			MaxPriorityFeePerGas:       decimalString(rd.MaxPriorityFeePerGas),
+			TransactionCalldataGasUsed: decimalString(rd.TransactionCalldataGasUsed),
+			RelayWorker:                rd.RelayWorker,
+			Paymaster:                  rd.Paymaster,
+			Forwarder:                  rd.Forwarder,
+			PaymasterData:              rd.PaymasterData,
+			ClientID:                   decimalString(rd.ClientID),
+		},
+	})
+}
+
+// UnmarshalJSON decodes a request in the GSN relay server format
+func (req *GSNRelayRequest) UnmarshalJSON(input []byte) error {
+	var dec gsnRelayRequestJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+
+	var err error
+	parse := func(field, s string) *big.Int {
+		if err != nil {
+			return nil
+		}
+		v, ok := new(big.Int).SetString(s, 0)
+		if !ok {
+			err = fmt.Errorf("invalid %s: %q", field, s)
+		}
+		return v
+	}
+
+	r, rd := dec.Request, dec.RelayData
+	out := GSNRelayRequest{
+		Request: GSNForwardRequest{
+			From:           r.From,
+			To:             r.To,
+			Value:          parse("value", r.Value),
+			Gas:            parse("gas", r.Gas),
+			Nonce:          parse("nonce", r.Nonce),
+			Data:           r.Data,
+			ValidUntilTime: parse("validUntilTime", r.ValidUntilTime),
+		},
+		RelayData: GSNRelayData{
+			MaxFeePerGas:               parse("maxFeePerGas", rd.MaxFeePerGas),
+			MaxPriorityFeePerGas:       parse("maxPriorityFeePerGas", rd.MaxPriorityFeePerGas),
+			TransactionCalldataGasUsed: parse("transactionCalldataGasUsed", rd.TransactionCalldataGasUsed),
+			RelayWorker:                rd.RelayWorker,
+			Paymaster:                  rd.Paymaster,
+			Forwarder:                  rd.Forwarder,
+			PaymasterData:              rd.PaymasterData,
+			ClientID:                   parse("clientId", rd.ClientID),
+		},
+	}
+	if err != nil {
+		return err
+	}
+
+	*req = out
+	return nil
+}
+
+// decimalString formats a possibly nil integer as a base-10 string
+func decimalString(v *big.Int) string {
+	if v == nil {
+		return "0"
+	}
+	return v.String()
+}
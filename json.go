@@ -0,0 +1,174 @@
+package eip2771toolkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// metaTxJSON is the encoded form of MetaTx. Amount and nonce are decimal strings since
+// random nonces and token amounts exceed the integers JavaScript represents exactly.
+type metaTxJSON struct {
+	From     common.Address `json:"from"`
+	To       common.Address `json:"to"`
+	Token    common.Address `json:"token"`
+	Amount   string         `json:"amount"`
+	Gas      uint64         `json:"gas"`
+	Nonce    string         `json:"nonce"`
+	Deadline uint64         `json:"deadline"`
+}
+
+// metaTxInputJSON is the decoded form of MetaTx, accepting numbers and decimal or hex strings
+type metaTxInputJSON struct {
+	From     common.Address `json:"from"`
+	To       common.Address `json:"to"`
+	Token    common.Address `json:"token"`
+	Amount   *jsonQuantity  `json:"amount"`
+	Gas      *jsonQuantity  `json:"gas"`
+	Nonce    *jsonQuantity  `json:"nonce"`
+	Deadline *jsonQuantity  `json:"deadline"`
+}
+
+// MarshalJSON encodes the request with 0x-hex addresses, the amount and nonce as decimal
+// strings and gas and deadline as numbers
+func (m MetaTx) MarshalJSON() ([]byte, error) {
+	return json.Marshal(metaTxJSON{
+		From:     m.From,
+		To:       m.To,
+		Token:    m.Token,
+		Amount:   decimalString(m.Amount),
+		Gas:      m.Gas,
+		Nonce:    strconv.FormatUint(m.Nonce, 10),
+		Deadline: m.Deadline,
+	})
+}
+
+// UnmarshalJSON decodes a request whose amount, gas, nonce and deadline are numbers or
+// decimal or 0x-hex strings. Amounts that are not a uint256 are refused with ErrInvalidAmount.
+func (m *MetaTx) UnmarshalJSON(input []byte) error {
+	var dec metaTxInputJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+
+	out := MetaTx{From: dec.From, To: dec.To, Token: dec.Token}
+	if dec.Amount != nil {
+		out.Amount = dec.Amount.Int()
+		if out.Amount.Sign() < 0 || out.Amount.Cmp(maxUint256) > 0 {
+			return fmt.Errorf("%w: %s is not a uint256", ErrInvalidAmount, out.Amount)
+		}
+	}
+	var err error
+	if out.Gas, err = dec.Gas.Uint64("gas"); err != nil {
+		return err
+	}
+	if out.Nonce, err = dec.Nonce.Uint64("nonce"); err != nil {
+		return err
+	}
+	if out.Deadline, err = dec.Deadline.Uint64("deadline"); err != nil {
+		return err
+	}
+
+	*m = out
+	return nil
+}
+
+// signatureInputJSON is the {v, r, s} form of a Signature
+type signatureInputJSON struct {
+	V *jsonQuantity `json:"v"`
+	R signatureWord `json:"r"`
+	S signatureWord `json:"s"`
+}
+
+// MarshalJSON encodes the signature as its 65-byte r || s || v hex string
+func (s Signature) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hexutil.Encode(s.ToBytes()))
+}
+
+// UnmarshalJSON decodes a 65-byte r || s || v hex string, or an object with v and hex r and s
+// as split by wallet libraries
+func (s *Signature) UnmarshalJSON(input []byte) error {
+	if len(input) > 0 && input[0] == '"' {
+		var raw hexutil.Bytes
+		if err := json.Unmarshal(input, &raw); err != nil {
+			return fmt.Errorf("invalid signature: %w", err)
+		}
+		return s.FromBytes(raw)
+	}
+
+	var dec signatureInputJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	v, err := dec.V.Uint64("v")
+	if err != nil {
+		return err
+	}
+	if v > 255 {
+		return fmt.Errorf("invalid v: %d", v)
+	}
+	*s = Signature{V: byte(v), R: dec.R, S: dec.S}
+	return nil
+}
+
+// signatureWord is a signature's r or s, decoded from a hex string or a byte array
+type signatureWord [32]byte
+
+// UnmarshalJSON implements json.Unmarshaler
+func (w *signatureWord) UnmarshalJSON(input []byte) error {
+	if len(input) > 0 && input[0] == '"' {
+		var raw hexutil.Bytes
+		if err := json.Unmarshal(input, &raw); err != nil {
+			return err
+		}
+		if len(raw) != 32 {
+			return fmt.Errorf("invalid signature word length %d, expected 32 bytes", len(raw))
+		}
+		copy(w[:], raw)
+		return nil
+	}
+	return json.Unmarshal(input, (*[32]byte)(w))
+}
+
+// jsonQuantity is an integer encoded as a JSON number or a decimal or 0x-hex string
+type jsonQuantity big.Int
+
+// UnmarshalJSON implements json.Unmarshaler
+func (q *jsonQuantity) UnmarshalJSON(input []byte) error {
+	text := string(input)
+	base := 10
+	if len(input) > 0 && input[0] == '"' {
+		if err := json.Unmarshal(input, &text); err != nil {
+			return err
+		}
+		base = 0
+	} else if bytes.ContainsAny(input, ".eE") {
+		return fmt.Errorf("invalid integer %s", text)
+	}
+	if _, ok := (*big.Int)(q).SetString(text, base); !ok {
+		return fmt.Errorf("invalid integer %s", input)
+	}
+	return nil
+}
+
+// Int returns the quantity as a big.Int
+func (q *jsonQuantity) Int() *big.Int {
+	return new(big.Int).Set((*big.Int)(q))
+}
+
+// Uint64 returns the quantity as a uint64, zero if it is missing
+func (q *jsonQuantity) Uint64(field string) (uint64, error) {
+	if q == nil {
+		return 0, nil
+	}
+	v := (*big.Int)(q)
+	if v.Sign() < 0 || !v.IsUint64() {
+		return 0, fmt.Errorf("invalid %s: %s out of range", field, v)
+	}
+	return v.Uint64(), nil
+}
@@ -0,0 +1,46 @@
+package eip2771toolkit
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestMetaTxUnmarshalJSONAmount(t *testing.T) {
+	overflow := new(big.Int).Add(maxUint256, big.NewInt(1))
+	tests := []struct {
+		amount string // JSON value of the amount
+		want   string // decoded amount, or "" for ErrInvalidAmount
+	}{
+		{`"1500000"`, "1500000"},
+		{`1500000`, "1500000"},
+		{`"0x10"`, "16"},
+		{`"` + maxUint256.String() + `"`, maxUint256.String()},
+		{maxUint256.String(), maxUint256.String()},
+		{`"0x` + maxUint256.Text(16) + `"`, maxUint256.String()},
+		{`"` + overflow.String() + `"`, ""},
+		{overflow.String(), ""},
+		{`"0x` + overflow.Text(16) + `"`, ""},
+		{`"` + new(big.Int).Lsh(big.NewInt(1), 300).String() + `"`, ""},
+		{`"-1"`, ""},
+		{`-1`, ""},
+	}
+	for _, tt := range tests {
+		var metaTx MetaTx
+		err := json.Unmarshal([]byte(`{"amount":`+tt.amount+`,"gas":100000,"nonce":"0","deadline":1}`), &metaTx)
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("amount %s: got %v, want ErrInvalidAmount", tt.amount, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("amount %s: %v", tt.amount, err)
+			continue
+		}
+		if metaTx.Amount.String() != tt.want {
+			t.Errorf("amount %s decoded as %s, want %s", tt.amount, metaTx.Amount, tt.want)
+		}
+	}
+}
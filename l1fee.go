@@ -0,0 +1,308 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	// OP_GAS_PRICE_ORACLE is the GasPriceOracle predeploy of OP Stack chains
+	OP_GAS_PRICE_ORACLE = common.HexToAddress("0x420000000000000000000000000000000000000F")
+
+	// ARBITRUM_NODE_INTERFACE is the NodeInterface virtual contract of Arbitrum chains
+	ARBITRUM_NODE_INTERFACE = common.HexToAddress("0x00000000000000000000000000000000000000C8")
+)
+
+var (
+	getL1FeeSelector               = crypto.Keccak256([]byte("getL1Fee(bytes)"))[:4]
+	gasEstimateL1ComponentSelector = crypto.Keccak256([]byte("gasEstimateL1Component(address,bool,bytes)"))[:4]
+)
+
+// L1DataFee is what a rollup charges for posting a transaction to L1
+type L1DataFee struct {
+	Fee *big.Int // wei
+
+	// GasUnits is the part of the transaction's gas limit paying Fee. Arbitrum charges the
+	// data fee as extra L2 gas, already covered by EstimateGas; OP Stack chains charge it on
+	// top of the gas and leave this zero.
+	GasUnits uint64
+}
+
+// L1FeeEstimator estimates the L1 data fee of a transaction on a rollup
+type L1FeeEstimator interface {
+	// EstimateL1Fee returns the data fee of tx at block, nil for the latest block. The
+	// transaction need not be signed.
+	EstimateL1Fee(ctx context.Context, client EthClient, tx *types.Transaction, block *big.Int) (L1DataFee, error)
+}
+
+// DefaultL1FeeEstimator returns the L1 fee estimator of known rollups, or nil for chains
+// without an L1 data fee
+func DefaultL1FeeEstimator(chainID *big.Int) L1FeeEstimator {
+	if chainID == nil || !chainID.IsUint64() {
+		return nil
+	}
+	switch chainID.Uint64() {
+	case 10, 8453, 11155420, 84532: // OP Mainnet, Base, OP Sepolia, Base Sepolia
+		return OPStackL1Fee{}
+	case 42161, 42170, 421614: // Arbitrum One, Arbitrum Nova, Arbitrum Sepolia
+		return ArbitrumL1Fee{}
+	}
+	return nil
+}
+
+// OPStackL1Fee asks the GasPriceOracle predeploy for the L1 data fee of a transaction
+type OPStackL1Fee struct{}
+
+// EstimateL1Fee implements L1FeeEstimator
+func (OPStackL1Fee) EstimateL1Fee(ctx context.Context, client EthClient, tx *types.Transaction, block *big.Int) (L1DataFee, error) {
+	unsigned, err := unsignedTxBytes(tx)
+	if err != nil {
+		return L1DataFee{}, err
+	}
+	args, err := packArguments([]string{"bytes"}, unsigned)
+	if err != nil {
+		return L1DataFee{}, err
+	}
+
+	out, err := client.CallContract(ctx, ethereum.CallMsg{
+		To:   &OP_GAS_PRICE_ORACLE,
+		Data: append(append([]byte{}, getL1FeeSelector...), args...),
+	}, block)
+	if err != nil {
+		return L1DataFee{}, fmt.Errorf("failed to call GasPriceOracle.getL1Fee: %w", err)
+	}
+	if len(out) < 32 {
+		return L1DataFee{}, fmt.Errorf("unexpected GasPriceOracle.getL1Fee result length %d", len(out))
+	}
+	return L1DataFee{Fee: new(big.Int).SetBytes(out[:32])}, nil
+}
+
+// ArbitrumL1Fee asks the NodeInterface for the L1 component of a transaction's gas
+type ArbitrumL1Fee struct{}
+
+// EstimateL1Fee implements L1FeeEstimator
+func (ArbitrumL1Fee) EstimateL1Fee(ctx context.Context, client EthClient, tx *types.Transaction, block *big.Int) (L1DataFee, error) {
+	var to common.Address
+	if tx.To() != nil {
+		to = *tx.To()
+	}
+	args, err := packArguments([]string{"address", "bool", "bytes"}, to, tx.To() == nil, tx.Data())
+	if err != nil {
+		return L1DataFee{}, err
+	}
+
+	out, err := client.CallContract(ctx, ethereum.CallMsg{
+		To:   &ARBITRUM_NODE_INTERFACE,
+		Data: append(append([]byte{}, gasEstimateL1ComponentSelector...), args...),
+	}, block)
+	if err != nil {
+		return L1DataFee{}, fmt.Errorf("failed to call NodeInterface.gasEstimateL1Component: %w", err)
+	}
+	if len(out) < 64 {
+		return L1DataFee{}, fmt.Errorf("unexpected NodeInterface.gasEstimateL1Component result length %d", len(out))
+	}
+
+	// Returns (uint64 gasEstimateForL1, uint256 baseFee, uint256 l1BaseFeeEstimate)
+	gasForL1 := new(big.Int).SetBytes(out[:32])
+	baseFee := new(big.Int).SetBytes(out[32:64])
+	return L1DataFee{Fee: new(big.Int).Mul(gasForL1, baseFee), GasUnits: gasForL1.Uint64()}, nil
+}
+
+// newArguments builds unnamed ABI arguments of the given solidity types
+func newArguments(typeNames []string) (abi.Arguments, error) {
+	args := make(abi.Arguments, len(typeNames))
+	for i, name := range typeNames {
+		typ, err := abi.NewType(name, "", nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ABI type %s: %w", name, err)
+		}
+		args[i] = abi.Argument{Type: typ}
+	}
+	return args, nil
+}
+
+// unpackArguments ABI-decodes data as the given solidity types
+func unpackArguments(typeNames []string, data []byte) ([]interface{}, error) {
+	args, err := newArguments(typeNames)
+	if err != nil {
+		return nil, err
+	}
+	values, err := args.Unpack(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode arguments: %w", err)
+	}
+	return values, nil
+}
+
+// packArguments ABI-encodes values as the given solidity types
+func packArguments(typeNames []string, values ...interface{}) ([]byte, error) {
+	args, err := newArguments(typeNames)
+	if err != nil {
+		return nil, err
+	}
+	packed, err := args.Pack(values...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode arguments: %w", err)
+	}
+	return packed, nil
+}
+
+// unsignedTxBytes returns the RLP encoding of tx without its signature, the form rollups
+// price data for
+func unsignedTxBytes(tx *types.Transaction) ([]byte, error) {
+	var fields []interface{}
+	switch tx.Type() {
+	case types.LegacyTxType:
+		fields = []interface{}{tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data()}
+	case types.DynamicFeeTxType:
+		fields = []interface{}{tx.ChainId(), tx.Nonce(), tx.GasTipCap(), tx.GasFeeCap(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList()}
+	default:
+		return nil, fmt.Errorf("unsupported transaction type %d", tx.Type())
+	}
+	encoded, err := rlp.EncodeToBytes(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	if tx.Type() != types.LegacyTxType {
+		encoded = append([]byte{tx.Type()}, encoded...)
+	}
+	return encoded, nil
+}
+
+// CostEstimate is the expected cost of a relay transaction
+type CostEstimate struct {
+	GasLimit     uint64   `json:"gasLimit"`
+	FeePerGas    *big.Int `json:"feePerGas"`    // gas price or EIP-1559 fee cap the relay is bid at
+	ExecutionFee *big.Int `json:"executionFee"` // gas limit, less the L1 gas units, times FeePerGas
+	L1Fee        *big.Int `json:"l1Fee"`        // rollup data fee, zero on L1 chains
+}
+
+// Total returns the execution fee plus the L1 data fee
+func (c CostEstimate) Total() *big.Int {
+	return new(big.Int).Add(c.ExecutionFee, c.L1Fee)
+}
+
+// WithL1FeeEstimator makes cost estimates and relay results include the L1 data fee of a rollup
+func WithL1FeeEstimator(estimator L1FeeEstimator) RelayerOption {
+	return func(r *Relayer) {
+		r.l1Fees = estimator
+	}
+}
+
+// EstimateMetaTxCost estimates what relaying a single meta transaction would cost, an upper
+// bound since the bid's fee per gas and the gas limit are rarely paid in full
+func (r *Relayer) EstimateMetaTxCost(ctx context.Context, metaTx MetaTx, sig Signature) (CostEstimate, error) {
+	data, err := r.PackExecuteCalldata(ctx, metaTx, sig)
+	if err != nil {
+		return CostEstimate{}, err
+	}
+	forwardRequest, err := metaTx.ForwardRequest()
+	if err != nil {
+		return CostEstimate{}, err
+	}
+	return r.estimateCost(ctx, data, forwardRequest.Value, BatchMetaTxRequestList{{MetaTx: metaTx, Signature: sig}})
+}
+
+// EstimateBatchCost estimates what relaying a batch through executeBatch would cost
+func (r *Relayer) EstimateBatchCost(ctx context.Context, batchRequests BatchMetaTxRequestList, refundReceiver common.Address) (CostEstimate, error) {
+	data, value, err := packExecuteBatchCall(r.registry, r.profile.Schema, batchRequests, refundReceiver)
+	if err != nil {
+		return CostEstimate{}, err
+	}
+	return r.estimateCost(ctx, data, value, batchRequests)
+}
+
+// estimateCost prices a forwarder call the way submit would, without sending it
+func (r *Relayer) estimateCost(ctx context.Context, data []byte, value *big.Int, requests BatchMetaTxRequestList) (CostEstimate, error) {
+	return r.priceCall(ctx, data, value, requests, 0)
+}
+
+// priceCall prices a forwarder call with gasLimit, or with the node's gas estimate if it is zero
+func (r *Relayer) priceCall(ctx context.Context, data []byte, value *big.Int, requests BatchMetaTxRequestList, gasLimit uint64) (CostEstimate, error) {
+	chainID, err := r.chainID(ctx)
+	if err != nil {
+		return CostEstimate{}, err
+	}
+	suggestedFee, gasPrice, err := r.suggestFee(ctx)
+	if err != nil {
+		return CostEstimate{}, err
+	}
+	if gasLimit == 0 {
+		gasLimit, err = r.estimateGasLimit(ctx, ethereum.CallMsg{
+			From:     r.address,
+			To:       &r.forwarder,
+			GasPrice: gasPrice,
+			Value:    value,
+			Data:     data,
+		})
+		if err != nil {
+			return CostEstimate{}, err
+		}
+	}
+	bid, err := r.bidder.Bid(ctx, BidContext{
+		ChainID:           chainID,
+		GasLimit:          gasLimit,
+		Requests:          len(requests),
+		NearestDeadline:   nearestDeadline(requests),
+		Now:               time.Now(),
+		SuggestedGasPrice: gasPrice,
+		SuggestedFee:      suggestedFee,
+		Client:            r.client,
+	})
+	if err != nil {
+		return CostEstimate{}, fmt.Errorf("failed to bid relay fee: %w", err)
+	}
+
+	feePerGas := bid.GasPrice
+	if bid.IsDynamic() {
+		feePerGas = bid.GasFeeCap
+	}
+	estimate := CostEstimate{GasLimit: gasLimit, FeePerGas: feePerGas, L1Fee: new(big.Int)}
+	executionGas := gasLimit
+	if r.l1Fees != nil {
+		// The nonce barely changes the encoded size, so the estimate does not reserve one
+		tx, err := bid.newTransaction(chainID, 0, r.forwarder, value, gasLimit, data)
+		if err != nil {
+			return CostEstimate{}, err
+		}
+		l1, err := r.l1Fees.EstimateL1Fee(ctx, r.client, tx, nil)
+		if err != nil {
+			return CostEstimate{}, err
+		}
+		estimate.L1Fee = l1.Fee
+		if l1.GasUnits < executionGas {
+			executionGas -= l1.GasUnits
+		}
+	}
+	estimate.ExecutionFee = new(big.Int).Mul(feePerGas, new(big.Int).SetUint64(executionGas))
+	return estimate, nil
+}
+
+// l1Fee returns the L1 data fee charged on top of the gas of a mined relay transaction, or
+// nil if the relayer has no L1 fee estimator or the chain charges it as gas
+func (r *Relayer) l1Fee(ctx context.Context, receipt *types.Receipt) (*big.Int, error) {
+	if r.l1Fees == nil || r.sandbox {
+		return nil, nil
+	}
+	tx, _, err := r.client.TransactionByHash(ctx, receipt.TxHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relay transaction: %w", err)
+	}
+	l1, err := r.l1Fees.EstimateL1Fee(ctx, r.client, tx, receipt.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if l1.GasUnits > 0 {
+		return nil, nil
+	}
+	return l1.Fee, nil
+}
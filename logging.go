@@ -0,0 +1,44 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DiscardLogger drops every record. It is the default logger of relayers, retry clients and
+// the relay server, which are silent unless given one.
+var DiscardLogger = slog.New(discardHandler{})
+
+// discardHandler is an slog.Handler that is never enabled
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// WithLogger makes the relayer log sent, replaced and failed relay transactions to logger
+func WithLogger(logger *slog.Logger) RelayerOption {
+	return func(r *Relayer) {
+		r.logger = logger
+	}
+}
+
+// log returns the relayer's logger
+func (r *Relayer) log() *slog.Logger {
+	if r.logger == nil {
+		return DiscardLogger
+	}
+	return r.logger
+}
+
+// txAttrs returns the log attributes of a relay transaction
+func txAttrs(tx *types.Transaction) []interface{} {
+	attrs := []interface{}{"tx", tx.Hash(), "nonce", tx.Nonce(), "gas", tx.Gas()}
+	if tx.Type() == types.DynamicFeeTxType {
+		return append(attrs, "gasFeeCap", tx.GasFeeCap(), "gasTipCap", tx.GasTipCap())
+	}
+	return append(attrs, "gasPrice", tx.GasPrice())
+}
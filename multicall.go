@@ -0,0 +1,120 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MULTICALL3_ADDRESS is the address of Multicall3, deployed at the same address on most chains
+var MULTICALL3_ADDRESS = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// MULTICALL_MAX_CALLS is the number of calls aggregated into one Multicall3 call, keeping each
+// within the gas limit nodes apply to eth_call
+const MULTICALL_MAX_CALLS = 500
+
+// multicall3ABI is the aggregate3 function of Multicall3
+const multicall3ABI = `[{"name":"aggregate3","type":"function","stateMutability":"payable",
+"inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}]}],
+"outputs":[{"name":"returnData","type":"tuple[]","components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}]}]}]`
+
+// multicall3Call is a Call3 of Multicall3
+type multicall3Call struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+// GetMetaTxNonces returns the forwarder nonces of users, in order, reading them through
+// Multicall3 in one round trip per MULTICALL_MAX_CALLS users. On chains without Multicall3 the
+// nonces are read with a JSON-RPC batch of calls, see BatchCallContract.
+func GetMetaTxNonces(ctx context.Context, forwarder common.Address, users []common.Address, client EthClient) ([]uint64, error) {
+	return GetMetaTxNoncesWithProfile(ctx, DefaultRegistry.DefaultForwarderProfile(), forwarder, users, client)
+}
+
+// GetMetaTxNoncesWithProfile is GetMetaTxNonces for a forwarder described by profile
+func GetMetaTxNoncesWithProfile(ctx context.Context, profile *ForwarderProfile, forwarder common.Address, users []common.Address, client EthClient) ([]uint64, error) {
+	return getMetaTxNonces(ctx, DefaultRegistry, profile, forwarder, users, client)
+}
+
+// getMetaTxNonces implements GetMetaTxNoncesWithProfile with the ABIs of registry
+func getMetaTxNonces(ctx context.Context, registry *Registry, profile *ForwarderProfile, forwarder common.Address, users []common.Address, client EthClient) ([]uint64, error) {
+	if len(users) == 1 {
+		nonce, err := getMetaTxNonce(ctx, registry, profile, forwarder, users[0], client)
+		if err != nil {
+			return nil, err
+		}
+		return []uint64{nonce}, nil
+	}
+
+	nonces := make([]uint64, 0, len(users))
+	for start := 0; start < len(users); start += MULTICALL_MAX_CALLS {
+		end := min(start+MULTICALL_MAX_CALLS, len(users))
+		chunk, err := multicallNonces(ctx, registry, profile, forwarder, users[start:end], client)
+		if err != nil {
+			return nil, err
+		}
+		if chunk == nil {
+			return readNonces(ctx, registry, profile, forwarder, users, client)
+		}
+		nonces = append(nonces, chunk...)
+	}
+	return nonces, nil
+}
+
+// multicallNonces reads the nonces of users in one Multicall3 call, or returns nil if the chain
+// has no Multicall3
+func multicallNonces(ctx context.Context, registry *Registry, profile *ForwarderProfile, forwarder common.Address, users []common.Address, client EthClient) ([]uint64, error) {
+	schema := profile.Schema
+	forwarderABI, err := registry.ABI(schema.ForwarderABI)
+	if err != nil {
+		return nil, err
+	}
+	multicallABI, err := registry.ABI(multicall3ABI)
+	if err != nil {
+		return nil, err
+	}
+
+	calls := make([]multicall3Call, len(users))
+	for i, user := range users {
+		data, err := forwarderABI.Pack(schema.NonceMethod, profile.NonceArgs(user)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pack %s call: %w", schema.NonceMethod, err)
+		}
+		calls[i] = multicall3Call{Target: forwarder, CallData: data}
+	}
+	data, err := multicallABI.Pack("aggregate3", calls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack aggregate3 call: %w", err)
+	}
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &MULTICALL3_ADDRESS, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Multicall3: %w", err)
+	}
+	if len(out) == 0 {
+		return nil, nil // no Multicall3 on this chain
+	}
+
+	var results []struct {
+		Success    bool
+		ReturnData []byte
+	}
+	if err := multicallABI.UnpackIntoInterface(&results, "aggregate3", out); err != nil {
+		return nil, fmt.Errorf("failed to unpack aggregate3 result: %w", err)
+	}
+	if len(results) != len(users) {
+		return nil, fmt.Errorf("Multicall3 returned %d results for %d calls", len(results), len(users))
+	}
+	nonces := make([]uint64, len(users))
+	for i, result := range results {
+		var nonce *big.Int
+		if err := forwarderABI.UnpackIntoInterface(&nonce, schema.NonceMethod, result.ReturnData); err != nil {
+			return nil, fmt.Errorf("failed to unpack nonce of %s: %w", users[i].Hex(), err)
+		}
+		nonces[i] = nonce.Uint64()
+	}
+	return nonces, nil
+}
@@ -0,0 +1,109 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MetaTxSigner signs a MetaTx for a forwarder domain. custodial.Signer and KeySigner implement it.
+type MetaTxSigner interface {
+	SignMetaTx(ctx context.Context, metaTx MetaTx, domainSeparator []byte) (Signature, error)
+}
+
+// KeySigner signs with a private key held in process
+type KeySigner struct {
+	Key    *ecdsa.PrivateKey
+	Schema *RequestSchema // nil for OZForwarderV5Schema
+}
+
+// SignMetaTx implements MetaTxSigner
+func (k KeySigner) SignMetaTx(ctx context.Context, metaTx MetaTx, domainSeparator []byte) (Signature, error) {
+	schema := k.Schema
+	if schema == nil {
+		schema = OZForwarderV5Schema
+	}
+	return SignMetaTxWithSchema(schema, metaTx, k.Key, domainSeparator)
+}
+
+// ChainRelayResult is the outcome of relaying on one chain
+type ChainRelayResult struct {
+	MetaTx    MetaTx // the template with the chain's forwarder nonce
+	Signature Signature
+	TxHash    common.Hash
+	Err       error
+}
+
+// RelayOnChains relays the same logical transfer on several chains concurrently. For each
+// chain the template's nonce is replaced by the signer's forwarder nonce on that chain, the
+// request is signed for the chain's domain and relayed with relayerPrivKey through a client
+// dialed from the chain's RPC URLs. The template's token must be deployed at the same
+// address on every chain. A failure on one chain does not affect the others; results are
+// keyed by chain ID.
+func RelayOnChains(ctx context.Context, metaTxTemplate MetaTx, signer MetaTxSigner, relayerPrivKey *ecdsa.PrivateKey, chains []*ChainConfig) map[uint64]ChainRelayResult {
+	results := make(map[uint64]ChainRelayResult, len(chains))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, cfg := range chains {
+		wg.Add(1)
+		go func(cfg *ChainConfig) {
+			defer wg.Done()
+			res := relayOnChain(ctx, metaTxTemplate, signer, relayerPrivKey, cfg)
+
+			mu.Lock()
+			results[cfg.ChainID.Uint64()] = res
+			mu.Unlock()
+		}(cfg)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// relayOnChain signs and relays the template on one chain
+func relayOnChain(ctx context.Context, metaTx MetaTx, signer MetaTxSigner, relayerPrivKey *ecdsa.PrivateKey, cfg *ChainConfig) ChainRelayResult {
+	fail := func(err error) ChainRelayResult {
+		return ChainRelayResult{MetaTx: metaTx, Err: fmt.Errorf("chain %s: %w", cfg.ChainID, err)}
+	}
+
+	client, err := cfg.Dial(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	defer client.Close()
+
+	profile, err := cfg.profile()
+	if err != nil {
+		return fail(err)
+	}
+	metaTx.Nonce, err = GetMetaTxNonceWithProfile(ctx, profile, cfg.Forwarder, metaTx.From, client)
+	if err != nil {
+		return fail(err)
+	}
+
+	domainSeparator, err := cfg.DomainSeparator()
+	if err != nil {
+		return fail(err)
+	}
+	sig, err := signer.SignMetaTx(ctx, metaTx, domainSeparator)
+	if err != nil {
+		return fail(fmt.Errorf("failed to sign MetaTx: %w", err))
+	}
+
+	relayer, err := cfg.NewRelayer(relayerPrivKey, client)
+	if err != nil {
+		return fail(err)
+	}
+	txHash, err := relayer.RelayMetaTx(ctx, metaTx, sig)
+	if err != nil {
+		res := fail(err)
+		res.Signature = sig
+		return res
+	}
+
+	return ChainRelayResult{MetaTx: metaTx, Signature: sig, TxHash: txHash}
+}
@@ -0,0 +1,163 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NonceKey identifies the forwarder nonce sequence of one signer
+type NonceKey struct {
+	ChainID   uint64
+	Forwarder common.Address
+	User      common.Address
+}
+
+// NonceStore persists the forwarder nonces reserved for users. Sharing one store between
+// backend replicas prevents two replicas from handing out the same nonce, which would make
+// one of the two signed requests unexecutable.
+type NonceStore interface {
+	// Reserve atomically returns the next unreserved nonce, at least floor, and marks
+	// count nonces starting there as reserved
+	Reserve(ctx context.Context, key NonceKey, floor, count uint64) (uint64, error)
+
+	// Reset forgets all reservations of key
+	Reset(ctx context.Context, key NonceKey) error
+}
+
+// MemoryNonceStore is an in-process NonceStore, safe for concurrent use
+type MemoryNonceStore struct {
+	mu   sync.Mutex
+	next map[NonceKey]uint64
+}
+
+// NewMemoryNonceStore creates an empty MemoryNonceStore
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{next: make(map[NonceKey]uint64)}
+}
+
+// Reserve implements NonceStore
+func (s *MemoryNonceStore) Reserve(ctx context.Context, key NonceKey, floor, count uint64) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := s.next[key]
+	if floor > start {
+		start = floor
+	}
+	s.next[key] = start + count
+	return start, nil
+}
+
+// Reset implements NonceStore
+func (s *MemoryNonceStore) Reset(ctx context.Context, key NonceKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.next, key)
+	return nil
+}
+
+// UserNonceManager hands out forwarder nonces for users signing meta transactions.
+// Nonces are reserved on top of the on-chain nonce, so several requests can be signed
+// before any of them is relayed.
+type UserNonceManager struct {
+	forwarder common.Address
+	client    EthClient
+	profile   *ForwarderProfile
+	store     NonceStore
+
+	mu      sync.Mutex
+	chainID *big.Int
+}
+
+// UserNonceOption configures optional UserNonceManager behaviour
+type UserNonceOption func(*UserNonceManager)
+
+// WithNonceStore persists reservations in store instead of process memory
+func WithNonceStore(store NonceStore) UserNonceOption {
+	return func(m *UserNonceManager) {
+		m.store = store
+	}
+}
+
+// WithNonceProfile sets the forwarder profile used to query on-chain nonces
+func WithNonceProfile(profile *ForwarderProfile) UserNonceOption {
+	return func(m *UserNonceManager) {
+		m.profile = profile
+	}
+}
+
+// NewUserNonceManager creates a nonce manager for the given forwarder
+func NewUserNonceManager(forwarder common.Address, ethClient EthClient, opts ...UserNonceOption) *UserNonceManager {
+	m := &UserNonceManager{
+		forwarder: forwarder,
+		client:    ethClient,
+		profile:   DefaultRegistry.DefaultForwarderProfile(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.store == nil {
+		m.store = NewMemoryNonceStore()
+	}
+	return m
+}
+
+// Next reserves and returns the next nonce for user
+func (m *UserNonceManager) Next(ctx context.Context, user common.Address) (uint64, error) {
+	return m.Reserve(ctx, user, 1)
+}
+
+// Reserve reserves count consecutive nonces for user and returns the first one
+func (m *UserNonceManager) Reserve(ctx context.Context, user common.Address, count uint64) (uint64, error) {
+	if count == 0 {
+		return 0, fmt.Errorf("nonce count must be positive")
+	}
+
+	key, err := m.key(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+
+	onChain, err := GetMetaTxNonceWithProfile(ctx, m.profile, m.forwarder, user, m.client)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get on-chain nonce: %w", err)
+	}
+
+	nonce, err := m.store.Reserve(ctx, key, onChain, count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve nonce: %w", err)
+	}
+	return nonce, nil
+}
+
+// Reset drops the reservations of user so the next nonce follows the chain again.
+// Use it when reserved nonces were signed but will never be relayed, as they would
+// otherwise block every later request of the user.
+func (m *UserNonceManager) Reset(ctx context.Context, user common.Address) error {
+	key, err := m.key(ctx, user)
+	if err != nil {
+		return err
+	}
+	if err := m.store.Reset(ctx, key); err != nil {
+		return fmt.Errorf("failed to reset nonces: %w", err)
+	}
+	return nil
+}
+
+// key returns the store key of user on the manager's forwarder
+func (m *UserNonceManager) key(ctx context.Context, user common.Address) (NonceKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.chainID == nil {
+		chainID, err := m.client.ChainID(ctx)
+		if err != nil {
+			return NonceKey{}, fmt.Errorf("failed to get chain ID: %w", err)
+		}
+		m.chainID = chainID
+	}
+	return NonceKey{ChainID: m.chainID.Uint64(), Forwarder: m.forwarder, User: user}, nil
+}
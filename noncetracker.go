@@ -0,0 +1,149 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NonceTracker keeps the next forwarder nonce of each user locally, read from the chain once
+// per user and advanced as nonces are handed out and requests relayed, so services signing
+// many sequential requests per user need no nonce read per request. Unlike UserNonceManager
+// it does not follow the chain afterwards: Reset a user whose requests were not executed, or
+// were signed elsewhere. It is safe for concurrent use.
+type NonceTracker struct {
+	forwarder common.Address
+	client    EthClient
+	profile   *ForwarderProfile
+
+	mu   sync.Mutex
+	next map[common.Address]uint64
+}
+
+// NewNonceTracker creates a tracker of nonces of the forwarder, read through client
+func NewNonceTracker(forwarder common.Address, client EthClient) *NonceTracker {
+	return NewNonceTrackerWithProfile(DefaultRegistry.DefaultForwarderProfile(), forwarder, client)
+}
+
+// NewNonceTrackerWithProfile is NewNonceTracker for a forwarder described by profile
+func NewNonceTrackerWithProfile(profile *ForwarderProfile, forwarder common.Address, client EthClient) *NonceTracker {
+	return &NonceTracker{forwarder: forwarder, client: client, profile: profile, next: make(map[common.Address]uint64)}
+}
+
+// WithNonceTracker advances tracker past the nonces of the requests the relayer executed. The
+// tracker moves when a mined relay is recorded (see RecordRelay), after the forwarder's
+// ExecutedForwardRequest events, so relays that are dropped, revert or skip requests leave it
+// where it was; relays still pending are not reflected yet.
+func WithNonceTracker(tracker *NonceTracker) RelayerOption {
+	return func(r *Relayer) {
+		r.nonceTracker = tracker
+	}
+}
+
+// Next returns the next nonce of user and advances past it
+func (t *NonceTracker) Next(ctx context.Context, user common.Address) (uint64, error) {
+	return t.Reserve(ctx, user, 1)
+}
+
+// Reserve returns the first of count consecutive nonces of user and advances past them
+func (t *NonceTracker) Reserve(ctx context.Context, user common.Address, count uint64) (uint64, error) {
+	if count == 0 {
+		return 0, fmt.Errorf("nonce count must be positive")
+	}
+	if err := t.Seed(ctx, user); err != nil {
+		return 0, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	nonce := t.next[user]
+	t.next[user] = nonce + count
+	return nonce, nil
+}
+
+// Seed reads the forwarder nonces of the users not tracked yet, in one round trip, see
+// GetMetaTxNonces. Next and Reserve seed users on first use.
+func (t *NonceTracker) Seed(ctx context.Context, users ...common.Address) error {
+	t.mu.Lock()
+	var unseeded []common.Address
+	seen := make(map[common.Address]bool, len(users))
+	for _, user := range users {
+		if _, ok := t.next[user]; !ok && !seen[user] {
+			seen[user] = true
+			unseeded = append(unseeded, user)
+		}
+	}
+	t.mu.Unlock()
+	if len(unseeded) == 0 {
+		return nil
+	}
+
+	nonces, err := GetMetaTxNoncesWithProfile(ctx, t.profile, t.forwarder, unseeded, t.client)
+	if err != nil {
+		return fmt.Errorf("failed to seed nonces: %w", err)
+	}
+	for i, user := range unseeded {
+		t.advance(user, nonces[i])
+	}
+	return nil
+}
+
+// Observe records that the request of user with nonce was relayed, so the tracker hands out
+// only later nonces
+func (t *NonceTracker) Observe(user common.Address, nonce uint64) {
+	t.advance(user, nonce+1)
+}
+
+// Peek returns the next nonce of user without advancing, and false if user is not tracked
+func (t *NonceTracker) Peek(user common.Address) (uint64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	nonce, ok := t.next[user]
+	return nonce, ok
+}
+
+// Reset forgets user, so the next nonce is read from the chain again. Use it when handed out
+// nonces will never be executed, as they would block every later request of the user.
+func (t *NonceTracker) Reset(user common.Address) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.next, user)
+}
+
+// advance moves the next nonce of user to at least next
+func (t *NonceTracker) advance(user common.Address, next uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if current, ok := t.next[user]; !ok || next > current {
+		t.next[user] = next
+	}
+}
+
+// set moves the next nonce of user to next
+func (t *NonceTracker) set(user common.Address, next uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.next[user] = next
+}
+
+// snapshot returns the next nonce of every tracked user
+func (t *NonceTracker) snapshot() map[common.Address]uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	next := make(map[common.Address]uint64, len(t.next))
+	for user, nonce := range t.next {
+		next[user] = nonce
+	}
+	return next
+}
+
+// observeExecuted records the requests a forwarder executed, whether their call succeeded or
+// not: both use the nonce
+func (t *NonceTracker) observeExecuted(events []ExecutedForwardRequest) {
+	for _, event := range events {
+		if event.Nonce != nil && event.Nonce.IsUint64() {
+			t.Observe(event.Signer, event.Nonce.Uint64())
+		}
+	}
+}
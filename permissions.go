@@ -0,0 +1,205 @@
+package eip2771toolkit
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ArgConstraint checks one decoded call argument
+type ArgConstraint func(value interface{}) error
+
+// CallRule permits calls of one method, optionally on one target only and with constraints
+// on the decoded arguments
+type CallRule struct {
+	Target common.Address        // zero address for any target
+	Method string                // method signature, e.g. "approve(address,uint256)"
+	Args   map[int]ArgConstraint // constraints by argument index, unconstrained arguments accept any value
+}
+
+// compiledRule is a CallRule with its selector and argument types resolved
+type compiledRule struct {
+	CallRule
+	selector []byte
+	inputs   abi.Arguments
+}
+
+// CallPermissions is an allowlist of (target, selector, argument constraint) rules that a
+// forwarded call must match before it is signed or relayed. A call is permitted when any
+// rule matches it. Check takes any calldata, but the call of a MetaTx is always
+// transfer(address,uint256) on its Token, so for CheckMetaTx, WithCallPermissions and
+// custodial.CallPolicy only transfer rules matter: they restrict the token (Target), the
+// recipient (argument 0) and the amount (argument 1), see TransferRule.
+type CallPermissions struct {
+	rules []compiledRule
+}
+
+// NewCallPermissions compiles the rules. Method signatures use canonical Solidity types;
+// tuple arguments are not supported.
+func NewCallPermissions(rules ...CallRule) (*CallPermissions, error) {
+	p := &CallPermissions{}
+	for _, rule := range rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		p.rules = append(p.rules, compiled)
+	}
+	return p, nil
+}
+
+// TRANSFER_METHOD is the method every MetaTx calls on its token
+const TRANSFER_METHOD = "transfer(address,uint256)"
+
+// TransferRule permits MetaTx transfers of token, the zero address for any token, to one of
+// recipients, or anyone if there are none, of at most maxAmount, or any amount if nil
+func TransferRule(token common.Address, recipients []common.Address, maxAmount *big.Int) CallRule {
+	args := make(map[int]ArgConstraint)
+	if len(recipients) > 0 {
+		args[0] = AddressIn(recipients...)
+	}
+	if maxAmount != nil {
+		args[1] = UintAtMost(maxAmount)
+	}
+	return CallRule{Target: token, Method: TRANSFER_METHOD, Args: args}
+}
+
+// compileRule parses the method signature of a rule
+func compileRule(rule CallRule) (compiledRule, error) {
+	open := strings.Index(rule.Method, "(")
+	if open <= 0 || !strings.HasSuffix(rule.Method, ")") {
+		return compiledRule{}, fmt.Errorf("invalid method signature %q", rule.Method)
+	}
+
+	var inputs abi.Arguments
+	if params := rule.Method[open+1 : len(rule.Method)-1]; params != "" {
+		for _, param := range strings.Split(params, ",") {
+			typ, err := abi.NewType(strings.TrimSpace(param), "", nil)
+			if err != nil {
+				return compiledRule{}, fmt.Errorf("invalid argument type in %q: %w", rule.Method, err)
+			}
+			inputs = append(inputs, abi.Argument{Type: typ})
+		}
+	}
+	for i := range rule.Args {
+		if i < 0 || i >= len(inputs) {
+			return compiledRule{}, fmt.Errorf("constraint on argument %d of %q, which has %d arguments", i, rule.Method, len(inputs))
+		}
+	}
+
+	return compiledRule{
+		CallRule: rule,
+		selector: crypto.Keccak256([]byte(rule.Method))[:4],
+		inputs:   inputs,
+	}, nil
+}
+
+// Check returns nil if a rule permits calling target with data, or an error wrapping
+// ErrCallNotPermitted that explains the closest mismatch
+func (p *CallPermissions) Check(target common.Address, data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("%w: calldata of %s has no selector", ErrCallNotPermitted, target.Hex())
+	}
+
+	var reason error
+	for _, rule := range p.rules {
+		if rule.Target != (common.Address{}) && rule.Target != target {
+			continue
+		}
+		if !bytes.Equal(rule.selector, data[:4]) {
+			continue
+		}
+
+		err := rule.checkArgs(data[4:])
+		if err == nil {
+			return nil
+		}
+		reason = fmt.Errorf("%s on %s: %w", rule.Method, target.Hex(), err)
+	}
+
+	if reason != nil {
+		return fmt.Errorf("%w: %v", ErrCallNotPermitted, reason)
+	}
+	return fmt.Errorf("%w: selector %x on %s", ErrCallNotPermitted, data[:4], target.Hex())
+}
+
+// CheckMetaTx checks the call the forwarder would make for metaTx
+func (p *CallPermissions) CheckMetaTx(metaTx MetaTx) error {
+	req, err := metaTx.ForwardRequest()
+	if err != nil {
+		return err
+	}
+	return p.Check(req.To, req.Data)
+}
+
+// checkArgs decodes the arguments and applies the rule's constraints
+func (r compiledRule) checkArgs(argData []byte) error {
+	if len(r.Args) == 0 {
+		return nil
+	}
+	values, err := r.inputs.UnpackValues(argData)
+	if err != nil {
+		return fmt.Errorf("failed to decode arguments: %w", err)
+	}
+	for i, constraint := range r.Args {
+		if err := constraint(values[i]); err != nil {
+			return fmt.Errorf("argument %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// AddressIn constrains an address argument to the listed addresses
+func AddressIn(addrs ...common.Address) ArgConstraint {
+	return func(value interface{}) error {
+		addr, ok := value.(common.Address)
+		if !ok {
+			return fmt.Errorf("expected address, got %T", value)
+		}
+		for _, a := range addrs {
+			if a == addr {
+				return nil
+			}
+		}
+		return fmt.Errorf("address %s is not allowed", addr.Hex())
+	}
+}
+
+// UintAtMost constrains an unsigned integer argument to at most max
+func UintAtMost(max *big.Int) ArgConstraint {
+	return func(value interface{}) error {
+		v, ok := value.(*big.Int)
+		if !ok {
+			return fmt.Errorf("expected uint256, got %T", value)
+		}
+		if v.Cmp(max) > 0 {
+			return fmt.Errorf("value %s exceeds %s", v, max)
+		}
+		return nil
+	}
+}
+
+// WithCallPermissions makes the relayer refuse requests whose forwarded call is not permitted
+func WithCallPermissions(permissions *CallPermissions) RelayerOption {
+	return func(r *Relayer) {
+		r.permissions = permissions
+	}
+}
+
+// checkPermissions applies the relayer's call permissions, if any, to the requests
+func (r *Relayer) checkPermissions(requests BatchMetaTxRequestList) error {
+	if r.permissions == nil {
+		return nil
+	}
+	for i, req := range requests {
+		if err := r.permissions.CheckMetaTx(req.MetaTx); err != nil {
+			return fmt.Errorf("request at index %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package eip2771toolkit
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestCallPermissionsCheckMetaTx(t *testing.T) {
+	usdc := common.HexToAddress("0xc000000000000000000000000000000000000001")
+	reward := common.HexToAddress("0xc000000000000000000000000000000000000002")
+	other := common.HexToAddress("0xc000000000000000000000000000000000000003")
+	alice := common.HexToAddress("0xa11ce00000000000000000000000000000000000")
+	treasury := common.HexToAddress("0x7000000000000000000000000000000000000000")
+	user := common.HexToAddress("0x0000000000000000000000000000000000000001")
+
+	permissions, err := NewCallPermissions(
+		TransferRule(usdc, nil, big.NewInt(1000)),
+		TransferRule(reward, []common.Address{treasury}, nil),
+		// Never matches a MetaTx, whose call is always a transfer
+		CallRule{Target: other, Method: "approve(address,uint256)"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   common.Address
+		to      common.Address
+		amount  int64
+		allowed bool
+	}{
+		{"under the cap", usdc, alice, 999, true},
+		{"at the cap", usdc, alice, 1000, true},
+		{"over the cap", usdc, alice, 1001, false},
+		{"allowed recipient", reward, treasury, 1 << 40, true},
+		{"other recipient", reward, alice, 1, false},
+		{"token without a transfer rule", other, alice, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metaTx := NewMetaTx(user, tt.to, tt.token, big.NewInt(tt.amount), 100000, 0, 1)
+			err := permissions.CheckMetaTx(metaTx)
+			if tt.allowed && err != nil {
+				t.Fatalf("refused: %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrCallNotPermitted) {
+				t.Fatalf("got %v, want ErrCallNotPermitted", err)
+			}
+		})
+	}
+}
+
+func TestCallPermissionsCheck(t *testing.T) {
+	router := common.HexToAddress("0x0000000000000000000000000000000000000a11")
+	permissions, err := NewCallPermissions(CallRule{
+		Method: "approve(address,uint256)",
+		Args:   map[int]ArgConstraint{0: AddressIn(router)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	approve := func(spender common.Address) []byte {
+		data := append([]byte{}, crypto.Keccak256([]byte("approve(address,uint256)"))[:4]...)
+		data = append(data, common.LeftPadBytes(spender.Bytes(), 32)...)
+		return append(data, common.LeftPadBytes(big.NewInt(5).Bytes(), 32)...)
+	}
+	target := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	if err := permissions.Check(target, approve(router)); err != nil {
+		t.Errorf("approve of the router refused: %v", err)
+	}
+	if err := permissions.Check(target, approve(target)); !errors.Is(err, ErrCallNotPermitted) {
+		t.Errorf("approve of another spender: got %v, want ErrCallNotPermitted", err)
+	}
+	if err := permissions.Check(target, approve(router)[:3]); !errors.Is(err, ErrCallNotPermitted) {
+		t.Errorf("calldata without selector: got %v, want ErrCallNotPermitted", err)
+	}
+	if err := permissions.Check(target, approve(router)[:20]); !errors.Is(err, ErrCallNotPermitted) {
+		t.Errorf("truncated arguments: got %v, want ErrCallNotPermitted", err)
+	}
+}
+
+func TestNewCallPermissionsRejectsInvalidRules(t *testing.T) {
+	for _, rule := range []CallRule{
+		{Method: "transfer"},
+		{Method: "transfer(adress,uint256)"},
+		{Method: TRANSFER_METHOD, Args: map[int]ArgConstraint{2: UintAtMost(big.NewInt(1))}},
+	} {
+		if _, err := NewCallPermissions(rule); err == nil {
+			t.Errorf("rule %q with constraints on %d arguments accepted", rule.Method, len(rule.Args))
+		}
+	}
+}
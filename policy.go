@@ -0,0 +1,183 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Policy decides whether the relayer sponsors a request. Policies run just before the relay
+// transaction is sent and refuse requests with a *PolicyError.
+type Policy interface {
+	Check(ctx context.Context, req BatchMetaTxRequest) error
+}
+
+// PolicyReverter is a Policy whose Check reserves something for the request, such as quota,
+// and that gives it back when the request is not relayed after all
+type PolicyReverter interface {
+	Policy
+	Revert(ctx context.Context, req BatchMetaTxRequest)
+}
+
+// PolicyError is returned when a Policy refuses a request. It wraps ErrPolicyViolation and
+// Cause, if set.
+type PolicyError struct {
+	Policy     string // name of the refusing policy, e.g. "target-allowlist"
+	Reason     string
+	Cause      error         // a more specific sentinel such as ErrQuotaExceeded, or nil
+	RetryAfter time.Duration // how long until the request may pass, 0 if waiting does not help
+}
+
+// Error implements error
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrPolicyViolation, e.Policy, e.Reason)
+}
+
+// Unwrap returns ErrPolicyViolation and Cause
+func (e *PolicyError) Unwrap() []error {
+	if e.Cause == nil {
+		return []error{ErrPolicyViolation}
+	}
+	return []error{ErrPolicyViolation, e.Cause}
+}
+
+// TargetAllowlist is a Policy that only sponsors requests whose forwarded call goes to one of
+// its targets, e.g. a sponsor's own token contracts. It is safe to change while relaying.
+type TargetAllowlist struct {
+	mu      sync.RWMutex
+	targets map[common.Address]bool
+}
+
+// NewTargetAllowlist creates an allowlist of the given targets
+func NewTargetAllowlist(targets ...common.Address) *TargetAllowlist {
+	a := &TargetAllowlist{targets: make(map[common.Address]bool)}
+	a.Allow(targets...)
+	return a
+}
+
+// Allow adds targets to the allowlist
+func (a *TargetAllowlist) Allow(targets ...common.Address) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, target := range targets {
+		a.targets[target] = true
+	}
+}
+
+// Disallow removes targets from the allowlist
+func (a *TargetAllowlist) Disallow(targets ...common.Address) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, target := range targets {
+		delete(a.targets, target)
+	}
+}
+
+// Allowed reports whether target is on the allowlist
+func (a *TargetAllowlist) Allowed(target common.Address) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.targets[target]
+}
+
+// Targets returns the allowed targets
+func (a *TargetAllowlist) Targets() []common.Address {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	targets := make([]common.Address, 0, len(a.targets))
+	for target := range a.targets {
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// Check refuses requests whose forwarded call target is not allowed
+func (a *TargetAllowlist) Check(ctx context.Context, req BatchMetaTxRequest) error {
+	if target := req.MetaTx.Token; !a.Allowed(target) {
+		return &PolicyError{Policy: "target-allowlist", Reason: fmt.Sprintf("target %s is not allowed", target.Hex())}
+	}
+	return nil
+}
+
+// WithPolicies adds policies every relayed request must pass, checked in order
+func WithPolicies(policies ...Policy) RelayerOption {
+	return func(r *Relayer) {
+		r.policies = append(r.policies, policies...)
+	}
+}
+
+// SetPolicies replaces the relayer's policies, except its budget (see SetBudget), e.g. after
+// a configuration reload. Relays already past their policy check are not affected.
+func (r *Relayer) SetPolicies(policies ...Policy) {
+	r.settingsMu.Lock()
+	defer r.settingsMu.Unlock()
+	r.policies = append([]Policy{}, policies...)
+	if r.budget != nil {
+		r.policies = append(r.policies, r.budget)
+	}
+}
+
+// activePolicies returns the policies relays are checked against
+func (r *Relayer) activePolicies() []Policy {
+	r.settingsMu.RLock()
+	defer r.settingsMu.RUnlock()
+	return r.policies
+}
+
+// sponsor runs the relayer's policies on the requests and submits them, reverting what the
+// policies reserved if the submission fails
+func (r *Relayer) sponsor(ctx context.Context, forwarder common.Address, data []byte, value *big.Int, requests BatchMetaTxRequestList) (common.Hash, error) {
+	if r.activeBudget() != nil && !recordsRelay(ctx) {
+		return common.Hash{}, fmt.Errorf("%w: submit with ContextWithRecordRelay and pass the mined result to RecordRelay", ErrBudgetNotRecorded)
+	}
+	policies := r.activePolicies()
+	if err := checkPolicies(ctx, policies, requests); err != nil {
+		r.log().Info("relay refused by policy", "forwarder", forwarder, "requests", len(requests), "error", err)
+		return common.Hash{}, err
+	}
+	txHash, err := r.submit(ctx, forwarder, data, value, requests)
+	if err != nil {
+		revertPolicies(ctx, policies, requests)
+		r.log().Warn("relay failed", "forwarder", forwarder, "requests", len(requests), "error", err)
+		return common.Hash{}, err
+	}
+	if r.events != nil {
+		r.events.OnSubmitted(txHash, requests)
+	}
+	return txHash, nil
+}
+
+// checkPolicies applies policies to every request. When one refuses, the reservations already
+// made for the requests are reverted.
+func checkPolicies(ctx context.Context, policies []Policy, requests BatchMetaTxRequestList) error {
+	for i, req := range requests {
+		for j, policy := range policies {
+			err := policy.Check(ctx, req)
+			if err == nil {
+				continue
+			}
+			revertPolicies(ctx, policies[:j], requests[i:i+1])
+			revertPolicies(ctx, policies, requests[:i])
+			if len(requests) > 1 {
+				return fmt.Errorf("request at index %d: %w", i, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// revertPolicies gives back what policies reserved for the requests
+func revertPolicies(ctx context.Context, policies []Policy, requests BatchMetaTxRequestList) {
+	for _, req := range requests {
+		for _, policy := range policies {
+			if reverter, ok := policy.(PolicyReverter); ok {
+				reverter.Revert(ctx, req)
+			}
+		}
+	}
+}
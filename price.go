@@ -0,0 +1,153 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	decimalsSelector        = crypto.Keccak256([]byte("decimals()"))[:4]
+	latestRoundDataSelector = crypto.Keccak256([]byte("latestRoundData()"))[:4]
+)
+
+// PriceOracle reports what the chain's native currency is worth, for expressing relay costs
+// in fiat
+type PriceOracle interface {
+	// NativePriceUSD returns the USD price of one ether (1e18 wei) of the native currency
+	NativePriceUSD(ctx context.Context) (*big.Float, error)
+}
+
+// StaticPrice is a PriceOracle with a fixed price, for tests and chains without a feed
+type StaticPrice struct {
+	USD *big.Float
+}
+
+// NativePriceUSD implements PriceOracle
+func (p StaticPrice) NativePriceUSD(ctx context.Context) (*big.Float, error) {
+	if p.USD == nil {
+		return nil, fmt.Errorf("static price not set")
+	}
+	return new(big.Float).Set(p.USD), nil
+}
+
+// ChainlinkFeed is a PriceOracle reading a Chainlink native/USD aggregator, e.g. ETH / USD
+type ChainlinkFeed struct {
+	caller ethereum.ContractCaller
+	feed   common.Address
+	maxAge time.Duration
+
+	mu       sync.Mutex
+	decimals *uint8 // read once
+}
+
+// NewChainlinkFeed reads the aggregator at feed. Answers last updated more than maxAge ago
+// are refused with ErrStalePrice; a zero maxAge accepts any.
+func NewChainlinkFeed(caller ethereum.ContractCaller, feed common.Address, maxAge time.Duration) *ChainlinkFeed {
+	return &ChainlinkFeed{caller: caller, feed: feed, maxAge: maxAge}
+}
+
+// NativePriceUSD implements PriceOracle
+func (f *ChainlinkFeed) NativePriceUSD(ctx context.Context) (*big.Float, error) {
+	decimals, err := f.readDecimals(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := f.caller.CallContract(ctx, ethereum.CallMsg{To: &f.feed, Data: latestRoundDataSelector}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s.latestRoundData: %w", f.feed.Hex(), err)
+	}
+	values, err := unpackArguments([]string{"uint80", "int256", "uint256", "uint256", "uint80"}, out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s.latestRoundData: %w", f.feed.Hex(), err)
+	}
+	answer := values[1].(*big.Int)
+	updatedAt := values[3].(*big.Int)
+	if answer.Sign() <= 0 {
+		return nil, fmt.Errorf("feed %s answered non-positive price %s", f.feed.Hex(), answer)
+	}
+	if f.maxAge > 0 {
+		if age := time.Since(time.Unix(updatedAt.Int64(), 0)); age > f.maxAge {
+			return nil, fmt.Errorf("%w: feed %s was updated %s ago", ErrStalePrice, f.feed.Hex(), age.Round(time.Second))
+		}
+	}
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	return new(big.Float).Quo(new(big.Float).SetInt(answer), new(big.Float).SetInt(scale)), nil
+}
+
+// readDecimals returns the decimals of the feed's answers
+func (f *ChainlinkFeed) readDecimals(ctx context.Context) (uint8, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.decimals != nil {
+		return *f.decimals, nil
+	}
+
+	out, err := f.caller.CallContract(ctx, ethereum.CallMsg{To: &f.feed, Data: decimalsSelector}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call %s.decimals: %w", f.feed.Hex(), err)
+	}
+	values, err := unpackArguments([]string{"uint8"}, out)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s.decimals: %w", f.feed.Hex(), err)
+	}
+	decimals := values[0].(uint8)
+	f.decimals = &decimals
+	return decimals, nil
+}
+
+// WeiToUSD converts an amount of wei to USD at the oracle's price
+func WeiToUSD(ctx context.Context, oracle PriceOracle, wei *big.Int) (*big.Float, error) {
+	price, err := oracle.NativePriceUSD(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ether := new(big.Float).Quo(new(big.Float).SetInt(wei), new(big.Float).SetInt(weiPerEther))
+	return ether.Mul(ether, price), nil
+}
+
+// WithPriceOracle sets the oracle EstimateRelayCostUSD prices costs with
+func WithPriceOracle(oracle PriceOracle) RelayerOption {
+	return func(r *Relayer) {
+		r.priceOracle = oracle
+	}
+}
+
+// EstimateRelayCost estimates what relaying metaTx alone would cost before it is signed,
+// from its worst-case gas (see EstimateBatchGas) rather than the node's estimate, at the
+// relayer's current bid and including any L1 data fee
+func (r *Relayer) EstimateRelayCost(ctx context.Context, metaTx MetaTx) (CostEstimate, error) {
+	// All-ones signatures cost the most calldata
+	sig := Signature{V: 0xff, R: common.MaxHash, S: common.MaxHash}
+	data, err := r.PackExecuteCalldata(ctx, metaTx, sig)
+	if err != nil {
+		return CostEstimate{}, err
+	}
+	forwardRequest, err := metaTx.ForwardRequest()
+	if err != nil {
+		return CostEstimate{}, err
+	}
+	gasLimit := TX_BASE_GAS + CalldataGas(data) + FORWARDER_EXECUTE_OVERHEAD_GAS + forwardedGasNeeded(metaTx.Gas)
+	return r.priceCall(ctx, data, forwardRequest.Value, BatchMetaTxRequestList{{MetaTx: metaTx, Signature: sig}}, gasLimit)
+}
+
+// EstimateRelayCostUSD is EstimateRelayCost in USD at the price of the relayer's oracle, for
+// dashboards and quotes that express sponsorship in fiat
+func (r *Relayer) EstimateRelayCostUSD(ctx context.Context, metaTx MetaTx) (*big.Float, error) {
+	if r.priceOracle == nil {
+		return nil, fmt.Errorf("relayer has no price oracle, see WithPriceOracle")
+	}
+	estimate, err := r.EstimateRelayCost(ctx, metaTx)
+	if err != nil {
+		return nil, err
+	}
+	return WeiToUSD(ctx, r.priceOracle, estimate.Total())
+}
@@ -0,0 +1,135 @@
+package eip2771toolkit
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Flashbots endpoints on Ethereum mainnet
+const (
+	FLASHBOTS_PROTECT_RPC_URL = "https://rpc.flashbots.net"
+	FLASHBOTS_RELAY_URL       = "https://relay.flashbots.net"
+)
+
+var defaultPrivateRelayClient = &http.Client{Timeout: 10 * time.Second}
+
+// WithTxSender broadcasts relay transactions through sender instead of the relayer's
+// client, e.g. a private or MEV-protected RPC. Receipts are still read through the client.
+// Transactions sent privately stay out of the public mempool, so high-value transfers cannot
+// be frontrun and the relayer nonce cannot be griefed by copies of its transactions.
+func WithTxSender(sender ethereum.TransactionSender) RelayerOption {
+	return func(r *Relayer) {
+		r.sender = sender
+	}
+}
+
+// DialPrivateRPC connects to an RPC endpoint accepting eth_sendRawTransaction privately, such
+// as FLASHBOTS_PROTECT_RPC_URL, for use with WithTxSender
+func DialPrivateRPC(ctx context.Context, url string) (*ethclient.Client, error) {
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to private RPC %s: %w", url, err)
+	}
+	return client, nil
+}
+
+// FlashbotsSender submits transactions with eth_sendPrivateTransaction, which block builders
+// include without gossiping them to the public mempool
+type FlashbotsSender struct {
+	URL        string            // default FLASHBOTS_RELAY_URL
+	AuthKey    *ecdsa.PrivateKey // signs the X-Flashbots-Signature header; any key, it only builds reputation
+	Fast       bool              // share with all builders for faster inclusion
+	HTTPClient *http.Client      // defaults to a client with a 10 second timeout
+}
+
+// privateTxParams is the parameter object of eth_sendPrivateTransaction
+type privateTxParams struct {
+	Tx          hexutil.Bytes          `json:"tx"`
+	Preferences map[string]interface{} `json:"preferences,omitempty"`
+}
+
+// SendTransaction implements ethereum.TransactionSender
+func (s *FlashbotsSender) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if s.AuthKey == nil {
+		return fmt.Errorf("flashbots sender requires an auth key")
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	params := privateTxParams{Tx: raw}
+	if s.Fast {
+		params.Preferences = map[string]interface{}{"fast": true}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "eth_sendPrivateTransaction",
+		"params":  []interface{}{params},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode private transaction request: %w", err)
+	}
+
+	// The header signs the hex string of the body hash as a personal message
+	digest := accounts.TextHash([]byte(hexutil.Encode(crypto.Keccak256(body))))
+	signature, err := crypto.Sign(digest, s.AuthKey)
+	if err != nil {
+		return fmt.Errorf("failed to sign private transaction request: %w", err)
+	}
+
+	url := s.URL
+	if url == "" {
+		url = FLASHBOTS_RELAY_URL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create private transaction request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Flashbots-Signature", crypto.PubkeyToAddress(s.AuthKey.PublicKey).Hex()+":"+hexutil.Encode(signature))
+
+	httpClient := s.HTTPClient
+	if httpClient == nil {
+		httpClient = defaultPrivateRelayClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send private transaction: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read private transaction response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("private relay returned %s: %s", resp.Status, bytes.TrimSpace(respBody))
+	}
+	var result struct {
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("failed to decode private transaction response: %w", err)
+	}
+	if result.Error != nil {
+		return fmt.Errorf("private relay rejected transaction: %s (code %d)", result.Error.Message, result.Error.Code)
+	}
+	return nil
+}
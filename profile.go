@@ -0,0 +1,91 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ForwarderProfile bundles everything needed to sign for and relay through one kind of
+// trusted forwarder: its request schema, how its EIP-712 domain is built and how its
+// per-signer nonce is queried.
+type ForwarderProfile struct {
+	Name   string
+	Schema *RequestSchema
+
+	// DomainSeparator builds the EIP-712 domain separator of a deployment of this forwarder
+	DomainSeparator func(chainId *big.Int, forwarder common.Address) ([]byte, error)
+
+	// NonceArgs returns the arguments of the schema's NonceMethod for a signer
+	NonceArgs func(user common.Address) []interface{}
+}
+
+// OZForwarderV5Profile is the profile of the OpenZeppelin v5 ERC2771Forwarder
+var OZForwarderV5Profile = &ForwarderProfile{
+	Name:            OZ_FORWARDER_V5_SCHEMA,
+	Schema:          OZForwarderV5Schema,
+	DomainSeparator: CreateDomainSeparatorForChain,
+	NonceArgs: func(user common.Address) []interface{} {
+		return []interface{}{user}
+	},
+}
+
+// profileForSchema wraps a bare schema into a profile using the OpenZeppelin domain and nonce getter
+func profileForSchema(schema *RequestSchema) *ForwarderProfile {
+	return &ForwarderProfile{
+		Name:            schema.Name,
+		Schema:          schema,
+		DomainSeparator: OZForwarderV5Profile.DomainSeparator,
+		NonceArgs:       OZForwarderV5Profile.NonceArgs,
+	}
+}
+
+// GetMetaTxNonceWithProfile retrieves the current nonce for a user from a forwarder described by profile
+func GetMetaTxNonceWithProfile(
+	ctx context.Context,
+	profile *ForwarderProfile,
+	contractAddr common.Address,
+	user common.Address,
+	ethClient EthClient,
+) (uint64, error) {
+	return getMetaTxNonce(ctx, DefaultRegistry, profile, contractAddr, user, ethClient)
+}
+
+// getMetaTxNonce implements GetMetaTxNonceWithProfile with the ABIs of registry
+func getMetaTxNonce(ctx context.Context, registry *Registry, profile *ForwarderProfile, contractAddr, user common.Address, ethClient EthClient) (uint64, error) {
+	schema := profile.Schema
+
+	// Parse forwarder contract ABI
+	parsedABI, err := registry.ABI(schema.ForwarderABI)
+	if err != nil {
+		return 0, err
+	}
+
+	// Pack the nonce method call
+	data, err := parsedABI.Pack(schema.NonceMethod, profile.NonceArgs(user)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to pack %s call: %w", schema.NonceMethod, err)
+	}
+
+	// Call contract
+	msg := ethereum.CallMsg{
+		To:   &contractAddr,
+		Data: data,
+	}
+	result, err := ethClient.CallContract(ctx, msg, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call contract: %w", err)
+	}
+
+	// Unpack result
+	var nonce *big.Int
+	err = parsedABI.UnpackIntoInterface(&nonce, schema.NonceMethod, result)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unpack result: %w", err)
+	}
+
+	return nonce.Uint64(), nil
+}
@@ -0,0 +1,59 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// nonceClient is an EthClient whose forwarder returns nonce for every signer and whose chain
+// has no Multicall3
+type nonceClient struct {
+	EthClient
+	nonce uint64
+}
+
+func (c *nonceClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if *msg.To == MULTICALL3_ADDRESS {
+		return nil, nil
+	}
+	return common.LeftPadBytes(new(big.Int).SetUint64(c.nonce).Bytes(), 32), nil
+}
+
+func TestRelayerReadsNoncesWithItsRegistry(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := NewRegistry()
+	relayer := NewRelayer(key, common.Address{}, &nonceClient{nonce: 5}, WithRegistry(registry))
+	users := []common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02")}
+
+	nonce, err := relayer.GetMetaTxNonce(context.Background(), users[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce != 5 {
+		t.Errorf("GetMetaTxNonce = %d, want 5", nonce)
+	}
+	nonces, err := relayer.GetMetaTxNonces(context.Background(), users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nonces) != 2 || nonces[0] != 5 || nonces[1] != 5 {
+		t.Errorf("GetMetaTxNonces = %v, want [5 5]", nonces)
+	}
+
+	for name, abiJSON := range map[string]string{"forwarder": OZForwarderV5Schema.ForwarderABI, "Multicall3": multicall3ABI} {
+		registry.mu.RLock()
+		_, ok := registry.abis[abiJSON]
+		registry.mu.RUnlock()
+		if !ok {
+			t.Errorf("%s ABI not parsed through the relayer's registry", name)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Priority orders queued requests; higher priorities are dequeued first
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+
+	numPriorities = int(PriorityHigh) + 1
+)
+
+// String returns the lower-case priority name
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	}
+	return "unknown"
+}
+
+// QueuedRequest is a signed request waiting to be relayed
+type QueuedRequest struct {
+	ID         uint64
+	Request    BatchMetaTxRequest
+	Priority   Priority
+	EnqueuedAt time.Time
+}
+
+// SignerNonce identifies the forwarder nonce slot of a signer. The forwarder executes at most
+// one request per slot, so requests sharing one are duplicates or conflicts.
+type SignerNonce struct {
+	From  common.Address
+	Nonce uint64
+}
+
+// SignerNonce returns the signer and forwarder nonce of the request
+func (r BatchMetaTxRequest) SignerNonce() SignerNonce {
+	return SignerNonce{From: r.MetaTx.From, Nonce: r.MetaTx.Nonce}
+}
+
+// BacklogStats describes the requests waiting at one priority, or in the whole queue
+type BacklogStats struct {
+	Depth            int     `json:"depth"`
+	OldestAgeSeconds float64 `json:"oldestAgeSeconds"`
+	NearestDeadline  uint64  `json:"nearestDeadline,omitempty"` // unix timestamp, 0 when empty
+}
+
+// QueueStats is a snapshot of the queue for metrics and autoscaling
+type QueueStats struct {
+	BacklogStats
+	ByPriority map[string]BacklogStats `json:"byPriority"`
+}
+
+// RequestQueue holds signed requests until they are relayed: highest priority first, FIFO
+// within a priority. It is safe for concurrent use.
+type RequestQueue struct {
+	mu     sync.Mutex
+	items  [numPriorities][]QueuedRequest
+	queued map[SignerNonce]uint64 // queue ID of the latest request pushed for each nonce slot
+	nextID uint64
+	now    func() time.Time
+	events Events
+}
+
+// NewRequestQueue creates an empty queue
+func NewRequestQueue() *RequestQueue {
+	return &RequestQueue{queued: make(map[SignerNonce]uint64), now: time.Now}
+}
+
+// SetEvents makes the queue notify events of every request pushed
+func (q *RequestQueue) SetEvents(events Events) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.events = events
+}
+
+// Push enqueues a request and returns its queue ID
+func (q *RequestQueue) Push(req BatchMetaTxRequest, priority Priority) uint64 {
+	q.mu.Lock()
+	item, events := q.push(req, priority), q.events
+	q.mu.Unlock()
+	notifyQueued(events, item)
+	return item.ID
+}
+
+// PushUnique enqueues a request unless one with the same signer and forwarder nonce is
+// already queued, in which case it returns that request's queue ID and false. Frontends
+// retrying a submission after a network error thus do not queue it twice.
+func (q *RequestQueue) PushUnique(req BatchMetaTxRequest, priority Priority) (uint64, bool) {
+	q.mu.Lock()
+	if id, ok := q.queued[req.SignerNonce()]; ok {
+		q.mu.Unlock()
+		return id, false
+	}
+	item, events := q.push(req, priority), q.events
+	q.mu.Unlock()
+	notifyQueued(events, item)
+	return item.ID, true
+}
+
+// Admit enqueues a request like PushUnique once policies, such as a Quota, accept it. A
+// request already queued for its nonce slot is not checked again. What the policies reserve
+// is kept when the request is later dropped or fails to relay, so a Quota admitting requests
+// here should not be given to the relayer too.
+func (q *RequestQueue) Admit(ctx context.Context, req BatchMetaTxRequest, priority Priority, policies ...Policy) (uint64, error) {
+	q.mu.Lock()
+	if id, ok := q.queued[req.SignerNonce()]; ok {
+		q.mu.Unlock()
+		return id, nil
+	}
+	if err := checkPolicies(ctx, policies, BatchMetaTxRequestList{req}); err != nil {
+		q.mu.Unlock()
+		return 0, err
+	}
+	item, events := q.push(req, priority), q.events
+	q.mu.Unlock()
+	notifyQueued(events, item)
+	return item.ID, nil
+}
+
+// notifyQueued reports a pushed request to events, if any. It is called without q.mu held
+// so that events may use the queue.
+func notifyQueued(events Events, item QueuedRequest) {
+	if events != nil {
+		events.OnQueued(item)
+	}
+}
+
+// push enqueues a request with q.mu held
+func (q *RequestQueue) push(req BatchMetaTxRequest, priority Priority) QueuedRequest {
+	if priority < PriorityLow {
+		priority = PriorityLow
+	}
+	if priority > PriorityHigh {
+		priority = PriorityHigh
+	}
+
+	q.nextID++
+	q.queued[req.SignerNonce()] = q.nextID
+	item := QueuedRequest{
+		ID:         q.nextID,
+		Request:    req,
+		Priority:   priority,
+		EnqueuedAt: q.now(),
+	}
+	q.items[priority] = append(q.items[priority], item)
+	return item
+}
+
+// Pop dequeues up to max requests, highest priority first
+func (q *RequestQueue) Pop(max int) []QueuedRequest {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var out []QueuedRequest
+	for p := numPriorities - 1; p >= 0 && len(out) < max; p-- {
+		n := max - len(out)
+		if n > len(q.items[p]) {
+			n = len(q.items[p])
+		}
+		out = append(out, q.items[p][:n]...)
+		q.items[p] = q.items[p][n:]
+	}
+	for _, item := range out {
+		if key := item.Request.SignerNonce(); q.queued[key] == item.ID {
+			delete(q.queued, key)
+		}
+	}
+	return out
+}
+
+// Batch dequeues up to max requests as a batch ready for RelayMetaTxBatch
+func (q *RequestQueue) Batch(max int) BatchMetaTxRequestList {
+	queued := q.Pop(max)
+	batch := make(BatchMetaTxRequestList, len(queued))
+	for i, item := range queued {
+		batch[i] = item.Request
+	}
+	return batch
+}
+
+// Len returns the number of queued requests
+func (q *RequestQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, items := range q.items {
+		n += len(items)
+	}
+	return n
+}
+
+// Stats returns the queue depth, the age of the oldest request and the nearest deadline,
+// overall and per priority
+func (q *RequestQueue) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	stats := QueueStats{ByPriority: make(map[string]BacklogStats, numPriorities)}
+	for p, items := range q.items {
+		backlog := backlogOf(items, now)
+		stats.ByPriority[Priority(p).String()] = backlog
+
+		stats.Depth += backlog.Depth
+		if backlog.OldestAgeSeconds > stats.OldestAgeSeconds {
+			stats.OldestAgeSeconds = backlog.OldestAgeSeconds
+		}
+		if backlog.NearestDeadline != 0 && (stats.NearestDeadline == 0 || backlog.NearestDeadline < stats.NearestDeadline) {
+			stats.NearestDeadline = backlog.NearestDeadline
+		}
+	}
+	return stats
+}
+
+// backlogOf summarizes the requests of one priority, which are in enqueue order
+func backlogOf(items []QueuedRequest, now time.Time) BacklogStats {
+	backlog := BacklogStats{Depth: len(items)}
+	if len(items) == 0 {
+		return backlog
+	}
+	backlog.OldestAgeSeconds = now.Sub(items[0].EnqueuedAt).Seconds()
+	for _, item := range items {
+		deadline := item.Request.MetaTx.Deadline
+		if backlog.NearestDeadline == 0 || deadline < backlog.NearestDeadline {
+			backlog.NearestDeadline = deadline
+		}
+	}
+	return backlog
+}
+
+// Handler serves the queue stats as JSON, for autoscalers and health dashboards
+func (q *RequestQueue) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(q.Stats())
+	})
+}
+
+// PublishExpvar exposes the queue stats under name on /debug/vars.
+// Like expvar.Publish it panics if name is already in use.
+func (q *RequestQueue) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return q.Stats()
+	}))
+}
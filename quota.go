@@ -0,0 +1,285 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// QuotaRule limits what one user may have relayed within a fixed time window. Windows are
+// aligned to the unix epoch, so a daily window resets at midnight UTC.
+type QuotaRule struct {
+	Window      time.Duration // e.g. time.Hour or 24*time.Hour
+	MaxRequests uint64        // requests per window, 0 for no limit
+	MaxGas      uint64        // cumulative inner call gas per window, 0 for no limit
+}
+
+// QuotaUsage is what a user had relayed in one window
+type QuotaUsage struct {
+	Requests uint64 `json:"requests"`
+	Gas      uint64 `json:"gas"`
+}
+
+// QuotaCounter stores quota usage by key. Counters in shared storage, such as Redis or a SQL
+// database, let several relayer processes enforce one quota. Implementations must be safe
+// for concurrent use.
+type QuotaCounter interface {
+	// Add atomically adds usage to the counter key, which expires at expires, and returns its
+	// new total
+	Add(ctx context.Context, key string, usage QuotaUsage, expires time.Time) (QuotaUsage, error)
+	// Sub takes usage added before back from the counter key
+	Sub(ctx context.Context, key string, usage QuotaUsage) error
+	// Get returns the counter key, zero if it does not exist or expired
+	Get(ctx context.Context, key string) (QuotaUsage, error)
+}
+
+// Quota is a Policy limiting the requests and gas relayed per signer, or per sponsor when
+// created with NewSponsorQuota. Check reserves the request's gas in every rule's current
+// window, and the reservation is reverted when the relay is refused or fails to send. Quota is also a QuotaReporter for FeedbackService.
+type Quota struct {
+	counter   QuotaCounter
+	rules     []QuotaRule
+	bySponsor bool
+	now       func() time.Time
+}
+
+// NewQuota creates a quota enforcing all rules, counting in counter
+func NewQuota(counter QuotaCounter, rules ...QuotaRule) *Quota {
+	return &Quota{counter: counter, rules: rules, now: time.Now}
+}
+
+// NewSponsorQuota creates a quota enforcing all rules per sponsor rather than per signer,
+// counting in counter. The sponsor of a request is the one of its context, see
+// ContextWithSponsor; requests without one share the quota of the sponsor "".
+func NewSponsorQuota(counter QuotaCounter, rules ...QuotaRule) *Quota {
+	return &Quota{counter: counter, rules: rules, bySponsor: true, now: time.Now}
+}
+
+// quotaKey returns the counter key of subject in the window of rule that starts at start
+func quotaKey(subject string, rule QuotaRule, start time.Time) string {
+	return fmt.Sprintf("quota:%s:%d:%d", subject, int64(rule.Window/time.Second), start.Unix())
+}
+
+// subject returns what req relayed with ctx is counted against: its signer, or its sponsor
+func (q *Quota) subject(ctx context.Context, req BatchMetaTxRequest) string {
+	if !q.bySponsor {
+		return req.MetaTx.From.Hex()
+	}
+	sponsor, _ := SponsorFromContext(ctx)
+	return sponsorSubject(sponsor)
+}
+
+// sponsorSubject returns the quota subject of a sponsor, kept apart from signer addresses
+func sponsorSubject(sponsor string) string {
+	return "sponsor/" + sponsor
+}
+
+// window returns the start and end of the rule's window containing now
+func (rule QuotaRule) window(now time.Time) (time.Time, time.Time) {
+	start := now.Truncate(rule.Window)
+	return start, start.Add(rule.Window)
+}
+
+// exceeds reports whether usage is over the rule's limits
+func (rule QuotaRule) exceeds(usage QuotaUsage) bool {
+	return (rule.MaxRequests > 0 && usage.Requests > rule.MaxRequests) ||
+		(rule.MaxGas > 0 && usage.Gas > rule.MaxGas)
+}
+
+// Reserve counts one request of from using gas against every rule, or returns a
+// *PolicyError wrapping ErrQuotaExceeded without counting it if a rule would be exceeded
+func (q *Quota) Reserve(ctx context.Context, from common.Address, gas uint64) error {
+	return q.reserve(ctx, from.Hex(), gas)
+}
+
+// reserve counts one request of subject using gas against every rule
+func (q *Quota) reserve(ctx context.Context, subject string, gas uint64) error {
+	now := q.now()
+	usage := QuotaUsage{Requests: 1, Gas: gas}
+	for i, rule := range q.rules {
+		start, end := rule.window(now)
+		key := quotaKey(subject, rule, start)
+		total, err := q.counter.Add(ctx, key, usage, end)
+		if err != nil {
+			q.release(ctx, subject, usage, now, i)
+			return fmt.Errorf("failed to count quota: %w", err)
+		}
+		if rule.exceeds(total) {
+			q.release(ctx, subject, usage, now, i+1)
+			return &PolicyError{
+				Policy:     "quota",
+				Reason:     fmt.Sprintf("%s used %d requests and %d gas of %s in the %s window", subject, total.Requests-1, total.Gas-gas, describeRule(rule), rule.Window),
+				Cause:      ErrQuotaExceeded,
+				RetryAfter: end.Sub(now),
+			}
+		}
+	}
+	return nil
+}
+
+// Release takes back a reservation of Reserve made in the current windows
+func (q *Quota) Release(ctx context.Context, from common.Address, gas uint64) {
+	q.release(ctx, from.Hex(), QuotaUsage{Requests: 1, Gas: gas}, q.now(), len(q.rules))
+}
+
+// release subtracts usage from the windows of the first n rules at now
+func (q *Quota) release(ctx context.Context, subject string, usage QuotaUsage, now time.Time, n int) {
+	for _, rule := range q.rules[:n] {
+		start, _ := rule.window(now)
+		q.counter.Sub(ctx, quotaKey(subject, rule, start), usage)
+	}
+}
+
+// Check reserves quota for the request
+func (q *Quota) Check(ctx context.Context, req BatchMetaTxRequest) error {
+	return q.reserve(ctx, q.subject(ctx, req), req.MetaTx.Gas)
+}
+
+// Revert releases the quota reserved for the request
+func (q *Quota) Revert(ctx context.Context, req BatchMetaTxRequest) {
+	q.release(ctx, q.subject(ctx, req), QuotaUsage{Requests: 1, Gas: req.MetaTx.Gas}, q.now(), len(q.rules))
+}
+
+// Usage returns what from used in the current window of each rule, in rule order
+func (q *Quota) Usage(ctx context.Context, from common.Address) ([]QuotaUsage, error) {
+	return q.usage(ctx, from.Hex())
+}
+
+// SponsorUsage returns what sponsor used of a quota created with NewSponsorQuota in the
+// current window of each rule, in rule order
+func (q *Quota) SponsorUsage(ctx context.Context, sponsor string) ([]QuotaUsage, error) {
+	return q.usage(ctx, sponsorSubject(sponsor))
+}
+
+// usage returns what subject used in the current window of each rule
+func (q *Quota) usage(ctx context.Context, subject string) ([]QuotaUsage, error) {
+	now := q.now()
+	usage := make([]QuotaUsage, len(q.rules))
+	for i, rule := range q.rules {
+		start, _ := rule.window(now)
+		u, err := q.counter.Get(ctx, quotaKey(subject, rule, start))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read quota: %w", err)
+		}
+		usage[i] = u
+	}
+	return usage, nil
+}
+
+// Quota reports the request limit with the fewest requests left, for FeedbackService
+func (q *Quota) Quota(ctx context.Context, from common.Address) (QuotaStatus, error) {
+	usage, err := q.Usage(ctx, from)
+	if err != nil {
+		return QuotaStatus{}, err
+	}
+
+	now := q.now()
+	var status QuotaStatus
+	found := false
+	for i, rule := range q.rules {
+		if rule.MaxRequests == 0 {
+			continue
+		}
+		used := usage[i].Requests
+		remaining := uint64(0)
+		if used < rule.MaxRequests {
+			remaining = rule.MaxRequests - used
+		}
+		if !found || remaining < status.Remaining {
+			_, end := rule.window(now)
+			status = QuotaStatus{Limit: rule.MaxRequests, Used: used, Remaining: remaining, ResetsAt: end}
+			found = true
+		}
+	}
+	return status, nil
+}
+
+// describeRule returns the limits of a rule as text
+func describeRule(rule QuotaRule) string {
+	switch {
+	case rule.MaxRequests > 0 && rule.MaxGas > 0:
+		return fmt.Sprintf("%d requests and %d gas", rule.MaxRequests, rule.MaxGas)
+	case rule.MaxRequests > 0:
+		return fmt.Sprintf("%d requests", rule.MaxRequests)
+	default:
+		return fmt.Sprintf("%d gas", rule.MaxGas)
+	}
+}
+
+// memoryQuotaEntry is one counter of a MemoryQuotaCounter
+type memoryQuotaEntry struct {
+	usage   QuotaUsage
+	expires time.Time
+}
+
+// MemoryQuotaCounter is a QuotaCounter kept in memory, for single-process relayers
+type MemoryQuotaCounter struct {
+	mu        sync.Mutex
+	entries   map[string]memoryQuotaEntry
+	nextPrune time.Time
+	now       func() time.Time
+}
+
+// NewMemoryQuotaCounter creates an empty in-memory counter
+func NewMemoryQuotaCounter() *MemoryQuotaCounter {
+	return &MemoryQuotaCounter{entries: make(map[string]memoryQuotaEntry), now: time.Now}
+}
+
+// Add adds usage to a counter
+func (c *MemoryQuotaCounter) Add(ctx context.Context, key string, usage QuotaUsage, expires time.Time) (QuotaUsage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	c.prune(now)
+
+	entry := c.entries[key]
+	if !entry.expires.After(now) {
+		entry = memoryQuotaEntry{}
+	}
+	entry.usage.Requests += usage.Requests
+	entry.usage.Gas += usage.Gas
+	entry.expires = expires
+	c.entries[key] = entry
+	return entry.usage, nil
+}
+
+// Sub takes usage back from a counter
+func (c *MemoryQuotaCounter) Sub(ctx context.Context, key string, usage QuotaUsage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry.usage.Requests -= min(entry.usage.Requests, usage.Requests)
+	entry.usage.Gas -= min(entry.usage.Gas, usage.Gas)
+	c.entries[key] = entry
+	return nil
+}
+
+// Get returns a counter
+func (c *MemoryQuotaCounter) Get(ctx context.Context, key string) (QuotaUsage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !entry.expires.After(c.now()) {
+		return QuotaUsage{}, nil
+	}
+	return entry.usage, nil
+}
+
+// prune drops expired counters at most once a minute, with c.mu held
+func (c *MemoryQuotaCounter) prune(now time.Time) {
+	if now.Before(c.nextPrune) {
+		return
+	}
+	for key, entry := range c.entries {
+		if !entry.expires.After(now) {
+			delete(c.entries, key)
+		}
+	}
+	c.nextPrune = now.Add(time.Minute)
+}
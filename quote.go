@@ -0,0 +1,183 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// FEE_QUOTE_TYPEHASH is the EIP-712 struct typehash of a FeeQuote
+const FEE_QUOTE_TYPEHASH = "FeeQuote(bytes32 requestId,address relayer,address token,uint256 maxFee,uint256 validUntil)"
+
+// FeeQuote is the most a relayer will charge for relaying one request. Both parties sign it
+// as EIP-712 typed data under the domain the request was signed in, so the agreement can be
+// checked by anyone before and after the relay.
+type FeeQuote struct {
+	RequestID  common.Hash    `json:"requestId"`
+	Relayer    common.Address `json:"relayer"`
+	Token      common.Address `json:"token"`  // token the fee is paid in, zero for wei
+	MaxFee     *big.Int       `json:"maxFee"` // in base units of Token
+	ValidUntil uint64         `json:"validUntil"`
+}
+
+// SignedFeeQuote is a FeeQuote signed by the relayer and, once accepted, countersigned by
+// the request's signer
+type SignedFeeQuote struct {
+	Quote            FeeQuote   `json:"quote"`
+	RelayerSignature Signature  `json:"relayerSignature"`
+	UserSignature    *Signature `json:"userSignature,omitempty"` // nil until the user accepts
+}
+
+// HashFeeQuote returns the EIP-712 digest of a quote
+func HashFeeQuote(quote FeeQuote, domainSeparator []byte) ([]byte, error) {
+	if quote.MaxFee == nil || quote.MaxFee.Sign() < 0 {
+		return nil, fmt.Errorf("%w: invalid max fee", ErrInvalidFeeQuote)
+	}
+
+	data := make([]byte, 0, 32*6)
+	data = append(data, crypto.Keccak256([]byte(FEE_QUOTE_TYPEHASH))...)
+	data = append(data, quote.RequestID.Bytes()...)
+	data = append(data, encodeAddressWord(quote.Relayer)...)
+	data = append(data, encodeAddressWord(quote.Token)...)
+	data = append(data, encodeUintWord(quote.MaxFee)...)
+	data = append(data, encodeUint64Word(quote.ValidUntil)...)
+	return typedDataDigest(domainSeparator, crypto.Keccak256(data)), nil
+}
+
+// SignFeeQuote signs a quote, as the relayer or as the user accepting it
+func SignFeeQuote(quote FeeQuote, privKey *ecdsa.PrivateKey, domainSeparator []byte) (Signature, error) {
+	digest, err := HashFeeQuote(quote, domainSeparator)
+	if err != nil {
+		return Signature{}, err
+	}
+	return signDigest(digest, privKey)
+}
+
+// checkFeeQuoteSigner returns an error wrapping ErrInvalidFeeQuote unless signer signed quote
+func checkFeeQuoteSigner(quote FeeQuote, sig Signature, domainSeparator []byte, signer common.Address, party string) error {
+	digest, err := HashFeeQuote(quote, domainSeparator)
+	if err != nil {
+		return err
+	}
+	recovered, err := recoverSigner(digest, sig)
+	if err != nil {
+		return fmt.Errorf("%w: %s signature: %v", ErrInvalidFeeQuote, party, err)
+	}
+	if recovered != signer {
+		return fmt.Errorf("%w: %s signature is from %s, expected %s", ErrInvalidFeeQuote, party, recovered.Hex(), signer.Hex())
+	}
+	return nil
+}
+
+// Verify checks that the quote is signed by its relayer, accepted by user and valid at now,
+// a unix timestamp. Expired quotes fail with ErrFeeQuoteExpired.
+func (q SignedFeeQuote) Verify(domainSeparator []byte, user common.Address, now uint64) error {
+	if err := checkFeeQuoteSigner(q.Quote, q.RelayerSignature, domainSeparator, q.Quote.Relayer, "relayer"); err != nil {
+		return err
+	}
+	if q.UserSignature == nil {
+		return fmt.Errorf("%w: not accepted by %s", ErrInvalidFeeQuote, user.Hex())
+	}
+	if err := checkFeeQuoteSigner(q.Quote, *q.UserSignature, domainSeparator, user, "user"); err != nil {
+		return err
+	}
+	if now > q.Quote.ValidUntil {
+		return fmt.Errorf("%w: valid until %d", ErrFeeQuoteExpired, q.Quote.ValidUntil)
+	}
+	return nil
+}
+
+// AcceptFeeQuote checks a relayer's quote for metaTx and countersigns it with the user's
+// key. The wallet should show the user MaxFee and Token before calling it.
+func AcceptFeeQuote(quote SignedFeeQuote, metaTx MetaTx, userPrivKey *ecdsa.PrivateKey, domainSeparator []byte) (SignedFeeQuote, error) {
+	id, err := requestID(OZForwarderV5Schema, metaTx, domainSeparator)
+	if err != nil {
+		return SignedFeeQuote{}, err
+	}
+	return acceptFeeQuote(quote, id, userPrivKey, domainSeparator)
+}
+
+// AcceptFeeQuoteWithSchema is AcceptFeeQuote for requests signed with a forwarder schema
+// other than the default
+func AcceptFeeQuoteWithSchema(schema *RequestSchema, quote SignedFeeQuote, metaTx MetaTx, userPrivKey *ecdsa.PrivateKey, domainSeparator []byte) (SignedFeeQuote, error) {
+	id, err := requestID(schema, metaTx, domainSeparator)
+	if err != nil {
+		return SignedFeeQuote{}, err
+	}
+	return acceptFeeQuote(quote, id, userPrivKey, domainSeparator)
+}
+
+// acceptFeeQuote countersigns a quote for the request with id
+func acceptFeeQuote(quote SignedFeeQuote, id common.Hash, userPrivKey *ecdsa.PrivateKey, domainSeparator []byte) (SignedFeeQuote, error) {
+	if quote.Quote.RequestID != id {
+		return SignedFeeQuote{}, fmt.Errorf("%w: quote is for request %s, not %s", ErrInvalidFeeQuote, quote.Quote.RequestID.Hex(), id.Hex())
+	}
+	if err := checkFeeQuoteSigner(quote.Quote, quote.RelayerSignature, domainSeparator, quote.Quote.Relayer, "relayer"); err != nil {
+		return SignedFeeQuote{}, err
+	}
+	if uint64(time.Now().Unix()) > quote.Quote.ValidUntil {
+		return SignedFeeQuote{}, fmt.Errorf("%w: valid until %d", ErrFeeQuoteExpired, quote.Quote.ValidUntil)
+	}
+
+	sig, err := SignFeeQuote(quote.Quote, userPrivKey, domainSeparator)
+	if err != nil {
+		return SignedFeeQuote{}, err
+	}
+	quote.UserSignature = &sig
+	return quote, nil
+}
+
+// QuoteFee signs a quote of at most maxFee in token (zero for wei) for relaying metaTx,
+// valid for ttl
+func (r *Relayer) QuoteFee(ctx context.Context, metaTx MetaTx, token common.Address, maxFee *big.Int, ttl time.Duration) (SignedFeeQuote, error) {
+	domainSeparator, err := r.domainSeparator(ctx)
+	if err != nil {
+		return SignedFeeQuote{}, err
+	}
+	id, err := requestID(r.profile.Schema, metaTx, domainSeparator)
+	if err != nil {
+		return SignedFeeQuote{}, err
+	}
+
+	quote := FeeQuote{
+		RequestID:  id,
+		Relayer:    r.address,
+		Token:      token,
+		MaxFee:     new(big.Int).Set(maxFee),
+		ValidUntil: uint64(time.Now().Add(ttl).Unix()),
+	}
+	sig, err := SignFeeQuote(quote, r.privKey, domainSeparator)
+	if err != nil {
+		return SignedFeeQuote{}, err
+	}
+	return SignedFeeQuote{Quote: quote, RelayerSignature: sig}, nil
+}
+
+// CheckFeeQuote checks, before relaying req, that quote is one of this relayer's, for req,
+// accepted by its signer and still valid
+func (r *Relayer) CheckFeeQuote(ctx context.Context, quote SignedFeeQuote, req BatchMetaTxRequest) error {
+	if quote.Quote.Relayer != r.address {
+		return fmt.Errorf("%w: quoted by %s, not this relayer", ErrInvalidFeeQuote, quote.Quote.Relayer.Hex())
+	}
+	domainSeparator, err := r.domainSeparator(ctx)
+	if err != nil {
+		return err
+	}
+	id, err := requestID(r.profile.Schema, req.MetaTx, domainSeparator)
+	if err != nil {
+		return err
+	}
+	if quote.Quote.RequestID != id {
+		return fmt.Errorf("%w: quote is for request %s, not %s", ErrInvalidFeeQuote, quote.Quote.RequestID.Hex(), id.Hex())
+	}
+	now, err := r.deadlineNow(ctx)
+	if err != nil {
+		return err
+	}
+	return quote.Verify(domainSeparator, req.MetaTx.From, now)
+}
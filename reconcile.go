@@ -0,0 +1,151 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NonceIssue is why a pending request cannot execute with the nonce it was signed with
+type NonceIssue string
+
+const (
+	NonceConsumed  NonceIssue = "consumed"  // the signer's forwarder nonce moved past it: the request executed, or another one used its nonce
+	NonceGap       NonceIssue = "gap"       // earlier nonces of the signer are neither used nor pending, so it cannot execute
+	NonceDuplicate NonceIssue = "duplicate" // an earlier pending request holds the same nonce
+)
+
+// NonceFinding is a pending request that needs re-signing, or dropping if it was executed
+type NonceFinding struct {
+	Index    int // position among the reconciled requests
+	Request  BatchMetaTxRequest
+	Issue    NonceIssue
+	OnChain  uint64 // the signer's forwarder nonce
+	Expected uint64 // the nonce the request would need to execute after the pending ones before it
+}
+
+// NonceDrift is a tracked user whose next nonce disagrees with the chain and the pending
+// requests: behind, the tracker would hand out used or held nonces; ahead, it handed out
+// nonces that are neither executed nor pending, which will block later requests
+type NonceDrift struct {
+	User     common.Address
+	Tracked  uint64 // next nonce the tracker hands out
+	Expected uint64 // next nonce after the on-chain nonce and the executable pending requests
+}
+
+// NonceReport is the outcome of ReconcileNonces
+type NonceReport struct {
+	OnChain  map[common.Address]uint64 // forwarder nonce per user
+	Findings []NonceFinding            // in request order
+	Drift    []NonceDrift              // ordered by user
+}
+
+// OK reports whether every pending request can execute and the tracker agrees with the chain
+func (r *NonceReport) OK() bool {
+	return len(r.Findings) == 0 && len(r.Drift) == 0
+}
+
+// Apply moves the drifted users of tracker to their expected next nonce
+func (r *NonceReport) Apply(tracker *NonceTracker) {
+	for _, drift := range r.Drift {
+		tracker.set(drift.User, drift.Expected)
+	}
+}
+
+// ReconcileNonces compares the requests still waiting to be relayed, and the nonces tracker
+// hands out if not nil, with the forwarder nonces on the chain. Pending requests whose nonce
+// was consumed, is held twice, or follows a gap are reported as findings to re-sign; consumed
+// ones may also have executed, which the caller's records tell. pending must hold all waiting
+// requests of their signers, or the missing ones are reported as gaps.
+func ReconcileNonces(ctx context.Context, forwarder common.Address, client EthClient, pending BatchMetaTxRequestList, tracker *NonceTracker) (*NonceReport, error) {
+	return reconcileNonces(ctx, DefaultRegistry, DefaultRegistry.DefaultForwarderProfile(), forwarder, client, pending, tracker)
+}
+
+// ReconcileNonces is ReconcileNonces on the relayer's forwarder
+func (r *Relayer) ReconcileNonces(ctx context.Context, pending BatchMetaTxRequestList, tracker *NonceTracker) (*NonceReport, error) {
+	return reconcileNonces(ctx, r.registry, r.profile, r.forwarder, r.client, pending, tracker)
+}
+
+// reconcileNonces implements ReconcileNonces for a forwarder described by profile, with the
+// ABIs of registry
+func reconcileNonces(ctx context.Context, registry *Registry, profile *ForwarderProfile, forwarder common.Address, client EthClient, pending BatchMetaTxRequestList, tracker *NonceTracker) (*NonceReport, error) {
+	var tracked map[common.Address]uint64
+	if tracker != nil {
+		tracked = tracker.snapshot()
+	}
+	var users []common.Address
+	seen := make(map[common.Address]bool)
+	for _, req := range pending {
+		if !seen[req.MetaTx.From] {
+			seen[req.MetaTx.From] = true
+			users = append(users, req.MetaTx.From)
+		}
+	}
+	for user := range tracked {
+		if !seen[user] {
+			seen[user] = true
+			users = append(users, user)
+		}
+	}
+	report := &NonceReport{OnChain: make(map[common.Address]uint64, len(users))}
+	if len(users) == 0 {
+		return report, nil
+	}
+	nonces, err := getMetaTxNonces(ctx, registry, profile, forwarder, users, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile nonces: %w", err)
+	}
+	for i, user := range users {
+		report.OnChain[user] = nonces[i]
+	}
+
+	// Walk each signer's requests in nonce order from its on-chain nonce
+	order := make([]int, len(pending))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return pending[order[a]].MetaTx.Nonce < pending[order[b]].MetaTx.Nonce
+	})
+	expected := make(map[common.Address]uint64, len(users))
+	for user, nonce := range report.OnChain {
+		expected[user] = nonce
+	}
+	blocked := make(map[common.Address]bool)
+	seenSlots := make(map[SignerNonce]bool, len(pending))
+	for _, i := range order {
+		req := pending[i]
+		signer, nonce := req.MetaTx.From, req.MetaTx.Nonce
+		finding := NonceFinding{Index: i, Request: req, OnChain: report.OnChain[signer], Expected: expected[signer]}
+		switch {
+		case nonce < finding.OnChain:
+			finding.Issue = NonceConsumed
+		case seenSlots[req.SignerNonce()]:
+			finding.Issue = NonceDuplicate
+		case blocked[signer] || nonce > expected[signer]:
+			blocked[signer] = true
+			finding.Issue = NonceGap
+			seenSlots[req.SignerNonce()] = true
+		default:
+			seenSlots[req.SignerNonce()] = true
+			expected[signer] = nonce + 1
+			continue
+		}
+		report.Findings = append(report.Findings, finding)
+	}
+	sort.Slice(report.Findings, func(a, b int) bool {
+		return report.Findings[a].Index < report.Findings[b].Index
+	})
+
+	for user, next := range tracked {
+		if next != expected[user] {
+			report.Drift = append(report.Drift, NonceDrift{User: user, Tracked: next, Expected: expected[user]})
+		}
+	}
+	sort.Slice(report.Drift, func(a, b int) bool {
+		return report.Drift[a].User.Cmp(report.Drift[b].User) < 0
+	})
+	return report, nil
+}
@@ -0,0 +1,179 @@
+package eip2771toolkit
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// DEFAULT_GAS_LIMIT is the inner call gas limit used by the *WithDefaultGas helpers
+const DEFAULT_GAS_LIMIT = 100000
+
+// Registry holds the mutable presets of the toolkit: request schemas, forwarder profiles,
+// parsed ABIs and defaults. All methods are safe for concurrent use, so servers can update
+// presets at runtime. Independent Registry instances can be created with NewRegistry; the
+// package-level Register/Lookup functions operate on DefaultRegistry.
+type Registry struct {
+	mu              sync.RWMutex
+	schemas         map[string]*RequestSchema
+	profiles        map[string]*ForwarderProfile
+	abis            map[string]*abi.ABI
+	deployments     map[uint64]ForwarderDeployment
+	defaultProfile  *ForwarderProfile
+	defaultGasLimit uint64
+}
+
+// NewRegistry creates a registry preloaded with the built-in schemas and profiles
+func NewRegistry() *Registry {
+	biconomy := NewBiconomyProfile(0)
+	return &Registry{
+		schemas: map[string]*RequestSchema{
+			OZ_FORWARDER_V5_SCHEMA:     OZForwarderV5Schema,
+			BICONOMY_FORWARDER_PROFILE: biconomy.Schema,
+		},
+		profiles: map[string]*ForwarderProfile{
+			OZ_FORWARDER_V5_SCHEMA:     OZForwarderV5Profile,
+			BICONOMY_FORWARDER_PROFILE: biconomy,
+		},
+		abis:            make(map[string]*abi.ABI),
+		deployments:     knownForwarderDeployments(),
+		defaultProfile:  OZForwarderV5Profile,
+		defaultGasLimit: DEFAULT_GAS_LIMIT,
+	}
+}
+
+// DefaultRegistry is the registry used by package-level functions and by Relayers created without WithRegistry
+var DefaultRegistry = NewRegistry()
+
+// RegisterRequestSchema makes a schema available by name, replacing any schema with the same name
+func (reg *Registry) RegisterRequestSchema(schema *RequestSchema) error {
+	if schema == nil || schema.Name == "" {
+		return fmt.Errorf("request schema must have a name")
+	}
+	if schema.PrimaryType == "" || len(schema.Fields) == 0 {
+		return fmt.Errorf("request schema %s must declare a primary type and fields", schema.Name)
+	}
+	if (schema.RequestData == nil && schema.ExecuteArgs == nil) || schema.ExecuteMethod == "" {
+		return fmt.Errorf("request schema %s must declare its execute method and request data", schema.Name)
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.schemas[schema.Name] = schema
+	return nil
+}
+
+// LookupRequestSchema returns the schema registered under name
+func (reg *Registry) LookupRequestSchema(name string) (*RequestSchema, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	schema, ok := reg.schemas[name]
+	return schema, ok
+}
+
+// RegisterForwarderProfile makes a profile selectable by name, replacing any profile with the same name.
+// The profile's schema is registered alongside it.
+func (reg *Registry) RegisterForwarderProfile(profile *ForwarderProfile) error {
+	if profile == nil || profile.Name == "" {
+		return fmt.Errorf("forwarder profile must have a name")
+	}
+	if profile.Schema == nil || profile.DomainSeparator == nil || profile.NonceArgs == nil {
+		return fmt.Errorf("forwarder profile %s must declare a schema, domain separator and nonce arguments", profile.Name)
+	}
+	if err := reg.RegisterRequestSchema(profile.Schema); err != nil {
+		return err
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.profiles[profile.Name] = profile
+	return nil
+}
+
+// LookupForwarderProfile returns the profile registered under name
+func (reg *Registry) LookupForwarderProfile(name string) (*ForwarderProfile, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	profile, ok := reg.profiles[name]
+	return profile, ok
+}
+
+// DefaultForwarderProfile returns the profile new Relayers use unless configured otherwise
+func (reg *Registry) DefaultForwarderProfile() *ForwarderProfile {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return reg.defaultProfile
+}
+
+// SetDefaultForwarderProfile changes the profile new Relayers use unless configured otherwise
+func (reg *Registry) SetDefaultForwarderProfile(profile *ForwarderProfile) error {
+	if err := reg.RegisterForwarderProfile(profile); err != nil {
+		return err
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.defaultProfile = profile
+	return nil
+}
+
+// DefaultGasLimit returns the inner call gas limit used when none is given
+func (reg *Registry) DefaultGasLimit() uint64 {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return reg.defaultGasLimit
+}
+
+// SetDefaultGasLimit changes the inner call gas limit used when none is given
+func (reg *Registry) SetDefaultGasLimit(gas uint64) error {
+	if gas == 0 {
+		return fmt.Errorf("default gas limit must be positive")
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.defaultGasLimit = gas
+	return nil
+}
+
+// ABI returns the parsed form of a JSON ABI, parsing it only on first use
+func (reg *Registry) ABI(abiJSON string) (*abi.ABI, error) {
+	reg.mu.RLock()
+	parsed, ok := reg.abis[abiJSON]
+	reg.mu.RUnlock()
+	if ok {
+		return parsed, nil
+	}
+
+	parsedABI, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.abis[abiJSON] = &parsedABI
+	return &parsedABI, nil
+}
+
+// RegisterRequestSchema registers a schema in DefaultRegistry
+func RegisterRequestSchema(schema *RequestSchema) error {
+	return DefaultRegistry.RegisterRequestSchema(schema)
+}
+
+// LookupRequestSchema looks a schema up in DefaultRegistry
+func LookupRequestSchema(name string) (*RequestSchema, bool) {
+	return DefaultRegistry.LookupRequestSchema(name)
+}
+
+// RegisterForwarderProfile registers a profile in DefaultRegistry
+func RegisterForwarderProfile(profile *ForwarderProfile) error {
+	return DefaultRegistry.RegisterForwarderProfile(profile)
+}
+
+// LookupForwarderProfile looks a profile up in DefaultRegistry
+func LookupForwarderProfile(name string) (*ForwarderProfile, bool) {
+	return DefaultRegistry.LookupForwarderProfile(name)
+}
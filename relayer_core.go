@@ -0,0 +1,440 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Relayer submits meta transactions to an ERC2771Forwarder contract using a single relayer key
+type Relayer struct {
+	privKey   *ecdsa.PrivateKey
+	address   common.Address
+	forwarder common.Address
+	client    EthClient
+	bidder    BidStrategy
+	gas       GasStrategy
+	profile   *ForwarderProfile
+	registry  *Registry
+
+	validator   Validator
+	batchLimits BatchLimits
+	permissions *CallPermissions
+	policies    []Policy
+	feeTracker  *FeeTracker
+	budget      *Budget
+	feeCaps     *FeeCaps
+	l1Fees      L1FeeEstimator
+	priceOracle PriceOracle
+	logger      *slog.Logger
+	events      Events
+	sender      ethereum.TransactionSender // nil to broadcast through client
+	gasMargin   GasMargin
+	nonces      *NonceManager
+	replayCheck bool
+	replays     replayCache
+
+	nonceTracker *NonceTracker
+
+	settingsMu sync.RWMutex // guards policies, budget, feeCaps and gasMargin, which may be reloaded
+
+	chainTimeDeadlines  bool
+	deadlineBuffer      time.Duration
+	minDeadlineLifetime time.Duration
+
+	chainMu            sync.Mutex
+	cachedChainID      *big.Int
+	chainCheckedAt     time.Time
+	chainCheckInterval time.Duration
+
+	sandbox      bool
+	sandboxMu    sync.Mutex
+	sandboxTxs   map[common.Hash]sandboxTx
+	sandboxOrder []common.Hash // hashes of sandboxTxs, oldest first
+
+	sentMu  sync.Mutex
+	sentTxs map[uint64]*types.Transaction // last transaction sent per relayer nonce
+}
+
+// DEFAULT_CHAIN_ID_CHECK_INTERVAL is how long a Relayer trusts its cached chain ID before asking the node again
+const DEFAULT_CHAIN_ID_CHECK_INTERVAL = time.Minute
+
+// RelayerOption configures optional Relayer behaviour
+type RelayerOption func(*Relayer)
+
+// WithBidStrategy sets the strategy used to price relay transactions
+func WithBidStrategy(bidder BidStrategy) RelayerOption {
+	return func(r *Relayer) {
+		r.bidder = bidder
+	}
+}
+
+// WithRequestSchema sets the forwarder request schema used to encode relayed calls,
+// keeping the OpenZeppelin domain and nonce getter
+func WithRequestSchema(schema *RequestSchema) RelayerOption {
+	return func(r *Relayer) {
+		r.profile = profileForSchema(schema)
+	}
+}
+
+// WithForwarderProfile selects the kind of forwarder the relayer submits to
+func WithForwarderProfile(profile *ForwarderProfile) RelayerOption {
+	return func(r *Relayer) {
+		r.profile = profile
+	}
+}
+
+// WithRegistry sets the registry the relayer takes its default profile and ABI cache from
+func WithRegistry(registry *Registry) RelayerOption {
+	return func(r *Relayer) {
+		r.registry = registry
+	}
+}
+
+// WithChainID pins the chain the relayer may submit to. Relaying fails with ErrChainIDChanged
+// if the node reports any other chain.
+func WithChainID(chainID *big.Int) RelayerOption {
+	return func(r *Relayer) {
+		r.cachedChainID = new(big.Int).Set(chainID)
+	}
+}
+
+// WithChainIDCheckInterval sets how often the cached chain ID is compared with the node's,
+// zero to check before every relay
+func WithChainIDCheckInterval(interval time.Duration) RelayerOption {
+	return func(r *Relayer) {
+		r.chainCheckInterval = interval
+	}
+}
+
+// NewRelayer creates a Relayer for the given forwarder contract
+func NewRelayer(relayerPrivKey *ecdsa.PrivateKey, contractAddr common.Address, ethClient EthClient, opts ...RelayerOption) *Relayer {
+	r := &Relayer{
+		privKey:   relayerPrivKey,
+		address:   crypto.PubkeyToAddress(relayerPrivKey.PublicKey),
+		forwarder: contractAddr,
+		client:    ethClient,
+		bidder:    SuggestedGasPriceBidder{},
+		gas:       NodeGasStrategy{},
+		registry:  DefaultRegistry,
+		gasMargin: GasMargin{Percent: DEFAULT_GAS_MARGIN_PERCENT},
+		validator: DefaultValidators(),
+
+		batchLimits:        BatchLimits{MaxCalldataBytes: DEFAULT_MAX_BATCH_CALLDATA_BYTES},
+		replayCheck:        true,
+		chainCheckInterval: DEFAULT_CHAIN_ID_CHECK_INTERVAL,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.profile == nil {
+		r.profile = r.registry.DefaultForwarderProfile()
+	}
+	if r.nonces == nil {
+		r.nonces = NewNonceManager(ethClient, r.address)
+	}
+	return r
+}
+
+// Address returns the relayer account address
+func (r *Relayer) Address() common.Address {
+	return r.address
+}
+
+// Forwarder returns the forwarder contract address the relayer submits to
+func (r *Relayer) Forwarder() common.Address {
+	return r.forwarder
+}
+
+// GetMetaTxNonce returns the forwarder nonce the next request of user must be signed with
+func (r *Relayer) GetMetaTxNonce(ctx context.Context, user common.Address) (uint64, error) {
+	return getMetaTxNonce(ctx, r.registry, r.profile, r.forwarder, user, r.client)
+}
+
+// GetMetaTxNonces returns the forwarder nonces of users in order, see GetMetaTxNonces
+func (r *Relayer) GetMetaTxNonces(ctx context.Context, users []common.Address) ([]uint64, error) {
+	return getMetaTxNonces(ctx, r.registry, r.profile, r.forwarder, users, r.client)
+}
+
+// ValidateMetaTx applies the relayer's validators to a request, see WithValidators
+func (r *Relayer) ValidateMetaTx(ctx context.Context, metaTx MetaTx) error {
+	if err := r.validator.Validate(ctx, metaTx); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidMetaTx, err)
+	}
+	return nil
+}
+
+// RelayMetaTx submits a single meta transaction through the forwarder's execute method
+func (r *Relayer) RelayMetaTx(ctx context.Context, metaTx MetaTx, sig Signature) (common.Hash, error) {
+	// Validate inputs
+	if err := r.ValidateMetaTx(ctx, metaTx); err != nil {
+		return common.Hash{}, err
+	}
+
+	// Check deadline
+	now, err := r.deadlineNow(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := checkDeadline(now, metaTx.Deadline, r.minDeadlineLifetime); err != nil {
+		return common.Hash{}, err
+	}
+
+	data, err := r.PackExecuteCalldata(ctx, metaTx, sig)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	requests := BatchMetaTxRequestList{{MetaTx: metaTx, Signature: sig}}
+	if err := r.checkPermissions(requests); err != nil {
+		return common.Hash{}, err
+	}
+	if err := r.checkNotExecuted(ctx, requests); err != nil {
+		return common.Hash{}, err
+	}
+	return r.sponsor(ctx, r.forwarder, data, requests.TotalValue(), requests)
+}
+
+// PackExecuteCalldata returns the execute call the relayer would submit for a signed MetaTx
+func (r *Relayer) PackExecuteCalldata(ctx context.Context, metaTx MetaTx, sig Signature) ([]byte, error) {
+	schema := r.profile.Schema
+
+	// Some forwarders take the domain separator as an execute argument
+	var domainSeparator []byte
+	if schema.ExecuteArgs != nil {
+		var err error
+		domainSeparator, err = r.domainSeparator(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return packExecuteCall(r.registry, schema, metaTx, sig, domainSeparator)
+}
+
+// RelayMetaTxBatch submits multiple meta transactions through the forwarder's executeBatch method
+func (r *Relayer) RelayMetaTxBatch(ctx context.Context, batchRequests BatchMetaTxRequestList, refundReceiver common.Address) (common.Hash, error) {
+	if len(batchRequests) == 0 {
+		return common.Hash{}, fmt.Errorf("batch cannot be empty")
+	}
+	if err := r.batchLimits.checkRequests(batchRequests); err != nil {
+		return common.Hash{}, err
+	}
+
+	now, err := r.deadlineNow(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	// Validate all requests in the batch
+	for i, req := range batchRequests {
+		if err := r.validator.Validate(ctx, req.MetaTx); err != nil {
+			return common.Hash{}, fmt.Errorf("%w at index %d: %w", ErrInvalidMetaTx, i, err)
+		}
+
+		// Check deadline for each request
+		if err := checkDeadline(now, req.MetaTx.Deadline, r.minDeadlineLifetime); err != nil {
+			return common.Hash{}, fmt.Errorf("request at index %d: %w", i, err)
+		}
+	}
+
+	if err := r.checkPermissions(batchRequests); err != nil {
+		return common.Hash{}, err
+	}
+	if err := r.checkNotExecuted(ctx, batchRequests); err != nil {
+		return common.Hash{}, err
+	}
+
+	data, _, err := packExecuteBatchCall(r.registry, r.profile.Schema, batchRequests, refundReceiver)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := r.batchLimits.checkCalldata(data); err != nil {
+		return common.Hash{}, err
+	}
+
+	return r.sponsor(ctx, r.forwarder, data, batchRequests.TotalValue(), batchRequests)
+}
+
+// PackExecuteBatchCalldata returns the executeBatch call the relayer would submit for a signed batch
+func (r *Relayer) PackExecuteBatchCalldata(batchRequests BatchMetaTxRequestList, refundReceiver common.Address) ([]byte, error) {
+	data, _, err := packExecuteBatchCall(r.registry, r.profile.Schema, batchRequests, refundReceiver)
+	return data, err
+}
+
+// RelayMetaTxBatchAtomic submits multiple meta transactions atomically (no refund receiver)
+// If any request fails, the entire batch will revert
+func (r *Relayer) RelayMetaTxBatchAtomic(ctx context.Context, batchRequests BatchMetaTxRequestList) (common.Hash, error) {
+	// Use zero address as refund receiver for atomic execution
+	return r.RelayMetaTxBatch(ctx, batchRequests, common.Address{})
+}
+
+// chainID returns the chain ID of the connected network. The first answer is cached and
+// re-checked every chainCheckInterval; an endpoint that switched networks is refused.
+func (r *Relayer) chainID(ctx context.Context) (*big.Int, error) {
+	r.chainMu.Lock()
+	defer r.chainMu.Unlock()
+
+	if r.cachedChainID != nil && !r.chainCheckedAt.IsZero() && time.Since(r.chainCheckedAt) < r.chainCheckInterval {
+		return r.cachedChainID, nil
+	}
+
+	chainID, err := r.client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+	if r.cachedChainID != nil && chainID.Cmp(r.cachedChainID) != 0 {
+		return nil, fmt.Errorf("%w: expected %s, node reports %s", ErrChainIDChanged, r.cachedChainID, chainID)
+	}
+
+	r.cachedChainID = chainID
+	r.chainCheckedAt = time.Now()
+	return chainID, nil
+}
+
+// domainSeparator returns the EIP-712 domain separator of the relayer's forwarder
+func (r *Relayer) domainSeparator(ctx context.Context) ([]byte, error) {
+	chainID, err := r.chainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	domainSeparator, err := r.profile.DomainSeparator(chainID, r.forwarder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build domain separator: %w", err)
+	}
+	return domainSeparator, nil
+}
+
+// submit prices, signs and broadcasts a forwarder call on behalf of the given requests
+func (r *Relayer) submit(ctx context.Context, forwarder common.Address, data []byte, value *big.Int, requests BatchMetaTxRequestList) (common.Hash, error) {
+	// Get chain ID
+	chainID, err := r.chainID(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	// Get the current network fee from the gas strategy
+	suggestedFee, gasPrice, err := r.suggestFee(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	// Estimate gas, with the relayer's safety margin
+	gasLimit, err := r.estimateGasLimit(ctx, ethereum.CallMsg{
+		From:     r.address,
+		To:       &forwarder,
+		GasPrice: gasPrice,
+		Value:    value,
+		Data:     data,
+	})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	// The estimate covers the gas the requests use in the current state; the forwarder must
+	// also be able to pass each request its full Gas under the 63/64 rule, or it reverts
+	if err := CheckRelayGasLimit(gasLimit, requests, data); err != nil {
+		r.log().Debug("gas limit raised to forward the requests' gas", "estimate", gasLimit, "reason", err)
+		gasLimit = EstimateBatchGas(requests, data)
+	}
+	if r.sandbox {
+		if err := r.simulate(ctx, forwarder, requests); err != nil {
+			return common.Hash{}, err
+		}
+	}
+
+	// Ask the bid strategy for the fee to submit with, within the fee caps
+	bid, err := r.bidWithinCaps(ctx, BidContext{
+		ChainID:           chainID,
+		GasLimit:          gasLimit,
+		Requests:          len(requests),
+		NearestDeadline:   nearestDeadline(requests),
+		Now:               time.Now(),
+		SuggestedGasPrice: gasPrice,
+		SuggestedFee:      suggestedFee,
+		Client:            r.client,
+	})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if !r.sandbox {
+		if err := r.checkFunds(ctx, value, gasLimit, bid); err != nil {
+			return common.Hash{}, err
+		}
+	}
+
+	// Reserve the relayer nonce; it is given back if the transaction is not broadcast
+	nonce, err := r.nonces.Acquire(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	// Create transaction
+	tx, err := bid.newTransaction(chainID, nonce, forwarder, value, gasLimit, data)
+	if err != nil {
+		r.nonces.Release(nonce)
+		return common.Hash{}, err
+	}
+
+	// Sign transaction
+	signedTx, err := types.SignTx(tx, types.LatestSignerForChainID(chainID), r.privKey)
+	if err != nil {
+		r.nonces.Release(nonce)
+		return common.Hash{}, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+
+	// Send transaction
+	err = r.send(ctx, signedTx)
+	if err != nil {
+		if isNonceConflict(err) {
+			// Another sender used the nonce; continue from the node's view
+			r.nonces.Resync(ctx)
+		} else {
+			r.nonces.Release(nonce)
+		}
+		return common.Hash{}, fmt.Errorf("failed to send transaction: %w", err)
+	}
+
+	r.log().Info("relay transaction sent", append(txAttrs(signedTx), "forwarder", forwarder, "requests", len(requests))...)
+	return signedTx.Hash(), nil
+}
+
+// checkFunds refuses a relay transaction whose value and maximum fee exceed the relayer's
+// pending balance, which the node would reject only after the relayer nonce was used
+func (r *Relayer) checkFunds(ctx context.Context, value *big.Int, gasLimit uint64, bid FeeBid) error {
+	cost := new(big.Int)
+	if value != nil {
+		cost.Set(value)
+	}
+	if feePerGas := bid.feePerGas(); feePerGas != nil {
+		cost.Add(cost, new(big.Int).Mul(feePerGas, new(big.Int).SetUint64(gasLimit)))
+	}
+	balance, err := r.client.PendingBalanceAt(ctx, r.address)
+	if err != nil {
+		return fmt.Errorf("failed to get relayer balance: %w", err)
+	}
+	if balance.Cmp(cost) < 0 {
+		return fmt.Errorf("%w: %s holds %s wei, the transaction may cost %s", ErrInsufficientRelayerFunds, r.address.Hex(), balance, cost)
+	}
+	return nil
+}
+
+// nearestDeadline returns the earliest deadline among the requests, or 0 if there are none
+func nearestDeadline(requests BatchMetaTxRequestList) uint64 {
+	var nearest uint64
+	for _, req := range requests {
+		if nearest == 0 || req.MetaTx.Deadline < nearest {
+			nearest = req.MetaTx.Deadline
+		}
+	}
+	return nearest
+}
@@ -0,0 +1,112 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NonceManager hands out the transaction nonces of one relayer account. Fetching
+// PendingNonceAt for every relay races when several goroutines relay from the same key; the
+// manager instead reserves nonces locally, reuses nonces released after failed sends so no
+// gap blocks later transactions, and resyncs from the chain when the node disagrees.
+// It is safe for concurrent use.
+type NonceManager struct {
+	client  EthClient
+	address common.Address
+
+	mu       sync.Mutex
+	synced   bool
+	next     uint64
+	released []uint64 // sorted nonces given back below next
+}
+
+// NewNonceManager creates a manager for the transaction nonces of address
+func NewNonceManager(client EthClient, address common.Address) *NonceManager {
+	return &NonceManager{client: client, address: address}
+}
+
+// Acquire reserves the next nonce, preferring released ones. The caller must Release it if
+// the transaction is not broadcast.
+func (m *NonceManager) Acquire(ctx context.Context) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.synced {
+		if err := m.sync(ctx); err != nil {
+			return 0, err
+		}
+	}
+	if len(m.released) > 0 {
+		nonce := m.released[0]
+		m.released = m.released[1:]
+		return nonce, nil
+	}
+	nonce := m.next
+	m.next++
+	return nonce, nil
+}
+
+// Release gives back a nonce whose transaction was not broadcast
+func (m *NonceManager) Release(nonce uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.synced || nonce >= m.next {
+		return
+	}
+	if nonce == m.next-1 {
+		m.next--
+		return
+	}
+	i := sort.Search(len(m.released), func(i int) bool { return m.released[i] >= nonce })
+	if i < len(m.released) && m.released[i] == nonce {
+		return
+	}
+	m.released = append(m.released, 0)
+	copy(m.released[i+1:], m.released[i:])
+	m.released[i] = nonce
+}
+
+// Resync discards local state and continues from the node's pending nonce
+func (m *NonceManager) Resync(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sync(ctx)
+}
+
+// sync loads the pending nonce; m.mu must be held
+func (m *NonceManager) sync(ctx context.Context) error {
+	nonce, err := m.client.PendingNonceAt(ctx, m.address)
+	if err != nil {
+		return fmt.Errorf("failed to get relayer nonce: %w", err)
+	}
+	m.next = nonce
+	m.released = nil
+	m.synced = true
+	return nil
+}
+
+// isNonceConflict reports whether a send failed because the nonce is already used on chain
+// or by another pending transaction, meaning the local nonce state is out of date
+func isNonceConflict(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "nonce too low") || strings.Contains(msg, "replacement transaction underpriced")
+}
+
+// WithNonceManager shares a NonceManager between relayers sending from the same key. The
+// manager must belong to the relayer's address.
+func WithNonceManager(nonces *NonceManager) RelayerOption {
+	return func(r *Relayer) {
+		r.nonces = nonces
+	}
+}
+
+// NonceManager returns the manager handing out the relayer's transaction nonces
+func (r *Relayer) NonceManager() *NonceManager {
+	return r.nonces
+}
@@ -0,0 +1,249 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// pendingNonceClient is an EthClient answering only PendingNonceAt
+type pendingNonceClient struct {
+	EthClient
+	nonce atomic.Uint64
+	calls atomic.Int64
+	err   error
+}
+
+func (c *pendingNonceClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	c.calls.Add(1)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.nonce.Load(), nil
+}
+
+// acquire acquires a nonce or fails the test
+func acquire(t *testing.T, m *NonceManager) uint64 {
+	t.Helper()
+	nonce, err := m.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nonce
+}
+
+func TestNonceManagerConcurrentAcquire(t *testing.T) {
+	client := &pendingNonceClient{}
+	client.nonce.Store(7)
+	m := NewNonceManager(client, common.Address{})
+
+	const n = 200
+	nonces := make([]uint64, n)
+	var wg sync.WaitGroup
+	for i := range nonces {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := m.Acquire(context.Background())
+			if err != nil {
+				t.Error(err)
+			}
+			nonces[i] = nonce
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+	for i, nonce := range nonces {
+		if nonce != uint64(7+i) {
+			t.Fatalf("nonces %v are not 7..%d without gaps or duplicates", nonces, 7+n-1)
+		}
+	}
+	if calls := client.calls.Load(); calls != 1 {
+		t.Errorf("read the pending nonce %d times, want 1", calls)
+	}
+}
+
+func TestNonceManagerRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		acquire int      // nonces acquired from 0 first
+		release []uint64 // then released in order
+		want    []uint64 // the next nonces acquired
+	}{
+		{"latest", 3, []uint64{2}, []uint64{2, 3}},
+		{"not the latest", 3, []uint64{1}, []uint64{1, 3, 4}},
+		{"out of order", 5, []uint64{3, 0, 1}, []uint64{0, 1, 3, 5}},
+		{"twice", 3, []uint64{0, 0}, []uint64{0, 3}},
+		{"never acquired", 2, []uint64{5}, []uint64{2, 3}},
+		{"all, latest first", 3, []uint64{2, 1, 0}, []uint64{0, 1, 2}},
+		{"all, oldest first", 3, []uint64{0, 1, 2}, []uint64{0, 1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewNonceManager(&pendingNonceClient{}, common.Address{})
+			for i := 0; i < tt.acquire; i++ {
+				acquire(t, m)
+			}
+			for _, nonce := range tt.release {
+				m.Release(nonce)
+			}
+			for i, want := range tt.want {
+				if got := acquire(t, m); got != want {
+					t.Fatalf("acquire %d after release = %d, want %d", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNonceManagerReleaseBeforeSync(t *testing.T) {
+	client := &pendingNonceClient{}
+	client.nonce.Store(4)
+	m := NewNonceManager(client, common.Address{})
+	m.Release(2)
+	if got := acquire(t, m); got != 4 {
+		t.Fatalf("acquired %d, want the pending nonce 4", got)
+	}
+}
+
+func TestNonceManagerConcurrentAcquireRelease(t *testing.T) {
+	m := NewNonceManager(&pendingNonceClient{}, common.Address{})
+
+	// Every other goroutine fails to broadcast and gives its nonce back
+	const n = 200
+	var mu sync.Mutex
+	held := make(map[uint64]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := m.Acquire(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if i%2 == 1 {
+				m.Release(nonce)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if held[nonce] {
+				t.Errorf("nonce %d handed out twice", nonce)
+			}
+			held[nonce] = true
+		}()
+	}
+	wg.Wait()
+
+	// The released nonces fill the gaps: acquiring again leaves none below the highest
+	for len(held) < n {
+		nonce := acquire(t, m)
+		if held[nonce] {
+			t.Fatalf("nonce %d handed out twice", nonce)
+		}
+		held[nonce] = true
+	}
+	for nonce := uint64(0); nonce < n; nonce++ {
+		if !held[nonce] {
+			t.Fatalf("nonce %d skipped", nonce)
+		}
+	}
+}
+
+func TestNonceManagerResync(t *testing.T) {
+	client := &pendingNonceClient{}
+	m := NewNonceManager(client, common.Address{})
+	for i := 0; i < 3; i++ {
+		acquire(t, m)
+	}
+	m.Release(1)
+
+	// Another sender used nonces 0..9; the send of nonce 3 fails with a conflict
+	client.nonce.Store(10)
+	if !isNonceConflict(errors.New("nonce too low: next nonce 10, tx nonce 3")) {
+		t.Fatal("nonce too low is not a conflict")
+	}
+	if err := m.Resync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// The released nonce 1 is used on chain and must not come back
+	for _, want := range []uint64{10, 11} {
+		if got := acquire(t, m); got != want {
+			t.Fatalf("acquired %d after resync, want %d", got, want)
+		}
+	}
+}
+
+func TestNonceManagerConcurrentResync(t *testing.T) {
+	client := &pendingNonceClient{}
+	client.nonce.Store(100)
+	m := NewNonceManager(client, common.Address{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if nonce, err := m.Acquire(context.Background()); err == nil && nonce%3 == 0 {
+				m.Release(nonce)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := m.Resync(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The last resync leaves the manager at the node's pending nonce or after it, never before
+	if got := acquire(t, m); got < 100 {
+		t.Fatalf("acquired %d, below the pending nonce 100", got)
+	}
+}
+
+func TestNonceManagerSyncError(t *testing.T) {
+	client := &pendingNonceClient{err: errors.New("node down")}
+	m := NewNonceManager(client, common.Address{})
+	if _, err := m.Acquire(context.Background()); err == nil {
+		t.Fatal("Acquire succeeded without the pending nonce")
+	}
+	if err := m.Resync(context.Background()); err == nil {
+		t.Fatal("Resync succeeded without the pending nonce")
+	}
+
+	// The next Acquire tries again
+	client.err = nil
+	client.nonce.Store(3)
+	if got := acquire(t, m); got != 3 {
+		t.Fatalf("acquired %d, want 3", got)
+	}
+}
+
+func TestIsNonceConflict(t *testing.T) {
+	tests := []struct {
+		err  string
+		want bool
+	}{
+		{"nonce too low: address 0x01, tx: 3 state: 10", true},
+		{"Nonce too low", true},
+		{"replacement transaction underpriced", true},
+		{"insufficient funds for gas * price + value", false},
+		{"nonce too high", false},
+		{"already known", false},
+	}
+	for _, tt := range tests {
+		if got := isNonceConflict(errors.New(tt.err)); got != tt.want {
+			t.Errorf("isNonceConflict(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
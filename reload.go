@@ -0,0 +1,109 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DEFAULT_CONFIG_WATCH_INTERVAL is how often WatchConfig checks the configuration file for changes
+const DEFAULT_CONFIG_WATCH_INTERVAL = 5 * time.Second
+
+// Reload takes over the settings of next that running relayers can change: policies, budget
+// limits, and the fee caps and gas margin of chains configured in both. Quota usage and
+// budget spending recorded so far carry over into next's policies. Keys, chains, endpoints,
+// forwarders and server settings take effect only after a restart. Push the new settings to
+// relayers with ApplyTo.
+func (c *Config) Reload(next *Config) {
+	for _, bySponsor := range []bool{false, true} {
+		if quota, prev := findQuota(next.Policies, bySponsor), findQuota(c.Policies, bySponsor); quota != nil && prev != nil {
+			quota.counter = prev.counter
+		}
+	}
+	if next.Budget != nil && c.Budget != nil {
+		next.Budget.ledger = c.Budget.ledger
+	}
+	c.Policies = next.Policies
+	c.Budget = next.Budget
+
+	for _, chain := range c.Chains {
+		for _, nextChain := range next.Chains {
+			if chain.ChainID != nil && nextChain.ChainID != nil && chain.ChainID.Cmp(nextChain.ChainID) == 0 {
+				chain.FeeCaps = nextChain.FeeCaps
+				chain.GasMargin = nextChain.GasMargin
+			}
+		}
+	}
+}
+
+// findQuota returns the first Quota of policies counting per sponsor or per signer, or nil
+func findQuota(policies []Policy, bySponsor bool) *Quota {
+	for _, policy := range policies {
+		if quota, ok := policy.(*Quota); ok && quota.bySponsor == bySponsor {
+			return quota
+		}
+	}
+	return nil
+}
+
+// ApplyTo sets the policies and budget of a running relayer created from this configuration
+func (c *Config) ApplyTo(r *Relayer) {
+	r.SetPolicies(c.Policies...)
+	r.SetBudget(c.Budget)
+}
+
+// ApplyTo sets the fee caps and gas margin of a running relayer created for this chain. A
+// chain without a gas margin leaves the relayer's unchanged.
+func (c *ChainConfig) ApplyTo(r *Relayer) {
+	r.SetFeeCaps(c.FeeCaps)
+	if c.GasMargin != nil {
+		r.SetGasMargin(*c.GasMargin)
+	}
+}
+
+// WatchConfig loads the configuration file at path again whenever it changes, checked every
+// interval (default DEFAULT_CONFIG_WATCH_INTERVAL), or the process receives SIGHUP, and
+// passes the result to reload until ctx is done. Load errors are passed too, with a nil
+// Config, so the caller can keep running on the previous configuration.
+func WatchConfig(ctx context.Context, path string, interval time.Duration, reload func(*Config, error)) error {
+	if interval <= 0 {
+		interval = DEFAULT_CONFIG_WATCH_INTERVAL
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat config: %w", err)
+	}
+	modTime, size := info.ModTime(), info.Size()
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-hangups:
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil {
+				reload(nil, fmt.Errorf("failed to stat config: %w", err))
+				continue
+			}
+			if info.ModTime().Equal(modTime) && info.Size() == size {
+				continue
+			}
+		}
+
+		if info, err := os.Stat(path); err == nil {
+			modTime, size = info.ModTime(), info.Size()
+		}
+		reload(LoadConfig(path))
+	}
+}
@@ -0,0 +1,204 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DEFAULT_FINALITY_DEPTH is the number of blocks after which a relay is no longer watched for reorgs
+const DEFAULT_FINALITY_DEPTH = 64
+
+// ReorgEvent reports a relay transaction whose block left the canonical chain
+type ReorgEvent struct {
+	Hash        common.Hash  // relay transaction
+	BlockNumber uint64       // block it was mined in before the reorg
+	BlockHash   common.Hash  // hash of that block
+	Reincluded  *RelayResult // set when the transaction is mined again, in another block
+	Rebroadcast bool         // the transaction was sent again after being dropped
+	Err         error        // error of the rebroadcast, if any
+}
+
+// Dropped reports whether the transaction is not in any block after the reorg
+func (e ReorgEvent) Dropped() bool {
+	return e.Reincluded == nil
+}
+
+// watchedRelay is a mined relay transaction watched by a ReorgWatcher
+type watchedRelay struct {
+	tx          *types.Transaction
+	blockNumber uint64
+	blockHash   common.Hash // zero while the transaction is dropped
+	lastResult  RelayResult
+}
+
+// ReorgWatcher keeps watching relays after they were mined and reports those whose block is
+// reorganized away, so downstream accounting can roll back or wait for re-inclusion. A
+// dropped transaction is optionally broadcast again; it stays valid as long as its nonce was
+// not reused. Relays are watched until they are FinalityDepth blocks deep.
+type ReorgWatcher struct {
+	Interval      time.Duration // how often watched relays are checked, default 15s
+	FinalityDepth uint64        // default DEFAULT_FINALITY_DEPTH
+	Rebroadcast   bool          // send dropped transactions again
+
+	relayer *Relayer
+	onReorg func(ReorgEvent)
+
+	mu      sync.Mutex
+	watched map[common.Hash]*watchedRelay
+}
+
+// NewReorgWatcher creates a watcher for relays sent by relayer. onReorg is called from the
+// watcher's goroutine for every relay that is dropped or moves to another block.
+func NewReorgWatcher(relayer *Relayer, onReorg func(ReorgEvent)) *ReorgWatcher {
+	return &ReorgWatcher{
+		Interval:      15 * time.Second,
+		FinalityDepth: DEFAULT_FINALITY_DEPTH,
+		relayer:       relayer,
+		onReorg:       onReorg,
+		watched:       make(map[common.Hash]*watchedRelay),
+	}
+}
+
+// Track starts watching a mined relay, as returned by the wait API
+func (w *ReorgWatcher) Track(ctx context.Context, result RelayResult) error {
+	tx, err := w.relayer.transactionByHash(ctx, result.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.watched[result.Hash] = &watchedRelay{
+		tx:          tx,
+		blockNumber: result.BlockNumber,
+		blockHash:   result.BlockHash,
+		lastResult:  result,
+	}
+	return nil
+}
+
+// Len returns the number of relays being watched
+func (w *ReorgWatcher) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.watched)
+}
+
+// Run checks the watched relays every Interval until ctx is done
+func (w *ReorgWatcher) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		// Node errors are transient; the next round checks the same relays again
+		_ = w.Check(ctx)
+	}
+}
+
+// Check compares every watched relay with the canonical chain once, reports reorgs and stops
+// watching relays that reached the finality depth
+func (w *ReorgWatcher) Check(ctx context.Context) error {
+	head, err := w.relayer.client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block number: %w", err)
+	}
+
+	w.mu.Lock()
+	hashes := make([]common.Hash, 0, len(w.watched))
+	for hash := range w.watched {
+		hashes = append(hashes, hash)
+	}
+	w.mu.Unlock()
+
+	var errs []error
+	for _, hash := range hashes {
+		event, err := w.check(ctx, hash, head)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("relay %s: %w", hash.Hex(), err))
+			continue
+		}
+		if event == nil {
+			continue
+		}
+		if event.Dropped() && w.relayer.events != nil {
+			w.relayer.events.OnDropped(hash)
+		}
+		if w.onReorg != nil {
+			w.onReorg(*event)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// check updates one watched relay and returns an event if it left its block
+func (w *ReorgWatcher) check(ctx context.Context, hash common.Hash, head uint64) (*ReorgEvent, error) {
+	w.mu.Lock()
+	watched, ok := w.watched[hash]
+	w.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+
+	receipt, err := w.relayer.transactionReceipt(ctx, hash)
+	if err != nil && !errors.Is(err, ethereum.NotFound) {
+		return nil, fmt.Errorf("failed to get transaction receipt: %w", err)
+	}
+
+	depth := w.FinalityDepth
+	if depth == 0 {
+		depth = DEFAULT_FINALITY_DEPTH
+	}
+
+	w.mu.Lock()
+	// Still in the block it was seen in. A dropped transaction is given up on at the same
+	// depth, counted from the block it was first mined in.
+	if (err == nil && receipt.BlockHash == watched.blockHash) || (err != nil && watched.blockHash == (common.Hash{})) {
+		if head >= watched.blockNumber+depth {
+			delete(w.watched, hash)
+		}
+		w.mu.Unlock()
+		return nil, nil
+	}
+
+	event := &ReorgEvent{
+		Hash:        hash,
+		BlockNumber: watched.lastResult.BlockNumber,
+		BlockHash:   watched.lastResult.BlockHash,
+	}
+
+	if err == nil {
+		result := newRelayResult(receipt)
+		event.Reincluded = &result
+		watched.blockNumber = result.BlockNumber
+		watched.blockHash = result.BlockHash
+		watched.lastResult = result
+		w.mu.Unlock()
+		return event, nil
+	}
+
+	watched.blockHash = common.Hash{}
+	tx := watched.tx
+	w.mu.Unlock()
+
+	if w.Rebroadcast && tx != nil {
+		event.Err = w.relayer.send(ctx, tx)
+		event.Rebroadcast = event.Err == nil
+	}
+	return event, nil
+}
@@ -0,0 +1,148 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// SpeedUpRelayTx replaces a pending relay transaction with the same nonce and payload at a fee
+// raised by bumpPercent, for a submission languishing in the mempool. Nodes only accept a
+// replacement raised by at least DEFAULT_MIN_BUMP_PERCENT, so smaller bumps are raised to it.
+// It returns the hash of the replacement; either version may end up mined.
+func (r *Relayer) SpeedUpRelayTx(ctx context.Context, originalTxHash common.Hash, bumpPercent uint64) (common.Hash, error) {
+	if bumpPercent < DEFAULT_MIN_BUMP_PERCENT {
+		bumpPercent = DEFAULT_MIN_BUMP_PERCENT
+	}
+
+	original, err := r.pendingRelayTx(ctx, originalTxHash)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	bid := bumpBid(feeBidOf(original), bumpPercent)
+	return r.replace(ctx, original.Nonce(), bid, *original.To(), original.Value(), original.Gas(), original.Data())
+}
+
+// bumpBid raises every fee field of bid by percent
+func bumpBid(bid FeeBid, percent uint64) FeeBid {
+	if bid.IsDynamic() {
+		return FeeBid{GasTipCap: bumpFee(bid.GasTipCap, percent), GasFeeCap: bumpFee(bid.GasFeeCap, percent)}
+	}
+	return FeeBid{GasPrice: bumpFee(bid.GasPrice, percent)}
+}
+
+// pendingRelayTx returns a transaction sent by this relayer that is not mined yet
+func (r *Relayer) pendingRelayTx(ctx context.Context, txHash common.Hash) (*types.Transaction, error) {
+	tx, err := r.transactionByHash(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	if _, err := r.transactionReceipt(ctx, txHash); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAlreadyMined, txHash.Hex())
+	} else if !errors.Is(err, ethereum.NotFound) {
+		return nil, fmt.Errorf("failed to get transaction receipt: %w", err)
+	}
+
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover transaction sender: %w", err)
+	}
+	if sender != r.address {
+		return nil, fmt.Errorf("transaction %s was sent by %s, not by relayer %s", txHash.Hex(), sender.Hex(), r.address.Hex())
+	}
+	return tx, nil
+}
+
+// replace signs and sends a transaction at nonce priced with bid
+func (r *Relayer) replace(ctx context.Context, nonce uint64, bid FeeBid, to common.Address, value *big.Int, gasLimit uint64, data []byte) (common.Hash, error) {
+	chainID, err := r.chainID(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	tx, err := bid.newTransaction(chainID, nonce, to, value, gasLimit, data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	signedTx, err := types.SignTx(tx, types.LatestSignerForChainID(chainID), r.privKey)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	if err := r.send(ctx, signedTx); err != nil {
+		return common.Hash{}, fmt.Errorf("failed to send replacement transaction: %w", err)
+	}
+	r.log().Info("replacement transaction sent", txAttrs(signedTx)...)
+	return signedTx.Hash(), nil
+}
+
+// SENT_TX_HISTORY is how many nonces below the latest one a Relayer remembers sent transactions for
+const SENT_TX_HISTORY = 256
+
+// CancelRelayTx aborts the pending relay transaction at relayerNonce by replacing it with a
+// 0-value self-transfer at a higher fee, e.g. when its requests' deadlines are about to pass.
+// The fee is bumped from the transaction this relayer last sent at that nonce; if it has no
+// record of one (e.g. after a restart), the current gas price plus DEFAULT_MIN_BUMP_PERCENT is
+// used, which may be too low to replace it. Returns the hash of the cancellation.
+func (r *Relayer) CancelRelayTx(ctx context.Context, relayerNonce uint64) (common.Hash, error) {
+	mined, err := r.client.NonceAt(ctx, r.address, nil)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to get relayer nonce: %w", err)
+	}
+	if relayerNonce < mined {
+		return common.Hash{}, fmt.Errorf("%w: relayer nonce %d", ErrAlreadyMined, relayerNonce)
+	}
+
+	var bid FeeBid
+	if prev := r.sentAt(relayerNonce); prev != nil {
+		bid = bumpBid(feeBidOf(prev), DEFAULT_MIN_BUMP_PERCENT)
+	} else {
+		gasPrice, err := r.client.SuggestGasPrice(ctx)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("failed to get gas price: %w", err)
+		}
+		bid = FeeBid{GasPrice: bumpFee(gasPrice, DEFAULT_MIN_BUMP_PERCENT)}
+	}
+
+	return r.replace(ctx, relayerNonce, bid, r.address, big.NewInt(0), TX_BASE_GAS, nil)
+}
+
+// CancelRelayTxByHash aborts the pending relay transaction txHash like CancelRelayTx, with
+// the fee bumped from that transaction, so it also works for transactions this relayer has
+// no record of. Returns the hash of the cancellation.
+func (r *Relayer) CancelRelayTxByHash(ctx context.Context, txHash common.Hash) (common.Hash, error) {
+	original, err := r.pendingRelayTx(ctx, txHash)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	bid := bumpBid(feeBidOf(original), DEFAULT_MIN_BUMP_PERCENT)
+	return r.replace(ctx, original.Nonce(), bid, r.address, big.NewInt(0), TX_BASE_GAS, nil)
+}
+
+// rememberSent records tx as the last transaction sent at its nonce and forgets nonces
+// more than SENT_TX_HISTORY below it
+func (r *Relayer) rememberSent(tx *types.Transaction) {
+	r.sentMu.Lock()
+	defer r.sentMu.Unlock()
+	if r.sentTxs == nil {
+		r.sentTxs = make(map[uint64]*types.Transaction)
+	}
+	r.sentTxs[tx.Nonce()] = tx
+	for nonce := range r.sentTxs {
+		if nonce+SENT_TX_HISTORY < tx.Nonce() {
+			delete(r.sentTxs, nonce)
+		}
+	}
+}
+
+// sentAt returns the last transaction this relayer sent at nonce, or nil
+func (r *Relayer) sentAt(nonce uint64) *types.Transaction {
+	r.sentMu.Lock()
+	defer r.sentMu.Unlock()
+	return r.sentTxs[nonce]
+}
@@ -0,0 +1,123 @@
+package eip2771toolkit
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// REPLAY_CACHE_SIZE is how many signers a Relayer remembers forwarder nonces for. The least
+// recently used are forgotten first; their nonces are read from the node again when needed.
+const REPLAY_CACHE_SIZE = 10000
+
+// replayCache remembers the highest forwarder nonce seen per signer, for up to
+// REPLAY_CACHE_SIZE signers. Forwarder nonces only increase, so requests below the
+// remembered nonce are known to be used without asking the node again.
+type replayCache struct {
+	mu      sync.Mutex
+	entries map[common.Address]*list.Element // of *replayEntry
+	recent  list.List                        // most recently used first
+}
+
+// replayEntry is the remembered forwarder nonce of a signer
+type replayEntry struct {
+	signer common.Address
+	next   uint64
+}
+
+// get returns the remembered forwarder nonce of signer
+func (c *replayCache) get(signer common.Address) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[signer]
+	if !ok {
+		return 0, false
+	}
+	c.recent.MoveToFront(el)
+	return el.Value.(*replayEntry).next, true
+}
+
+// observe remembers a forwarder nonce read from the chain, forgetting the least recently
+// used signer beyond REPLAY_CACHE_SIZE
+func (c *replayCache) observe(signer common.Address, nonce uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[signer]; ok {
+		if entry := el.Value.(*replayEntry); nonce > entry.next {
+			entry.next = nonce
+		}
+		c.recent.MoveToFront(el)
+		return
+	}
+	if nonce == 0 {
+		return // nothing is known to be used yet
+	}
+	if c.entries == nil {
+		c.entries = make(map[common.Address]*list.Element)
+	}
+	c.entries[signer] = c.recent.PushFront(&replayEntry{signer: signer, next: nonce})
+	for c.recent.Len() > REPLAY_CACHE_SIZE {
+		oldest := c.recent.Back()
+		c.recent.Remove(oldest)
+		delete(c.entries, oldest.Value.(*replayEntry).signer)
+	}
+}
+
+// WithReplayCheck sets whether requests are checked against the signers' forwarder nonces
+// before relaying, default true. Disable it to save the nonce reads when requests are known
+// to be fresh.
+func WithReplayCheck(enabled bool) RelayerOption {
+	return func(r *Relayer) {
+		r.replayCheck = enabled
+	}
+}
+
+// checkNotExecuted returns an error wrapping ErrAlreadyExecuted if the forwarder nonce of a
+// request's signer has advanced past the request's nonce, which would make the forwarder
+// revert or skip it. The signers' nonces are read together, see GetMetaTxNonces, and not at
+// all for signers the cache already shows the request of as used.
+func (r *Relayer) checkNotExecuted(ctx context.Context, requests BatchMetaTxRequestList) error {
+	if !r.replayCheck {
+		return nil
+	}
+
+	checked := make(map[common.Address]uint64)
+	var unread []common.Address
+	for _, req := range requests {
+		signer := req.MetaTx.From
+		if _, ok := checked[signer]; ok {
+			continue
+		}
+		if cached, ok := r.replays.get(signer); ok && cached > req.MetaTx.Nonce {
+			checked[signer] = cached
+			continue
+		}
+		checked[signer] = 0
+		unread = append(unread, signer)
+	}
+	if len(unread) > 0 {
+		nonces, err := getMetaTxNonces(ctx, r.registry, r.profile, r.forwarder, unread, r.client)
+		if err != nil {
+			return err
+		}
+		for i, signer := range unread {
+			r.replays.observe(signer, nonces[i])
+			checked[signer] = nonces[i]
+		}
+	}
+
+	for i, req := range requests {
+		signer := req.MetaTx.From
+		if next := checked[signer]; req.MetaTx.Nonce < next {
+			err := fmt.Errorf("%w: nonce %d of %s is used, the forwarder expects %d", ErrAlreadyExecuted, req.MetaTx.Nonce, signer.Hex(), next)
+			if len(requests) > 1 {
+				err = fmt.Errorf("request at index %d: %w", i, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package eip2771toolkit
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// signerAt returns a distinct signer address for i
+func signerAt(i int) common.Address {
+	return common.BigToAddress(big.NewInt(int64(i + 1)))
+}
+
+func TestReplayCacheObserve(t *testing.T) {
+	var c replayCache
+	signer := signerAt(0)
+
+	c.observe(signer, 0)
+	if _, ok := c.get(signer); ok {
+		t.Fatal("nonce 0 remembered")
+	}
+	for _, tt := range []struct{ observe, want uint64 }{{3, 3}, {5, 5}, {4, 5}, {5, 5}} {
+		c.observe(signer, tt.observe)
+		if got, ok := c.get(signer); !ok || got != tt.want {
+			t.Fatalf("after observing %d: get = %d, %v, want %d", tt.observe, got, ok, tt.want)
+		}
+	}
+}
+
+func TestReplayCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	var c replayCache
+	for i := 0; i < REPLAY_CACHE_SIZE; i++ {
+		c.observe(signerAt(i), uint64(i+1))
+	}
+	// Signer 0 is used again, so signer 1 is now the least recently used
+	if _, ok := c.get(signerAt(0)); !ok {
+		t.Fatal("signer 0 not remembered")
+	}
+	c.observe(signerAt(REPLAY_CACHE_SIZE), 1)
+
+	if _, ok := c.get(signerAt(1)); ok {
+		t.Error("least recently used signer 1 still remembered")
+	}
+	for _, i := range []int{0, 2, REPLAY_CACHE_SIZE - 1, REPLAY_CACHE_SIZE} {
+		if _, ok := c.get(signerAt(i)); !ok {
+			t.Errorf("signer %d forgotten", i)
+		}
+	}
+
+	// Many more signers keep the cache at its size
+	for i := REPLAY_CACHE_SIZE + 1; i < 3*REPLAY_CACHE_SIZE; i++ {
+		c.observe(signerAt(i), 1)
+	}
+	if len(c.entries) != REPLAY_CACHE_SIZE || c.recent.Len() != REPLAY_CACHE_SIZE {
+		t.Fatalf("remembers %d signers in %d entries, want %d", len(c.entries), c.recent.Len(), REPLAY_CACHE_SIZE)
+	}
+	if _, ok := c.get(signerAt(3*REPLAY_CACHE_SIZE - 1)); !ok {
+		t.Error("latest signer forgotten")
+	}
+}
+
+func TestReplayCacheConcurrentUse(t *testing.T) {
+	var c replayCache
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < REPLAY_CACHE_SIZE; i++ {
+				c.observe(signerAt(g*REPLAY_CACHE_SIZE+i), uint64(i+1))
+				c.get(signerAt(i))
+			}
+		}()
+	}
+	wg.Wait()
+	if len(c.entries) != REPLAY_CACHE_SIZE || c.recent.Len() != REPLAY_CACHE_SIZE {
+		t.Fatalf("remembers %d signers in %d entries, want %d", len(c.entries), c.recent.Len(), REPLAY_CACHE_SIZE)
+	}
+}
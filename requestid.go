@@ -0,0 +1,53 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RequestID returns the identifier of a request for the default forwarder profile: its
+// EIP-712 digest on the forwarder at chainId. The digest covers the signer and forwarder
+// nonce, so it is unique per executable request and does not depend on the signature
+// encoding, making it usable as an idempotency key, primary key and deduplication handle.
+// Requests that cannot be hashed, e.g. with a deadline beyond uint48, get the zero hash;
+// use RequestIDWithProfile to see the error.
+func RequestID(metaTx MetaTx, chainId *big.Int, forwarder common.Address) common.Hash {
+	id, err := RequestIDWithProfile(DefaultRegistry.DefaultForwarderProfile(), metaTx, chainId, forwarder)
+	if err != nil {
+		return common.Hash{}
+	}
+	return id
+}
+
+// RequestIDWithProfile returns the identifier of a request signed for a forwarder of profile
+func RequestIDWithProfile(profile *ForwarderProfile, metaTx MetaTx, chainId *big.Int, forwarder common.Address) (common.Hash, error) {
+	domainSeparator, err := profile.DomainSeparator(chainId, forwarder)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to build domain separator: %w", err)
+	}
+	return requestID(profile.Schema, metaTx, domainSeparator)
+}
+
+// RequestID returns the identifier of a request relayed through the relayer's forwarder
+func (r *Relayer) RequestID(ctx context.Context, metaTx MetaTx) (common.Hash, error) {
+	domainSeparator, err := r.domainSeparator(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return requestID(r.profile.Schema, metaTx, domainSeparator)
+}
+
+// requestID hashes metaTx under schema and the domain
+func requestID(schema *RequestSchema, metaTx MetaTx, domainSeparator []byte) (common.Hash, error) {
+	if !validAmount(metaTx.Amount) {
+		return common.Hash{}, ErrInvalidAmount
+	}
+	digest, err := schema.HashMetaTx(metaTx, domainSeparator)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to hash MetaTx: %w", err)
+	}
+	return common.BytesToHash(digest), nil
+}
@@ -0,0 +1,400 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/big"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// RetryPolicy controls how failed node calls are retried
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first, default 3
+	BaseDelay   time.Duration // delay before the first retry, doubled after each attempt, default 200ms
+	MaxDelay    time.Duration // upper bound of a single delay, default 5s
+	Jitter      float64       // random fraction (0-1) added to or removed from each delay, default 0.2
+
+	// Retryable decides whether an error is a node failure worth retrying. It defaults to
+	// IsRetryableRPCError; application errors such as reverts are returned immediately.
+	Retryable func(error) bool
+}
+
+// BreakerState is the state of a CircuitBreaker
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // calls pass through
+	BreakerOpen                         // calls fail fast with ErrCircuitOpen
+	BreakerHalfOpen                     // one trial call is let through after the cooldown
+)
+
+// String returns the lower-case state name
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreaker stops calling a node that keeps failing. After FailureThreshold
+// consecutive node failures it opens for Cooldown, then lets a single trial call through.
+// It is safe for concurrent use.
+type CircuitBreaker struct {
+	FailureThreshold int           // default 5
+	Cooldown         time.Duration // default 30s
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// State returns the current breaker state
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown() {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// allow reports whether a call may proceed
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown() {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+		return true
+	case BreakerHalfOpen:
+		// Only the trial call is in flight
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	}
+	return true
+}
+
+// record updates the breaker with the outcome of a call; nodeFailure is false for
+// successes and application errors
+func (b *CircuitBreaker) record(nodeFailure bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !nodeFailure {
+		b.state = BreakerClosed
+		b.failures = 0
+		b.trial = false
+		return
+	}
+
+	b.failures++
+	threshold := b.FailureThreshold
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if b.state == BreakerHalfOpen || b.failures >= threshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+		b.trial = false
+	}
+}
+
+func (b *CircuitBreaker) cooldown() time.Duration {
+	if b.Cooldown <= 0 {
+		return 30 * time.Second
+	}
+	return b.Cooldown
+}
+
+// IsRetryableRPCError reports whether err looks like a transient node or transport failure:
+// network errors, HTTP 429 and 5xx responses and JSON-RPC rate limiting. Missing results,
+// reverts and other errors returned by the node for the request itself are not retryable.
+func IsRetryableRPCError(err error) bool {
+	if err == nil || errors.Is(err, ethereum.NotFound) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == 429 || httpErr.StatusCode >= 500
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		// -32005: limit exceeded, used by most providers for rate limiting
+		return rpcErr.ErrorCode() == -32005
+	}
+	if strings.Contains(err.Error(), "execution reverted") {
+		return false
+	}
+	return true
+}
+
+// RetryClient wraps an EthClient, retrying transient failures with exponential backoff
+// and jitter, behind an optional circuit breaker. Subscriptions are passed through.
+type RetryClient struct {
+	EthClient
+	Policy  RetryPolicy
+	Breaker *CircuitBreaker // nil to disable
+	Logger  *slog.Logger    // logs retries and open circuits, nil for none
+}
+
+// NewRetryClient wraps client with policy and a circuit breaker with default settings
+func NewRetryClient(client EthClient, policy RetryPolicy) *RetryClient {
+	return &RetryClient{EthClient: client, Policy: policy, Breaker: &CircuitBreaker{}}
+}
+
+// BreakerState returns the state of the client's circuit breaker, closed if it has none
+func (c *RetryClient) BreakerState() BreakerState {
+	if c.Breaker == nil {
+		return BreakerClosed
+	}
+	return c.Breaker.State()
+}
+
+// retryCall runs fn under the client's retry policy and circuit breaker
+func retryCall[T any](ctx context.Context, c *RetryClient, fn func() (T, error)) (T, error) {
+	var zero T
+	p := c.Policy
+	attempts := p.MaxAttempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryableRPCError
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return zero, ctx.Err()
+			case <-time.After(p.backoff(attempt)):
+			}
+		}
+
+		if c.Breaker != nil && !c.Breaker.allow() {
+			c.log().Warn("node call refused, circuit breaker open")
+			return zero, ErrCircuitOpen
+		}
+
+		res, err := fn()
+		nodeFailure := err != nil && retryable(err)
+		if c.Breaker != nil {
+			c.Breaker.record(nodeFailure)
+		}
+		if !nodeFailure {
+			return res, err
+		}
+		lastErr = err
+		c.log().Warn("node call failed", "attempt", attempt+1, "attempts", attempts, "error", err)
+	}
+	return zero, lastErr
+}
+
+// log returns the client's logger
+func (c *RetryClient) log() *slog.Logger {
+	if c.Logger == nil {
+		return DiscardLogger
+	}
+	return c.Logger
+}
+
+// backoff returns the delay before the given retry attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	base, max := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = 200 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 5 * time.Second
+	}
+	jitter := p.Jitter
+	if jitter == 0 {
+		jitter = 0.2
+	}
+
+	delay := base << (attempt - 1)
+	if delay > max || delay <= 0 {
+		delay = max
+	}
+	delta := (rand.Float64()*2 - 1) * jitter * float64(delay)
+	return delay + time.Duration(delta)
+}
+
+// BlockNumber implements ethereum.BlockNumberReader
+func (c *RetryClient) BlockNumber(ctx context.Context) (uint64, error) {
+	return retryCall(ctx, c, func() (uint64, error) { return c.EthClient.BlockNumber(ctx) })
+}
+
+// BlockByHash implements ethereum.ChainReader
+func (c *RetryClient) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return retryCall(ctx, c, func() (*types.Block, error) { return c.EthClient.BlockByHash(ctx, hash) })
+}
+
+// BlockByNumber implements ethereum.ChainReader
+func (c *RetryClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return retryCall(ctx, c, func() (*types.Block, error) { return c.EthClient.BlockByNumber(ctx, number) })
+}
+
+// HeaderByHash implements ethereum.ChainReader
+func (c *RetryClient) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return retryCall(ctx, c, func() (*types.Header, error) { return c.EthClient.HeaderByHash(ctx, hash) })
+}
+
+// HeaderByNumber implements ethereum.ChainReader
+func (c *RetryClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return retryCall(ctx, c, func() (*types.Header, error) { return c.EthClient.HeaderByNumber(ctx, number) })
+}
+
+// TransactionCount implements ethereum.ChainReader
+func (c *RetryClient) TransactionCount(ctx context.Context, blockHash common.Hash) (uint, error) {
+	return retryCall(ctx, c, func() (uint, error) { return c.EthClient.TransactionCount(ctx, blockHash) })
+}
+
+// TransactionInBlock implements ethereum.ChainReader
+func (c *RetryClient) TransactionInBlock(ctx context.Context, blockHash common.Hash, index uint) (*types.Transaction, error) {
+	return retryCall(ctx, c, func() (*types.Transaction, error) { return c.EthClient.TransactionInBlock(ctx, blockHash, index) })
+}
+
+// BalanceAt implements ethereum.ChainStateReader
+func (c *RetryClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return retryCall(ctx, c, func() (*big.Int, error) { return c.EthClient.BalanceAt(ctx, account, blockNumber) })
+}
+
+// StorageAt implements ethereum.ChainStateReader
+func (c *RetryClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	return retryCall(ctx, c, func() ([]byte, error) { return c.EthClient.StorageAt(ctx, account, key, blockNumber) })
+}
+
+// CodeAt implements ethereum.ChainStateReader
+func (c *RetryClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return retryCall(ctx, c, func() ([]byte, error) { return c.EthClient.CodeAt(ctx, account, blockNumber) })
+}
+
+// NonceAt implements ethereum.ChainStateReader
+func (c *RetryClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return retryCall(ctx, c, func() (uint64, error) { return c.EthClient.NonceAt(ctx, account, blockNumber) })
+}
+
+// CallContract implements ethereum.ContractCaller
+func (c *RetryClient) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return retryCall(ctx, c, func() ([]byte, error) { return c.EthClient.CallContract(ctx, call, blockNumber) })
+}
+
+// EstimateGas implements ethereum.GasEstimator
+func (c *RetryClient) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	return retryCall(ctx, c, func() (uint64, error) { return c.EthClient.EstimateGas(ctx, call) })
+}
+
+// SuggestGasPrice implements ethereum.GasPricer
+func (c *RetryClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return retryCall(ctx, c, func() (*big.Int, error) { return c.EthClient.SuggestGasPrice(ctx) })
+}
+
+// SuggestGasTipCap implements ethereum.GasPricer1559
+func (c *RetryClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return retryCall(ctx, c, func() (*big.Int, error) { return c.EthClient.SuggestGasTipCap(ctx) })
+}
+
+// FeeHistory implements ethereum.FeeHistoryReader
+func (c *RetryClient) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error) {
+	return retryCall(ctx, c, func() (*ethereum.FeeHistory, error) {
+		return c.EthClient.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
+	})
+}
+
+// FilterLogs implements ethereum.LogFilterer
+func (c *RetryClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return retryCall(ctx, c, func() ([]types.Log, error) { return c.EthClient.FilterLogs(ctx, q) })
+}
+
+// PendingBalanceAt implements ethereum.PendingStateReader
+func (c *RetryClient) PendingBalanceAt(ctx context.Context, account common.Address) (*big.Int, error) {
+	return retryCall(ctx, c, func() (*big.Int, error) { return c.EthClient.PendingBalanceAt(ctx, account) })
+}
+
+// PendingStorageAt implements ethereum.PendingStateReader
+func (c *RetryClient) PendingStorageAt(ctx context.Context, account common.Address, key common.Hash) ([]byte, error) {
+	return retryCall(ctx, c, func() ([]byte, error) { return c.EthClient.PendingStorageAt(ctx, account, key) })
+}
+
+// PendingCodeAt implements ethereum.PendingStateReader
+func (c *RetryClient) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return retryCall(ctx, c, func() ([]byte, error) { return c.EthClient.PendingCodeAt(ctx, account) })
+}
+
+// PendingNonceAt implements ethereum.PendingStateReader
+func (c *RetryClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return retryCall(ctx, c, func() (uint64, error) { return c.EthClient.PendingNonceAt(ctx, account) })
+}
+
+// PendingTransactionCount implements ethereum.PendingStateReader
+func (c *RetryClient) PendingTransactionCount(ctx context.Context) (uint, error) {
+	return retryCall(ctx, c, func() (uint, error) { return c.EthClient.PendingTransactionCount(ctx) })
+}
+
+// TransactionByHash implements ethereum.TransactionReader
+func (c *RetryClient) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	type result struct {
+		tx      *types.Transaction
+		pending bool
+	}
+	res, err := retryCall(ctx, c, func() (result, error) {
+		tx, pending, err := c.EthClient.TransactionByHash(ctx, txHash)
+		return result{tx, pending}, err
+	})
+	return res.tx, res.pending, err
+}
+
+// TransactionReceipt implements ethereum.TransactionReader
+func (c *RetryClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return retryCall(ctx, c, func() (*types.Receipt, error) { return c.EthClient.TransactionReceipt(ctx, txHash) })
+}
+
+// SendTransaction implements ethereum.TransactionSender. Resending the same signed
+// transaction is safe; a node that already received it from an earlier attempt
+// answers "already known", which is treated as success.
+func (c *RetryClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	_, err := retryCall(ctx, c, func() (struct{}, error) {
+		err := c.EthClient.SendTransaction(ctx, tx)
+		if err != nil && strings.Contains(err.Error(), "already known") {
+			return struct{}{}, nil
+		}
+		return struct{}{}, err
+	})
+	return err
+}
+
+// ChainID implements ethereum.ChainIDReader
+func (c *RetryClient) ChainID(ctx context.Context) (*big.Int, error) {
+	return retryCall(ctx, c, func() (*big.Int, error) { return c.EthClient.ChainID(ctx) })
+}
+
+var _ EthClient = (*RetryClient)(nil)
@@ -0,0 +1,146 @@
+package eip2771toolkit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// panicSelector is the selector of the Panic(uint256) error solidity raises on failed asserts,
+// arithmetic overflow and similar
+var panicSelector = []byte{0x4e, 0x48, 0x7b, 0x71}
+
+// RevertError is a reverted call with its decoded reason. It matches ErrContractCallFailed
+// and, for forwarder custom errors, the corresponding error such as ErrForwarderInvalidSigner.
+type RevertError struct {
+	Reason    string        // Error(string) message, Panic description or known custom error, empty for other custom errors
+	PanicCode *big.Int      // Panic(uint256) code, nil for other reverts
+	Name      string        // known custom error name, e.g. ERC2771ForwarderInvalidSigner
+	Args      []interface{} // known custom error arguments
+	Err       error         // forwarder custom error, e.g. ErrForwarderExpiredRequest; nil for other reverts
+	Data      []byte        // raw revert data
+
+	related error
+}
+
+// Error implements error
+func (e *RevertError) Error() string {
+	switch {
+	case e.Reason != "":
+		return "execution reverted: " + e.Reason
+	case len(e.Data) >= 4:
+		return "execution reverted with error " + hexutil.Encode(e.Data[:4]) + ", data " + hexutil.Encode(e.Data)
+	}
+	return "execution reverted"
+}
+
+// Unwrap makes RevertError match ErrContractCallFailed and the forwarder error, if any
+func (e *RevertError) Unwrap() []error {
+	errs := []error{ErrContractCallFailed}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	if e.related != nil {
+		errs = append(errs, e.related)
+	}
+	return errs
+}
+
+// DecodeRevert decodes revert data: Error(string), Panic(uint256) and the forwarder's custom
+// errors get a Reason, other custom errors keep only the raw data
+func DecodeRevert(data []byte) *RevertError {
+	revert := &RevertError{Data: common.CopyBytes(data)}
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		revert.Reason = reason
+	}
+	if len(data) == 4+32 && bytes.Equal(data[:4], panicSelector) {
+		revert.PanicCode = new(big.Int).SetBytes(data[4:])
+	}
+	decodeForwarderError(revert)
+	return revert
+}
+
+// revertData extracts the revert data a node attached to a JSON-RPC error
+func revertData(err error) ([]byte, bool) {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil, false
+	}
+	hex, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil, false
+	}
+	data, decodeErr := hexutil.Decode(hex)
+	if decodeErr != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// isRevert reports whether err says the call reverted
+func isRevert(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "revert")
+}
+
+// explainRevert turns a reverted call's error into a RevertError. Nodes that drop the revert
+// data from estimates are asked again with eth_call on top of block, nil for the latest one.
+// Errors other than reverts are returned unchanged.
+func explainRevert(ctx context.Context, client EthClient, msg ethereum.CallMsg, block *big.Int, err error) error {
+	if data, ok := revertData(err); ok {
+		return DecodeRevert(data)
+	}
+	if !isRevert(err) {
+		return err
+	}
+	_, callErr := client.CallContract(ctx, msg, block)
+	if callErr == nil {
+		return err
+	}
+	if data, ok := revertData(callErr); ok {
+		return DecodeRevert(data)
+	}
+	return err
+}
+
+// RevertReason explains why a mined relay transaction reverted by replaying it with eth_call
+// on top of the state before its block. It returns nil if the transaction succeeded or the
+// replay does not revert, since transactions earlier in the block are not taken into account.
+func (r *Relayer) RevertReason(ctx context.Context, txHash common.Hash) error {
+	receipt, err := r.transactionReceipt(ctx, txHash)
+	if err != nil {
+		return fmt.Errorf("failed to get transaction receipt: %w", err)
+	}
+	if receipt.Status != 0 {
+		return nil
+	}
+	tx, err := r.transactionByHash(ctx, txHash)
+	if err != nil {
+		return fmt.Errorf("failed to get relay transaction: %w", err)
+	}
+
+	msg := ethereum.CallMsg{
+		From:  r.address,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+	parent := new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1))
+	_, err = r.client.CallContract(ctx, msg, parent)
+	if err == nil {
+		return nil
+	}
+	if data, ok := revertData(err); ok {
+		return DecodeRevert(data)
+	}
+	return fmt.Errorf("failed to replay relay transaction: %w", err)
+}
@@ -0,0 +1,124 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// RPC_BATCH_MAX_CALLS is the number of calls sent in one JSON-RPC batch, within the batch size
+// limits of common providers
+const RPC_BATCH_MAX_CALLS = 100
+
+// rpcClientProvider is implemented by clients built on a JSON-RPC connection, such as
+// *ethclient.Client
+type rpcClientProvider interface {
+	Client() *rpc.Client
+}
+
+// BatchCallContract executes eth_calls of msgs at the latest block and returns their results
+// in order. Clients connected over JSON-RPC, such as *ethclient.Client, send the calls in
+// batches of RPC_BATCH_MAX_CALLS, one round trip each; other clients call one at a time.
+func BatchCallContract(ctx context.Context, client ethereum.ContractCaller, msgs []ethereum.CallMsg) ([][]byte, error) {
+	results := make([][]byte, len(msgs))
+	provider, ok := client.(rpcClientProvider)
+	if !ok || provider.Client() == nil {
+		for i, msg := range msgs {
+			out, err := client.CallContract(ctx, msg, nil)
+			if err != nil {
+				return nil, fmt.Errorf("call at index %d: %w", i, err)
+			}
+			results[i] = out
+		}
+		return results, nil
+	}
+
+	for start := 0; start < len(msgs); start += RPC_BATCH_MAX_CALLS {
+		end := min(start+RPC_BATCH_MAX_CALLS, len(msgs))
+		outs := make([]hexutil.Bytes, end-start)
+		batch := make([]rpc.BatchElem, end-start)
+		for i := range batch {
+			batch[i] = rpc.BatchElem{
+				Method: "eth_call",
+				Args:   []interface{}{callArg(msgs[start+i]), "latest"},
+				Result: &outs[i],
+			}
+		}
+		if err := provider.Client().BatchCallContext(ctx, batch); err != nil {
+			return nil, fmt.Errorf("failed to send call batch: %w", err)
+		}
+		for i, elem := range batch {
+			if elem.Error != nil {
+				return nil, fmt.Errorf("call at index %d: %w", start+i, elem.Error)
+			}
+			results[start+i] = outs[i]
+		}
+	}
+	return results, nil
+}
+
+// callArg converts msg into eth_call parameters, like ethclient does
+func callArg(msg ethereum.CallMsg) interface{} {
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"to":   msg.To,
+	}
+	if len(msg.Data) > 0 {
+		arg["input"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	if msg.GasPrice != nil {
+		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
+	}
+	if msg.GasFeeCap != nil {
+		arg["maxFeePerGas"] = (*hexutil.Big)(msg.GasFeeCap)
+	}
+	if msg.GasTipCap != nil {
+		arg["maxPriorityFeePerGas"] = (*hexutil.Big)(msg.GasTipCap)
+	}
+	if msg.AccessList != nil {
+		arg["accessList"] = msg.AccessList
+	}
+	return arg
+}
+
+// readNonces reads the nonces of users with one eth_call each, batched where the client allows,
+// see BatchCallContract
+func readNonces(ctx context.Context, registry *Registry, profile *ForwarderProfile, forwarder common.Address, users []common.Address, client EthClient) ([]uint64, error) {
+	schema := profile.Schema
+	forwarderABI, err := registry.ABI(schema.ForwarderABI)
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]ethereum.CallMsg, len(users))
+	for i, user := range users {
+		data, err := forwarderABI.Pack(schema.NonceMethod, profile.NonceArgs(user)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pack %s call: %w", schema.NonceMethod, err)
+		}
+		msgs[i] = ethereum.CallMsg{To: &forwarder, Data: data}
+	}
+	outs, err := BatchCallContract(ctx, client, msgs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonces: %w", err)
+	}
+	nonces := make([]uint64, len(users))
+	for i, out := range outs {
+		var nonce *big.Int
+		if err := forwarderABI.UnpackIntoInterface(&nonce, schema.NonceMethod, out); err != nil {
+			return nil, fmt.Errorf("failed to unpack nonce of %s: %w", users[i].Hex(), err)
+		}
+		nonces[i] = nonce.Uint64()
+	}
+	return nonces, nil
+}
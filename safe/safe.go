@@ -0,0 +1,376 @@
+// Package safe proposes forwarder calls as Safe (Gnosis Safe) multisig transactions through
+// the Safe Transaction Service, so a Safe can act as the gas-paying relayer. Meta transactions
+// are still signed by their users; the proposal carries the proposer's signature over the
+// Safe transaction and the remaining owners confirm and execute it in their usual workflow.
+package safe
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// DEFAULT_ORIGIN is the origin recorded with proposed transactions
+const DEFAULT_ORIGIN = "eip2771toolkit"
+
+// Operation is the kind of call a Safe transaction makes
+type Operation uint8
+
+const (
+	Call         Operation = 0
+	DelegateCall Operation = 1
+)
+
+var (
+	// domainTypeHash is keccak256("EIP712Domain(uint256 chainId,address verifyingContract)"), used by Safe v1.3.0 and later
+	domainTypeHash = crypto.Keccak256([]byte("EIP712Domain(uint256 chainId,address verifyingContract)"))
+	// safeTxTypeHash is the EIP-712 type hash of SafeTx
+	safeTxTypeHash = crypto.Keccak256([]byte("SafeTx(address to,uint256 value,bytes data,uint8 operation,uint256 safeTxGas,uint256 baseGas,uint256 gasPrice,address gasToken,address refundReceiver,uint256 nonce)"))
+)
+
+// SafeTx is a Safe multisig transaction. Relay proposals leave the gas fields and refund
+// settings zero: the executing owner pays gas, as a relayer would.
+type SafeTx struct {
+	To             common.Address
+	Value          *big.Int
+	Data           []byte
+	Operation      Operation
+	SafeTxGas      *big.Int
+	BaseGas        *big.Int
+	GasPrice       *big.Int
+	GasToken       common.Address
+	RefundReceiver common.Address
+	Nonce          *big.Int
+}
+
+// Hash returns the EIP-712 hash owners sign (safeTxHash) for the Safe at safe on chainID
+func (tx SafeTx) Hash(chainID *big.Int, safe common.Address) common.Hash {
+	domainSeparator := crypto.Keccak256(
+		domainTypeHash,
+		common.LeftPadBytes(chainID.Bytes(), 32),
+		common.LeftPadBytes(safe.Bytes(), 32),
+	)
+	structHash := crypto.Keccak256(
+		safeTxTypeHash,
+		common.LeftPadBytes(tx.To.Bytes(), 32),
+		common.LeftPadBytes(bigOrZero(tx.Value).Bytes(), 32),
+		crypto.Keccak256(tx.Data),
+		common.LeftPadBytes([]byte{byte(tx.Operation)}, 32),
+		common.LeftPadBytes(bigOrZero(tx.SafeTxGas).Bytes(), 32),
+		common.LeftPadBytes(bigOrZero(tx.BaseGas).Bytes(), 32),
+		common.LeftPadBytes(bigOrZero(tx.GasPrice).Bytes(), 32),
+		common.LeftPadBytes(tx.GasToken.Bytes(), 32),
+		common.LeftPadBytes(tx.RefundReceiver.Bytes(), 32),
+		common.LeftPadBytes(bigOrZero(tx.Nonce).Bytes(), 32),
+	)
+	return common.BytesToHash(crypto.Keccak256([]byte("\x19\x01"), domainSeparator, structHash))
+}
+
+// Client proposes forwarder calls to the Safe Transaction Service of one chain. It implements
+// eip2771toolkit.RelayBackend; task IDs are safeTxHashes.
+type Client struct {
+	BaseURL     string // Transaction Service URL of the chain, e.g. https://safe-transaction-mainnet.safe.global
+	APIKey      string // optional, sent as a bearer token to API gateways that require one
+	ChainID     *big.Int
+	Safe        common.Address    // the Safe acting as relayer
+	ProposerKey *ecdsa.PrivateKey // key of a Safe owner or registered delegate
+	Forwarder   common.Address
+	Profile     *eip2771toolkit.ForwarderProfile
+	Origin      string
+	HTTPClient  *http.Client
+}
+
+// NewClient creates a Client proposing calls to the OpenZeppelin forwarder at forwarder
+func NewClient(baseURL string, chainID *big.Int, safe common.Address, proposerKey *ecdsa.PrivateKey, forwarder common.Address) *Client {
+	return &Client{
+		BaseURL:     baseURL,
+		ChainID:     chainID,
+		Safe:        safe,
+		ProposerKey: proposerKey,
+		Forwarder:   forwarder,
+		Profile:     eip2771toolkit.OZForwarderV5Profile,
+		Origin:      DEFAULT_ORIGIN,
+		HTTPClient:  http.DefaultClient,
+	}
+}
+
+// proposeRequest is the body of the multisig-transactions endpoint
+type proposeRequest struct {
+	To                      string `json:"to"`
+	Value                   string `json:"value"`
+	Data                    string `json:"data"`
+	Operation               uint8  `json:"operation"`
+	SafeTxGas               string `json:"safeTxGas"`
+	BaseGas                 string `json:"baseGas"`
+	GasPrice                string `json:"gasPrice"`
+	GasToken                string `json:"gasToken"`
+	RefundReceiver          string `json:"refundReceiver"`
+	Nonce                   string `json:"nonce"`
+	ContractTransactionHash string `json:"contractTransactionHash"`
+	Sender                  string `json:"sender"`
+	Signature               string `json:"signature"`
+	Origin                  string `json:"origin,omitempty"`
+}
+
+// safeInfo is the response of the safe endpoint; the nonce is a string or a number depending
+// on the service version
+type safeInfo struct {
+	Nonce json.Number `json:"nonce"`
+}
+
+// multisigTx is a multisig transaction as returned by the service
+type multisigTx struct {
+	Nonce                 json.Number `json:"nonce"`
+	SafeTxHash            string      `json:"safeTxHash"`
+	IsExecuted            bool        `json:"isExecuted"`
+	IsSuccessful          *bool       `json:"isSuccessful"`
+	TransactionHash       *string     `json:"transactionHash"`
+	BlockNumber           *uint64     `json:"blockNumber"`
+	ConfirmationsRequired int         `json:"confirmationsRequired"`
+	Confirmations         []struct {
+		Owner string `json:"owner"`
+	} `json:"confirmations"`
+}
+
+// multisigTxList is a page of multisig transactions
+type multisigTxList struct {
+	Results []multisigTx `json:"results"`
+}
+
+// SubmitMetaTx proposes the forwarder execute call for a signed MetaTx and returns the safeTxHash
+func (c *Client) SubmitMetaTx(ctx context.Context, metaTx eip2771toolkit.MetaTx, sig eip2771toolkit.Signature) (string, error) {
+	if c.ChainID == nil {
+		return "", fmt.Errorf("safe client requires a chain ID")
+	}
+	if c.Forwarder == (common.Address{}) {
+		return "", eip2771toolkit.ErrZeroAddress
+	}
+
+	// Some forwarders take the domain separator as an execute argument
+	domainSeparator, err := c.Profile.DomainSeparator(c.ChainID, c.Forwarder)
+	if err != nil {
+		return "", fmt.Errorf("failed to build domain separator: %w", err)
+	}
+
+	data, err := eip2771toolkit.PackExecuteCalldataWithProfile(c.Profile, metaTx, sig, domainSeparator)
+	if err != nil {
+		return "", err
+	}
+	forwardRequest, err := metaTx.ForwardRequest()
+	if err != nil {
+		return "", err
+	}
+
+	safeTxHash, err := c.ProposeCall(ctx, data, forwardRequest.Value)
+	if err != nil {
+		return "", err
+	}
+	return safeTxHash.Hex(), nil
+}
+
+// ProposeBatch proposes the forwarder executeBatch call for a signed batch and returns the safeTxHash
+func (c *Client) ProposeBatch(ctx context.Context, batchRequests eip2771toolkit.BatchMetaTxRequestList, refundReceiver common.Address) (common.Hash, error) {
+	data, err := eip2771toolkit.PackExecuteBatchCalldataWithProfile(c.Profile, batchRequests, refundReceiver)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	value := big.NewInt(0)
+	for i, req := range batchRequests {
+		forwardRequest, err := req.MetaTx.ForwardRequest()
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("failed to prepare request %d: %w", i, err)
+		}
+		value.Add(value, forwardRequest.Value)
+	}
+	return c.ProposeCall(ctx, data, value)
+}
+
+// ProposeCall proposes a call of the forwarder with data at the Safe's next free nonce
+func (c *Client) ProposeCall(ctx context.Context, data []byte, value *big.Int) (common.Hash, error) {
+	nonce, err := c.NextNonce(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return c.Propose(ctx, SafeTx{
+		To:        c.Forwarder,
+		Value:     value,
+		Data:      data,
+		Operation: Call,
+		Nonce:     nonce,
+	})
+}
+
+// Propose signs tx with the proposer key and submits it to the Transaction Service
+func (c *Client) Propose(ctx context.Context, tx SafeTx) (common.Hash, error) {
+	if c.ChainID == nil {
+		return common.Hash{}, fmt.Errorf("safe client requires a chain ID")
+	}
+	if c.ProposerKey == nil {
+		return common.Hash{}, fmt.Errorf("safe client requires a proposer key")
+	}
+
+	safeTxHash := tx.Hash(c.ChainID, c.Safe)
+	signature, err := crypto.Sign(safeTxHash.Bytes(), c.ProposerKey)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to sign safe transaction: %w", err)
+	}
+	signature[64] += 27 // Safe expects v in {27, 28} for EOA signatures
+
+	req := proposeRequest{
+		To:                      tx.To.Hex(),
+		Value:                   bigOrZero(tx.Value).String(),
+		Data:                    hexutil.Encode(tx.Data),
+		Operation:               uint8(tx.Operation),
+		SafeTxGas:               bigOrZero(tx.SafeTxGas).String(),
+		BaseGas:                 bigOrZero(tx.BaseGas).String(),
+		GasPrice:                bigOrZero(tx.GasPrice).String(),
+		GasToken:                tx.GasToken.Hex(),
+		RefundReceiver:          tx.RefundReceiver.Hex(),
+		Nonce:                   bigOrZero(tx.Nonce).String(),
+		ContractTransactionHash: safeTxHash.Hex(),
+		Sender:                  crypto.PubkeyToAddress(c.ProposerKey.PublicKey).Hex(),
+		Signature:               hexutil.Encode(signature),
+		Origin:                  c.Origin,
+	}
+
+	path := "/api/v1/safes/" + c.Safe.Hex() + "/multisig-transactions/"
+	if err := c.do(ctx, http.MethodPost, path, req, nil); err != nil {
+		return common.Hash{}, err
+	}
+	return safeTxHash, nil
+}
+
+// NextNonce returns the first Safe nonce not used by an executed or queued transaction
+func (c *Client) NextNonce(ctx context.Context) (*big.Int, error) {
+	var info safeInfo
+	if err := c.do(ctx, http.MethodGet, "/api/v1/safes/"+c.Safe.Hex()+"/", nil, &info); err != nil {
+		return nil, err
+	}
+	nonce, ok := new(big.Int).SetString(info.Nonce.String(), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid safe nonce %q", info.Nonce)
+	}
+
+	// Transactions already queued by other proposers occupy the following nonces
+	query := url.Values{
+		"nonce__gte": {nonce.String()},
+		"ordering":   {"-nonce"},
+		"limit":      {"1"},
+	}
+	var queued multisigTxList
+	path := "/api/v1/safes/" + c.Safe.Hex() + "/multisig-transactions/?" + query.Encode()
+	if err := c.do(ctx, http.MethodGet, path, nil, &queued); err != nil {
+		return nil, err
+	}
+	if len(queued.Results) > 0 {
+		last, ok := new(big.Int).SetString(queued.Results[0].Nonce.String(), 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid queued nonce %q", queued.Results[0].Nonce)
+		}
+		if last.Cmp(nonce) >= 0 {
+			nonce = last.Add(last, big.NewInt(1))
+		}
+	}
+	return nonce, nil
+}
+
+// TaskStatus fetches the proposal by safeTxHash. It stays pending until the owners have
+// confirmed and executed it.
+func (c *Client) TaskStatus(ctx context.Context, taskID string) (eip2771toolkit.RelayTaskStatus, error) {
+	var tx multisigTx
+	if err := c.do(ctx, http.MethodGet, "/api/v1/multisig-transactions/"+url.PathEscape(taskID)+"/", nil, &tx); err != nil {
+		return eip2771toolkit.RelayTaskStatus{}, err
+	}
+
+	status := eip2771toolkit.RelayTaskStatus{
+		TaskID:  taskID,
+		State:   eip2771toolkit.RelayTaskPending,
+		Message: strconv.Itoa(len(tx.Confirmations)) + "/" + strconv.Itoa(tx.ConfirmationsRequired) + " confirmations",
+	}
+	if tx.TransactionHash != nil {
+		status.TxHash = common.HexToHash(*tx.TransactionHash)
+	}
+	if tx.BlockNumber != nil {
+		status.BlockNumber = *tx.BlockNumber
+	}
+	if tx.IsExecuted {
+		status.State = eip2771toolkit.RelayTaskSucceeded
+		if tx.IsSuccessful != nil && !*tx.IsSuccessful {
+			status.State = eip2771toolkit.RelayTaskReverted
+		}
+	}
+	return status, nil
+}
+
+// do sends a JSON request to the Transaction Service and decodes the JSON response into out, if not nil
+func (c *Client) do(ctx context.Context, method, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode safe request: %w", err)
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create safe request: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call safe transaction service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read safe transaction service response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("safe transaction service returned %s: %s", resp.Status, bytes.TrimSpace(respBody))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode safe transaction service response: %w", err)
+	}
+	return nil
+}
+
+// bigOrZero returns v, or zero if v is nil
+func bigOrZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
+
+var _ eip2771toolkit.RelayBackend = (*Client)(nil)
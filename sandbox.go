@@ -0,0 +1,124 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// sandboxTx is a relay transaction that was signed but not broadcast
+type sandboxTx struct {
+	tx   *types.Transaction
+	head uint64 // block number the transaction was simulated on
+}
+
+// SANDBOX_TX_HISTORY is how many of its latest sandbox transactions a Relayer remembers
+const SANDBOX_TX_HISTORY = 4096
+
+// WithSandbox enables dry-run mode: relay transactions are estimated, simulated and signed
+// but never broadcast. The hashes returned are those of the signed transactions, and the
+// relayer answers lookups of them with synthetic successful receipts, so staging
+// environments can run the whole pipeline without spending gas or executing requests.
+// Only the latest SANDBOX_TX_HISTORY transactions are remembered; older ones are not found.
+func WithSandbox(enabled bool) RelayerOption {
+	return func(r *Relayer) {
+		r.sandbox = enabled
+	}
+}
+
+// Sandbox reports whether the relayer runs in dry-run mode
+func (r *Relayer) Sandbox() bool {
+	return r.sandbox
+}
+
+// SandboxTransaction returns a transaction the relayer signed but did not broadcast in sandbox mode
+func (r *Relayer) SandboxTransaction(txHash common.Hash) (*types.Transaction, bool) {
+	r.sandboxMu.Lock()
+	defer r.sandboxMu.Unlock()
+	stx, ok := r.sandboxTxs[txHash]
+	return stx.tx, ok
+}
+
+// simulate replays the inner transfers of requests against the latest state, surfacing
+// transfers that would return false, which gas estimation alone does not catch
+func (r *Relayer) simulate(ctx context.Context, forwarder common.Address, requests BatchMetaTxRequestList) error {
+	for i, req := range requests {
+		if err := CheckTransferResult(ctx, r.client, forwarder, req.MetaTx, nil); err != nil {
+			return fmt.Errorf("simulation of request at index %d failed: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// send broadcasts a signed relay transaction, or records it in sandbox mode
+func (r *Relayer) send(ctx context.Context, tx *types.Transaction) error {
+	if !r.sandbox {
+		var sender ethereum.TransactionSender = r.client
+		if r.sender != nil {
+			sender = r.sender
+		}
+		if err := sender.SendTransaction(ctx, tx); err != nil {
+			return err
+		}
+		r.rememberSent(tx)
+		return nil
+	}
+
+	head, err := r.client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block number: %w", err)
+	}
+
+	r.sandboxMu.Lock()
+	defer r.sandboxMu.Unlock()
+	if r.sandboxTxs == nil {
+		r.sandboxTxs = make(map[common.Hash]sandboxTx)
+	}
+	if _, ok := r.sandboxTxs[tx.Hash()]; !ok {
+		r.sandboxOrder = append(r.sandboxOrder, tx.Hash())
+	}
+	r.sandboxTxs[tx.Hash()] = sandboxTx{tx: tx, head: head}
+	for len(r.sandboxOrder) > SANDBOX_TX_HISTORY {
+		delete(r.sandboxTxs, r.sandboxOrder[0])
+		r.sandboxOrder = r.sandboxOrder[1:]
+	}
+	r.rememberSent(tx)
+	return nil
+}
+
+// transactionByHash looks a relay transaction up on chain or among sandbox transactions
+func (r *Relayer) transactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, error) {
+	if r.sandbox {
+		if tx, ok := r.SandboxTransaction(txHash); ok {
+			return tx, nil
+		}
+	}
+	tx, _, err := r.client.TransactionByHash(ctx, txHash)
+	return tx, err
+}
+
+// transactionReceipt returns the receipt of a relay transaction. Sandbox transactions get a
+// synthetic successful receipt in the block after the one they were simulated on.
+func (r *Relayer) transactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if r.sandbox {
+		r.sandboxMu.Lock()
+		stx, ok := r.sandboxTxs[txHash]
+		r.sandboxMu.Unlock()
+		if ok {
+			return &types.Receipt{
+				Type:              stx.tx.Type(),
+				Status:            types.ReceiptStatusSuccessful,
+				TxHash:            txHash,
+				GasUsed:           stx.tx.Gas(),
+				CumulativeGasUsed: stx.tx.Gas(),
+				BlockNumber:       new(big.Int).SetUint64(stx.head + 1),
+			}, nil
+		}
+		return nil, ethereum.NotFound
+	}
+	return r.client.TransactionReceipt(ctx, txHash)
+}
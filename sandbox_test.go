@@ -0,0 +1,67 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// headClient is an EthClient answering only BlockNumber
+type headClient struct {
+	EthClient
+	head uint64
+}
+
+func (c *headClient) BlockNumber(ctx context.Context) (uint64, error) {
+	return c.head, nil
+}
+
+func TestSandboxForgetsOldestTransactions(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	relayer := NewRelayer(key, common.Address{}, &headClient{head: 7}, WithSandbox(true))
+
+	const extra = 10
+	hashes := make([]common.Hash, SANDBOX_TX_HISTORY+extra)
+	for i := range hashes {
+		tx := types.NewTx(&types.LegacyTx{Nonce: uint64(i), Gas: TX_BASE_GAS})
+		if err := relayer.send(context.Background(), tx); err != nil {
+			t.Fatal(err)
+		}
+		hashes[i] = tx.Hash()
+		// Sending the same transaction again keeps one entry
+		if err := relayer.send(context.Background(), tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(relayer.sandboxTxs); n != SANDBOX_TX_HISTORY {
+		t.Fatalf("remembers %d sandbox transactions, want %d", n, SANDBOX_TX_HISTORY)
+	}
+	if n := len(relayer.sandboxOrder); n != SANDBOX_TX_HISTORY {
+		t.Fatalf("orders %d sandbox transactions, want %d", n, SANDBOX_TX_HISTORY)
+	}
+
+	for i, hash := range hashes {
+		_, ok := relayer.SandboxTransaction(hash)
+		receipt, err := relayer.transactionReceipt(context.Background(), hash)
+		if i < extra {
+			if ok || !errors.Is(err, ethereum.NotFound) {
+				t.Fatalf("transaction %d still found after %d newer ones", i, SANDBOX_TX_HISTORY)
+			}
+			continue
+		}
+		if !ok || err != nil {
+			t.Fatalf("transaction %d not found: %v", i, err)
+		}
+		if receipt.BlockNumber.Uint64() != 8 || receipt.Status != types.ReceiptStatusSuccessful {
+			t.Fatalf("receipt of transaction %d in block %d with status %d, want a success in block 8", i, receipt.BlockNumber, receipt.Status)
+		}
+	}
+}
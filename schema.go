@@ -0,0 +1,261 @@
+package eip2771toolkit
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// OZ_FORWARDER_V5_SCHEMA is the registry name of the OpenZeppelin v5 ERC2771Forwarder schema
+const OZ_FORWARDER_V5_SCHEMA = "oz-erc2771forwarder-v5"
+
+// ForwardRequest is the schema-independent description of the call a MetaTx asks the forwarder to make
+type ForwardRequest struct {
+	From     common.Address
+	To       common.Address // call target, the token contract for ERC20 transfers
+	Value    *big.Int
+	Gas      *big.Int
+	Nonce    *big.Int
+	Deadline *big.Int
+	Data     []byte
+}
+
+// ForwardRequest converts the MetaTx into the call the forwarder executes
+func (m *MetaTx) ForwardRequest() (ForwardRequest, error) {
+	transferData, err := m.TransferData()
+	if err != nil {
+		return ForwardRequest{}, fmt.Errorf("failed to prepare transfer data: %w", err)
+	}
+
+	return ForwardRequest{
+		From:     m.From,
+		To:       m.Token,       // Target is the token contract
+		Value:    big.NewInt(0), // No ETH value for ERC20 transfer
+		Gas:      new(big.Int).SetUint64(m.Gas),
+		Nonce:    new(big.Int).SetUint64(m.Nonce),
+		Deadline: new(big.Int).SetUint64(m.Deadline),
+		Data:     transferData,
+	}, nil
+}
+
+// SchemaField is one member of the signed EIP-712 request struct
+type SchemaField struct {
+	Name  string                               // member name as it appears in the type string
+	Type  string                               // solidity type: address, uintN, bool, bytes, string or bytes32
+	Value func(req ForwardRequest) interface{} // extracts the member value from the request
+}
+
+// RequestSchema describes the signed struct layout and ABI of a forwarder version.
+// Supporting a new forwarder version (or a fork adding fields) is done by registering a new
+// schema instead of editing the hashing and relaying code.
+type RequestSchema struct {
+	Name        string        // registry name
+	PrimaryType string        // EIP-712 primary type name, e.g. ForwardRequest
+	Fields      []SchemaField // signed members in type string order
+
+	ForwarderABI       string // JSON ABI of the forwarder contract
+	ExecuteMethod      string // single request execution method
+	ExecuteBatchMethod string // batch execution method, empty if unsupported
+	NonceMethod        string // per-signer nonce getter
+
+	// RequestData builds the ABI argument passed to the execute methods for one signed request
+	RequestData func(req ForwardRequest, sig Signature) interface{}
+
+	// ExecuteArgs optionally builds the full argument list of ExecuteMethod, for forwarders
+	// whose execute method takes more than the request itself. When nil the single
+	// argument built by RequestData is passed.
+	ExecuteArgs func(req ForwardRequest, sig Signature, domainSeparator []byte) []interface{}
+
+	typeHashOnce sync.Once
+	typeHash     []byte
+}
+
+// TypeString returns the EIP-712 type string, e.g. "ForwardRequest(address from,...)"
+func (s *RequestSchema) TypeString() string {
+	members := make([]string, len(s.Fields))
+	for i, f := range s.Fields {
+		members[i] = f.Type + " " + f.Name
+	}
+	return s.PrimaryType + "(" + strings.Join(members, ",") + ")"
+}
+
+// TypeHash returns keccak256 of the type string
+func (s *RequestSchema) TypeHash() []byte {
+	s.typeHashOnce.Do(func() {
+		s.typeHash = crypto.Keccak256([]byte(s.TypeString()))
+	})
+	return s.typeHash
+}
+
+// HashStruct computes the EIP-712 struct hash of the request
+func (s *RequestSchema) HashStruct(req ForwardRequest) ([]byte, error) {
+	structData := make([]byte, 0, 32*(len(s.Fields)+1))
+	structData = append(structData, s.TypeHash()...)
+
+	for _, f := range s.Fields {
+		word, err := encodeTypedWord(f.Type, f.Value(req))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode field %s: %w", f.Name, err)
+		}
+		structData = append(structData, word...)
+	}
+
+	return crypto.Keccak256(structData), nil
+}
+
+// HashMetaTx generates the EIP-712 digest for a MetaTx under this schema
+func (s *RequestSchema) HashMetaTx(metaTx MetaTx, domainSeparator []byte) ([]byte, error) {
+	req, err := metaTx.ForwardRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	structHash, err := s.HashStruct(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return typedDataDigest(domainSeparator, structHash), nil
+}
+
+// SignMetaTxWithSchema signs a MetaTx using EIP-712 under the given schema
+func SignMetaTxWithSchema(schema *RequestSchema, metaTx MetaTx, userPrivKey *ecdsa.PrivateKey, domainSeparator []byte) (Signature, error) {
+	hash, err := schema.HashMetaTx(metaTx, domainSeparator)
+	if err != nil {
+		return Signature{}, fmt.Errorf("failed to hash MetaTx: %w", err)
+	}
+	return signDigest(hash, userPrivKey)
+}
+
+// VerifyMetaTxSignatureWithSchema verifies a MetaTx signature under the given schema
+func VerifyMetaTxSignatureWithSchema(schema *RequestSchema, metaTx MetaTx, sig Signature, domainSeparator []byte) (bool, error) {
+	hash, err := schema.HashMetaTx(metaTx, domainSeparator)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash MetaTx: %w", err)
+	}
+
+	signer, err := recoverSigner(hash, sig)
+	if err != nil {
+		return false, err
+	}
+
+	return signer == metaTx.From, nil
+}
+
+// encodeTypedWord encodes an atomic EIP-712 member as a 32-byte word.
+// Dynamic bytes and string members are replaced by their keccak256 hash.
+func encodeTypedWord(typ string, value interface{}) ([]byte, error) {
+	switch {
+	case typ == "address":
+		addr, ok := value.(common.Address)
+		if !ok {
+			return nil, fmt.Errorf("expected common.Address, got %T", value)
+		}
+		return encodeAddressWord(addr), nil
+
+	case strings.HasPrefix(typ, "uint"):
+		switch v := value.(type) {
+		case *big.Int:
+			if v != nil && v.Sign() < 0 {
+				return nil, fmt.Errorf("negative value for %s", typ)
+			}
+			if v != nil && v.BitLen() > 256 {
+				return nil, fmt.Errorf("value for %s exceeds 256 bits", typ)
+			}
+			return encodeUintWord(v), nil
+		case uint64:
+			return encodeUint64Word(v), nil
+		}
+		return nil, fmt.Errorf("expected *big.Int or uint64, got %T", value)
+
+	case typ == "bool":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool, got %T", value)
+		}
+		word := make([]byte, 32)
+		if b {
+			word[31] = 1
+		}
+		return word, nil
+
+	case typ == "bytes":
+		b, ok := value.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("expected []byte, got %T", value)
+		}
+		return crypto.Keccak256(b), nil
+
+	case typ == "string":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", value)
+		}
+		return crypto.Keccak256([]byte(s)), nil
+
+	case typ == "bytes32":
+		switch v := value.(type) {
+		case [32]byte:
+			return v[:], nil
+		case common.Hash:
+			return v.Bytes(), nil
+		}
+		return nil, fmt.Errorf("expected [32]byte, got %T", value)
+	}
+
+	return nil, fmt.Errorf("unsupported EIP-712 member type %s", typ)
+}
+
+// executeArgs returns the ExecuteMethod arguments for one signed request
+func (s *RequestSchema) executeArgs(req ForwardRequest, sig Signature, domainSeparator []byte) []interface{} {
+	if s.ExecuteArgs != nil {
+		return s.ExecuteArgs(req, sig, domainSeparator)
+	}
+	return []interface{}{s.RequestData(req, sig)}
+}
+
+// ozForwardRequestData mirrors ERC2771Forwarder.ForwardRequestData for ABI encoding
+type ozForwardRequestData struct {
+	From      common.Address
+	To        common.Address
+	Value     *big.Int
+	Gas       *big.Int
+	Deadline  *big.Int // uint48 in contract but use uint256 for ABI encoding
+	Data      []byte
+	Signature []byte
+}
+
+// OZForwarderV5Schema is the OpenZeppelin v5 ERC2771Forwarder request schema
+var OZForwarderV5Schema = &RequestSchema{
+	Name:        OZ_FORWARDER_V5_SCHEMA,
+	PrimaryType: "ForwardRequest",
+	Fields: []SchemaField{
+		{Name: "from", Type: "address", Value: func(r ForwardRequest) interface{} { return r.From }},
+		{Name: "to", Type: "address", Value: func(r ForwardRequest) interface{} { return r.To }},
+		{Name: "value", Type: "uint256", Value: func(r ForwardRequest) interface{} { return r.Value }},
+		{Name: "gas", Type: "uint256", Value: func(r ForwardRequest) interface{} { return r.Gas }},
+		{Name: "nonce", Type: "uint256", Value: func(r ForwardRequest) interface{} { return r.Nonce }},
+		{Name: "deadline", Type: "uint48", Value: func(r ForwardRequest) interface{} { return r.Deadline }},
+		{Name: "data", Type: "bytes", Value: func(r ForwardRequest) interface{} { return r.Data }},
+	},
+	ForwarderABI:       ERC2771ForwarderABI,
+	ExecuteMethod:      "execute",
+	ExecuteBatchMethod: "executeBatch",
+	NonceMethod:        "nonces",
+	RequestData: func(r ForwardRequest, sig Signature) interface{} {
+		return ozForwardRequestData{
+			From:      r.From,
+			To:        r.To,
+			Value:     r.Value,
+			Gas:       r.Gas,
+			Deadline:  r.Deadline,
+			Data:      r.Data,
+			Signature: sig.ToBytes(),
+		}
+	},
+}
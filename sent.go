@@ -0,0 +1,44 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// SentTransaction is a mined transaction of an account
+type SentTransaction struct {
+	Hash        common.Hash     `json:"hash"`
+	Nonce       uint64          `json:"nonce"`
+	To          *common.Address `json:"to"` // nil for contract creations
+	BlockNumber uint64          `json:"blockNumber"`
+}
+
+// SentTransactions returns the transactions sender sent that were mined in blocks [from, to],
+// in chain order. It reads every block of the range, so keep ranges short on remote nodes.
+func SentTransactions(ctx context.Context, client EthClient, sender common.Address, from, to uint64) ([]SentTransaction, error) {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+	signer := types.LatestSignerForChainID(chainID)
+
+	var sent []SentTransaction
+	for number := from; number <= to; number++ {
+		block, err := client.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block %d: %w", number, err)
+		}
+		for _, tx := range block.Transactions() {
+			txSender, err := types.Sender(signer, tx)
+			if err != nil || txSender != sender {
+				continue // e.g. deposit transactions of rollups
+			}
+			sent = append(sent, SentTransaction{Hash: tx.Hash(), Nonce: tx.Nonce(), To: tx.To(), BlockNumber: number})
+		}
+	}
+	return sent, nil
+}
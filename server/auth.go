@@ -0,0 +1,194 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// Request authentication headers
+const (
+	API_KEY_HEADER           = "X-API-Key"
+	REQUEST_TIMESTAMP_HEADER = "X-EIP2771-Timestamp"
+	REQUEST_SIGNATURE_HEADER = "X-EIP2771-Signature"
+	REQUEST_NONCE_HEADER     = "X-EIP2771-Nonce"
+)
+
+// DEFAULT_REQUEST_SIGNATURE_TOLERANCE is how far the timestamp of a signed request may be
+// from the server's clock
+const DEFAULT_REQUEST_SIGNATURE_TOLERANCE = 5 * time.Minute
+
+// APIKey lets a client call the API as Sponsor: the quota and budget of the requests it
+// submits are those of Sponsor, see eip2771toolkit.ContextWithSponsor. Clients send Key in
+// the X-API-Key header or as a bearer token. With a Secret, every request must also carry the
+// unix time in X-EIP2771-Timestamp, a unique value in X-EIP2771-Nonce and the SignRequest
+// signature in X-EIP2771-Signature, so a leaked key alone cannot be used and a captured
+// request cannot be sent again.
+type APIKey struct {
+	Key     string
+	Sponsor string
+	Secret  []byte
+}
+
+// apiKeyAuth authenticates requests with API keys
+type apiKeyAuth struct {
+	keys      map[[32]byte]APIKey // by the SHA-256 of the key, so lookups take no time depending on it
+	tolerance time.Duration
+
+	mu     sync.Mutex
+	seen   map[string]time.Time // nonces of signed requests accepted, until their timestamp expires
+	pruned time.Time
+}
+
+// WithAPIKeys requires every API request to authenticate with one of keys, see APIKey.
+// Requests without a known key, or with a missing or invalid signature, are answered with
+// 401 Unauthorized.
+func WithAPIKeys(keys ...APIKey) Option {
+	return func(s *Server) {
+		if s.auth == nil {
+			s.auth = newAPIKeyAuth()
+		}
+		s.auth.add(keys)
+	}
+}
+
+// RequireAPIKeys wraps next, e.g. a handler mounted beside the API, with the authentication
+// of WithAPIKeys
+func RequireAPIKeys(next http.Handler, keys ...APIKey) http.Handler {
+	auth := newAPIKeyAuth()
+	auth.add(keys)
+	return auth.wrap(next)
+}
+
+// newAPIKeyAuth creates an authenticator without keys
+func newAPIKeyAuth() *apiKeyAuth {
+	return &apiKeyAuth{
+		keys:      make(map[[32]byte]APIKey),
+		tolerance: DEFAULT_REQUEST_SIGNATURE_TOLERANCE,
+		seen:      make(map[string]time.Time),
+	}
+}
+
+// add accepts keys
+func (a *apiKeyAuth) add(keys []APIKey) {
+	for _, key := range keys {
+		a.keys[sha256.Sum256([]byte(key.Key))] = key
+	}
+}
+
+// SignRequest returns the X-EIP2771-Signature value of an API request sent at timestamp with
+// nonce: "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<nonce>.<method> <path>.<body>"
+// keyed with the API key's secret. path is the request target as sent, including the query if
+// any. nonce must not repeat within DEFAULT_REQUEST_SIGNATURE_TOLERANCE, e.g. a random UUID.
+func SignRequest(secret []byte, timestamp, nonce, method, path string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(nonce))
+	mac.Write([]byte("."))
+	mac.Write([]byte(method + " " + path))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// wrap authenticates requests before passing them to next with their sponsor in the context
+func (a *apiKeyAuth) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key, err := a.authenticate(req)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="eip2771"`)
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+		next.ServeHTTP(w, req.WithContext(eip2771toolkit.ContextWithSponsor(req.Context(), key.Sponsor)))
+	})
+}
+
+// authenticate returns the API key req is made with, checking its signature if the key has a
+// secret
+func (a *apiKeyAuth) authenticate(req *http.Request) (APIKey, error) {
+	raw := req.Header.Get(API_KEY_HEADER)
+	if raw == "" {
+		if token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok {
+			raw = strings.TrimSpace(token)
+		}
+	}
+	if raw == "" {
+		return APIKey{}, ErrMissingAPIKey
+	}
+	key, ok := a.keys[sha256.Sum256([]byte(raw))]
+	if !ok {
+		return APIKey{}, ErrInvalidAPIKey
+	}
+	if len(key.Secret) == 0 {
+		return key, nil
+	}
+
+	timestamp, signature := req.Header.Get(REQUEST_TIMESTAMP_HEADER), req.Header.Get(REQUEST_SIGNATURE_HEADER)
+	nonce := req.Header.Get(REQUEST_NONCE_HEADER)
+	if timestamp == "" || nonce == "" || signature == "" {
+		return APIKey{}, fmt.Errorf("%w: missing %s, %s or %s", ErrInvalidRequestSignature,
+			REQUEST_TIMESTAMP_HEADER, REQUEST_NONCE_HEADER, REQUEST_SIGNATURE_HEADER)
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, MAX_REQUEST_BODY_BYTES+1))
+	if err != nil {
+		return APIKey{}, fmt.Errorf("failed to read request: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	// The request target as sent, before handlers like http.StripPrefix rewrote the URL
+	target := req.RequestURI
+	if target == "" {
+		target = req.URL.RequestURI()
+	}
+	if !hmac.Equal([]byte(signature), []byte(SignRequest(key.Secret, timestamp, nonce, req.Method, target, body))) {
+		return APIKey{}, ErrInvalidRequestSignature
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return APIKey{}, fmt.Errorf("%w: invalid timestamp %q", ErrInvalidRequestSignature, timestamp)
+	}
+	signedAt := time.Unix(unix, 0)
+	if age := time.Since(signedAt); age > a.tolerance || age < -a.tolerance {
+		return APIKey{}, fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidRequestSignature)
+	}
+	if !a.use(raw, nonce, signedAt.Add(a.tolerance)) {
+		return APIKey{}, fmt.Errorf("%w: nonce already used", ErrInvalidRequestSignature)
+	}
+	return key, nil
+}
+
+// use records the nonce of a signed request made with key until expires, when its timestamp
+// is no longer accepted, and returns false if it was already used
+func (a *apiKeyAuth) use(key, nonce string, expires time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(a.pruned) > time.Minute {
+		for id, expiry := range a.seen {
+			if now.After(expiry) {
+				delete(a.seen, id)
+			}
+		}
+		a.pruned = now
+	}
+
+	hash := sha256.Sum256([]byte(key))
+	id := string(hash[:]) + nonce
+	if expiry, ok := a.seen[id]; ok && !now.After(expiry) {
+		return false
+	}
+	a.seen[id] = expires
+	return true
+}
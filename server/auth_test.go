@@ -0,0 +1,209 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+var testSecret = []byte("globex-secret")
+
+// newAuthHandler returns a handler behind RequireAPIKeys that echoes the sponsor and body
+func newAuthHandler() http.Handler {
+	next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sponsor, _ := eip2771toolkit.SponsorFromContext(req.Context())
+		body, _ := io.ReadAll(req.Body)
+		io.WriteString(w, sponsor+":"+string(body))
+	})
+	return RequireAPIKeys(next,
+		APIKey{Key: "acme-key", Sponsor: "acme"},
+		APIKey{Key: "globex-key", Sponsor: "globex", Secret: testSecret},
+	)
+}
+
+// signedRequest builds a POST /requests made with the globex key
+func signedRequest(at time.Time, nonce, body string, sign func(timestamp string) string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/requests", strings.NewReader(body))
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	req.Header.Set(API_KEY_HEADER, "globex-key")
+	req.Header.Set(REQUEST_TIMESTAMP_HEADER, timestamp)
+	req.Header.Set(REQUEST_NONCE_HEADER, nonce)
+	req.Header.Set(REQUEST_SIGNATURE_HEADER, sign(timestamp))
+	return req
+}
+
+func TestRequireAPIKeys(t *testing.T) {
+	const body = `{"from":"0x01"}`
+	valid := func(nonce string) func(string) string {
+		return func(timestamp string) string {
+			return SignRequest(testSecret, timestamp, nonce, http.MethodPost, "/requests", []byte(body))
+		}
+	}
+
+	tests := []struct {
+		name     string
+		requests func() []*http.Request // all but the last must be accepted
+		wantCode int
+		wantBody string
+	}{
+		{
+			name: "valid signature",
+			requests: func() []*http.Request {
+				return []*http.Request{signedRequest(time.Now(), "n1", body, valid("n1"))}
+			},
+			wantCode: http.StatusOK,
+			wantBody: "globex:" + body,
+		},
+		{
+			name: "bad signature",
+			requests: func() []*http.Request {
+				return []*http.Request{signedRequest(time.Now(), "n1", body, func(timestamp string) string {
+					return SignRequest([]byte("other-secret"), timestamp, "n1", http.MethodPost, "/requests", []byte(body))
+				})}
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "tampered body",
+			requests: func() []*http.Request {
+				return []*http.Request{signedRequest(time.Now(), "n1", `{"from":"0x02"}`, valid("n1"))}
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "signed with another nonce",
+			requests: func() []*http.Request {
+				return []*http.Request{signedRequest(time.Now(), "n2", body, valid("n1"))}
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "expired timestamp",
+			requests: func() []*http.Request {
+				at := time.Now().Add(-DEFAULT_REQUEST_SIGNATURE_TOLERANCE - time.Minute)
+				return []*http.Request{signedRequest(at, "n1", body, valid("n1"))}
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "future timestamp",
+			requests: func() []*http.Request {
+				at := time.Now().Add(DEFAULT_REQUEST_SIGNATURE_TOLERANCE + time.Minute)
+				return []*http.Request{signedRequest(at, "n1", body, valid("n1"))}
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "replayed nonce",
+			requests: func() []*http.Request {
+				now := time.Now()
+				return []*http.Request{
+					signedRequest(now, "n1", body, valid("n1")),
+					signedRequest(now, "n1", body, valid("n1")),
+				}
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "fresh nonce after another",
+			requests: func() []*http.Request {
+				now := time.Now()
+				return []*http.Request{
+					signedRequest(now, "n1", body, valid("n1")),
+					signedRequest(now, "n2", body, valid("n2")),
+				}
+			},
+			wantCode: http.StatusOK,
+			wantBody: "globex:" + body,
+		},
+		{
+			name: "missing nonce",
+			requests: func() []*http.Request {
+				req := signedRequest(time.Now(), "n1", body, valid("n1"))
+				req.Header.Del(REQUEST_NONCE_HEADER)
+				return []*http.Request{req}
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "missing key",
+			requests: func() []*http.Request {
+				return []*http.Request{httptest.NewRequest(http.MethodPost, "/requests", strings.NewReader(body))}
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "unknown key",
+			requests: func() []*http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/requests", strings.NewReader(body))
+				req.Header.Set(API_KEY_HEADER, "initech-key")
+				return []*http.Request{req}
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "bearer key without secret",
+			requests: func() []*http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/requests", strings.NewReader(body))
+				req.Header.Set("Authorization", "Bearer acme-key")
+				return []*http.Request{req}
+			},
+			wantCode: http.StatusOK,
+			wantBody: "acme:" + body,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newAuthHandler()
+			requests := tt.requests()
+			for i, req := range requests {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				if i < len(requests)-1 {
+					if rec.Code != http.StatusOK {
+						t.Fatalf("request %d: status %d, want %d", i, rec.Code, http.StatusOK)
+					}
+					continue
+				}
+				if rec.Code != tt.wantCode {
+					t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+				}
+				if tt.wantCode == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+					t.Error("missing WWW-Authenticate header")
+				}
+				if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+					t.Errorf("body %q, want %q", rec.Body.String(), tt.wantBody)
+				}
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthPrunesExpiredNonces(t *testing.T) {
+	auth := newAPIKeyAuth()
+	expired := time.Now().Add(-time.Second)
+	if !auth.use("globex-key", "old", expired) {
+		t.Fatal("first use of a nonce refused")
+	}
+	// An expired entry no longer blocks its nonce, and is dropped once the cache is pruned
+	auth.pruned = time.Time{}
+	if !auth.use("globex-key", "new", time.Now().Add(time.Minute)) {
+		t.Fatal("first use of a nonce refused")
+	}
+	if len(auth.seen) != 1 {
+		t.Fatalf("%d nonces remembered, want 1", len(auth.seen))
+	}
+	if auth.use("globex-key", "new", time.Now().Add(time.Minute)) {
+		t.Fatal("nonce accepted twice")
+	}
+	if !auth.use("acme-key", "new", time.Now().Add(time.Minute)) {
+		t.Fatal("nonce of another key refused")
+	}
+}
@@ -0,0 +1,33 @@
+package server
+
+import "errors"
+
+var (
+	// ErrNotFound is returned when a store holds no record with the requested ID
+	ErrNotFound = errors.New("request not found")
+
+	// ErrDuplicate is returned when a store already holds a record with the same ID, or a
+	// record that has not failed holds the same signer nonce
+	ErrDuplicate = errors.New("request already submitted")
+
+	// ErrNonceConflict is returned when a different request with the same signer and forwarder
+	// nonce was already submitted
+	ErrNonceConflict = errors.New("another request with the same signer nonce was already submitted")
+
+	// ErrStoreNotListable is returned by Server.Poll when the server's store does not implement
+	// RecordLister
+	ErrStoreNotListable = errors.New("store cannot list records")
+
+	// ErrMissingAPIKey is returned when a server requiring API keys gets a request without one
+	ErrMissingAPIKey = errors.New("missing API key")
+
+	// ErrInvalidAPIKey is returned when a request carries an API key the server does not know
+	ErrInvalidAPIKey = errors.New("invalid API key")
+
+	// ErrInvalidRequestSignature is returned when a request made with an API key that has a
+	// secret is unsigned, or its signature or timestamp does not verify
+	ErrInvalidRequestSignature = errors.New("invalid request signature")
+
+	// ErrInvalidWebhookSignature is returned when a webhook delivery's signature does not verify
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+)
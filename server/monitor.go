@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// DEFAULT_MONITOR_INTERVAL is how often the monitor polls the store by default
+const DEFAULT_MONITOR_INTERVAL = 5 * time.Second
+
+// MonitorPolicy controls how the monitor of WithMonitor handles relay transactions that are
+// not mined. Zero fields take their defaults.
+type MonitorPolicy struct {
+	Interval      time.Duration // how often the store is polled, default DEFAULT_MONITOR_INTERVAL
+	BumpAfter     time.Duration // how long a relay transaction may stay unmined before it is sped up, 0 for never
+	BumpPercent   uint64        // fee increase of each speed-up, at least eip2771toolkit.DEFAULT_MIN_BUMP_PERCENT
+	MaxBumps      int           // replacements per request, after which its transaction is left pending; 0 for no limit
+	CancelExpired bool          // cancel relay transactions still unmined after their request's deadline
+}
+
+// WithMonitor tracks submitted requests with one background monitor polling the store, see
+// Server.Poll, instead of one goroutine per request. It also picks up requests submitted
+// before a restart, and speeds up or cancels relay transactions as policy says. The store
+// must implement RecordLister. Of several servers sharing a store, enable it on one.
+func WithMonitor(policy MonitorPolicy) Option {
+	return func(s *Server) {
+		s.monitor = &policy
+	}
+}
+
+// Poll checks the relay transactions of the submitted and executed records on the relayer's
+// forwarder once, as the monitor of WithMonitor does every interval. Mined requests are
+// recorded as executed or failed, executed ones as confirmed once the server's confirmations
+// are reached, and relay transactions that are not mined yet are sped up or cancelled as the
+// monitor's policy says. Without WithMonitor nothing is replaced. Errors of single records
+// are joined; the other records are still checked.
+func (s *Server) Poll(ctx context.Context) error {
+	lister, ok := s.store.(RecordLister)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrStoreNotListable, s.store)
+	}
+	records, err := listRecords(ctx, lister, s.relayer.Forwarder(), StatusSubmitted, StatusExecuted)
+	if err != nil {
+		return err
+	}
+
+	var policy MonitorPolicy
+	if s.monitor != nil {
+		policy = *s.monitor
+	}
+	var errs []error
+	for _, rec := range records {
+		if err := s.check(ctx, policy, rec); err != nil {
+			errs = append(errs, fmt.Errorf("request %s: %w", rec.ID.Hex(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runMonitor polls the store every interval until the server is closed
+func (s *Server) runMonitor() {
+	interval := s.monitor.Interval
+	if interval <= 0 {
+		interval = DEFAULT_MONITOR_INTERVAL
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Poll(s.ctx); err != nil && s.ctx.Err() == nil {
+			s.logger.Warn("monitor poll failed", "error", err)
+		}
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check moves rec on if any version of its relay transaction is mined, or replaces it
+func (s *Server) check(ctx context.Context, policy MonitorPolicy, rec Record) error {
+	hashes := append([]common.Hash{rec.TxHash}, rec.Replaced...)
+	result, confirmed, err := s.relayer.LookupRelay(ctx, s.confirmations, hashes...)
+	if err != nil {
+		return err
+	}
+	switch {
+	case result == nil && rec.Status == StatusSubmitted:
+		return s.escalate(ctx, policy, rec)
+	case result == nil:
+		// Reorganized out after it executed; wait for its new inclusion
+		return nil
+	case rec.Status == StatusSubmitted:
+		s.mined(ctx, rec, *result, confirmed)
+	case confirmed:
+		s.confirm(minedRecord(rec, *result), *result)
+	}
+	return nil
+}
+
+// mined records the outcome of the relay transaction of rec and confirms it if confirmed
+func (s *Server) mined(ctx context.Context, rec Record, result eip2771toolkit.RelayResult, confirmed bool) {
+	requests := eip2771toolkit.BatchMetaTxRequestList{rec.Envelope.Request}
+	sponsorCtx := ctx
+	if sponsor, ok := s.sponsors.LoadAndDelete(rec.ID); ok {
+		sponsorCtx = eip2771toolkit.ContextWithSponsor(ctx, sponsor.(string))
+	}
+	if err := s.relayer.RecordRelay(sponsorCtx, result, requests); err != nil {
+		s.logger.Error("relay not recorded", "id", rec.ID, "tx", result.Hash, "error", err)
+	}
+
+	rec = minedRecord(rec, result)
+	if !result.Succeeded() {
+		s.fail(rec, fmt.Sprintf("relay transaction %s reverted", rec.TxHash.Hex()))
+		return
+	}
+	if err := s.relayer.CheckExecuted(ctx, rec.TxHash, requests); err != nil {
+		reason := err.Error()
+		if rec.Error != "" {
+			reason = rec.Error // cancelled, keep the reason
+		}
+		s.fail(rec, reason)
+		return
+	}
+	rec.Error = ""
+	rec = s.update(rec, StatusExecuted)
+	s.logger.Info("request executed", "id", rec.ID, "tx", rec.TxHash, "block", rec.BlockNumber)
+	s.notify(EventMined, rec)
+	if confirmed {
+		s.confirm(rec, result)
+	}
+}
+
+// confirm records the executed rec as confirmed, or failed if a reorganization included a
+// reverting version of its relay transaction
+func (s *Server) confirm(rec Record, result eip2771toolkit.RelayResult) {
+	if !result.Succeeded() {
+		s.fail(rec, fmt.Sprintf("relay transaction %s reverted after a reorganization", rec.TxHash.Hex()))
+		return
+	}
+	s.logger.Info("request confirmed", "id", rec.ID, "tx", rec.TxHash, "block", rec.BlockNumber)
+	s.notify(EventConfirmed, s.update(rec, StatusConfirmed))
+}
+
+// escalate speeds up or cancels the unmined relay transaction of rec as policy says
+func (s *Server) escalate(ctx context.Context, policy MonitorPolicy, rec Record) error {
+	deadline := rec.Envelope.Request.MetaTx.Deadline
+	if policy.CancelExpired && rec.Error == "" && deadline != 0 && uint64(time.Now().Unix()) > deadline {
+		txHash, err := s.relayer.CancelRelayTxByHash(ctx, rec.TxHash)
+		if errors.Is(err, eip2771toolkit.ErrAlreadyMined) {
+			return nil // picked up by the next poll
+		}
+		if err != nil {
+			return fmt.Errorf("failed to cancel relay transaction: %w", err)
+		}
+		rec.Error = fmt.Sprintf("relay transaction cancelled, the request's deadline %d passed", deadline)
+		s.replaced(rec, txHash)
+		return nil
+	}
+
+	if policy.BumpAfter <= 0 || time.Since(rec.UpdatedAt) < policy.BumpAfter ||
+		(policy.MaxBumps > 0 && len(rec.Replaced) >= policy.MaxBumps) {
+		return nil
+	}
+	txHash, err := s.relayer.SpeedUpRelayTx(ctx, rec.TxHash, policy.BumpPercent)
+	if errors.Is(err, eip2771toolkit.ErrAlreadyMined) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to speed up relay transaction: %w", err)
+	}
+	s.replaced(rec, txHash)
+	return nil
+}
+
+// replaced stores rec with txHash as its relay transaction and notifies the webhooks
+func (s *Server) replaced(rec Record, txHash common.Hash) {
+	rec.Replaced = append(append([]common.Hash{}, rec.Replaced...), rec.TxHash)
+	rec.TxHash = txHash
+	s.logger.Info("relay transaction replaced", "id", rec.ID, "tx", txHash, "replaced", rec.Replaced[len(rec.Replaced)-1])
+	s.notify(EventReplaced, s.update(rec, StatusSubmitted))
+}
+
+// minedRecord returns rec with the mined version of its relay transaction as TxHash
+func minedRecord(rec Record, result eip2771toolkit.RelayResult) Record {
+	rec.BlockNumber = result.BlockNumber
+	if result.Hash == rec.TxHash {
+		return rec
+	}
+	replaced := []common.Hash{rec.TxHash}
+	for _, hash := range rec.Replaced {
+		if hash != result.Hash {
+			replaced = append(replaced, hash)
+		}
+	}
+	rec.TxHash, rec.Replaced = result.Hash, replaced
+	return rec
+}
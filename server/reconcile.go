@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// RECONCILE_PAGE_SIZE is how many records ReconcileStore reads from the store at a time
+const RECONCILE_PAGE_SIZE = 500
+
+// RecordLister is a Store that can list its records, such as MemoryStore and SQLStore
+type RecordLister interface {
+	List(ctx context.Context, filter RecordFilter) ([]Record, error)
+}
+
+// ReconcileStore reconciles the pending and submitted records of store on the relayer's
+// forwarder, and the nonces tracker hands out if not nil, against the chain, see
+// eip2771toolkit.ReconcileNonces. It returns the report with the record of each finding, in
+// the same order. A submitted record whose nonce was consumed has usually executed and is
+// waiting for its receipt to be processed; check it before having the request re-signed.
+func ReconcileStore(ctx context.Context, relayer *eip2771toolkit.Relayer, store RecordLister, tracker *eip2771toolkit.NonceTracker) (*eip2771toolkit.NonceReport, []Record, error) {
+	records, err := listRecords(ctx, store, relayer.Forwarder(), StatusPending, StatusSubmitted)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pending := make(eip2771toolkit.BatchMetaTxRequestList, len(records))
+	for i, rec := range records {
+		pending[i] = rec.Envelope.Request
+	}
+	report, err := relayer.ReconcileNonces(ctx, pending, tracker)
+	if err != nil {
+		return nil, nil, err
+	}
+	found := make([]Record, len(report.Findings))
+	for i, finding := range report.Findings {
+		found[i] = records[finding.Index]
+	}
+	return report, found, nil
+}
+
+// listRecords returns the records of store on forwarder with any of statuses, reading
+// RECONCILE_PAGE_SIZE at a time
+func listRecords(ctx context.Context, store RecordLister, forwarder common.Address, statuses ...Status) ([]Record, error) {
+	var records []Record
+	for _, status := range statuses {
+		for offset := 0; ; offset += RECONCILE_PAGE_SIZE {
+			page, err := store.List(ctx, RecordFilter{Status: status, Limit: RECONCILE_PAGE_SIZE, Offset: offset})
+			if err != nil {
+				return nil, err
+			}
+			for _, rec := range page {
+				if rec.Envelope.Domain.Forwarder == forwarder {
+					records = append(records, rec)
+				}
+			}
+			if len(page) < RECONCILE_PAGE_SIZE {
+				break
+			}
+		}
+	}
+	return records, nil
+}
+
+// DiscrepancyKind is a way the store and the chain disagree
+type DiscrepancyKind string
+
+const (
+	// UnknownTransaction is a forwarder transaction of the relayer account no record holds
+	UnknownTransaction DiscrepancyKind = "unknown-transaction"
+	// UnrecordedExecution is a forwarder event for a request the store has no record of, or
+	// whose record's status does not match the event's outcome
+	UnrecordedExecution DiscrepancyKind = "unrecorded-execution"
+	// MissingExecution is a record executed within the blocks that the forwarder emitted no
+	// event for there
+	MissingExecution DiscrepancyKind = "missing-execution"
+)
+
+// Discrepancy is one disagreement between the store and the chain
+type Discrepancy struct {
+	Kind        DiscrepancyKind                `json:"kind"`
+	TxHash      common.Hash                    `json:"txHash"`
+	BlockNumber uint64                         `json:"blockNumber"`
+	Event       *eip2771toolkit.ForwarderEvent `json:"event,omitempty"`
+	Record      *Record                        `json:"record,omitempty"`
+	Detail      string                         `json:"detail"`
+}
+
+// BlockReport is the outcome of ReconcileBlocks
+type BlockReport struct {
+	FromBlock     uint64        `json:"fromBlock"`
+	ToBlock       uint64        `json:"toBlock"`
+	Transactions  int           `json:"transactions"` // forwarder transactions of the relayer account
+	Events        int           `json:"events"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// OK reports whether the store and the chain agree
+func (r *BlockReport) OK() bool {
+	return len(r.Discrepancies) == 0
+}
+
+// ReconcileBlocks cross-references the records of store on forwarder with the forwarder
+// transactions relayer sent and the forwarder events in blocks [from, to], for audits. It
+// reports transactions no record holds, events whose request is unrecorded or recorded with
+// another outcome, and records executed in the blocks without an event. The store must
+// implement RecordLister. Transactions and events are read block by block; keep the range
+// within what the node serves.
+func ReconcileBlocks(ctx context.Context, client eip2771toolkit.EthClient, forwarder, relayer common.Address, store Store, from, to uint64) (*BlockReport, error) {
+	lister, ok := store.(RecordLister)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrStoreNotListable, store)
+	}
+	sent, err := eip2771toolkit.SentTransactions(ctx, client, relayer, from, to)
+	if err != nil {
+		return nil, err
+	}
+	events, err := eip2771toolkit.FilterForwarderEvents(ctx, forwarder, client, from, to, nil)
+	if err != nil {
+		return nil, err
+	}
+	report := &BlockReport{FromBlock: from, ToBlock: to, Events: len(events)}
+
+	// Records are looked up by the transactions they hold, then by signer nonce
+	byTx := make(map[common.Hash][]Record)
+	recordsOf := func(txHash common.Hash) ([]Record, error) {
+		if recs, ok := byTx[txHash]; ok {
+			return recs, nil
+		}
+		recs, err := lister.List(ctx, RecordFilter{TxHash: txHash, Limit: RECONCILE_PAGE_SIZE})
+		if err != nil {
+			return nil, err
+		}
+		byTx[txHash] = recs
+		return recs, nil
+	}
+
+	executedIn := make(map[common.Hash]bool) // transactions whose events name a record
+	seen := make(map[eip2771toolkit.SignerNonce]bool)
+	for i := range events {
+		event := &events[i]
+		if !event.Nonce.IsUint64() {
+			continue
+		}
+		slot := eip2771toolkit.SignerNonce{From: event.Signer, Nonce: event.Nonce.Uint64()}
+		seen[slot] = true
+		rec, err := findRecord(ctx, store, forwarder, slot, event.TxHash, recordsOf)
+		if err != nil {
+			return nil, err
+		}
+		if rec == nil {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind: UnrecordedExecution, TxHash: event.TxHash, BlockNumber: event.BlockNumber, Event: event,
+				Detail: fmt.Sprintf("no record of signer %s nonce %d", slot.From.Hex(), slot.Nonce),
+			})
+			continue
+		}
+		executedIn[event.TxHash] = true
+		if detail := outcomeMismatch(*rec, *event); detail != "" {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind: UnrecordedExecution, TxHash: event.TxHash, BlockNumber: event.BlockNumber, Event: event, Record: rec, Detail: detail,
+			})
+		}
+	}
+
+	for _, tx := range sent {
+		if tx.To == nil || *tx.To != forwarder {
+			continue
+		}
+		report.Transactions++
+		if executedIn[tx.Hash] {
+			continue
+		}
+		recs, err := recordsOf(tx.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if len(recs) == 0 {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind: UnknownTransaction, TxHash: tx.Hash, BlockNumber: tx.BlockNumber,
+				Detail: fmt.Sprintf("relayer nonce %d", tx.Nonce),
+			})
+		}
+	}
+
+	records, err := listRecords(ctx, lister, forwarder, StatusExecuted, StatusConfirmed)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		rec := &records[i]
+		if rec.BlockNumber < from || rec.BlockNumber > to || seen[rec.Envelope.Request.SignerNonce()] {
+			continue
+		}
+		report.Discrepancies = append(report.Discrepancies, Discrepancy{
+			Kind: MissingExecution, TxHash: rec.TxHash, BlockNumber: rec.BlockNumber, Record: rec,
+			Detail: fmt.Sprintf("record is %s, but the forwarder emitted no event for it", rec.Status),
+		})
+	}
+	return report, nil
+}
+
+// findRecord returns the record of slot, preferring one holding txHash, or nil if there is none
+func findRecord(ctx context.Context, store Store, forwarder common.Address, slot eip2771toolkit.SignerNonce, txHash common.Hash, recordsOf func(common.Hash) ([]Record, error)) (*Record, error) {
+	recs, err := recordsOf(txHash)
+	if err != nil {
+		return nil, err
+	}
+	for i := range recs {
+		if recs[i].Envelope.Request.SignerNonce() == slot && recs[i].Envelope.Domain.Forwarder == forwarder {
+			return &recs[i], nil
+		}
+	}
+	rec, err := store.GetByNonce(ctx, forwarder, slot)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// outcomeMismatch describes how rec disagrees with event, or returns "" if it agrees
+func outcomeMismatch(rec Record, event eip2771toolkit.ForwarderEvent) string {
+	switch {
+	case event.Success && rec.Status != StatusExecuted && rec.Status != StatusConfirmed:
+		return fmt.Sprintf("executed, but the record is %s", rec.Status)
+	case !event.Success && rec.Status != StatusFailed:
+		return fmt.Sprintf("inner call failed, but the record is %s", rec.Status)
+	case rec.TxHash != event.TxHash:
+		return fmt.Sprintf("executed by %s, but the record holds %s", event.TxHash.Hex(), rec.TxHash.Hex())
+	}
+	return ""
+}
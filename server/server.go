@@ -0,0 +1,478 @@
+// Package server exposes a Relayer as a REST service, so a gasless transaction service needs
+// no more glue than an http.Server:
+//
+//	POST /requests          submit a signed request, as BatchMetaTxRequest JSON; idempotent
+//	GET  /requests/{id}     status of a submitted request by its RequestID
+//	GET  /nonce/{address}   forwarder nonce the address's next request must be signed with
+//
+// Submitted requests are checked against the forwarder, relayed at once and tracked until
+// their relay transaction is mined and confirmed, optionally notifying webhooks at each step.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// MAX_REQUEST_BODY_BYTES bounds the size of a submitted request
+const MAX_REQUEST_BODY_BYTES = 64 << 10
+
+// DEFAULT_PENDING_TIMEOUT is how long a pending record may hold its nonce by default
+const DEFAULT_PENDING_TIMEOUT = 2 * time.Minute
+
+// Server serves the relay REST API for one relayer
+type Server struct {
+	relayer       *eip2771toolkit.Relayer
+	store         Store
+	waitOpts      []eip2771toolkit.WaitOption
+	confirmations uint64
+	pendingAfter  time.Duration
+	signers       signerLocks
+	hooks         []Webhook
+	webhooks      []*webhookDispatcher
+	mux           *http.ServeMux
+	auth          *apiKeyAuth    // nil when requests need no API key
+	monitor       *MonitorPolicy // nil to track each request in its own goroutine
+	sponsors      sync.Map       // request ID -> sponsor, of requests the monitor has yet to see mined
+	logger        *slog.Logger
+
+	ctx    context.Context // cancelled by Close to stop tracking and webhook delivery
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithStore sets where request records are kept, default a MemoryStore
+func WithStore(store Store) Option {
+	return func(s *Server) {
+		s.store = store
+	}
+}
+
+// WithWaitOptions sets how relay transactions are waited for while tracking them
+func WithWaitOptions(opts ...eip2771toolkit.WaitOption) Option {
+	return func(s *Server) {
+		s.waitOpts = opts
+	}
+}
+
+// WithPendingTimeout sets how long a pending record, one whose relay transaction is being
+// sent, may hold its nonce. Records of a process that stopped while sending are taken over
+// by the next submission for their nonce once the timeout passed.
+func WithPendingTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.pendingAfter = d
+	}
+}
+
+// WithConfirmations sets how many blocks, counting the one including it, must hold a relay
+// transaction before its request is confirmed, default 1
+func WithConfirmations(n uint64) Option {
+	return func(s *Server) {
+		s.confirmations = n
+	}
+}
+
+// WithWebhooks notifies the webhooks of every request's lifecycle events
+func WithWebhooks(hooks ...Webhook) Option {
+	return func(s *Server) {
+		s.hooks = append(s.hooks, hooks...)
+	}
+}
+
+// WithLogger makes the server log submissions, request outcomes, webhook delivery failures
+// and store errors to logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// New creates a server relaying through relayer
+func New(relayer *eip2771toolkit.Relayer, opts ...Option) *Server {
+	s := &Server{relayer: relayer, pendingAfter: DEFAULT_PENDING_TIMEOUT, logger: eip2771toolkit.DiscardLogger}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.store == nil {
+		s.store = NewMemoryStore()
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	for i := range s.hooks {
+		d := &webhookDispatcher{hook: &s.hooks[i], events: make(chan WebhookEvent, WEBHOOK_QUEUE_SIZE), logger: s.logger}
+		s.webhooks = append(s.webhooks, d)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			d.run(s.ctx)
+		}()
+	}
+	if s.monitor != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.runMonitor()
+		}()
+	}
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("POST /requests", s.handleSubmit)
+	s.mux.HandleFunc("GET /requests/{id}", s.handleStatus)
+	s.mux.HandleFunc("GET /nonce/{address}", s.handleNonce)
+	return s
+}
+
+// Handler returns the HTTP handler of the API
+func (s *Server) Handler() http.Handler {
+	if s.auth != nil {
+		return s.auth.wrap(s.mux)
+	}
+	return s.mux
+}
+
+// Close stops tracking submitted requests and delivering webhooks, and waits for both to
+// return. Requests still pending keep their status; undelivered events are dropped.
+func (s *Server) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+// Submit checks a signed request against the forwarder, relays it and starts tracking it.
+// Submissions are idempotent: resubmitting a request returns its record with ErrDuplicate,
+// and a different request for a nonce another one holds returns the holder's record with
+// ErrNonceConflict, without relaying either. The nonce is reserved in the store before
+// relaying, so this holds across servers sharing a store. Requests the relayer's validators
+// reject are refused before anything else.
+func (s *Server) Submit(ctx context.Context, req eip2771toolkit.BatchMetaTxRequest) (Record, error) {
+	if err := s.relayer.ValidateMetaTx(ctx, req.MetaTx); err != nil {
+		return Record{}, err
+	}
+	id, err := s.relayer.RequestID(ctx, req.MetaTx)
+	if err != nil {
+		return Record{}, err
+	}
+	unlock := s.signers.lock(req.MetaTx.From)
+	defer unlock()
+
+	if existing, err := s.existing(ctx, id, req); err != nil || existing.ID != (common.Hash{}) {
+		return existing, err
+	}
+
+	envelope, err := s.relayer.Seal(ctx, req)
+	if err != nil {
+		return Record{}, err
+	}
+	if err := s.relayer.CheckEnvelope(ctx, envelope); err != nil {
+		return Record{}, err
+	}
+
+	now := time.Now()
+	rec := Record{
+		ID:        id,
+		Envelope:  envelope,
+		Status:    StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.store.Create(ctx, rec); err != nil {
+		if errors.Is(err, ErrDuplicate) {
+			// Another server reserved the nonce first
+			if existing, err := s.existing(ctx, id, req); err != nil || existing.ID != (common.Hash{}) {
+				return existing, err
+			}
+		}
+		return Record{}, fmt.Errorf("failed to store request %s: %w", id.Hex(), err)
+	}
+
+	sponsor, sponsored := eip2771toolkit.SponsorFromContext(ctx)
+	if s.monitor != nil && sponsored {
+		s.sponsors.Store(id, sponsor)
+	}
+	// track or the monitor records the mined relay
+	txHash, err := s.relayer.RelayMetaTx(eip2771toolkit.ContextWithRecordRelay(ctx), req.MetaTx, req.Signature)
+	if err != nil {
+		s.sponsors.Delete(id)
+		s.logger.Warn("request not relayed", "id", id, "signer", req.MetaTx.From, "nonce", req.MetaTx.Nonce, "error", err)
+		if err := s.store.Delete(context.WithoutCancel(ctx), id); err != nil {
+			s.logger.Error("failed to delete pending request", "id", id, "error", err)
+		}
+		return Record{}, err
+	}
+	rec.TxHash = txHash
+	rec = s.update(rec, StatusSubmitted)
+	s.logger.Info("request submitted", "id", id, "signer", req.MetaTx.From, "nonce", req.MetaTx.Nonce, "tx", txHash)
+	s.notify(EventSubmitted, rec)
+
+	if s.monitor != nil {
+		return rec, nil
+	}
+	// Mined relays are charged to the sponsor the request was submitted as
+	trackCtx := s.ctx
+	if sponsored {
+		trackCtx = eip2771toolkit.ContextWithSponsor(trackCtx, sponsor)
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.track(trackCtx, rec)
+	}()
+	return rec, nil
+}
+
+// existing returns the record of a request submitted before with ErrDuplicate, or the record
+// holding its nonce with ErrNonceConflict. A pending holder past the pending timeout is
+// deleted instead. It returns a zero record if the request may be relayed.
+func (s *Server) existing(ctx context.Context, id common.Hash, req eip2771toolkit.BatchMetaTxRequest) (Record, error) {
+	rec, err := s.store.Get(ctx, id)
+	if err == nil && !s.abandoned(rec) {
+		return rec, ErrDuplicate
+	}
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return Record{}, err
+	}
+
+	if err != nil {
+		rec, err = s.store.GetByNonce(ctx, s.relayer.Forwarder(), req.SignerNonce())
+		if errors.Is(err, ErrNotFound) {
+			return Record{}, nil
+		}
+		if err != nil {
+			return Record{}, err
+		}
+		if !s.abandoned(rec) {
+			return rec, ErrNonceConflict
+		}
+	}
+
+	if err := s.store.Delete(ctx, rec.ID); err != nil && !errors.Is(err, ErrNotFound) {
+		return Record{}, err
+	}
+	return Record{}, nil
+}
+
+// abandoned reports whether rec is pending for longer than the pending timeout
+func (s *Server) abandoned(rec Record) bool {
+	return rec.Status == StatusPending && time.Since(rec.UpdatedAt) > s.pendingAfter
+}
+
+// track waits for the relay transaction of rec to be mined and confirmed and records the
+// outcome. ctx is the server's, carrying the sponsor of the request.
+func (s *Server) track(ctx context.Context, rec Record) {
+	result, err := s.relayer.WaitForRelay(ctx, rec.TxHash, s.waitOpts...)
+	if err != nil {
+		return // closed, or the wait timed out: the request stays submitted
+	}
+	if err := s.relayer.RecordRelay(ctx, result, eip2771toolkit.BatchMetaTxRequestList{rec.Envelope.Request}); err != nil {
+		s.logger.Error("relay not recorded", "id", rec.ID, "tx", rec.TxHash, "error", err)
+	}
+	rec.BlockNumber = result.BlockNumber
+	if !result.Succeeded() {
+		s.fail(rec, fmt.Sprintf("relay transaction %s reverted", rec.TxHash.Hex()))
+		return
+	}
+	if err := s.relayer.CheckExecuted(s.ctx, rec.TxHash, eip2771toolkit.BatchMetaTxRequestList{rec.Envelope.Request}); err != nil {
+		s.fail(rec, err.Error())
+		return
+	}
+	rec = s.update(rec, StatusExecuted)
+	s.logger.Info("request executed", "id", rec.ID, "tx", rec.TxHash, "block", rec.BlockNumber)
+	s.notify(EventMined, rec)
+
+	if s.confirmations > 1 {
+		opts := append(append([]eip2771toolkit.WaitOption{}, s.waitOpts...), eip2771toolkit.WithConfirmations(s.confirmations))
+		if result, err = s.relayer.WaitForRelay(s.ctx, rec.TxHash, opts...); err != nil {
+			return
+		}
+		// A reorganization may have moved the transaction to another block
+		rec.BlockNumber = result.BlockNumber
+		if !result.Succeeded() {
+			s.fail(rec, fmt.Sprintf("relay transaction %s reverted after a reorganization", rec.TxHash.Hex()))
+			return
+		}
+	}
+	s.logger.Info("request confirmed", "id", rec.ID, "tx", rec.TxHash, "block", rec.BlockNumber)
+	s.notify(EventConfirmed, s.update(rec, StatusConfirmed))
+}
+
+// update stores rec with status
+func (s *Server) update(rec Record, status Status) Record {
+	rec.Status = status
+	rec.UpdatedAt = time.Now()
+	if err := s.store.Update(s.ctx, rec); err != nil {
+		s.logger.Error("failed to store request", "id", rec.ID, "status", status, "error", err)
+	}
+	return rec
+}
+
+// fail stores rec as failed with reason and notifies the webhooks
+func (s *Server) fail(rec Record, reason string) {
+	rec.Error = reason
+	s.logger.Warn("request failed", "id", rec.ID, "tx", rec.TxHash, "reason", reason)
+	s.notify(EventFailed, s.update(rec, StatusFailed))
+}
+
+// signerLocks serializes submissions per signer within the process
+type signerLocks struct {
+	mu    sync.Mutex
+	locks map[common.Address]*signerLock
+}
+
+// signerLock is a mutex shared by the submissions of one signer
+type signerLock struct {
+	sync.Mutex
+	waiters int
+}
+
+// lock locks the signer and returns the unlock function
+func (l *signerLocks) lock(signer common.Address) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[common.Address]*signerLock)
+	}
+	sl, ok := l.locks[signer]
+	if !ok {
+		sl = &signerLock{}
+		l.locks[signer] = sl
+	}
+	sl.waiters++
+	l.mu.Unlock()
+
+	sl.Lock()
+	return func() {
+		sl.Unlock()
+		l.mu.Lock()
+		if sl.waiters--; sl.waiters == 0 {
+			delete(l.locks, signer)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// handleSubmit serves POST /requests
+func (s *Server) handleSubmit(w http.ResponseWriter, req *http.Request) {
+	var signed eip2771toolkit.BatchMetaTxRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, MAX_REQUEST_BODY_BYTES)).Decode(&signed); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	rec, err := s.Submit(req.Context(), signed)
+	switch {
+	case errors.Is(err, ErrDuplicate):
+		writeJSON(w, http.StatusOK, rec)
+	case errors.Is(err, ErrNonceConflict):
+		writeJSON(w, http.StatusConflict, rec)
+	case err != nil:
+		var policyErr *eip2771toolkit.PolicyError
+		if errors.As(err, &policyErr) && policyErr.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(policyErr.RetryAfter.Seconds()))))
+		}
+		writeError(w, submitErrorStatus(err), err)
+	default:
+		writeJSON(w, http.StatusAccepted, rec)
+	}
+}
+
+// handleStatus serves GET /requests/{id}
+func (s *Server) handleStatus(w http.ResponseWriter, req *http.Request) {
+	raw, err := hexutil.Decode(req.PathValue("id"))
+	if err != nil || len(raw) != common.HashLength {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request ID %q", req.PathValue("id")))
+		return
+	}
+	rec, err := s.store.Get(req.Context(), common.BytesToHash(raw))
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rec)
+}
+
+// nonceResponse is the body of GET /nonce/{address}
+type nonceResponse struct {
+	Address common.Address `json:"address"`
+	Nonce   string         `json:"nonce"` // decimal, like MetaTx JSON nonces
+}
+
+// handleNonce serves GET /nonce/{address}
+func (s *Server) handleNonce(w http.ResponseWriter, req *http.Request) {
+	address := req.PathValue("address")
+	user, err := eip2771toolkit.ParseAddress(address, false)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	nonce, err := s.relayer.GetMetaTxNonce(req.Context(), user)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonceResponse{Address: user, Nonce: strconv.FormatUint(nonce, 10)})
+}
+
+// submitErrorStatus maps a Submit error to an HTTP status: 429 for signers over their quota,
+// 403 for other requests the relay policies refuse, 422 for requests the forwarder would not
+// execute, 502 for failures talking to the node
+func submitErrorStatus(err error) int {
+	if errors.Is(err, eip2771toolkit.ErrQuotaExceeded) {
+		return http.StatusTooManyRequests
+	}
+	if errors.Is(err, eip2771toolkit.ErrPolicyViolation) {
+		return http.StatusForbidden
+	}
+	for _, target := range []error{
+		eip2771toolkit.ErrInvalidSignature,
+		eip2771toolkit.ErrInvalidSignatureLength,
+		eip2771toolkit.ErrExpiredDeadline,
+		eip2771toolkit.ErrDeadlineTooSoon,
+		eip2771toolkit.ErrInvalidNonce,
+		eip2771toolkit.ErrAlreadyExecuted,
+		eip2771toolkit.ErrZeroAddress,
+		eip2771toolkit.ErrInvalidAmount,
+		eip2771toolkit.ErrInvalidMetaTx,
+		eip2771toolkit.ErrCallNotPermitted,
+		eip2771toolkit.ErrUnexecutable,
+	} {
+		if errors.Is(err, target) {
+			return http.StatusUnprocessableEntity
+		}
+	}
+	return http.StatusBadGateway
+}
+
+// errorResponse is the body of error responses
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeError writes err as a JSON error body
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
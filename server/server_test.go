@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// chainClient is an EthClient answering only ChainID
+type chainClient struct {
+	eip2771toolkit.EthClient
+}
+
+func (c chainClient) ChainID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func TestSubmitRefusesOutOfRangeAmounts(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+	for name, opts := range map[string][]eip2771toolkit.RelayerOption{
+		"default validators": nil,
+		"no validators":      {eip2771toolkit.WithValidators()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			relayer := eip2771toolkit.NewRelayer(key, common.HexToAddress("0xf0"), chainClient{}, opts...)
+			srv := New(relayer)
+			defer srv.Close()
+
+			for _, amount := range []*big.Int{
+				nil,
+				big.NewInt(-1),
+				new(big.Int).Add(maxUint256, big.NewInt(1)),
+				new(big.Int).Lsh(big.NewInt(1), 300),
+			} {
+				metaTx := eip2771toolkit.NewMetaTx(common.HexToAddress("0x01"), common.HexToAddress("0x02"), common.HexToAddress("0x03"), amount, 100000, 0, 1<<40)
+				_, err := srv.Submit(context.Background(), eip2771toolkit.BatchMetaTxRequest{MetaTx: metaTx})
+				if !errors.Is(err, eip2771toolkit.ErrInvalidAmount) {
+					t.Fatalf("amount %v: got %v, want ErrInvalidAmount", amount, err)
+				}
+				if status := submitErrorStatus(err); status != http.StatusUnprocessableEntity {
+					t.Errorf("amount %v answered with status %d, want 422", amount, status)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,460 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// SQLDialect describes the SQL differences between the databases SQLStore supports
+type SQLDialect struct {
+	Name string
+
+	numberedParams bool   // $1, $2, ... instead of ?
+	jsonType       string // column type of the request envelope
+	lockStatement  string // serializes migrations between processes, empty if the database locks itself
+}
+
+var (
+	// SQLiteDialect is for single-binary deployments, with any database/sql SQLite driver such
+	// as modernc.org/sqlite or github.com/mattn/go-sqlite3
+	SQLiteDialect = &SQLDialect{Name: "sqlite", jsonType: "TEXT"}
+
+	// PostgresDialect is for HA deployments sharing one database, with a database/sql Postgres
+	// driver such as github.com/jackc/pgx/v5/stdlib or github.com/lib/pq
+	PostgresDialect = &SQLDialect{
+		Name:           "postgres",
+		numberedParams: true,
+		jsonType:       "JSONB",
+		lockStatement:  "SELECT pg_advisory_xact_lock(2771)",
+	}
+)
+
+// rebind rewrites the ? placeholders of query for the dialect
+func (d *SQLDialect) rebind(query string) string {
+	if !d.numberedParams {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// sqlMigration is one schema version
+type sqlMigration struct {
+	version    int
+	statements func(d *SQLDialect) []string
+}
+
+// sqlMigrations are applied in order by SQLStore.Migrate. Released versions must never change;
+// schema changes are appended as new versions.
+var sqlMigrations = []sqlMigration{
+	{
+		version: 1,
+		statements: func(d *SQLDialect) []string {
+			return []string{
+				`CREATE TABLE eip2771_requests (
+					id           TEXT PRIMARY KEY,
+					signer       TEXT NOT NULL,
+					nonce        TEXT NOT NULL,
+					chain_id     BIGINT NOT NULL,
+					forwarder    TEXT NOT NULL,
+					status       TEXT NOT NULL,
+					tx_hash      TEXT NOT NULL,
+					block_number BIGINT NOT NULL,
+					error        TEXT NOT NULL,
+					envelope     ` + d.jsonType + ` NOT NULL,
+					created_at   BIGINT NOT NULL,
+					updated_at   BIGINT NOT NULL
+				)`,
+				`CREATE INDEX eip2771_requests_signer_nonce ON eip2771_requests (signer, nonce)`,
+				`CREATE INDEX eip2771_requests_tx_hash ON eip2771_requests (tx_hash)`,
+				`CREATE INDEX eip2771_requests_status_created ON eip2771_requests (status, created_at)`,
+				`CREATE INDEX eip2771_requests_created ON eip2771_requests (created_at)`,
+			}
+		},
+	},
+	{
+		version: 2,
+		statements: func(d *SQLDialect) []string {
+			return []string{
+				`CREATE UNIQUE INDEX eip2771_requests_nonce_holder ON eip2771_requests (forwarder, signer, nonce) WHERE status <> 'failed'`,
+			}
+		},
+	},
+	{
+		version: 3,
+		statements: func(d *SQLDialect) []string {
+			return []string{
+				`ALTER TABLE eip2771_requests ADD COLUMN replaced TEXT NOT NULL DEFAULT ''`,
+			}
+		},
+	},
+}
+
+// SQLStore is a Store in a SQL database. Request IDs, addresses and hashes are stored as
+// lowercase 0x-hex, nonces as 20-digit zero-padded decimals so they sort numerically, and
+// times as unix milliseconds. A partial unique index lets one record that has not failed
+// hold each nonce, also across processes sharing the database.
+type SQLStore struct {
+	db      *sql.DB
+	dialect *SQLDialect
+}
+
+// OpenSQLStore migrates the schema of db to the latest version and returns a store on it
+func OpenSQLStore(ctx context.Context, db *sql.DB, dialect *SQLDialect) (*SQLStore, error) {
+	s := &SQLStore{db: db, dialect: dialect}
+	if err := s.Migrate(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Migrate applies the schema versions the database does not have yet, in one transaction
+func (s *SQLStore) Migrate(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	if s.dialect.lockStatement != "" {
+		if _, err := tx.ExecContext(ctx, s.dialect.lockStatement); err != nil {
+			return fmt.Errorf("failed to lock for migration: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS eip2771_schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at BIGINT NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	var current sql.NullInt64
+	if err := tx.QueryRowContext(ctx, `SELECT MAX(version) FROM eip2771_schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for _, m := range sqlMigrations {
+		if int64(m.version) <= current.Int64 {
+			continue
+		}
+		for _, stmt := range m.statements(s.dialect) {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("failed to apply schema version %d: %w", m.version, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, s.dialect.rebind(`INSERT INTO eip2771_schema_migrations (version, applied_at) VALUES (?, ?)`), m.version, time.Now().UnixMilli()); err != nil {
+			return fmt.Errorf("failed to record schema version %d: %w", m.version, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration: %w", err)
+	}
+	return nil
+}
+
+// SchemaVersion returns the latest schema version applied to the database
+func (s *SQLStore) SchemaVersion(ctx context.Context) (int, error) {
+	var version sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, `SELECT MAX(version) FROM eip2771_schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// Create adds a record
+func (s *SQLStore) Create(ctx context.Context, rec Record) error {
+	envelope, err := json.Marshal(rec.Envelope)
+	if err != nil {
+		return fmt.Errorf("failed to encode envelope: %w", err)
+	}
+	meta := rec.Envelope.Request.MetaTx
+
+	_, err = s.db.ExecContext(ctx, s.dialect.rebind(`INSERT INTO eip2771_requests
+		(id, signer, nonce, chain_id, forwarder, status, tx_hash, replaced, block_number, error, envelope, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		hexKey(rec.ID.Bytes()), hexKey(meta.From.Bytes()), sqlNonce(meta.Nonce), int64(rec.Envelope.Domain.ChainID),
+		hexKey(rec.Envelope.Domain.Forwarder.Bytes()), string(rec.Status), hexKey(rec.TxHash.Bytes()), sqlHashes(rec.Replaced),
+		int64(rec.BlockNumber), rec.Error, string(envelope), rec.CreatedAt.UnixMilli(), rec.UpdatedAt.UnixMilli(),
+	)
+	if err != nil {
+		// Drivers report unique violations differently; look the conflicting record up instead
+		if _, getErr := s.Get(ctx, rec.ID); getErr == nil {
+			return ErrDuplicate
+		}
+		if _, getErr := s.GetByNonce(ctx, rec.Envelope.Domain.Forwarder, rec.Envelope.Request.SignerNonce()); getErr == nil && rec.Status != StatusFailed {
+			return ErrDuplicate
+		}
+		return fmt.Errorf("failed to insert request: %w", err)
+	}
+	return nil
+}
+
+// Update replaces a record's status, transactions, error and update time
+func (s *SQLStore) Update(ctx context.Context, rec Record) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(`UPDATE eip2771_requests
+		SET status = ?, tx_hash = ?, replaced = ?, block_number = ?, error = ?, updated_at = ?
+		WHERE id = ?`),
+		string(rec.Status), hexKey(rec.TxHash.Bytes()), sqlHashes(rec.Replaced), int64(rec.BlockNumber), rec.Error, rec.UpdatedAt.UnixMilli(),
+		hexKey(rec.ID.Bytes()),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update request: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update request: %w", err)
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a record
+func (s *SQLStore) Delete(ctx context.Context, id common.Hash) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(`DELETE FROM eip2771_requests WHERE id = ?`), hexKey(id.Bytes()))
+	if err != nil {
+		return fmt.Errorf("failed to delete request: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete request: %w", err)
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// recordColumns are the columns scanRecord reads, in order
+const recordColumns = `id, status, tx_hash, replaced, block_number, error, envelope, created_at, updated_at`
+
+// Get returns a record
+func (s *SQLStore) Get(ctx context.Context, id common.Hash) (Record, error) {
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(`SELECT `+recordColumns+` FROM eip2771_requests WHERE id = ?`), hexKey(id.Bytes()))
+	rec, err := scanRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Record{}, ErrNotFound
+	}
+	return rec, err
+}
+
+// GetByNonce returns the record holding a nonce
+func (s *SQLStore) GetByNonce(ctx context.Context, forwarder common.Address, slot eip2771toolkit.SignerNonce) (Record, error) {
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(`SELECT `+recordColumns+` FROM eip2771_requests
+		WHERE forwarder = ? AND signer = ? AND nonce = ? AND status <> 'failed'`),
+		hexKey(forwarder.Bytes()), hexKey(slot.From.Bytes()), sqlNonce(slot.Nonce))
+	rec, err := scanRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Record{}, ErrNotFound
+	}
+	return rec, err
+}
+
+// RecordFilter selects records for dashboards. Zero fields match everything.
+type RecordFilter struct {
+	Signer common.Address
+	Status Status
+	TxHash common.Hash
+	Since  time.Time // created at or after
+	Until  time.Time // created before
+	Limit  int       // default 100
+	Offset int
+}
+
+// List returns the records matching filter, newest first
+func (s *SQLStore) List(ctx context.Context, filter RecordFilter) ([]Record, error) {
+	where, args := filter.where()
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	args = append(args, limit, filter.Offset)
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`SELECT `+recordColumns+` FROM eip2771_requests`+where+
+		` ORDER BY created_at DESC, id LIMIT ? OFFSET ?`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list requests: %w", err)
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		rec, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list requests: %w", err)
+	}
+	return records, nil
+}
+
+// CountByStatus returns how many records matching filter have each status; Limit and
+// Offset are ignored
+func (s *SQLStore) CountByStatus(ctx context.Context, filter RecordFilter) (map[Status]int, error) {
+	where, args := filter.where()
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`SELECT status, COUNT(*) FROM eip2771_requests`+where+` GROUP BY status`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count requests: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[Status]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to count requests: %w", err)
+		}
+		counts[Status(status)] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count requests: %w", err)
+	}
+	return counts, nil
+}
+
+// LatestNonce returns the highest nonce of the signer's stored requests on forwarder, and false
+// if there are none
+func (s *SQLStore) LatestNonce(ctx context.Context, forwarder, signer common.Address) (uint64, bool, error) {
+	var nonce sql.NullString
+	err := s.db.QueryRowContext(ctx, s.dialect.rebind(`SELECT MAX(nonce) FROM eip2771_requests WHERE forwarder = ? AND signer = ?`),
+		hexKey(forwarder.Bytes()), hexKey(signer.Bytes())).Scan(&nonce)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read latest nonce: %w", err)
+	}
+	if !nonce.Valid {
+		return 0, false, nil
+	}
+	n, err := strconv.ParseUint(nonce.String, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid stored nonce %q: %w", nonce.String, err)
+	}
+	return n, true, nil
+}
+
+// where returns the WHERE clause and arguments of the filter
+func (f RecordFilter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if f.Signer != (common.Address{}) {
+		conds = append(conds, "signer = ?")
+		args = append(args, hexKey(f.Signer.Bytes()))
+	}
+	if f.Status != "" {
+		conds = append(conds, "status = ?")
+		args = append(args, string(f.Status))
+	}
+	if f.TxHash != (common.Hash{}) {
+		conds = append(conds, "tx_hash = ?")
+		args = append(args, hexKey(f.TxHash.Bytes()))
+	}
+	if !f.Since.IsZero() {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, f.Since.UnixMilli())
+	}
+	if !f.Until.IsZero() {
+		conds = append(conds, "created_at < ?")
+		args = append(args, f.Until.UnixMilli())
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// matches reports whether rec is selected by the filter, as where does in SQL
+func (f RecordFilter) matches(rec Record) bool {
+	return (f.Signer == (common.Address{}) || rec.Envelope.Request.MetaTx.From == f.Signer) &&
+		(f.Status == "" || rec.Status == f.Status) &&
+		(f.TxHash == (common.Hash{}) || rec.TxHash == f.TxHash) &&
+		(f.Since.IsZero() || !rec.CreatedAt.Before(f.Since)) &&
+		(f.Until.IsZero() || rec.CreatedAt.Before(f.Until))
+}
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRecord reads the recordColumns of one row
+func scanRecord(row rowScanner) (Record, error) {
+	var (
+		id, status, txHash, replaced, errText, envelope string
+		blockNumber, createdAt, updatedAt               int64
+	)
+	if err := row.Scan(&id, &status, &txHash, &replaced, &blockNumber, &errText, &envelope, &createdAt, &updatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Record{}, err
+		}
+		return Record{}, fmt.Errorf("failed to read request: %w", err)
+	}
+	rec := Record{
+		ID:          common.HexToHash(id),
+		Status:      Status(status),
+		TxHash:      common.HexToHash(txHash),
+		Replaced:    parseSQLHashes(replaced),
+		BlockNumber: uint64(blockNumber),
+		Error:       errText,
+		CreatedAt:   time.UnixMilli(createdAt),
+		UpdatedAt:   time.UnixMilli(updatedAt),
+	}
+	if err := json.Unmarshal([]byte(envelope), &rec.Envelope); err != nil {
+		return Record{}, fmt.Errorf("failed to decode envelope of request %s: %w", id, err)
+	}
+	return rec, nil
+}
+
+// hexKey encodes b as the lowercase 0x-hex used in key columns
+func hexKey(b []byte) string {
+	return hexutil.Encode(b)
+}
+
+// sqlNonce encodes a nonce as a zero-padded decimal that sorts numerically
+func sqlNonce(nonce uint64) string {
+	return fmt.Sprintf("%020d", nonce)
+}
+
+// sqlHashes encodes hashes as comma-separated hexKeys
+func sqlHashes(hashes []common.Hash) string {
+	keys := make([]string, len(hashes))
+	for i, hash := range hashes {
+		keys[i] = hexKey(hash.Bytes())
+	}
+	return strings.Join(keys, ",")
+}
+
+// parseSQLHashes decodes the hashes encoded by sqlHashes
+func parseSQLHashes(s string) []common.Hash {
+	if s == "" {
+		return nil
+	}
+	keys := strings.Split(s, ",")
+	hashes := make([]common.Hash, len(keys))
+	for i, key := range keys {
+		hashes[i] = common.HexToHash(key)
+	}
+	return hashes
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// Status is the lifecycle state of a submitted request
+type Status string
+
+const (
+	StatusPending   Status = "pending"   // accepted, the relay transaction is being sent
+	StatusSubmitted Status = "submitted" // the relay transaction was sent
+	StatusExecuted  Status = "executed"  // the relay transaction was mined and the forwarder executed the request
+	StatusConfirmed Status = "confirmed" // the executed request is buried under the server's confirmations
+	StatusFailed    Status = "failed"    // the relay transaction reverted, or the forwarder skipped or failed the request
+)
+
+// Record is a submitted request and what became of it
+type Record struct {
+	ID          common.Hash             `json:"id"` // the request's RequestID
+	Envelope    eip2771toolkit.Envelope `json:"envelope"`
+	Status      Status                  `json:"status"`
+	TxHash      common.Hash             `json:"txHash"`
+	Replaced    []common.Hash           `json:"replaced,omitempty"`    // earlier versions of the relay transaction, any of which may be mined instead
+	BlockNumber uint64                  `json:"blockNumber,omitempty"` // set once mined
+	Error       string                  `json:"error,omitempty"`       // why the request failed
+	CreatedAt   time.Time               `json:"createdAt"`
+	UpdatedAt   time.Time               `json:"updatedAt"`
+}
+
+// Store persists request records. A record holds its signer's forwarder nonce on its
+// forwarder until it fails, so that one request per nonce is relayed. Implementations must be
+// safe for concurrent use.
+type Store interface {
+	// Create adds a record, or returns ErrDuplicate if one with its ID exists or another
+	// record that has not failed holds its nonce
+	Create(ctx context.Context, rec Record) error
+	// Update replaces the record with rec's ID, or returns ErrNotFound
+	Update(ctx context.Context, rec Record) error
+	// Delete removes the record with id, or returns ErrNotFound
+	Delete(ctx context.Context, id common.Hash) error
+	// Get returns the record with id, or ErrNotFound
+	Get(ctx context.Context, id common.Hash) (Record, error)
+	// GetByNonce returns the record holding the nonce of signer on forwarder, or ErrNotFound
+	GetByNonce(ctx context.Context, forwarder common.Address, slot eip2771toolkit.SignerNonce) (Record, error)
+}
+
+// RecordQuerier is a Store answering dashboard queries, such as MemoryStore and SQLStore
+type RecordQuerier interface {
+	RecordLister
+	// CountByStatus returns how many records matching filter have each status; Limit and
+	// Offset are ignored
+	CountByStatus(ctx context.Context, filter RecordFilter) (map[Status]int, error)
+	// LatestNonce returns the highest nonce of the signer's stored requests on forwarder,
+	// and false if there are none
+	LatestNonce(ctx context.Context, forwarder, signer common.Address) (uint64, bool, error)
+}
+
+var (
+	_ RecordQuerier = (*MemoryStore)(nil)
+	_ RecordQuerier = (*SQLStore)(nil)
+)
+
+// nonceSlot is a signer's nonce on one forwarder
+type nonceSlot struct {
+	forwarder common.Address
+	eip2771toolkit.SignerNonce
+}
+
+// slotOf returns the nonce slot of rec
+func slotOf(rec Record) nonceSlot {
+	return nonceSlot{forwarder: rec.Envelope.Domain.Forwarder, SignerNonce: rec.Envelope.Request.SignerNonce()}
+}
+
+// MemoryStore is a Store kept in memory, for tests and single-process services that can
+// lose request status on restart
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[common.Hash]Record
+	holders map[nonceSlot]common.Hash // ID of the record holding each nonce
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[common.Hash]Record),
+		holders: make(map[nonceSlot]common.Hash),
+	}
+}
+
+// Create adds a record
+func (m *MemoryStore) Create(ctx context.Context, rec Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.records[rec.ID]; ok {
+		return ErrDuplicate
+	}
+	if _, ok := m.holders[slotOf(rec)]; ok && rec.Status != StatusFailed {
+		return ErrDuplicate
+	}
+	m.records[rec.ID] = rec
+	m.hold(rec)
+	return nil
+}
+
+// Update replaces a record
+func (m *MemoryStore) Update(ctx context.Context, rec Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.records[rec.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	m.release(old)
+	m.records[rec.ID] = rec
+	m.hold(rec)
+	return nil
+}
+
+// Delete removes a record
+func (m *MemoryStore) Delete(ctx context.Context, id common.Hash) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.records[id]
+	if !ok {
+		return ErrNotFound
+	}
+	m.release(rec)
+	delete(m.records, id)
+	return nil
+}
+
+// Get returns a record
+func (m *MemoryStore) Get(ctx context.Context, id common.Hash) (Record, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rec, ok := m.records[id]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+	return rec, nil
+}
+
+// GetByNonce returns the record holding a nonce
+func (m *MemoryStore) GetByNonce(ctx context.Context, forwarder common.Address, slot eip2771toolkit.SignerNonce) (Record, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	id, ok := m.holders[nonceSlot{forwarder: forwarder, SignerNonce: slot}]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+	return m.records[id], nil
+}
+
+// List returns the records matching filter, newest first, like SQLStore.List
+func (m *MemoryStore) List(ctx context.Context, filter RecordFilter) ([]Record, error) {
+	m.mu.RLock()
+	var records []Record
+	for _, rec := range m.records {
+		if filter.matches(rec) {
+			records = append(records, rec)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].CreatedAt.Equal(records[j].CreatedAt) {
+			return records[i].CreatedAt.After(records[j].CreatedAt)
+		}
+		return records[i].ID.Cmp(records[j].ID) < 0
+	})
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	if filter.Offset >= len(records) {
+		return nil, nil
+	}
+	records = records[filter.Offset:]
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+// CountByStatus counts the records matching filter by status, like SQLStore.CountByStatus
+func (m *MemoryStore) CountByStatus(ctx context.Context, filter RecordFilter) (map[Status]int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	counts := make(map[Status]int)
+	for _, rec := range m.records {
+		if filter.matches(rec) {
+			counts[rec.Status]++
+		}
+	}
+	return counts, nil
+}
+
+// LatestNonce returns the highest nonce of a signer on forwarder, like SQLStore.LatestNonce
+func (m *MemoryStore) LatestNonce(ctx context.Context, forwarder, signer common.Address) (uint64, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var latest uint64
+	found := false
+	for _, rec := range m.records {
+		if slot := slotOf(rec); slot.forwarder == forwarder && slot.From == signer && (!found || slot.Nonce > latest) {
+			latest, found = slot.Nonce, true
+		}
+	}
+	return latest, found, nil
+}
+
+// hold makes rec the holder of its nonce unless it failed, with m.mu held
+func (m *MemoryStore) hold(rec Record) {
+	if rec.Status != StatusFailed {
+		m.holders[slotOf(rec)] = rec.ID
+	}
+}
+
+// release frees the nonce rec holds, with m.mu held
+func (m *MemoryStore) release(rec Record) {
+	if slot := slotOf(rec); m.holders[slot] == rec.ID {
+		delete(m.holders, slot)
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	_ "modernc.org/sqlite"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// queryStore is a Store answering dashboard queries
+type queryStore interface {
+	Store
+	RecordQuerier
+}
+
+// stores returns each built-in store, empty
+func stores(t *testing.T) map[string]queryStore {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "requests.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	sqlStore, err := OpenSQLStore(context.Background(), db, SQLiteDialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]queryStore{"memory": NewMemoryStore(), "sqlite": sqlStore}
+}
+
+// record returns a record of a request of signer with nonce on forwarder
+func record(forwarder, signer common.Address, nonce uint64, status Status) Record {
+	metaTx := eip2771toolkit.NewMetaTx(signer, common.HexToAddress("0xbeef"), common.HexToAddress("0x70c0"), big.NewInt(1), 100000, nonce, 1)
+	now := time.UnixMilli(time.Now().UnixMilli())
+	return Record{
+		ID: eip2771toolkit.RequestID(metaTx, big.NewInt(1), forwarder),
+		Envelope: eip2771toolkit.Envelope{
+			Request: eip2771toolkit.BatchMetaTxRequest{MetaTx: metaTx},
+			Domain:  eip2771toolkit.DomainBinding{ChainID: 1, Forwarder: forwarder},
+		},
+		Status:    status,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func TestStoreQueries(t *testing.T) {
+	forwarder := common.HexToAddress("0xf000000000000000000000000000000000000001")
+	upgraded := common.HexToAddress("0xf000000000000000000000000000000000000002")
+	alice := common.HexToAddress("0xa11ce00000000000000000000000000000000000")
+	bob := common.HexToAddress("0xb0b0000000000000000000000000000000000000")
+
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for _, rec := range []Record{
+				record(forwarder, alice, 3, StatusConfirmed),
+				record(forwarder, alice, 9, StatusFailed),
+				record(forwarder, alice, 10, StatusSubmitted),
+				record(upgraded, alice, 40, StatusPending),
+				record(forwarder, bob, 0, StatusSubmitted),
+			} {
+				if err := store.Create(ctx, rec); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			tests := []struct {
+				forwarder, signer common.Address
+				want              uint64
+				found             bool
+			}{
+				{forwarder, alice, 10, true},
+				{upgraded, alice, 40, true},
+				{forwarder, bob, 0, true},
+				{upgraded, bob, 0, false},
+				{forwarder, common.HexToAddress("0xca401"), 0, false},
+			}
+			for _, tt := range tests {
+				nonce, found, err := store.LatestNonce(ctx, tt.forwarder, tt.signer)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if nonce != tt.want || found != tt.found {
+					t.Errorf("LatestNonce(%s, %s) = %d, %v, want %d, %v", tt.forwarder.Hex(), tt.signer.Hex(), nonce, found, tt.want, tt.found)
+				}
+			}
+
+			counts, err := store.CountByStatus(ctx, RecordFilter{Signer: alice})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[Status]int{StatusConfirmed: 1, StatusFailed: 1, StatusSubmitted: 1, StatusPending: 1}
+			if len(counts) != len(want) {
+				t.Errorf("CountByStatus = %v, want %v", counts, want)
+			}
+			for status, n := range want {
+				if counts[status] != n {
+					t.Errorf("CountByStatus = %v, want %v", counts, want)
+					break
+				}
+			}
+			counts, err = store.CountByStatus(ctx, RecordFilter{Status: StatusSubmitted, Limit: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(counts) != 1 || counts[StatusSubmitted] != 2 {
+				t.Errorf("CountByStatus of submitted records = %v, want 2 ignoring Limit", counts)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WEBHOOK_QUEUE_SIZE is how many events may wait for delivery to one webhook
+const WEBHOOK_QUEUE_SIZE = 1024
+
+// Webhook delivery headers
+const (
+	WEBHOOK_EVENT_HEADER     = "X-EIP2771-Event"
+	WEBHOOK_TIMESTAMP_HEADER = "X-EIP2771-Timestamp"
+	WEBHOOK_SIGNATURE_HEADER = "X-EIP2771-Signature"
+)
+
+// EventType is a lifecycle event of a relayed request
+type EventType string
+
+const (
+	EventSubmitted EventType = "submitted" // the relay transaction was sent
+	EventReplaced  EventType = "replaced"  // the monitor sped up or cancelled the relay transaction
+	EventMined     EventType = "mined"     // the relay transaction was mined and the forwarder executed the request
+	EventConfirmed EventType = "confirmed" // the relay transaction is buried under the server's confirmations
+	EventFailed    EventType = "failed"    // the relay transaction reverted, or the forwarder skipped or failed the request
+)
+
+// WebhookEvent is the JSON body of a webhook delivery
+type WebhookEvent struct {
+	Type   EventType `json:"type"`
+	Time   time.Time `json:"time"`
+	Record Record    `json:"record"`
+}
+
+// Webhook posts request lifecycle events as JSON to URL. With a Secret, every delivery carries
+// the unix time in X-EIP2771-Timestamp and "sha256=" followed by the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with Secret in X-EIP2771-Signature; receivers check it with
+// VerifyWebhookSignature. Deliveries that fail or get a non-2xx answer are retried with
+// exponential backoff. Events are delivered one at a time in the order they happened.
+type Webhook struct {
+	URL         string
+	Secret      []byte
+	Events      []EventType       // events to deliver, empty for all
+	Headers     map[string]string // e.g. an authorization header for the receiving service
+	MaxAttempts int               // total attempts per event including the first, default 5
+	BaseDelay   time.Duration     // delay before the first retry, doubled after each attempt, default 1s
+	MaxDelay    time.Duration     // upper bound of a single delay, default 1m
+	HTTPClient  *http.Client      // defaults to a client with a 10 second timeout
+}
+
+var defaultWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// wants reports whether the webhook subscribes to events of type t
+func (h *Webhook) wants(t EventType) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Deliver posts one event, retrying until it is accepted, the attempts run out or ctx is done
+func (h *Webhook) Deliver(ctx context.Context, event WebhookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+
+	attempts := h.MaxAttempts
+	if attempts <= 0 {
+		attempts = 5
+	}
+	for attempt := 1; ; attempt++ {
+		err = h.post(ctx, event.Type, body)
+		if err == nil || attempt >= attempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(h.backoff(attempt)):
+		}
+	}
+}
+
+// post makes one delivery attempt
+func (h *Webhook) post(ctx context.Context, eventType EventType, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WEBHOOK_EVENT_HEADER, string(eventType))
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+	if len(h.Secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WEBHOOK_TIMESTAMP_HEADER, timestamp)
+		req.Header.Set(WEBHOOK_SIGNATURE_HEADER, SignWebhook(h.Secret, timestamp, body))
+	}
+
+	httpClient := h.HTTPClient
+	if httpClient == nil {
+		httpClient = defaultWebhookClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver webhook to %s: %w", h.URL, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s returned %s", h.URL, resp.Status)
+	}
+	return nil
+}
+
+// backoff returns the delay before the given retry attempt
+func (h *Webhook) backoff(attempt int) time.Duration {
+	base, max := h.BaseDelay, h.MaxDelay
+	if base <= 0 {
+		base = time.Second
+	}
+	if max <= 0 {
+		max = time.Minute
+	}
+	delay := base << (attempt - 1)
+	if delay > max || delay <= 0 {
+		delay = max
+	}
+	return delay
+}
+
+// SignWebhook returns the X-EIP2771-Signature value of a delivery body sent at timestamp
+func SignWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks the signature and timestamp headers of a received delivery
+// against its body. Deliveries timestamped more than tolerance away from now are rejected to
+// stop replays; a zero tolerance skips the check.
+func VerifyWebhookSignature(secret []byte, timestamp, signature string, body []byte, tolerance time.Duration) error {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return ErrInvalidWebhookSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(SignWebhook(secret, timestamp, body))) {
+		return ErrInvalidWebhookSignature
+	}
+	if tolerance > 0 {
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid timestamp %q", ErrInvalidWebhookSignature, timestamp)
+		}
+		if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+			return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidWebhookSignature)
+		}
+	}
+	return nil
+}
+
+// webhookDispatcher delivers events to one webhook in order from a queue
+type webhookDispatcher struct {
+	hook   *Webhook
+	events chan WebhookEvent
+	logger *slog.Logger
+}
+
+// run delivers queued events until ctx is done. Events whose delivery fails after all
+// attempts are dropped.
+func (d *webhookDispatcher) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-d.events:
+			if err := d.hook.Deliver(ctx, event); err != nil && ctx.Err() == nil {
+				d.logger.Warn("webhook delivery failed", "url", d.hook.URL, "event", event.Type, "id", event.Record.ID, "error", err)
+			}
+		}
+	}
+}
+
+// notify queues an event of rec for every webhook subscribed to it. It blocks while a queue
+// is full, until the server closes.
+func (s *Server) notify(eventType EventType, rec Record) {
+	event := WebhookEvent{Type: eventType, Time: time.Now(), Record: rec}
+	for _, d := range s.webhooks {
+		if !d.hook.wants(eventType) {
+			continue
+		}
+		select {
+		case d.events <- event:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
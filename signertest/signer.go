@@ -0,0 +1,151 @@
+// Package signertest provides a MetaTxSigner test double that misbehaves on demand, for
+// testing relay pipelines against slow, flaky or faulty remote signing services.
+package signertest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// ErrInjected is the default error returned by injected failures
+var ErrInjected = errors.New("signertest: injected signer failure")
+
+// MockSigner wraps a MetaTxSigner and injects latency, errors and wrong signatures. Random
+// faults are drawn from a seeded source, so runs are reproducible. It is safe for concurrent use.
+type MockSigner struct {
+	Signer eip2771toolkit.MetaTxSigner // signer producing the correct signatures
+
+	Latency            time.Duration // delay before every signature
+	Jitter             time.Duration // extra random delay up to Jitter
+	ErrorRate          float64       // probability of failing with Err
+	WrongSignatureRate float64       // probability of returning a well-formed signature by another key
+	Err                error         // injected error, ErrInjected if nil
+
+	mu        sync.Mutex
+	rng       *rand.Rand
+	wrongKey  *ecdsa.PrivateKey
+	failNext  int
+	wrongNext int
+	calls     int
+	failures  int
+	wrong     int
+}
+
+// NewMockSigner creates a MockSigner around signer that behaves until faults are configured
+func NewMockSigner(signer eip2771toolkit.MetaTxSigner, seed int64) *MockSigner {
+	wrongKey, err := crypto.GenerateKey()
+	if err != nil {
+		panic(err)
+	}
+	return &MockSigner{
+		Signer:   signer,
+		rng:      rand.New(rand.NewSource(seed)),
+		wrongKey: wrongKey,
+	}
+}
+
+// NewMockKeySigner creates a MockSigner signing with key
+func NewMockKeySigner(key *ecdsa.PrivateKey, seed int64) *MockSigner {
+	return NewMockSigner(eip2771toolkit.KeySigner{Key: key}, seed)
+}
+
+// FailNext makes the next n calls fail with Err regardless of ErrorRate
+func (m *MockSigner) FailNext(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failNext = n
+}
+
+// WrongSignatureNext makes the next n successful calls return wrong signatures
+func (m *MockSigner) WrongSignatureNext(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.wrongNext = n
+}
+
+// SignMetaTx implements eip2771toolkit.MetaTxSigner
+func (m *MockSigner) SignMetaTx(ctx context.Context, metaTx eip2771toolkit.MetaTx, domainSeparator []byte) (eip2771toolkit.Signature, error) {
+	delay, fail, wrong := m.draw()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return eip2771toolkit.Signature{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if fail {
+		if m.Err != nil {
+			return eip2771toolkit.Signature{}, m.Err
+		}
+		return eip2771toolkit.Signature{}, ErrInjected
+	}
+	if wrong {
+		return eip2771toolkit.SignMetaTx(metaTx, m.wrongKey, domainSeparator)
+	}
+	return m.Signer.SignMetaTx(ctx, metaTx, domainSeparator)
+}
+
+// draw decides the delay and fault of one call and updates the counters
+func (m *MockSigner) draw() (time.Duration, bool, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+
+	delay := m.Latency
+	if m.Jitter > 0 {
+		delay += time.Duration(m.rng.Int63n(int64(m.Jitter) + 1))
+	}
+
+	fail := m.failNext > 0 || m.rng.Float64() < m.ErrorRate
+	if m.failNext > 0 {
+		m.failNext--
+	}
+	if fail {
+		m.failures++
+		return delay, true, false
+	}
+
+	wrong := m.wrongNext > 0 || m.rng.Float64() < m.WrongSignatureRate
+	if m.wrongNext > 0 {
+		m.wrongNext--
+	}
+	if wrong {
+		m.wrong++
+	}
+	return delay, false, wrong
+}
+
+// Calls returns the number of SignMetaTx calls
+func (m *MockSigner) Calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
+
+// Failures returns the number of injected errors
+func (m *MockSigner) Failures() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.failures
+}
+
+// WrongSignatures returns the number of wrong signatures returned
+func (m *MockSigner) WrongSignatures() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.wrong
+}
+
+var _ eip2771toolkit.MetaTxSigner = (*MockSigner)(nil)
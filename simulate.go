@@ -0,0 +1,98 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+// SimulationResult is the projected outcome of relaying a request
+type SimulationResult struct {
+	InnerReturnData []byte // what the target returned to the forwarder
+	GasEstimate     uint64 // gas of the execute() transaction, zero when simulated at a past block
+	GasLimit        uint64 // GasEstimate with the relayer's gas margin, the limit it would relay with
+}
+
+// simulateConfig holds the settings of SimulateRelay
+type simulateConfig struct {
+	block *big.Int
+}
+
+// SimulateOption configures SimulateRelay
+type SimulateOption func(*simulateConfig)
+
+// WithSimulationBlock simulates on top of the state at block instead of the latest block.
+// Gas is only estimated against the latest block.
+func WithSimulationBlock(block *big.Int) SimulateOption {
+	return func(c *simulateConfig) {
+		c.block = block
+	}
+}
+
+// SimulateRelay dry-runs relaying a request through the relayer's forwarder without sending
+// anything, so servers can reject doomed requests before queuing them. It calls execute()
+// with eth_call and estimates its gas, and replays the inner call from the forwarder to
+// return the target's return data. A request that would revert fails with a RevertError
+// explaining the forwarder's or the target's reason; a token transfer returning false fails
+// with ErrTransferReturnedFalse. Sandbox checks and permissions are not applied.
+func (r *Relayer) SimulateRelay(ctx context.Context, metaTx MetaTx, sig Signature, opts ...SimulateOption) (SimulationResult, error) {
+	var cfg simulateConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	data, err := r.PackExecuteCalldata(ctx, metaTx, sig)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+	forwardRequest, err := metaTx.ForwardRequest()
+	if err != nil {
+		return SimulationResult{}, err
+	}
+
+	// The inner call as the forwarder makes it, with the signer appended (ERC-2771)
+	innerMsg := ethereum.CallMsg{
+		From:  r.forwarder,
+		To:    &forwardRequest.To,
+		Gas:   metaTx.Gas,
+		Value: forwardRequest.Value,
+		Data:  append(append([]byte{}, forwardRequest.Data...), metaTx.From.Bytes()...),
+	}
+	var result SimulationResult
+	innerRet, innerErr := r.client.CallContract(ctx, innerMsg, cfg.block)
+	if innerErr != nil {
+		innerErr = explainRevert(ctx, r.client, innerMsg, cfg.block, innerErr)
+	} else {
+		result.InnerReturnData = innerRet
+	}
+
+	msg := ethereum.CallMsg{
+		From:  r.address,
+		To:    &r.forwarder,
+		Value: forwardRequest.Value,
+		Data:  data,
+	}
+	if _, err := r.client.CallContract(ctx, msg, cfg.block); err != nil {
+		err = explainRevert(ctx, r.client, msg, cfg.block, err)
+		if innerErr != nil {
+			return result, fmt.Errorf("simulated relay failed: %w (inner call: %w)", err, innerErr)
+		}
+		return result, fmt.Errorf("simulated relay failed: %w", err)
+	}
+	if innerErr == nil {
+		if err := DecodeTransferResult(innerRet); err != nil {
+			return result, fmt.Errorf("simulated relay failed: %w", err)
+		}
+	}
+
+	if cfg.block == nil {
+		result.GasEstimate, err = r.client.EstimateGas(ctx, msg)
+		if err != nil {
+			return result, fmt.Errorf("failed to estimate gas: %w", explainRevert(ctx, r.client, msg, nil, err))
+		}
+		result.GasLimit = r.activeGasMargin().Apply(result.GasEstimate)
+	}
+	return result, nil
+}
@@ -0,0 +1,182 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// SplitBatchByGas partitions batch, in order, into executeBatch transactions whose estimated
+// gas stays within maxGasPerTx, e.g. the block gas limit or a lower target. The estimate
+// follows EstimateBatchGas, with each request's calldata priced as if it were sent alone
+// and a refund paid, so partitions are never underestimated. Requests are encoded for the
+// default forwarder profile.
+func SplitBatchByGas(batch BatchMetaTxRequestList, maxGasPerTx uint64) ([]BatchMetaTxRequestList, error) {
+	return splitBatchByGas(DefaultRegistry, DefaultRegistry.DefaultForwarderProfile().Schema, batch, maxGasPerTx, BatchLimits{})
+}
+
+// SplitBatchByGas partitions batch like the package-level SplitBatchByGas, encoding requests
+// for the relayer's forwarder profile and keeping partitions within its BatchLimits
+func (r *Relayer) SplitBatchByGas(batch BatchMetaTxRequestList, maxGasPerTx uint64) ([]BatchMetaTxRequestList, error) {
+	return splitBatchByGas(r.registry, r.profile.Schema, batch, maxGasPerTx, r.batchLimits)
+}
+
+// batchFixedGas is the gas of an executeBatch transaction before any request
+const batchFixedGas = TX_BASE_GAS + FORWARDER_BATCH_BASE_GAS + FORWARDER_REFUND_GAS
+
+// batchRequestGas is the gas req adds to an executeBatch transaction, its calldata priced as
+// if it were sent alone, and the size of that calldata
+func batchRequestGas(registry *Registry, schema *RequestSchema, req BatchMetaTxRequest) (uint64, int, error) {
+	// An all-ones refund receiver prices the refund address as non-zero calldata
+	data, _, err := packExecuteBatchCall(registry, schema, BatchMetaTxRequestList{req}, common.MaxAddress)
+	if err != nil {
+		return 0, 0, err
+	}
+	return CalldataGas(data) + FORWARDER_BATCH_PER_REQUEST_GAS + forwardedGasNeeded(req.MetaTx.Gas), len(data), nil
+}
+
+// splitBatchByGas greedily fills partitions until the next request would exceed maxGasPerTx
+// or limits. The calldata size of a partition is bounded by the sum of its requests' sizes
+// when sent alone.
+func splitBatchByGas(registry *Registry, schema *RequestSchema, batch BatchMetaTxRequestList, maxGasPerTx uint64, limits BatchLimits) ([]BatchMetaTxRequestList, error) {
+	fixed := uint64(batchFixedGas)
+
+	var partitions []BatchMetaTxRequestList
+	var current BatchMetaTxRequestList
+	gas, size := fixed, 0
+	for i, req := range batch {
+		reqGas, reqSize, err := batchRequestGas(registry, schema, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request %d: %w", i, err)
+		}
+		if fixed+reqGas > maxGasPerTx {
+			return nil, fmt.Errorf("request %d alone needs %d gas, above the limit of %d", i, fixed+reqGas, maxGasPerTx)
+		}
+		if limits.MaxCalldataBytes > 0 && reqSize > limits.MaxCalldataBytes {
+			return nil, fmt.Errorf("%w: request %d alone needs %d bytes of calldata, above the limit of %d", ErrBatchTooLarge, i, reqSize, limits.MaxCalldataBytes)
+		}
+
+		full := gas+reqGas > maxGasPerTx ||
+			(limits.MaxRequests > 0 && len(current) >= limits.MaxRequests) ||
+			(limits.MaxCalldataBytes > 0 && size+reqSize > limits.MaxCalldataBytes)
+		if len(current) > 0 && full {
+			partitions = append(partitions, current)
+			current, gas, size = nil, fixed, 0
+		}
+		current = append(current, req)
+		gas += reqGas
+		size += reqSize
+	}
+	if len(current) > 0 {
+		partitions = append(partitions, current)
+	}
+	return partitions, nil
+}
+
+// ChunkResult is the outcome of one chunk of a chunked relay
+type ChunkResult struct {
+	Index    int
+	Requests BatchMetaTxRequestList
+	Hash     common.Hash  // zero if the chunk was not sent
+	Result   *RelayResult // nil if the chunk was not mined
+	Err      error
+}
+
+// chunkConfig holds the settings of RelayMetaTxBatchChunked
+type chunkConfig struct {
+	concurrency int
+	waitOpts    []WaitOption
+}
+
+// ChunkOption configures RelayMetaTxBatchChunked
+type ChunkOption func(*chunkConfig)
+
+// WithChunkConcurrency lets up to n chunks be pending at once, default 1. Chunks are always
+// sent in order. Only raise it when later chunks do not depend on earlier ones, e.g. requests
+// of one signer with consecutive nonces: a chunk is estimated against the latest block, where
+// requests whose nonce an earlier pending chunk uses are not yet executable.
+func WithChunkConcurrency(n int) ChunkOption {
+	return func(c *chunkConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithChunkWaitOptions sets how each chunk's receipt is waited for
+func WithChunkWaitOptions(opts ...WaitOption) ChunkOption {
+	return func(c *chunkConfig) {
+		c.waitOpts = opts
+	}
+}
+
+// RelayMetaTxBatchChunked relays a large batch, such as an airdrop, as executeBatch
+// transactions of at most chunkSize requests, in order, waiting for each to be mined. A
+// failing chunk does not stop the others; every chunk's hash, receipt and error are returned,
+// along with the chunk errors joined.
+func (r *Relayer) RelayMetaTxBatchChunked(ctx context.Context, batch BatchMetaTxRequestList, chunkSize int, refundReceiver common.Address, opts ...ChunkOption) ([]ChunkResult, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive")
+	}
+	cfg := chunkConfig{concurrency: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.concurrency <= 0 {
+		cfg.concurrency = 1
+	}
+
+	var results []ChunkResult
+	for start := 0; start < len(batch); start += chunkSize {
+		end := start + chunkSize
+		if end > len(batch) {
+			end = len(batch)
+		}
+		results = append(results, ChunkResult{Index: len(results), Requests: batch[start:end]})
+	}
+
+	slots := make(chan struct{}, cfg.concurrency)
+	var wg sync.WaitGroup
+	for i := range results {
+		chunk := &results[i]
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			chunk.Err = ctx.Err()
+			continue
+		}
+
+		// Sending stays on this goroutine so relayer nonces follow the chunk order
+		chunk.Hash, chunk.Err = r.RelayMetaTxBatch(ContextWithRecordRelay(ctx), chunk.Requests, refundReceiver)
+		if chunk.Err != nil {
+			<-slots
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			result, err := r.WaitForRelay(ctx, chunk.Hash, cfg.waitOpts...)
+			if err != nil {
+				chunk.Err = err
+				return
+			}
+			chunk.Result = &result
+			chunk.Err = r.RecordRelay(ctx, result, chunk.Requests)
+			if !result.Succeeded() {
+				chunk.Err = fmt.Errorf("%w: relay transaction %s reverted", ErrContractCallFailed, chunk.Hash.Hex())
+			}
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, chunk := range results {
+		if chunk.Err != nil {
+			errs = append(errs, fmt.Errorf("chunk %d: %w", chunk.Index, chunk.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
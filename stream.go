@@ -0,0 +1,183 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AirdropSource yields airdrop rows one at a time, returning io.EOF after the last one.
+// AirdropCSVReader.Next is a source.
+type AirdropSource func() (AirdropRow, error)
+
+// AirdropRowsSource returns a source yielding rows in order
+func AirdropRowsSource(rows []AirdropRow) AirdropSource {
+	i := 0
+	return func() (AirdropRow, error) {
+		if i == len(rows) {
+			return AirdropRow{}, io.EOF
+		}
+		i++
+		return rows[i-1], nil
+	}
+}
+
+// BatchStream signs token transfers pulled from a source with sequential forwarder nonces and
+// groups them into executeBatch transactions within a gas limit and the relayer's BatchLimits,
+// holding only the batch being built in memory. Each request's deadline is the TTL from when
+// it is signed, so long-running streams do not sign requests that expire before they are
+// relayed.
+type BatchStream struct {
+	relayer         *Relayer
+	signerKey       *ecdsa.PrivateKey
+	signer          common.Address
+	token           common.Address
+	source          AirdropSource
+	cfg             AirdropConfig
+	domainSeparator []byte
+	nonce           uint64
+
+	pending     *BatchMetaTxRequest // signed request that did not fit the previous batch
+	pendingGas  uint64
+	pendingSize int
+	done        bool
+}
+
+// NewBatchStream creates a stream of signed transfer batches from signerKey, starting at the
+// signer's current forwarder nonce. Zero fields of cfg take their defaults.
+func (r *Relayer) NewBatchStream(ctx context.Context, signerKey *ecdsa.PrivateKey, token common.Address, source AirdropSource, cfg AirdropConfig) (*BatchStream, error) {
+	signer := AddressFromPrivateKey(signerKey)
+	nonce, err := getMetaTxNonce(ctx, r.registry, r.profile, r.forwarder, signer, r.client)
+	if err != nil {
+		return nil, err
+	}
+	domainSeparator, err := r.domainSeparator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &BatchStream{
+		relayer:         r,
+		signerKey:       signerKey,
+		signer:          signer,
+		token:           token,
+		source:          source,
+		cfg:             cfg.withDefaults(r),
+		domainSeparator: domainSeparator,
+		nonce:           nonce,
+	}, nil
+}
+
+// NextNonce returns the nonce the next signed request will use
+func (s *BatchStream) NextNonce() uint64 {
+	if s.pending != nil {
+		return s.pending.MetaTx.Nonce
+	}
+	return s.nonce
+}
+
+// Next returns the next batch, or io.EOF once the source is exhausted. Batches must be relayed
+// in order, since each continues the previous one's nonces.
+func (s *BatchStream) Next() (BatchMetaTxRequestList, error) {
+	fixed := uint64(batchFixedGas)
+	limits := s.relayer.batchLimits
+	var batch BatchMetaTxRequestList
+	gas, size := fixed, 0
+	if s.pending != nil {
+		batch = append(batch, *s.pending)
+		gas += s.pendingGas
+		size += s.pendingSize
+		s.pending = nil
+	}
+
+	for !s.done {
+		row, err := s.source()
+		if err == io.EOF {
+			s.done = true
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read airdrop row: %w", err)
+		}
+
+		metaTx := NewMetaTx(s.signer, row.Recipient, s.token, row.Amount, s.cfg.Gas, s.nonce, uint64(time.Now().Add(s.cfg.TTL).Unix()))
+		sig, err := SignMetaTxWithSchema(s.relayer.profile.Schema, metaTx, s.signerKey, s.domainSeparator)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign request with nonce %d: %w", metaTx.Nonce, err)
+		}
+		req := BatchMetaTxRequest{MetaTx: metaTx, Signature: sig}
+		reqGas, reqSize, err := batchRequestGas(s.relayer.registry, s.relayer.profile.Schema, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request with nonce %d: %w", metaTx.Nonce, err)
+		}
+		if fixed+reqGas > s.cfg.MaxGasPerTx {
+			return nil, fmt.Errorf("request with nonce %d alone needs %d gas, above the limit of %d", metaTx.Nonce, fixed+reqGas, s.cfg.MaxGasPerTx)
+		}
+		s.nonce++
+
+		full := gas+reqGas > s.cfg.MaxGasPerTx ||
+			(limits.MaxRequests > 0 && len(batch) >= limits.MaxRequests) ||
+			(limits.MaxCalldataBytes > 0 && size+reqSize > limits.MaxCalldataBytes)
+		if len(batch) > 0 && full {
+			s.pending, s.pendingGas, s.pendingSize = &req, reqGas, reqSize
+			return batch, nil
+		}
+		batch = append(batch, req)
+		gas += reqGas
+		size += reqSize
+	}
+
+	if len(batch) == 0 {
+		return nil, io.EOF
+	}
+	return batch, nil
+}
+
+// AirdropStream transfers token from the signer to every row of source like Airdrop, but
+// builds, signs and relays one transaction at a time through a BatchStream, so lists of any
+// size run in constant memory. Each transaction's row outcomes are passed to onBatch once it
+// is mined; failed or skipped rows are reported there rather than in the returned error. The
+// stream stops, returning the error, when a transaction cannot be sent or reverts (its rows
+// are still passed to onBatch), when the source fails, or when onBatch returns an error.
+func (r *Relayer) AirdropStream(ctx context.Context, signerKey *ecdsa.PrivateKey, token common.Address, source AirdropSource, cfg AirdropConfig, onBatch func([]AirdropResult) error) error {
+	stream, err := r.NewBatchStream(ctx, signerKey, token, source, cfg)
+	if err != nil {
+		return err
+	}
+
+	for {
+		batch, err := stream.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		results := make([]AirdropResult, len(batch))
+		for i, req := range batch {
+			results[i] = AirdropResult{
+				AirdropRow: AirdropRow{Recipient: req.MetaTx.To, Amount: req.MetaTx.Amount},
+				Nonce:      req.MetaTx.Nonce,
+				Status:     AirdropNotSent,
+			}
+		}
+		relayErr := r.relayAirdropPart(ctx, batch, results, stream.cfg)
+		if relayErr != nil {
+			for i := range results {
+				if results[i].Err == nil {
+					results[i].Err = relayErr
+				}
+			}
+		}
+		if err := onBatch(results); err != nil {
+			return err
+		}
+		if relayErr != nil {
+			return relayErr
+		}
+	}
+}
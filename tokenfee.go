@@ -0,0 +1,170 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TokenFee makes users pay for relaying in an ERC20 token instead of the relayer sponsoring
+// it: the user signs one more transfer, of the fee from them to Recipient, after the requests
+// of their batch. Relay such batches with RelayMetaTxBatchAtomic so that a failing fee
+// transfer reverts the whole batch instead of being skipped.
+type TokenFee struct {
+	Token          common.Address // token the fee is paid in
+	Recipient      common.Address // receives the fee, usually the relayer address
+	TokensPerEther *big.Int       // fee token base units worth one ether (1e18 wei)
+	MarkupPercent  uint64         // added to the gas cost, e.g. to cover price moves until the relay
+	Gas            uint64         // inner gas of the fee transfer, default the registry's default gas limit
+}
+
+// weiPerEther is 1e18
+var weiPerEther = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+// FeeForCost converts a relay cost in wei to the fee in token base units, with the markup,
+// rounded up
+func (f TokenFee) FeeForCost(costWei *big.Int) *big.Int {
+	fee := new(big.Int).Mul(costWei, f.TokensPerEther)
+	fee.Mul(fee, new(big.Int).SetUint64(100+f.MarkupPercent))
+	divisor := new(big.Int).Mul(weiPerEther, big.NewInt(100))
+	fee.Add(fee, divisor).Sub(fee, big.NewInt(1))
+	return fee.Div(fee, divisor)
+}
+
+// FeeForGas returns the fee for relaying gas at gasPrice wei
+func (f TokenFee) FeeForGas(gas uint64, gasPrice *big.Int) *big.Int {
+	return f.FeeForCost(new(big.Int).Mul(new(big.Int).SetUint64(gas), gasPrice))
+}
+
+// feeGas returns the inner gas of the fee transfer
+func (f TokenFee) feeGas() uint64 {
+	if f.Gas > 0 {
+		return f.Gas
+	}
+	return DefaultRegistry.DefaultGasLimit()
+}
+
+// FeeMetaTx returns the unsigned fee transfer paying amount for metaTxs, which must all be
+// from one signer: the nonce after the last request's and the earliest deadline of the batch
+func (f TokenFee) FeeMetaTx(metaTxs []MetaTx, amount *big.Int) (MetaTx, error) {
+	if len(metaTxs) == 0 {
+		return MetaTx{}, fmt.Errorf("batch cannot be empty")
+	}
+	if f.Recipient == (common.Address{}) || f.Token == (common.Address{}) {
+		return MetaTx{}, fmt.Errorf("token fee: %w", ErrZeroAddress)
+	}
+
+	from := metaTxs[0].From
+	nonce := metaTxs[0].Nonce
+	deadline := metaTxs[0].Deadline
+	for i, metaTx := range metaTxs {
+		if metaTx.From != from {
+			return MetaTx{}, fmt.Errorf("request at index %d has different from address: expected %s, got %s", i, from.Hex(), metaTx.From.Hex())
+		}
+		nonce = max(nonce, metaTx.Nonce)
+		deadline = min(deadline, metaTx.Deadline)
+	}
+	return NewMetaTx(from, f.Recipient, f.Token, amount, f.feeGas(), nonce+1, deadline), nil
+}
+
+// EstimateGas returns an upper bound of the executeBatch gas of metaTxs with the fee transfer
+// appended, following SplitBatchByGas, for the default forwarder profile
+func (f TokenFee) EstimateGas(metaTxs []MetaTx) (uint64, error) {
+	return f.estimateGas(DefaultRegistry, DefaultRegistry.DefaultForwarderProfile().Schema, metaTxs)
+}
+
+// estimateGas prices metaTxs and the fee transfer with all-ones signatures, which cost the
+// most calldata
+func (f TokenFee) estimateGas(registry *Registry, schema *RequestSchema, metaTxs []MetaTx) (uint64, error) {
+	feeMetaTx, err := f.FeeMetaTx(metaTxs, new(big.Int).Set(common.MaxHash.Big()))
+	if err != nil {
+		return 0, err
+	}
+
+	sig := Signature{V: 0xff, R: common.MaxHash, S: common.MaxHash}
+	gas := uint64(batchFixedGas)
+	for _, metaTx := range append(append([]MetaTx{}, metaTxs...), feeMetaTx) {
+		requestGas, _, err := batchRequestGas(registry, schema, BatchMetaTxRequest{MetaTx: metaTx, Signature: sig})
+		if err != nil {
+			return 0, err
+		}
+		gas += requestGas
+	}
+	return gas, nil
+}
+
+// Append returns metaTxs followed by the fee transfer for relaying them at gasPrice wei
+func (f TokenFee) Append(metaTxs []MetaTx, gasPrice *big.Int) ([]MetaTx, error) {
+	gas, err := f.EstimateGas(metaTxs)
+	if err != nil {
+		return nil, err
+	}
+	return f.append(metaTxs, f.FeeForGas(gas, gasPrice))
+}
+
+// append returns metaTxs followed by a fee transfer of amount
+func (f TokenFee) append(metaTxs []MetaTx, amount *big.Int) ([]MetaTx, error) {
+	feeMetaTx, err := f.FeeMetaTx(metaTxs, amount)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]MetaTx{}, metaTxs...), feeMetaTx), nil
+}
+
+// Paid returns what the requests of batch transfer to Recipient in Token, for relayers
+// checking a batch pays its fee before relaying it
+func (f TokenFee) Paid(batch BatchMetaTxRequestList) *big.Int {
+	paid := new(big.Int)
+	for _, req := range batch {
+		if req.MetaTx.Token == f.Token && req.MetaTx.To == f.Recipient && req.MetaTx.Amount != nil {
+			paid.Add(paid, req.MetaTx.Amount)
+		}
+	}
+	return paid
+}
+
+// NewMetaTxBatchWithTokenFee creates a batch like NewMetaTxBatch followed by the fee
+// transfer for relaying it at gasPrice wei
+func NewMetaTxBatchWithTokenFee(
+	from common.Address,
+	recipients []common.Address,
+	token common.Address,
+	amounts []*big.Int,
+	gas uint64,
+	startingNonce uint64,
+	deadline uint64,
+	fee TokenFee,
+	gasPrice *big.Int,
+) ([]MetaTx, error) {
+	metaTxs, err := NewMetaTxBatch(from, recipients, token, amounts, gas, startingNonce, deadline)
+	if err != nil {
+		return nil, err
+	}
+	return fee.Append(metaTxs, gasPrice)
+}
+
+// QuoteTokenFee returns the fee for relaying metaTxs with the fee transfer appended, priced
+// at the relayer's current suggested fee per gas for its forwarder profile. The quote is an
+// upper bound of the gas cost; rollup L1 data fees are not included.
+func (r *Relayer) QuoteTokenFee(ctx context.Context, metaTxs []MetaTx, fee TokenFee) (*big.Int, error) {
+	gas, err := fee.estimateGas(r.registry, r.profile.Schema, metaTxs)
+	if err != nil {
+		return nil, err
+	}
+	_, gasPrice, err := r.suggestFee(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fee.FeeForGas(gas, gasPrice), nil
+}
+
+// AppendTokenFee returns metaTxs followed by the fee transfer quoted by QuoteTokenFee
+func (r *Relayer) AppendTokenFee(ctx context.Context, metaTxs []MetaTx, fee TokenFee) ([]MetaTx, error) {
+	amount, err := r.QuoteTokenFee(ctx, metaTxs, fee)
+	if err != nil {
+		return nil, err
+	}
+	return fee.append(metaTxs, amount)
+}
@@ -0,0 +1,127 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// RPCCaller makes raw JSON-RPC calls, e.g. *rpc.Client or ethclient.Client.Client()
+type RPCCaller interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// CallFrame is one call of a debug_traceCall callTracer trace
+type CallFrame struct {
+	Type         string          `json:"type"` // CALL, STATICCALL, DELEGATECALL, ...
+	From         common.Address  `json:"from"`
+	To           *common.Address `json:"to,omitempty"`
+	Value        *hexutil.Big    `json:"value,omitempty"`
+	Gas          hexutil.Uint64  `json:"gas"`
+	GasUsed      hexutil.Uint64  `json:"gasUsed"`
+	Input        hexutil.Bytes   `json:"input"`
+	Output       hexutil.Bytes   `json:"output,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	RevertReason string          `json:"revertReason,omitempty"`
+	Calls        []CallFrame     `json:"calls,omitempty"`
+}
+
+// FirstFailure returns the deepest failed call on the path of failures from f, which is
+// where a revert originated, or nil if f did not fail
+func (f *CallFrame) FirstFailure() *CallFrame {
+	if f.Error == "" {
+		return nil
+	}
+	for i := range f.Calls {
+		if failed := f.Calls[i].FirstFailure(); failed != nil {
+			return failed
+		}
+	}
+	return f
+}
+
+// String renders the call tree, one indented line per call, with decoded revert reasons
+func (f *CallFrame) String() string {
+	var b strings.Builder
+	f.write(&b, 0)
+	return b.String()
+}
+
+// write renders f and its calls at depth
+func (f *CallFrame) write(b *strings.Builder, depth int) {
+	to := "(create)"
+	if f.To != nil {
+		to = f.To.Hex()
+	}
+	fmt.Fprintf(b, "%s%s %s -> %s", strings.Repeat("  ", depth), f.Type, f.From.Hex(), to)
+	if len(f.Input) >= 4 {
+		fmt.Fprintf(b, " %s", hexutil.Encode(f.Input[:4]))
+	}
+	fmt.Fprintf(b, " gas %d/%d", uint64(f.GasUsed), uint64(f.Gas))
+	if f.Error != "" {
+		fmt.Fprintf(b, " FAILED: %s", f.Error)
+		if len(f.Output) > 0 {
+			fmt.Fprintf(b, " (%s)", DecodeRevert(f.Output))
+		} else if f.RevertReason != "" {
+			fmt.Fprintf(b, " (%s)", f.RevertReason)
+		}
+	}
+	b.WriteString("\n")
+	for i := range f.Calls {
+		f.Calls[i].write(b, depth+1)
+	}
+}
+
+// TraceCall runs msg with debug_traceCall and the callTracer on top of block, nil for the
+// latest block. The node must expose the debug namespace.
+func TraceCall(ctx context.Context, caller RPCCaller, msg ethereum.CallMsg, block *big.Int) (*CallFrame, error) {
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"data": hexutil.Bytes(msg.Data),
+	}
+	if msg.To != nil {
+		arg["to"] = msg.To
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	blockTag := "latest"
+	if block != nil {
+		blockTag = hexutil.EncodeBig(block)
+	}
+
+	var frame CallFrame
+	err := caller.CallContext(ctx, &frame, "debug_traceCall", arg, blockTag, map[string]interface{}{"tracer": "callTracer"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to trace call: %w", err)
+	}
+	return &frame, nil
+}
+
+// TraceRelay traces relaying a request through the relayer's forwarder with debug_traceCall,
+// for diagnosing failing relays: the call tree shows whether the forwarder, the signature
+// check (a STATICCALL to the ecrecover precompile) or the inner token call failed
+func (r *Relayer) TraceRelay(ctx context.Context, caller RPCCaller, metaTx MetaTx, sig Signature) (*CallFrame, error) {
+	data, err := r.PackExecuteCalldata(ctx, metaTx, sig)
+	if err != nil {
+		return nil, err
+	}
+	forwardRequest, err := metaTx.ForwardRequest()
+	if err != nil {
+		return nil, err
+	}
+	return TraceCall(ctx, caller, ethereum.CallMsg{
+		From:  r.address,
+		To:    &r.forwarder,
+		Value: forwardRequest.Value,
+		Data:  data,
+	}, nil)
+}
@@ -0,0 +1,79 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DecodeTransferResult interprets the return data of an ERC20 transfer call.
+// Tokens that return nothing (e.g. USDT) are treated as successful, as they revert on failure.
+func DecodeTransferResult(ret []byte) error {
+	if len(ret) == 0 {
+		return nil
+	}
+
+	parsedABI, err := DefaultRegistry.ABI(ERC20TransferABI)
+	if err != nil {
+		return err
+	}
+	values, err := parsedABI.Unpack("transfer", ret)
+	if err != nil {
+		return fmt.Errorf("failed to decode transfer result: %w", err)
+	}
+	if ok, _ := values[0].(bool); !ok {
+		return ErrTransferReturnedFalse
+	}
+	return nil
+}
+
+// CheckTransferResult replays the inner token transfer of a MetaTx as the forwarder would
+// make it (sender appended to the calldata) on top of the state at blockNumber, and returns
+// ErrTransferReturnedFalse if the token reports failure. A reverting transfer is reported as
+// ErrContractCallFailed. To check an executed request, pass the block before its inclusion;
+// transactions earlier in the same block are not taken into account.
+func CheckTransferResult(ctx context.Context, client EthClient, forwarder common.Address, metaTx MetaTx, blockNumber *big.Int) error {
+	transferData, err := metaTx.TransferData()
+	if err != nil {
+		return fmt.Errorf("failed to prepare transfer data: %w", err)
+	}
+
+	// ERC-2771: the forwarder appends the signer address to the calldata
+	data := make([]byte, 0, len(transferData)+common.AddressLength)
+	data = append(data, transferData...)
+	data = append(data, metaTx.From.Bytes()...)
+
+	msg := ethereum.CallMsg{
+		From: forwarder,
+		To:   &metaTx.Token,
+		Gas:  metaTx.Gas,
+		Data: data,
+	}
+	ret, err := client.CallContract(ctx, msg, blockNumber)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrContractCallFailed, err)
+	}
+
+	return DecodeTransferResult(ret)
+}
+
+// CheckTransferResults verifies the inner transfers of a mined relay transaction. Requests
+// are replayed against the state before the transaction's block, so transfers in the same
+// batch that depend on each other's balance changes may be misreported.
+func (r *Relayer) CheckTransferResults(ctx context.Context, txHash common.Hash, requests BatchMetaTxRequestList) error {
+	receipt, err := r.transactionReceipt(ctx, txHash)
+	if err != nil {
+		return fmt.Errorf("failed to get transaction receipt: %w", err)
+	}
+
+	parent := new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1))
+	for i, req := range requests {
+		if err := CheckTransferResult(ctx, r.client, r.forwarder, req.MetaTx, parent); err != nil {
+			return fmt.Errorf("request at index %d: %w", i, err)
+		}
+	}
+	return nil
+}
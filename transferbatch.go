@@ -0,0 +1,97 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TransferBatchBuilder assembles ERC20 transfers of one signer, each with its own token,
+// recipient, amount and gas, with sequential nonces, e.g. an onboarding flow paying out
+// several tokens in one relay transaction. It builds transfers only, as MetaTx encodes
+// nothing else.
+type TransferBatchBuilder struct {
+	from     common.Address
+	nonce    uint64
+	deadline uint64
+	metaTxs  []MetaTx
+	err      error
+}
+
+// NewTransferBatchBuilder creates a builder for transfers of from, numbered from
+// startingNonce, that expire at deadline unless Deadline changes it
+func NewTransferBatchBuilder(from common.Address, startingNonce, deadline uint64) *TransferBatchBuilder {
+	return &TransferBatchBuilder{from: from, nonce: startingNonce, deadline: deadline}
+}
+
+// Deadline sets the deadline of the requests added after it, for batches of requests that
+// expire at different times
+func (b *TransferBatchBuilder) Deadline(deadline uint64) *TransferBatchBuilder {
+	b.deadline = deadline
+	return b
+}
+
+// Transfer adds a transfer of amount of token to to, with gas for the inner call or, if zero,
+// the default registry's gas limit. Invalid transfers are reported by MetaTxs and Sign.
+func (b *TransferBatchBuilder) Transfer(token, to common.Address, amount *big.Int, gas uint64) *TransferBatchBuilder {
+	if gas == 0 {
+		gas = DefaultRegistry.DefaultGasLimit()
+	}
+	metaTx := NewMetaTx(b.from, to, token, amount, gas, b.nonce+uint64(len(b.metaTxs)), b.deadline)
+	if err := validateMetaTx(metaTx); err != nil && b.err == nil {
+		b.err = fmt.Errorf("invalid transfer at index %d: %w", len(b.metaTxs), err)
+	}
+	b.metaTxs = append(b.metaTxs, metaTx)
+	return b
+}
+
+// TransferTo adds a transfer like Transfer to a recipient given as a hex address or an ENS
+// name resolved with resolver. Unresolvable names are reported by MetaTxs and Sign.
+func (b *TransferBatchBuilder) TransferTo(ctx context.Context, resolver *ENSResolver, token common.Address, recipient string, amount *big.Int, gas uint64) *TransferBatchBuilder {
+	to, err := resolver.ResolveRecipient(ctx, recipient)
+	if err != nil && b.err == nil {
+		b.err = fmt.Errorf("invalid transfer at index %d: %w", len(b.metaTxs), err)
+	}
+	return b.Transfer(token, to, amount, gas)
+}
+
+// Len returns the number of requests added
+func (b *TransferBatchBuilder) Len() int {
+	return len(b.metaTxs)
+}
+
+// TotalGas returns the inner call gas of all requests added
+func (b *TransferBatchBuilder) TotalGas() uint64 {
+	var total uint64
+	for _, metaTx := range b.metaTxs {
+		total += metaTx.Gas
+	}
+	return total
+}
+
+// MetaTxs returns the unsigned requests, or the first invalid transfer added
+func (b *TransferBatchBuilder) MetaTxs() ([]MetaTx, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.metaTxs) == 0 {
+		return nil, fmt.Errorf("batch is empty")
+	}
+	return append([]MetaTx(nil), b.metaTxs...), nil
+}
+
+// Sign signs the requests with the key of the builder's signer
+func (b *TransferBatchBuilder) Sign(ctx context.Context, userPrivKey *ecdsa.PrivateKey, domainSeparator []byte) (BatchMetaTxRequestList, error) {
+	if signer := crypto.PubkeyToAddress(userPrivKey.PublicKey); signer != b.from {
+		return nil, fmt.Errorf("key of %s cannot sign requests of %s", signer.Hex(), b.from.Hex())
+	}
+	metaTxs, err := b.MetaTxs()
+	if err != nil {
+		return nil, err
+	}
+	return CreateBatchFromSingleUser(ctx, metaTxs, userPrivKey, domainSeparator)
+}
@@ -1,6 +1,7 @@
 package eip2771toolkit
 
 import (
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -54,11 +55,17 @@ func (s *Signature) FromBytes(data []byte) error {
 	return nil
 }
 
-// TotalValue calculates the total ETH value needed for the batch
+// TotalValue calculates the total ETH value the batch forwards, the sum of the value of each
+// request's forwarded call. ERC20 transfers forward none.
 func (batch BatchMetaTxRequestList) TotalValue() *big.Int {
 	total := big.NewInt(0)
-	// For ERC20 transfers, we don't send ETH value, so this returns 0
-	// But this method is available for future extensibility
+	for _, req := range batch {
+		forwardRequest, err := req.MetaTx.ForwardRequest()
+		if err != nil || forwardRequest.Value == nil {
+			continue
+		}
+		total.Add(total, forwardRequest.Value)
+	}
 	return total
 }
 
@@ -67,8 +74,19 @@ func (batch BatchMetaTxRequestList) Count() int {
 	return len(batch)
 }
 
-// TransferData creates the calldata for ERC20 transfer
+// validAmount reports whether amount is a token amount a transfer can carry: set, not
+// negative and at most a uint256
+func validAmount(amount *big.Int) bool {
+	return amount != nil && amount.Sign() >= 0 && amount.Cmp(maxUint256) <= 0
+}
+
+// TransferData creates the calldata for ERC20 transfer, or returns ErrInvalidAmount if the
+// amount is not a uint256
 func (m *MetaTx) TransferData() ([]byte, error) {
+	if !validAmount(m.Amount) {
+		return nil, fmt.Errorf("%w: %v is not a uint256", ErrInvalidAmount, m.Amount)
+	}
+
 	// ERC20 transfer function signature: transfer(address,uint256)
 	transferSignature := crypto.Keccak256([]byte("transfer(address,uint256)"))[:4]
 
@@ -0,0 +1,78 @@
+package eip2771toolkit
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestMetaTxAmountRange(t *testing.T) {
+	forwarder := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	domainSeparator, err := CreateDomainSeparatorForChain(big.NewInt(1), forwarder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sig Signature
+
+	tests := []struct {
+		name   string
+		amount *big.Int
+		valid  bool
+	}{
+		{"zero", big.NewInt(0), true},
+		{"one", big.NewInt(1), true},
+		{"max uint256", new(big.Int).Set(maxUint256), true},
+		{"nil", nil, false},
+		{"negative", big.NewInt(-1), false},
+		{"2^256", new(big.Int).Add(maxUint256, big.NewInt(1)), false},
+		{"2^300", new(big.Int).Lsh(big.NewInt(1), 300), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metaTx := NewMetaTx(common.HexToAddress("0x01"), common.HexToAddress("0x02"), common.HexToAddress("0x03"), tt.amount, 100000, 0, 1)
+			check := func(what string, err error) {
+				t.Helper()
+				if tt.valid && err != nil {
+					t.Errorf("%s: %v", what, err)
+				}
+				if !tt.valid && !errors.Is(err, ErrInvalidAmount) {
+					t.Errorf("%s: got %v, want ErrInvalidAmount", what, err)
+				}
+			}
+
+			_, err := metaTx.TransferData()
+			check("TransferData", err)
+			_, err = requestID(OZForwarderV5Schema, metaTx, domainSeparator)
+			check("requestID", err)
+			_, err = HashMetaTx(metaTx, domainSeparator)
+			check("HashMetaTx", err)
+			_, err = PackExecuteCalldata(metaTx, sig)
+			check("PackExecuteCalldata", err)
+			if id := RequestID(metaTx, big.NewInt(1), forwarder); !tt.valid && id != (common.Hash{}) {
+				t.Errorf("RequestID = %s, want the zero hash", id.Hex())
+			}
+		})
+	}
+}
+
+func TestBatchTotalValue(t *testing.T) {
+	request := func(amount int64) BatchMetaTxRequest {
+		return BatchMetaTxRequest{MetaTx: NewMetaTx(common.HexToAddress("0x01"), common.HexToAddress("0x02"), common.HexToAddress("0x03"), big.NewInt(amount), 100000, 0, 1)}
+	}
+	tests := []struct {
+		name  string
+		batch BatchMetaTxRequestList
+	}{
+		{"empty", nil},
+		{"one transfer", BatchMetaTxRequestList{request(5)}},
+		{"transfers", BatchMetaTxRequestList{request(5), request(1e18), request(0)}},
+	}
+	for _, tt := range tests {
+		// ERC20 transfers forward no ether, whatever token amount they move
+		if total := tt.batch.TotalValue(); total.Sign() != 0 {
+			t.Errorf("%s: TotalValue = %s, want 0", tt.name, total)
+		}
+	}
+}
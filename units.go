@@ -0,0 +1,114 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ParseAmount parses a human amount such as "1.5" into base units of a token with decimals,
+// exactly: ParseAmount("1.5", 6) is 1500000. Only plain decimal notation is accepted, no
+// signs, exponents or separators, and amounts with more fraction digits than decimals are
+// refused with ErrInvalidAmount rather than rounded, as are amounts beyond a uint256.
+func ParseAmount(amount string, decimals uint8) (*big.Int, error) {
+	text := strings.TrimSpace(amount)
+	whole, frac, _ := strings.Cut(text, ".")
+	if whole == "" && frac == "" || !isDigits(whole) || !isDigits(frac) {
+		return nil, fmt.Errorf("%w: %q is not a decimal number", ErrInvalidAmount, amount)
+	}
+	if len(frac) > int(decimals) {
+		return nil, fmt.Errorf("%w: %q has %d fraction digits, the token has %d decimals", ErrInvalidAmount, amount, len(frac), decimals)
+	}
+	digits := whole + frac + strings.Repeat("0", int(decimals)-len(frac))
+	value, _ := new(big.Int).SetString(digits, 10)
+	if value.Cmp(maxUint256) > 0 {
+		return nil, fmt.Errorf("%w: %q at %d decimals exceeds a uint256", ErrInvalidAmount, amount, decimals)
+	}
+	return value, nil
+}
+
+// isDigits reports whether s consists of ASCII digits only
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ToUnits converts a decimal amount into base units of a token with decimals, like ParseAmount
+func ToUnits(amount string, decimals uint8) (*big.Int, error) {
+	return ParseAmount(amount, decimals)
+}
+
+// FromUnits formats an amount in base units of a token with decimals as an exact decimal
+// string without trailing zeros, e.g. 1500000 at 6 decimals is "1.5"
+func FromUnits(amount *big.Int, decimals uint8) string {
+	if amount == nil {
+		return "0"
+	}
+	whole, frac := new(big.Int).QuoRem(new(big.Int).Abs(amount), unitScale(decimals), new(big.Int))
+	sign := ""
+	if amount.Sign() < 0 {
+		sign = "-"
+	}
+	if frac.Sign() == 0 {
+		return sign + whole.String()
+	}
+	digits := fmt.Sprintf("%0*s", int(decimals), frac.String())
+	return sign + whole.String() + "." + strings.TrimRight(digits, "0")
+}
+
+// FormatAmount formats an amount in base units of a token with decimals with exactly precision
+// fraction digits, e.g. 1234567 at 6 decimals and precision 2 is "1.23", for logs, receipts
+// and CLI output. Digits beyond precision are truncated toward zero, so a formatted balance
+// never shows more than is there; a negative precision formats exactly, like FromUnits.
+func FormatAmount(amount *big.Int, decimals uint8, precision int) string {
+	if precision < 0 {
+		return FromUnits(amount, decimals)
+	}
+	if amount == nil {
+		amount = new(big.Int)
+	}
+	whole, frac := new(big.Int).QuoRem(new(big.Int).Abs(amount), unitScale(decimals), new(big.Int))
+	digits := fmt.Sprintf("%0*s", int(decimals), frac.String())
+	if decimals == 0 {
+		digits = ""
+	}
+	if len(digits) > precision {
+		digits = digits[:precision]
+	} else {
+		digits += strings.Repeat("0", precision-len(digits))
+	}
+	sign := ""
+	if amount.Sign() < 0 && (whole.Sign() != 0 || strings.Trim(digits, "0") != "") {
+		sign = "-"
+	}
+	if precision == 0 {
+		return sign + whole.String()
+	}
+	return sign + whole.String() + "." + digits
+}
+
+// unitScale returns 10^decimals
+func unitScale(decimals uint8) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+}
+
+// FetchTokenDecimals reads the decimals of an ERC20 token
+func FetchTokenDecimals(ctx context.Context, token common.Address, client ethereum.ContractCaller) (uint8, error) {
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &token, Data: decimalsSelector}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call %s.decimals: %w", token.Hex(), err)
+	}
+	values, err := unpackArguments([]string{"uint8"}, out)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s.decimals: %w", token.Hex(), err)
+	}
+	return values[0].(uint8), nil
+}
@@ -0,0 +1,146 @@
+package eip2771toolkit
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals uint8
+		want     string // base units, or "" for ErrInvalidAmount
+	}{
+		{"1.5", 6, "1500000"},
+		{"0.000001", 6, "1"},
+		{"1", 18, "1000000000000000000"},
+		{"1.", 6, "1000000"},
+		{".5", 6, "500000"},
+		{"007", 2, "700"},
+		{" 2.25\n", 2, "225"},
+		{"42", 0, "42"},
+		{"0", 255, "0"},
+		{"1", 77, "1" + strings.Repeat("0", 77)},
+		{maxUint256.String(), 0, maxUint256.String()},
+
+		// Negative and signed input
+		{"-1", 6, ""},
+		{"-0.5", 6, ""},
+		{"+1", 6, ""},
+
+		// More fraction digits than the token has
+		{"0.0000001", 6, ""},
+		{"1.50", 1, ""},
+		{"1.0", 0, ""},
+		{"1.", 0, "1"},
+
+		// Beyond a uint256 once scaled by decimals
+		{"1", 78, ""},
+		{"2", 77, ""},
+		{"1", 255, ""},
+		{"115792089237316195423570985008687907853269984665640564039457584007913129639936", 0, ""},
+
+		// Not a plain decimal number
+		{"", 6, ""},
+		{".", 6, ""},
+		{"1e18", 18, ""},
+		{"0x10", 18, ""},
+		{"1,000", 6, ""},
+		{"1.2.3", 6, ""},
+		{"1 000", 6, ""},
+		{"١", 6, ""},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.amount, tt.decimals)
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("ParseAmount(%q, %d) = %v, %v, want ErrInvalidAmount", tt.amount, tt.decimals, got, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseAmount(%q, %d): %v", tt.amount, tt.decimals, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParseAmount(%q, %d) = %s, want %s", tt.amount, tt.decimals, got, tt.want)
+		}
+		if units, err := ToUnits(tt.amount, tt.decimals); err != nil || units.Cmp(got) != 0 {
+			t.Errorf("ToUnits(%q, %d) = %v, %v, want %s like ParseAmount", tt.amount, tt.decimals, units, err, got)
+		}
+	}
+}
+
+func TestFromUnits(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals uint8
+		want     string
+	}{
+		{"1500000", 6, "1.5"},
+		{"1", 6, "0.000001"},
+		{"1000000000000000000", 18, "1"},
+		{"1230000", 6, "1.23"},
+		{"0", 18, "0"},
+		{"42", 0, "42"},
+		{"-1500000", 6, "-1.5"},
+		{"-1", 6, "-0.000001"},
+		{"1", 255, "0." + strings.Repeat("0", 254) + "1"},
+		{maxUint256.String(), 18, "115792089237316195423570985008687907853269984665640564039457.584007913129639935"},
+	}
+	for _, tt := range tests {
+		amount, _ := new(big.Int).SetString(tt.amount, 10)
+		if got := FromUnits(amount, tt.decimals); got != tt.want {
+			t.Errorf("FromUnits(%s, %d) = %q, want %q", tt.amount, tt.decimals, got, tt.want)
+		}
+	}
+	if got := FromUnits(nil, 6); got != "0" {
+		t.Errorf("FromUnits(nil, 6) = %q, want \"0\"", got)
+	}
+}
+
+func TestFromUnitsRoundTrip(t *testing.T) {
+	for _, decimals := range []uint8{0, 1, 6, 18, 77} {
+		for _, units := range []string{"0", "1", "10", "123456789", "1000000000000000000000"} {
+			amount, _ := new(big.Int).SetString(units, 10)
+			back, err := ToUnits(FromUnits(amount, decimals), decimals)
+			if err != nil {
+				t.Errorf("ToUnits(FromUnits(%s, %d)): %v", units, decimals, err)
+				continue
+			}
+			if back.Cmp(amount) != 0 {
+				t.Errorf("%s at %d decimals came back as %s", units, decimals, back)
+			}
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount    int64
+		decimals  uint8
+		precision int
+		want      string
+	}{
+		{1234567, 6, 2, "1.23"},
+		{1239999, 6, 2, "1.23"},
+		{1500000, 6, 4, "1.5000"},
+		{1500000, 6, 8, "1.50000000"},
+		{1999999, 6, 0, "1"},
+		{42, 0, 2, "42.00"},
+		{-1234567, 6, 2, "-1.23"},
+		{-1, 6, 2, "0.00"},
+		{-1000000, 6, 0, "-1"},
+		{1234567, 6, -1, "1.234567"},
+	}
+	for _, tt := range tests {
+		if got := FormatAmount(big.NewInt(tt.amount), tt.decimals, tt.precision); got != tt.want {
+			t.Errorf("FormatAmount(%d, %d, %d) = %q, want %q", tt.amount, tt.decimals, tt.precision, got, tt.want)
+		}
+	}
+	if got := FormatAmount(nil, 6, 2); got != "0.00" {
+		t.Errorf("FormatAmount(nil, 6, 2) = %q, want \"0.00\"", got)
+	}
+}
@@ -46,9 +46,9 @@ func NewMetaTxWithDelay(from, to, token common.Address, amount *big.Int, gas, no
 	return NewMetaTx(from, to, token, amount, gas, nonce, deadline)
 }
 
-// NewMetaTxWithDefaultGas creates a new MetaTx with default gas limit of 100000
+// NewMetaTxWithDefaultGas creates a new MetaTx with the default registry's gas limit (100000 unless changed)
 func NewMetaTxWithDefaultGas(from, to, token common.Address, amount *big.Int, nonce uint64, deadline uint64) MetaTx {
-	return NewMetaTx(from, to, token, amount, 100000, nonce, deadline)
+	return NewMetaTx(from, to, token, amount, DefaultRegistry.DefaultGasLimit(), nonce, deadline)
 }
 
 // IsValidAddress checks if the given address is valid (not zero address)
@@ -56,7 +56,7 @@ func IsValidAddress(addr common.Address) bool {
 	return addr != (common.Address{})
 }
 
-// ToWei converts ether amount to wei
+// ToWei converts ether amount to wei. big.Float math may round; use ToUnits for exact amounts.
 func ToWei(ether *big.Float) *big.Int {
 	wei := new(big.Float)
 	wei.Mul(ether, big.NewFloat(1e18))
@@ -66,7 +66,7 @@ func ToWei(ether *big.Float) *big.Int {
 	return result
 }
 
-// FromWei converts wei to ether
+// FromWei converts wei to ether. big.Float math may round; use FromUnits for exact amounts.
 func FromWei(wei *big.Int) *big.Float {
 	ether := new(big.Float)
 	ether.SetInt(wei)
@@ -184,6 +184,42 @@ func NewMetaTxBatch(
 	return metaTxs, nil
 }
 
+// NewMetaTxBatchWithDeadlines creates multiple MetaTx with sequential nonces, each expiring at
+// the deadline deadlineOf returns for its index, e.g. to keep the deadlines of requests
+// collected over time. Every deadline must be set.
+func NewMetaTxBatchWithDeadlines(
+	from common.Address,
+	recipients []common.Address,
+	token common.Address,
+	amounts []*big.Int,
+	gas uint64,
+	startingNonce uint64,
+	deadlineOf func(i int) uint64,
+) ([]MetaTx, error) {
+	metaTxs, err := NewMetaTxBatch(from, recipients, token, amounts, gas, startingNonce, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range metaTxs {
+		metaTxs[i].Deadline = deadlineOf(i)
+		if metaTxs[i].Deadline == 0 {
+			return nil, fmt.Errorf("no deadline at index %d: %w", i, ErrExpiredDeadline)
+		}
+	}
+	return metaTxs, nil
+}
+
+// DeadlinesOf returns a deadline function for NewMetaTxBatchWithDeadlines reading one deadline
+// per item from deadlines; items past its end get none
+func DeadlinesOf(deadlines []uint64) func(i int) uint64 {
+	return func(i int) uint64 {
+		if i >= len(deadlines) {
+			return 0
+		}
+		return deadlines[i]
+	}
+}
+
 // NewMetaTxBatchWithDefaultGas creates multiple MetaTx with sequential nonces and default gas limit
 func NewMetaTxBatchWithDefaultGas(
 	from common.Address,
@@ -193,7 +229,28 @@ func NewMetaTxBatchWithDefaultGas(
 	startingNonce uint64,
 	deadline uint64,
 ) ([]MetaTx, error) {
-	return NewMetaTxBatch(from, recipients, token, amounts, 100000, startingNonce, deadline)
+	return NewMetaTxBatch(from, recipients, token, amounts, DefaultRegistry.DefaultGasLimit(), startingNonce, deadline)
+}
+
+// NewMetaTxBatchAutoNonce creates multiple MetaTx with sequential nonces starting at the
+// current on-chain nonce of from at the forwarder. Requests of from that are signed but not
+// yet mined are not accounted for; use a UserNonceManager to sign batches back to back.
+func NewMetaTxBatchAutoNonce(
+	ctx context.Context,
+	client EthClient,
+	forwarder common.Address,
+	from common.Address,
+	recipients []common.Address,
+	token common.Address,
+	amounts []*big.Int,
+	gas uint64,
+	deadline uint64,
+) ([]MetaTx, error) {
+	startingNonce, err := GetMetaTxNonceWithProfile(ctx, DefaultRegistry.DefaultForwarderProfile(), forwarder, from, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce of %s: %w", from.Hex(), err)
+	}
+	return NewMetaTxBatch(from, recipients, token, amounts, gas, startingNonce, deadline)
 }
 
 // ValidateBatchNonces checks if all nonces in the batch are sequential and starting from expected nonce
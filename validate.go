@@ -0,0 +1,125 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Validator checks a request before it is relayed. Unlike a Policy, which decides whether the
+// relayer is willing to sponsor a request, a Validator rejects requests that are malformed.
+type Validator interface {
+	Validate(ctx context.Context, metaTx MetaTx) error
+}
+
+// ValidatorFunc adapts a function to a Validator, for custom rules
+type ValidatorFunc func(ctx context.Context, metaTx MetaTx) error
+
+// Validate implements Validator
+func (f ValidatorFunc) Validate(ctx context.Context, metaTx MetaTx) error {
+	return f(ctx, metaTx)
+}
+
+// ValidationChain is a Validator running its rules in order, stopping at the first error
+type ValidationChain []Validator
+
+// Validate implements Validator
+func (c ValidationChain) Validate(ctx context.Context, metaTx MetaTx) error {
+	for _, rule := range c {
+		if err := rule.Validate(ctx, metaTx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DefaultValidators returns the rules every relayer applies unless WithValidators replaces
+// them: non-zero addresses, a positive amount and a set deadline
+func DefaultValidators() ValidationChain {
+	return ValidationChain{NonZeroAddresses(), AmountBounds(nil, nil), DeadlineWindow(0, 0)}
+}
+
+// WithValidators replaces the relayer's request validation with the rules, run in order.
+// Include DefaultValidators to add rules rather than replace them. The forwarder's own checks,
+// of the signature, nonce and deadline, still apply to every request.
+func WithValidators(rules ...Validator) RelayerOption {
+	return func(r *Relayer) {
+		r.validator = ValidationChain(rules)
+	}
+}
+
+// validateMetaTx applies the default rules
+func validateMetaTx(metaTx MetaTx) error {
+	return DefaultValidators().Validate(context.Background(), metaTx)
+}
+
+// NonZeroAddresses rejects requests with a zero signer, recipient or token with ErrZeroAddress
+func NonZeroAddresses() Validator {
+	return ValidatorFunc(func(ctx context.Context, metaTx MetaTx) error {
+		if metaTx.From == (common.Address{}) || metaTx.To == (common.Address{}) || metaTx.Token == (common.Address{}) {
+			return ErrZeroAddress
+		}
+		return nil
+	})
+}
+
+// AmountBounds rejects requests transferring less than min or more than max with
+// ErrInvalidAmount. Amounts must be positive and fit a uint256; nil bounds leave that side
+// open up to these limits.
+func AmountBounds(min, max *big.Int) Validator {
+	return ValidatorFunc(func(ctx context.Context, metaTx MetaTx) error {
+		if metaTx.Amount == nil || metaTx.Amount.Sign() <= 0 {
+			return ErrInvalidAmount
+		}
+		if metaTx.Amount.Cmp(maxUint256) > 0 {
+			return fmt.Errorf("%w: %s is not a uint256", ErrInvalidAmount, metaTx.Amount)
+		}
+		if min != nil && metaTx.Amount.Cmp(min) < 0 {
+			return fmt.Errorf("%w: %s is below the minimum of %s", ErrInvalidAmount, metaTx.Amount, min)
+		}
+		if max != nil && metaTx.Amount.Cmp(max) > 0 {
+			return fmt.Errorf("%w: %s is above the maximum of %s", ErrInvalidAmount, metaTx.Amount, max)
+		}
+		return nil
+	})
+}
+
+// DeadlineWindow rejects requests without a deadline with ErrExpiredDeadline, and by the host
+// clock those expiring within minRemaining with ErrDeadlineTooSoon and those expiring more
+// than maxAhead from now with ErrDeadlineTooFar. Zero durations skip the respective check;
+// the relayer's own deadline checks, see WithMinDeadlineLifetime, apply either way.
+func DeadlineWindow(minRemaining, maxAhead time.Duration) Validator {
+	return ValidatorFunc(func(ctx context.Context, metaTx MetaTx) error {
+		if metaTx.Deadline == 0 {
+			return ErrExpiredDeadline
+		}
+		if minRemaining <= 0 && maxAhead <= 0 {
+			return nil
+		}
+		remaining := time.Until(time.Unix(int64(metaTx.Deadline), 0))
+		if minRemaining > 0 && remaining < minRemaining {
+			return fmt.Errorf("%w: expires in %s, at least %s required", ErrDeadlineTooSoon, remaining.Round(time.Second), minRemaining)
+		}
+		if maxAhead > 0 && remaining > maxAhead {
+			return fmt.Errorf("%w: expires in %s, at most %s allowed", ErrDeadlineTooFar, remaining.Round(time.Second), maxAhead)
+		}
+		return nil
+	})
+}
+
+// GasBounds rejects requests asking for less than min or more than max inner call gas with
+// ErrInvalidGas; zero bounds leave that side open
+func GasBounds(min, max uint64) Validator {
+	return ValidatorFunc(func(ctx context.Context, metaTx MetaTx) error {
+		if min > 0 && metaTx.Gas < min {
+			return fmt.Errorf("%w: %d is below the minimum of %d", ErrInvalidGas, metaTx.Gas, min)
+		}
+		if max > 0 && metaTx.Gas > max {
+			return fmt.Errorf("%w: %d is above the maximum of %d", ErrInvalidGas, metaTx.Gas, max)
+		}
+		return nil
+	})
+}
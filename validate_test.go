@@ -0,0 +1,54 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestAmountBounds(t *testing.T) {
+	overflow := new(big.Int).Add(maxUint256, big.NewInt(1))
+	huge := new(big.Int).Lsh(big.NewInt(1), 300)
+	tests := []struct {
+		name     string
+		min, max *big.Int
+		amount   *big.Int
+		valid    bool
+	}{
+		{"open, one", nil, nil, big.NewInt(1), true},
+		{"open, max uint256", nil, nil, maxUint256, true},
+		{"open, 2^256", nil, nil, overflow, false},
+		{"open, 2^300", nil, nil, huge, false},
+		{"open, zero", nil, nil, big.NewInt(0), false},
+		{"open, negative", nil, nil, big.NewInt(-1), false},
+		{"open, nil", nil, nil, nil, false},
+		{"min, below", big.NewInt(10), nil, big.NewInt(9), false},
+		{"min, at", big.NewInt(10), nil, big.NewInt(10), true},
+		{"min, 2^256", big.NewInt(10), nil, overflow, false},
+		{"max, at", nil, big.NewInt(100), big.NewInt(100), true},
+		{"max, above", nil, big.NewInt(100), big.NewInt(101), false},
+		{"max above uint256, 2^256", nil, huge, overflow, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metaTx := NewMetaTx(common.HexToAddress("0x01"), common.HexToAddress("0x02"), common.HexToAddress("0x03"), tt.amount, 100000, 0, 1)
+			err := AmountBounds(tt.min, tt.max).Validate(context.Background(), metaTx)
+			if tt.valid && err != nil {
+				t.Fatalf("refused: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidAmount) {
+				t.Fatalf("got %v, want ErrInvalidAmount", err)
+			}
+		})
+	}
+}
+
+func TestDefaultValidatorsRefuseOverflowingAmounts(t *testing.T) {
+	metaTx := NewMetaTx(common.HexToAddress("0x01"), common.HexToAddress("0x02"), common.HexToAddress("0x03"), new(big.Int).Lsh(big.NewInt(1), 256), 100000, 0, 1)
+	if err := validateMetaTx(metaTx); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("got %v, want ErrInvalidAmount", err)
+	}
+}
@@ -0,0 +1,307 @@
+// Package vectors generates deterministic EIP-712 test vectors of the toolkit's forward request
+// hashing and signing, for checking other implementations (e.g. TypeScript or Rust frontends)
+// byte for byte against it. Every vector is signed with a fixed, publicly known key.
+package vectors
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+// TEST_PRIVATE_KEY signs every vector. It is the first anvil and Hardhat development key;
+// never hold funds with it.
+const TEST_PRIVATE_KEY = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+
+// Domain is the EIP-712 domain of a vector
+type Domain struct {
+	Name              string
+	Version           string
+	ChainID           *big.Int
+	VerifyingContract common.Address
+}
+
+// domainJSON is the encoded form of Domain, with the chain ID a decimal string
+type domainJSON struct {
+	Name              string         `json:"name"`
+	Version           string         `json:"version"`
+	ChainID           string         `json:"chainId"`
+	VerifyingContract common.Address `json:"verifyingContract"`
+}
+
+// MarshalJSON encodes the domain with the chain ID as a decimal string
+func (d Domain) MarshalJSON() ([]byte, error) {
+	return json.Marshal(domainJSON{Name: d.Name, Version: d.Version, ChainID: d.ChainID.String(), VerifyingContract: d.VerifyingContract})
+}
+
+// UnmarshalJSON decodes a domain whose chain ID is a decimal or 0x-hex string
+func (d *Domain) UnmarshalJSON(input []byte) error {
+	var dec domainJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	chainID, ok := new(big.Int).SetString(dec.ChainID, 0)
+	if !ok {
+		return fmt.Errorf("invalid chain ID %q", dec.ChainID)
+	}
+	*d = Domain{Name: dec.Name, Version: dec.Version, ChainID: chainID, VerifyingContract: dec.VerifyingContract}
+	return nil
+}
+
+// TypedDataField is a member of an EIP-712 type
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedData is the eth_signTypedData_v4 form of a vector, as wallets and libraries such as
+// ethers and viem take it. Integers are decimal strings and bytes 0x-hex.
+type TypedData struct {
+	Types       map[string][]TypedDataField `json:"types"`
+	PrimaryType string                      `json:"primaryType"`
+	Domain      map[string]interface{}      `json:"domain"`
+	Message     map[string]interface{}      `json:"message"`
+}
+
+// Vector is one request with every intermediate value of hashing and signing it
+type Vector struct {
+	Name      string                `json:"name"`
+	Domain    Domain                `json:"domain"`
+	MetaTx    eip2771toolkit.MetaTx `json:"metaTx"`
+	TypedData TypedData             `json:"typedData"`
+
+	TypeString      string        `json:"typeString"`
+	TypeHash        hexutil.Bytes `json:"typeHash"`
+	DomainSeparator hexutil.Bytes `json:"domainSeparator"`
+	StructHash      hexutil.Bytes `json:"structHash"`
+	Digest          hexutil.Bytes `json:"digest"`
+
+	Signer    common.Address           `json:"signer"`
+	Signature eip2771toolkit.Signature `json:"signature"` // r || s || v with v in {27, 28}
+}
+
+// testCase is the input of one vector
+type testCase struct {
+	name   string
+	domain Domain
+	metaTx func(from common.Address) eip2771toolkit.MetaTx
+}
+
+// testCases are the canonical inputs, covering the edges of every field's encoding
+var testCases = []testCase{
+	{
+		name:   "mainnet",
+		domain: defaultDomain(1),
+		metaTx: func(from common.Address) eip2771toolkit.MetaTx {
+			return eip2771toolkit.NewMetaTx(from, common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"),
+				common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"), big.NewInt(1_000_000), 100000, 0, 1767225600)
+		},
+	},
+	{
+		name:   "polygon-one-token",
+		domain: defaultDomain(137),
+		metaTx: func(from common.Address) eip2771toolkit.MetaTx {
+			return eip2771toolkit.NewMetaTx(from, common.HexToAddress("0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC"),
+				common.HexToAddress("0x3c499c542cEF5E3811e1192ce70d8cC03d5c3359"), eip2771toolkit.ToWei(big.NewFloat(1)), 120000, 7, 1767225600)
+		},
+	},
+	{
+		name:   "zero-values",
+		domain: defaultDomain(31337),
+		metaTx: func(from common.Address) eip2771toolkit.MetaTx {
+			return eip2771toolkit.NewMetaTx(from, common.Address{}, common.Address{}, big.NewInt(0), 0, 0, 0)
+		},
+	},
+	{
+		name:   "max-values",
+		domain: Domain{Name: "ERC2771Forwarder", Version: "1", ChainID: math.MaxBig256, VerifyingContract: common.HexToAddress("0xffffffffffffffffffffffffffffffffffffffff")},
+		metaTx: func(from common.Address) eip2771toolkit.MetaTx {
+			// The deadline is a uint48 and the nonce a uint64 here
+			return eip2771toolkit.NewMetaTx(from, common.HexToAddress("0xffffffffffffffffffffffffffffffffffffffff"),
+				common.HexToAddress("0xffffffffffffffffffffffffffffffffffffffff"), math.MaxBig256, 1<<64-1, 1<<64-1, 1<<48-1)
+		},
+	},
+	{
+		name:   "custom-domain",
+		domain: Domain{Name: "MyForwarder", Version: "2.1", ChainID: big.NewInt(8453), VerifyingContract: common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")},
+		metaTx: func(from common.Address) eip2771toolkit.MetaTx {
+			return eip2771toolkit.NewMetaTx(from, common.HexToAddress("0x90F79bf6EB2c4f870365E785982E1f101E93b906"),
+				common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"), big.NewInt(123456789), 65000, 42, 1893456000)
+		},
+	},
+}
+
+// defaultDomain returns the domain of the OpenZeppelin v5 forwarder replica of devnet
+func defaultDomain(chainID int64) Domain {
+	return Domain{
+		Name:              "ERC2771Forwarder",
+		Version:           "1",
+		ChainID:           big.NewInt(chainID),
+		VerifyingContract: common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3"),
+	}
+}
+
+// Key returns TEST_PRIVATE_KEY
+func Key() *ecdsa.PrivateKey {
+	key, err := crypto.HexToECDSA(TEST_PRIVATE_KEY)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// Generate returns the canonical vectors, the same on every run
+func Generate() ([]Vector, error) {
+	key := Key()
+	vectors := make([]Vector, 0, len(testCases))
+	for _, tc := range testCases {
+		v, err := NewVector(tc.name, tc.domain, tc.metaTx(crypto.PubkeyToAddress(key.PublicKey)), key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate vector %s: %w", tc.name, err)
+		}
+		vectors = append(vectors, v)
+	}
+	return vectors, nil
+}
+
+// NewVector hashes metaTx under domain with the OpenZeppelin v5 forwarder schema and signs it
+// with key
+func NewVector(name string, domain Domain, metaTx eip2771toolkit.MetaTx, key *ecdsa.PrivateKey) (Vector, error) {
+	schema := eip2771toolkit.OZForwarderV5Schema
+	req, err := metaTx.ForwardRequest()
+	if err != nil {
+		return Vector{}, err
+	}
+
+	domainSeparator, err := eip2771toolkit.BuildDomainSeparator(domain.Name, domain.Version, domain.ChainID, domain.VerifyingContract)
+	if err != nil {
+		return Vector{}, fmt.Errorf("failed to build domain separator: %w", err)
+	}
+	structHash, err := schema.HashStruct(req)
+	if err != nil {
+		return Vector{}, fmt.Errorf("failed to hash request: %w", err)
+	}
+	digest, err := schema.HashMetaTx(metaTx, domainSeparator)
+	if err != nil {
+		return Vector{}, fmt.Errorf("failed to hash MetaTx: %w", err)
+	}
+	sig, err := eip2771toolkit.SignMetaTxWithSchema(schema, metaTx, key, domainSeparator)
+	if err != nil {
+		return Vector{}, err
+	}
+
+	return Vector{
+		Name:            name,
+		Domain:          domain,
+		MetaTx:          metaTx,
+		TypedData:       typedData(schema, domain, req),
+		TypeString:      schema.TypeString(),
+		TypeHash:        schema.TypeHash(),
+		DomainSeparator: domainSeparator,
+		StructHash:      structHash,
+		Digest:          digest,
+		Signer:          crypto.PubkeyToAddress(key.PublicKey),
+		Signature:       sig,
+	}, nil
+}
+
+// Verify recomputes the hashes of v from its domain and request, reports the first that
+// differs and checks the signature recovers the signer, e.g. for vectors read back from JSON
+func Verify(v Vector) error {
+	domainSeparator, err := eip2771toolkit.BuildDomainSeparator(v.Domain.Name, v.Domain.Version, v.Domain.ChainID, v.Domain.VerifyingContract)
+	if err != nil {
+		return fmt.Errorf("failed to build domain separator: %w", err)
+	}
+	req, err := v.MetaTx.ForwardRequest()
+	if err != nil {
+		return err
+	}
+	structHash, err := eip2771toolkit.OZForwarderV5Schema.HashStruct(req)
+	if err != nil {
+		return fmt.Errorf("failed to hash request: %w", err)
+	}
+	digest, err := eip2771toolkit.HashMetaTx(v.MetaTx, domainSeparator)
+	if err != nil {
+		return err
+	}
+	switch typeHash := eip2771toolkit.OZForwarderV5Schema.TypeHash(); {
+	case !bytes.Equal(typeHash, v.TypeHash):
+		return fmt.Errorf("vector %s: type hash %x, want %x", v.Name, typeHash, []byte(v.TypeHash))
+	case !bytes.Equal(domainSeparator, v.DomainSeparator):
+		return fmt.Errorf("vector %s: domain separator %x, want %x", v.Name, domainSeparator, []byte(v.DomainSeparator))
+	case !bytes.Equal(structHash, v.StructHash):
+		return fmt.Errorf("vector %s: struct hash %x, want %x", v.Name, structHash, []byte(v.StructHash))
+	case !bytes.Equal(digest, v.Digest):
+		return fmt.Errorf("vector %s: digest %x, want %x", v.Name, digest, []byte(v.Digest))
+	}
+
+	valid, err := eip2771toolkit.VerifyMetaTxSignature(v.MetaTx, v.Signature, domainSeparator)
+	if err != nil {
+		return fmt.Errorf("vector %s: %w", v.Name, err)
+	}
+	if !valid || v.MetaTx.From != v.Signer {
+		return fmt.Errorf("vector %s: signature does not recover %s", v.Name, v.Signer.Hex())
+	}
+	return nil
+}
+
+// WriteJSON writes the canonical vectors to w as an indented JSON array
+func WriteJSON(w io.Writer) error {
+	vectors, err := Generate()
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(vectors); err != nil {
+		return fmt.Errorf("failed to encode vectors: %w", err)
+	}
+	return nil
+}
+
+// typedData builds the eth_signTypedData_v4 form of req under domain
+func typedData(schema *eip2771toolkit.RequestSchema, domain Domain, req eip2771toolkit.ForwardRequest) TypedData {
+	fields := make([]TypedDataField, len(schema.Fields))
+	message := make(map[string]interface{}, len(schema.Fields))
+	for i, f := range schema.Fields {
+		fields[i] = TypedDataField{Name: f.Name, Type: f.Type}
+		switch value := f.Value(req).(type) {
+		case *big.Int:
+			message[f.Name] = value.String()
+		case []byte:
+			message[f.Name] = hexutil.Encode(value)
+		default:
+			message[f.Name] = value
+		}
+	}
+
+	return TypedData{
+		Types: map[string][]TypedDataField{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			schema.PrimaryType: fields,
+		},
+		PrimaryType: schema.PrimaryType,
+		Domain: map[string]interface{}{
+			"name":              domain.Name,
+			"version":           domain.Version,
+			"chainId":           domain.ChainID.String(),
+			"verifyingContract": domain.VerifyingContract,
+		},
+		Message: message,
+	}
+}
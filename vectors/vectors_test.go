@@ -0,0 +1,167 @@
+package vectors
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"github.com/ethanzhrepo/eip2771toolkit"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/vectors.json")
+
+var goldenPath = filepath.Join("testdata", "vectors.json")
+
+// golden reads the committed vectors
+func golden(t *testing.T) []Vector {
+	t.Helper()
+	data, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []Vector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != len(testCases) {
+		t.Fatalf("%s has %d vectors, want %d", goldenPath, len(vectors), len(testCases))
+	}
+	return vectors
+}
+
+// TestGolden fails when the encoding, hashing or signing of any vector changes. Regenerate
+// testdata/vectors.json with -update only for intended changes, which break other
+// implementations checked against the vectors.
+func TestGolden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.WriteFile(goldenPath, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("generated vectors differ from %s; run go test ./vectors -update if the change is intended", goldenPath)
+	}
+}
+
+func TestVerifyGolden(t *testing.T) {
+	for _, v := range golden(t) {
+		if err := Verify(v); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestGoldenSignatures re-signs and recovers every vector through the toolkit's signer
+func TestGoldenSignatures(t *testing.T) {
+	key := Key()
+	for _, v := range golden(t) {
+		t.Run(v.Name, func(t *testing.T) {
+			sig, err := eip2771toolkit.SignMetaTx(v.MetaTx, key, v.DomainSeparator)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sig != v.Signature {
+				t.Errorf("SignMetaTx = %x, want %x", sig.ToBytes(), v.Signature.ToBytes())
+			}
+			if v.Signature.V != 27 && v.Signature.V != 28 {
+				t.Errorf("signature V %d, want 27 or 28", v.Signature.V)
+			}
+
+			valid, err := eip2771toolkit.VerifyMetaTxSignature(v.MetaTx, v.Signature, v.DomainSeparator)
+			if err != nil || !valid {
+				t.Errorf("VerifyMetaTxSignature = %v, %v", valid, err)
+			}
+			// Recovered independently of the toolkit, with V back in {0, 1}
+			raw := v.Signature.ToBytes()
+			raw[64] -= 27
+			pub, err := crypto.SigToPub(v.Digest, raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signer := crypto.PubkeyToAddress(*pub); signer != v.Signer {
+				t.Errorf("signature recovers %s, want %s", signer.Hex(), v.Signer.Hex())
+			}
+		})
+	}
+}
+
+// TestGoldenTypedData hashes the eth_signTypedData_v4 form of every vector with go-ethereum's
+// EIP-712 implementation, which shares no code with the toolkit's encoding
+func TestGoldenTypedData(t *testing.T) {
+	for _, v := range golden(t) {
+		t.Run(v.Name, func(t *testing.T) {
+			encoded, err := json.Marshal(v.TypedData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var typed apitypes.TypedData
+			if err := json.Unmarshal(encoded, &typed); err != nil {
+				t.Fatal(err)
+			}
+
+			domainSeparator, err := typed.HashStruct("EIP712Domain", typed.Domain.Map())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(domainSeparator, v.DomainSeparator) {
+				t.Errorf("domain separator %x, want %x", []byte(domainSeparator), []byte(v.DomainSeparator))
+			}
+			if typeHash := typed.TypeHash(typed.PrimaryType); !bytes.Equal(typeHash, v.TypeHash) {
+				t.Errorf("type hash %x, want %x", []byte(typeHash), []byte(v.TypeHash))
+			}
+			structHash, err := typed.HashStruct(typed.PrimaryType, typed.Message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(structHash, v.StructHash) {
+				t.Errorf("struct hash %x, want %x", []byte(structHash), []byte(v.StructHash))
+			}
+			digest, _, err := apitypes.TypedDataAndHash(typed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(digest, v.Digest) {
+				t.Errorf("digest %x, want %x", digest, []byte(v.Digest))
+			}
+		})
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(v *Vector)
+	}{
+		{"type hash", func(v *Vector) { v.TypeHash[0] ^= 1 }},
+		{"domain separator", func(v *Vector) { v.DomainSeparator[0] ^= 1 }},
+		{"struct hash", func(v *Vector) { v.StructHash[0] ^= 1 }},
+		{"digest", func(v *Vector) { v.Digest[0] ^= 1 }},
+		{"nonce", func(v *Vector) { v.MetaTx.Nonce++ }},
+		{"chain ID", func(v *Vector) { v.Domain.ChainID.SetInt64(2) }},
+		{"signature", func(v *Vector) { v.Signature.S[31] ^= 1 }},
+		{"signer", func(v *Vector) { v.Signer[0] ^= 1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := golden(t)[0]
+			tt.tamper(&v)
+			if err := Verify(v); err == nil {
+				t.Fatal("tampered vector verified")
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// RelayResult is the outcome of a mined relay transaction
+type RelayResult struct {
+	Hash        common.Hash `json:"hash"`
+	BlockNumber uint64      `json:"blockNumber"`
+	BlockHash   common.Hash `json:"blockHash"`
+	GasUsed     uint64      `json:"gasUsed"`
+	GasPrice    *big.Int    `json:"gasPrice,omitempty"` // effective gas price paid, nil in sandbox mode
+	L1Fee       *big.Int    `json:"l1Fee,omitempty"`    // rollup data fee paid on top of the gas, see WithL1FeeEstimator
+	Status      uint64      `json:"status"`             // types.ReceiptStatusSuccessful or types.ReceiptStatusFailed
+}
+
+// newRelayResult summarizes the receipt of a relay transaction
+func newRelayResult(receipt *types.Receipt) RelayResult {
+	return RelayResult{
+		Hash:        receipt.TxHash,
+		BlockNumber: receipt.BlockNumber.Uint64(),
+		BlockHash:   receipt.BlockHash,
+		GasUsed:     receipt.GasUsed,
+		GasPrice:    receipt.EffectiveGasPrice,
+		Status:      receipt.Status,
+	}
+}
+
+// Succeeded reports whether the relay transaction did not revert
+func (r RelayResult) Succeeded() bool {
+	return r.Status == types.ReceiptStatusSuccessful
+}
+
+// waitConfig holds the settings of the wait API
+type waitConfig struct {
+	interval      time.Duration
+	timeout       time.Duration
+	confirmations uint64
+}
+
+// WaitOption configures how long and how often receipts are polled for
+type WaitOption func(*waitConfig)
+
+// WithPollInterval sets how often the receipt is polled for, default 2s
+func WithPollInterval(interval time.Duration) WaitOption {
+	return func(c *waitConfig) {
+		c.interval = interval
+	}
+}
+
+// WithWaitTimeout bounds the wait, default 5 minutes; zero waits until ctx is done
+func WithWaitTimeout(timeout time.Duration) WaitOption {
+	return func(c *waitConfig) {
+		c.timeout = timeout
+	}
+}
+
+// WithConfirmations waits until the relay transaction has n confirmations, counting the
+// block it was mined in as the first, and is still in the canonical chain. The default of 1
+// returns as soon as it is mined.
+func WithConfirmations(n uint64) WaitOption {
+	return func(c *waitConfig) {
+		c.confirmations = n
+	}
+}
+
+// newWaitConfig applies opts to the defaults
+func newWaitConfig(opts []WaitOption) waitConfig {
+	cfg := waitConfig{interval: 2 * time.Second, timeout: 5 * time.Minute, confirmations: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.interval <= 0 {
+		cfg.interval = 2 * time.Second
+	}
+	return cfg
+}
+
+// RelayMetaTxAndWait relays a single meta transaction and waits for it to be mined.
+// A reverted relay transaction is returned with a failed Status, not as an error.
+func (r *Relayer) RelayMetaTxAndWait(ctx context.Context, metaTx MetaTx, sig Signature, opts ...WaitOption) (RelayResult, error) {
+	txHash, err := r.RelayMetaTx(ContextWithRecordRelay(ctx), metaTx, sig)
+	if err != nil {
+		return RelayResult{}, err
+	}
+	result, err := r.WaitForRelay(ctx, txHash, opts...)
+	if err != nil {
+		return result, err
+	}
+	return result, r.RecordRelay(ctx, result, BatchMetaTxRequestList{{MetaTx: metaTx, Signature: sig}})
+}
+
+// RelayMetaTxBatchAndWait relays a batch through executeBatch and waits for it to be mined
+func (r *Relayer) RelayMetaTxBatchAndWait(ctx context.Context, batchRequests BatchMetaTxRequestList, refundReceiver common.Address, opts ...WaitOption) (RelayResult, error) {
+	txHash, err := r.RelayMetaTxBatch(ContextWithRecordRelay(ctx), batchRequests, refundReceiver)
+	if err != nil {
+		return RelayResult{}, err
+	}
+	result, err := r.WaitForRelay(ctx, txHash, opts...)
+	if err != nil {
+		return result, err
+	}
+	return result, r.RecordRelay(ctx, result, batchRequests)
+}
+
+// WaitForRelay polls for the receipt of a relay transaction sent by this relayer. With
+// WithConfirmations it keeps polling until enough blocks were built on top of it; if the
+// transaction is reorganized out in the meantime, waiting continues for its new inclusion.
+func (r *Relayer) WaitForRelay(ctx context.Context, txHash common.Hash, opts ...WaitOption) (RelayResult, error) {
+	cfg := newWaitConfig(opts)
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+
+	for {
+		receipt, err := r.transactionReceipt(ctx, txHash)
+		if err != nil && !errors.Is(err, ethereum.NotFound) {
+			return RelayResult{}, fmt.Errorf("failed to get transaction receipt: %w", err)
+		}
+		if err == nil {
+			confirmed, err := r.confirmed(ctx, receipt, cfg.confirmations)
+			if err != nil {
+				return RelayResult{}, err
+			}
+			if confirmed {
+				result := newRelayResult(receipt)
+				if result.L1Fee, err = r.l1Fee(ctx, receipt); err != nil {
+					return RelayResult{}, err
+				}
+				return result, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return RelayResult{}, fmt.Errorf("timed out waiting for relay transaction %s: %w", txHash.Hex(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// LookupRelay returns the result of whichever of hashes, a relay transaction and its
+// replacements, is mined, or nil if none is yet, without waiting. confirmed reports whether
+// its block has at least confirmations blocks, itself included, in the chain.
+func (r *Relayer) LookupRelay(ctx context.Context, confirmations uint64, hashes ...common.Hash) (result *RelayResult, confirmed bool, err error) {
+	receipt, err := r.findReceipt(ctx, hashes)
+	if receipt == nil || err != nil {
+		return nil, false, err
+	}
+	if confirmed, err = r.confirmed(ctx, receipt, confirmations); err != nil {
+		return nil, false, err
+	}
+	mined := newRelayResult(receipt)
+	if mined.L1Fee, err = r.l1Fee(ctx, receipt); err != nil {
+		return nil, false, err
+	}
+	return &mined, confirmed, nil
+}
+
+// confirmed reports whether the block of receipt has at least confirmations blocks, itself
+// included, in the chain. Sandbox receipts are never mined and count as confirmed.
+func (r *Relayer) confirmed(ctx context.Context, receipt *types.Receipt, confirmations uint64) (bool, error) {
+	if confirmations <= 1 || r.sandbox {
+		return true, nil
+	}
+
+	head, err := r.client.BlockNumber(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get block number: %w", err)
+	}
+	mined := receipt.BlockNumber.Uint64()
+	return head >= mined && head-mined+1 >= confirmations, nil
+}
@@ -0,0 +1,288 @@
+package eip2771toolkit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DEFAULT_EVENT_POLL_INTERVAL is how often WatchForwarderEvents polls for new logs when the
+// client cannot subscribe
+const DEFAULT_EVENT_POLL_INTERVAL = 4 * time.Second
+
+// MAX_LOG_BLOCK_RANGE is how many blocks forwarder events are asked for in one eth_getLogs
+// call, below the limits common node providers enforce
+const MAX_LOG_BLOCK_RANGE = 2000
+
+// ForwarderEvent is an ExecutedForwardRequest event and where it was emitted
+type ForwarderEvent struct {
+	ExecutedForwardRequest
+	RequestID   common.Hash `json:"requestId,omitempty"` // of the known request holding the event's signer nonce, zero if none
+	TxHash      common.Hash `json:"txHash"`
+	BlockNumber uint64      `json:"blockNumber"`
+	BlockHash   common.Hash `json:"blockHash"`
+	Removed     bool        `json:"removed,omitempty"` // the block was reorganized out; the request may execute again
+}
+
+// Known reports whether the event was correlated with a locally known request
+func (e ForwarderEvent) Known() bool {
+	return e.RequestID != (common.Hash{})
+}
+
+// RequestLookup returns the RequestID of the locally known request holding a signer nonce,
+// and false if there is none
+type RequestLookup func(slot SignerNonce) (common.Hash, bool)
+
+// KnownRequests remembers the RequestIDs of the requests a process relays, so that its
+// Lookup method correlates forwarder events with them. It is safe for concurrent use.
+type KnownRequests struct {
+	mu  sync.Mutex
+	ids map[SignerNonce]common.Hash
+}
+
+// NewKnownRequests creates an empty set of known requests
+func NewKnownRequests() *KnownRequests {
+	return &KnownRequests{ids: make(map[SignerNonce]common.Hash)}
+}
+
+// Add remembers id as the RequestID of the request holding slot
+func (k *KnownRequests) Add(id common.Hash, slot SignerNonce) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.ids[slot] = id
+}
+
+// Remove forgets the request holding slot
+func (k *KnownRequests) Remove(slot SignerNonce) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.ids, slot)
+}
+
+// Lookup returns the RequestID of the request holding slot. It is a RequestLookup.
+func (k *KnownRequests) Lookup(slot SignerNonce) (common.Hash, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	id, ok := k.ids[slot]
+	return id, ok
+}
+
+// watchConfig holds the settings of WatchForwarderEvents
+type watchConfig struct {
+	fromBlock *uint64
+	interval  time.Duration
+	lookup    RequestLookup
+}
+
+// WatchOption configures WatchForwarderEvents
+type WatchOption func(*watchConfig)
+
+// WithWatchFromBlock also delivers the events of past blocks from block on, default only
+// those of new blocks
+func WithWatchFromBlock(block uint64) WatchOption {
+	return func(c *watchConfig) {
+		c.fromBlock = &block
+	}
+}
+
+// WithWatchPollInterval sets how often logs are polled for when the client cannot subscribe,
+// default DEFAULT_EVENT_POLL_INTERVAL
+func WithWatchPollInterval(interval time.Duration) WatchOption {
+	return func(c *watchConfig) {
+		c.interval = interval
+	}
+}
+
+// WithRequestLookup correlates events with locally known requests, e.g. KnownRequests.Lookup
+// or a lookup in the application's database
+func WithRequestLookup(lookup RequestLookup) WatchOption {
+	return func(c *watchConfig) {
+		c.lookup = lookup
+	}
+}
+
+// WatchForwarderEvents sends the ExecutedForwardRequest events forwarder emits to ch, in
+// chain order, until ctx is done or the client fails. Unlike a relay receipt, an event is
+// authoritative for every request it names, whoever relayed it. It subscribes with
+// eth_subscribe, which also reports events reorganized out with Removed set; when the client
+// cannot subscribe (e.g. over HTTP) it polls eth_getLogs instead, which does not.
+func WatchForwarderEvents(ctx context.Context, forwarder common.Address, client EthClient, ch chan<- ForwarderEvent, opts ...WatchOption) error {
+	cfg := watchConfig{interval: DEFAULT_EVENT_POLL_INTERVAL}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.interval <= 0 {
+		cfg.interval = DEFAULT_EVENT_POLL_INTERVAL
+	}
+	w := &forwarderWatch{forwarder: forwarder, client: client, ch: ch, lookup: cfg.lookup}
+
+	logs := make(chan types.Log, 64)
+	sub, err := client.SubscribeFilterLogs(ctx, w.query(nil, nil), logs)
+	if err != nil {
+		return w.poll(ctx, cfg)
+	}
+	defer sub.Unsubscribe()
+
+	// Catch up after subscribing, so that no block falls between the two
+	var caughtUp uint64
+	if cfg.fromBlock != nil {
+		if caughtUp, err = client.BlockNumber(ctx); err != nil {
+			return fmt.Errorf("failed to get block number: %w", err)
+		}
+		if err := w.deliverRange(ctx, *cfg.fromBlock, caughtUp); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			return fmt.Errorf("forwarder event subscription failed: %w", err)
+		case log := <-logs:
+			if cfg.fromBlock != nil && log.BlockNumber <= caughtUp && !log.Removed {
+				continue // delivered while catching up
+			}
+			if err := w.deliver(ctx, log); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// WatchEvents watches the events of the relayer's forwarder, see WatchForwarderEvents
+func (r *Relayer) WatchEvents(ctx context.Context, ch chan<- ForwarderEvent, opts ...WatchOption) error {
+	return WatchForwarderEvents(ctx, r.forwarder, r.client, ch, opts...)
+}
+
+// FilterForwarderEvents returns the ExecutedForwardRequest events forwarder emitted in blocks
+// [from, to], in chain order. lookup, if not nil, correlates them with known requests.
+func FilterForwarderEvents(ctx context.Context, forwarder common.Address, client EthClient, from, to uint64, lookup RequestLookup) ([]ForwarderEvent, error) {
+	w := &forwarderWatch{forwarder: forwarder, client: client, lookup: lookup}
+	var events []ForwarderEvent
+	err := w.filter(ctx, from, to, func(log types.Log) error {
+		if event, ok := w.event(log); ok {
+			events = append(events, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// forwarderWatch delivers the events of one forwarder
+type forwarderWatch struct {
+	forwarder common.Address
+	client    EthClient
+	ch        chan<- ForwarderEvent
+	lookup    RequestLookup
+}
+
+// query selects the forwarder's ExecutedForwardRequest logs in [from, to]; nil bounds are open
+func (w *forwarderWatch) query(from, to *big.Int) ethereum.FilterQuery {
+	return ethereum.FilterQuery{
+		FromBlock: from,
+		ToBlock:   to,
+		Addresses: []common.Address{w.forwarder},
+		Topics:    [][]common.Hash{{EXECUTED_FORWARD_REQUEST_TOPIC}},
+	}
+}
+
+// poll delivers the events of new blocks every interval
+func (w *forwarderWatch) poll(ctx context.Context, cfg watchConfig) error {
+	head, err := w.client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block number: %w", err)
+	}
+	next := head + 1
+	if cfg.fromBlock != nil {
+		next = *cfg.fromBlock
+	}
+
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+	for {
+		if head >= next {
+			if err := w.deliverRange(ctx, next, head); err != nil {
+				return err
+			}
+			next = head + 1
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if head, err = w.client.BlockNumber(ctx); err != nil {
+			return fmt.Errorf("failed to get block number: %w", err)
+		}
+	}
+}
+
+// deliverRange delivers the events of blocks [from, to]
+func (w *forwarderWatch) deliverRange(ctx context.Context, from, to uint64) error {
+	return w.filter(ctx, from, to, func(log types.Log) error {
+		return w.deliver(ctx, log)
+	})
+}
+
+// filter calls fn with the forwarder's logs of blocks [from, to], asking for
+// MAX_LOG_BLOCK_RANGE blocks at a time
+func (w *forwarderWatch) filter(ctx context.Context, from, to uint64, fn func(types.Log) error) error {
+	for start := from; start <= to; start += MAX_LOG_BLOCK_RANGE {
+		end := min(start+MAX_LOG_BLOCK_RANGE-1, to)
+		logs, err := w.client.FilterLogs(ctx, w.query(new(big.Int).SetUint64(start), new(big.Int).SetUint64(end)))
+		if err != nil {
+			return fmt.Errorf("failed to get forwarder logs of blocks %d-%d: %w", start, end, err)
+		}
+		for _, log := range logs {
+			if err := fn(log); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deliver sends the event of log to the channel, skipping logs that are not one
+func (w *forwarderWatch) deliver(ctx context.Context, log types.Log) error {
+	event, ok := w.event(log)
+	if !ok {
+		return nil
+	}
+	select {
+	case w.ch <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// event decodes log, and returns false if it is not an event of the forwarder
+func (w *forwarderWatch) event(log types.Log) (ForwarderEvent, bool) {
+	executed, ok := ParseExecutedForwardRequest(&log)
+	if !ok || log.Address != w.forwarder {
+		return ForwarderEvent{}, false
+	}
+	event := ForwarderEvent{
+		ExecutedForwardRequest: executed,
+		TxHash:                 log.TxHash,
+		BlockNumber:            log.BlockNumber,
+		BlockHash:              log.BlockHash,
+		Removed:                log.Removed,
+	}
+	if w.lookup != nil && executed.Nonce.IsUint64() {
+		if id, ok := w.lookup(SignerNonce{From: executed.Signer, Nonce: executed.Nonce.Uint64()}); ok {
+			event.RequestID = id
+		}
+	}
+	return event, true
+}